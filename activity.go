@@ -0,0 +1,124 @@
+package repodb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// operation names recorded in commit trailers and hook events
+const (
+	OpCommit       = "commit"
+	OpWriteFile    = "write_file"
+	OpWriteFiles   = "write_files"
+	OpRemoveFile   = "remove_file"
+	OpWriteMeta    = "write_meta"
+	OpRemoveMeta   = "remove_meta"
+	OpVacuumMeta   = "vacuum_meta"
+	OpRenameRepo   = "rename_repo"
+	OpRenameRecord = "rename_record"
+	OpCopyRecord   = "copy_record"
+	OpRollback     = "rollback"
+	OpRevertFile   = "revert_file"
+	OpDeleteWhere  = "delete_where"
+	OpPurgeDeleted = "purge_deleted"
+	OpRepairMeta   = "repair_meta"
+)
+
+// commit message trailer keys
+const (
+	trailerOperation      = "Repodb-Operation"
+	trailerRecord         = "Repodb-Record"
+	trailerIdempotencyKey = "Repodb-Idempotency-Key"
+)
+
+// Activity is a simplified view of an operation committed to a repo, for displaying
+// recent changes without knowledge of git.
+type Activity struct {
+	Operation      string
+	Record         string // folder/name of the changed record, if any
+	Actor          string // commit author name
+	IdempotencyKey string
+	Time           time.Time
+	Message        string // commit message, without trailers
+	Hash           string
+}
+
+// RecentActivity returns up to the last n operations committed to the repo, newest
+// first. Commits made outside of repodb are returned with operation OpCommit.
+func (repo *Repo) RecentActivity(n int) ([]Activity, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := r.Log(&git.LogOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read log of %s: %v", repo.Name, err)
+	}
+
+	activity := []Activity{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if len(activity) >= n {
+			return storer.ErrStop
+		}
+		a := parseTrailers(c.Message)
+		a.Actor = c.Author.Name
+		a.Time = c.Author.When
+		a.Hash = c.Hash.String()
+		activity = append(activity, a)
+		return nil
+	})
+	return activity, err
+}
+
+// trailers returns the repodb commit message trailers for the operation, record and
+// idempotency key
+func trailers(op, record, key string) string {
+	t := fmt.Sprintf("%s: %s", trailerOperation, op)
+	if record != "" {
+		t += fmt.Sprintf("\n%s: %s", trailerRecord, record)
+	}
+	if key != "" {
+		t += fmt.Sprintf("\n%s: %s", trailerIdempotencyKey, key)
+	}
+	return t
+}
+
+// parseTrailers splits the repodb trailers from the commit message
+func parseTrailers(msg string) Activity {
+	a := Activity{Operation: OpCommit, Message: strings.TrimSpace(msg)}
+	i := strings.LastIndex(a.Message, "\n\n")
+	if i < 0 {
+		return a
+	}
+	found := false
+	for _, line := range strings.Split(a.Message[i+2:], "\n") {
+		kv := strings.SplitN(line, ": ", 2)
+		if len(kv) != 2 {
+			return a
+		}
+		switch kv[0] {
+		case trailerOperation:
+			a.Operation = kv[1]
+			found = true
+		case trailerRecord:
+			a.Record = kv[1]
+		case trailerIdempotencyKey:
+			a.IdempotencyKey = kv[1]
+		}
+	}
+	if found {
+		a.Message = strings.TrimSpace(a.Message[:i])
+	}
+	return a
+}
@@ -0,0 +1,54 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_RecentActivity(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "ActivityRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "active.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("body"), repodb.CommitOptions{Msg: "add file", Opts: repodb.DBRepoCommitOptions.Opts}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RemoveFile(fr, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.RecentActivity(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Repo.RecentActivity() len = %v, want %v", len(got), 2)
+	}
+	want := []struct {
+		op, record, msgPrefix string
+	}{
+		{repodb.OpRemoveFile, "files/active.txt", repodb.DBRepoName},
+		{repodb.OpWriteFile, "files/active.txt", "add file"},
+	}
+	for i, w := range want {
+		a := got[i]
+		if a.Operation != w.op || a.Record != w.record || !strings.HasPrefix(a.Message, w.msgPrefix) || a.Actor != "repodb" {
+			t.Errorf("Repo.RecentActivity()[%d] = %+v, want %+v", i, a, w)
+		}
+		if strings.Contains(a.Message, "Repodb-") {
+			t.Errorf("Repo.RecentActivity()[%d] Message contains trailers: %q", i, a.Message)
+		}
+	}
+
+	all, err := repo.RecentActivity(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[2].Operation != repodb.OpWriteMeta {
+		t.Errorf("Repo.RecentActivity(100) = %+v", all)
+	}
+}
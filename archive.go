@@ -0,0 +1,156 @@
+package repodb
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ArchiveFormat is the file format written by Archive
+type ArchiveFormat int
+
+// archive formats
+const (
+	ArchiveTarGz ArchiveFormat = iota + 1
+	ArchiveZip
+)
+
+// String returns the file extension for the format
+func (f ArchiveFormat) String() string {
+	switch f {
+	case ArchiveTarGz:
+		return "tar.gz"
+	case ArchiveZip:
+		return "zip"
+	}
+	return fmt.Sprintf("ArchiveFormat(%d)", int(f))
+}
+
+// Archive writes the repo worktree at the commit to w in the format, with all entries
+// under a directory named after the repo. A zero commit archives HEAD. Files are
+// archived as committed, records written WithEncryption remain encrypted.
+func (repo *Repo) Archive(w io.Writer, format ArchiveFormat, commit Hash) (err error) {
+	defer repo.DB.metrics.observe("archive", time.Now(), &err)
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return err
+	}
+	if format != ArchiveTarGz && format != ArchiveZip {
+		return fmt.Errorf("unsupported archive format %v", format)
+	}
+
+	repo.DB.metrics.lock("repo", repo.RLocker())
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	hash := commit.Git()
+	if hash.IsZero() {
+		if hash, err = repo.head(); err != nil {
+			return err
+		}
+	}
+	c, err := r.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("unable to archive %s at %s: %v", repo.Name, hash, err)
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return fmt.Errorf("unable to archive %s at %s: %v", repo.Name, hash, err)
+	}
+
+	if format == ArchiveZip {
+		err = repo.archiveZip(w, tree, c.Committer.When)
+	} else {
+		err = repo.archiveTarGz(w, tree, c.Committer.When)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to archive %s at %s: %v", repo.Name, hash, err)
+	}
+	return nil
+}
+
+// archiveTarGz writes the tree files to w as a gzipped tarball
+func (repo *Repo) archiveTarGz(w io.Writer, tree *object.Tree, modTime time.Time) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	err := tree.Files().ForEach(func(f *object.File) error {
+		rc, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		hdr := &tar.Header{
+			Name:    path.Join(repo.Name, f.Name),
+			Size:    f.Size,
+			ModTime: modTime,
+			Mode:    0644,
+		}
+		switch f.Mode {
+		case filemode.Executable:
+			hdr.Mode = 0755
+		case filemode.Symlink:
+			b, err := ioutil.ReadAll(rc)
+			if err != nil {
+				return err
+			}
+			hdr.Typeflag, hdr.Linkname, hdr.Size, hdr.Mode = tar.TypeSymlink, string(b), 0, 0777
+			return tw.WriteHeader(hdr)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, rc)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// archiveZip writes the tree files to w as a zip file
+func (repo *Repo) archiveZip(w io.Writer, tree *object.Tree, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	err := tree.Files().ForEach(func(f *object.File) error {
+		rc, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+		hdr := &zip.FileHeader{
+			Name:     path.Join(repo.Name, f.Name),
+			Method:   zip.Deflate,
+			Modified: modTime,
+		}
+		hdr.SetMode(mode)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, rc)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,123 @@
+package repodb_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+// archiveFiles returns the file contents of a tar.gz or zip archive by name
+func archiveFiles(t *testing.T, format repodb.ArchiveFormat, b []byte) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	switch format {
+	case repodb.ArchiveTarGz:
+		gr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := tar.NewReader(gr)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := ioutil.ReadAll(tr)
+			files[hdr.Name] = string(content)
+		}
+	case repodb.ArchiveZip:
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := ioutil.ReadAll(rc)
+			rc.Close()
+			files[f.Name] = string(content)
+		}
+	}
+	return files
+}
+
+func TestRepo_Archive(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "ArchiveRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("first"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	first, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("second"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "b.txt"}, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		format  repodb.ArchiveFormat
+		commit  repodb.Hash
+		want    map[string]string
+		wantErr bool
+	}{
+		{"tar.gz head", repodb.ArchiveTarGz, repodb.ZeroHash, map[string]string{"ArchiveRepo/files/a.txt": "second", "ArchiveRepo/files/b.txt": "b"}, false},
+		{"zip head", repodb.ArchiveZip, repodb.ZeroHash, map[string]string{"ArchiveRepo/files/a.txt": "second", "ArchiveRepo/files/b.txt": "b"}, false},
+		{"tar.gz commit", repodb.ArchiveTarGz, first, map[string]string{"ArchiveRepo/files/a.txt": "first"}, false},
+		{"zip commit", repodb.ArchiveZip, first, map[string]string{"ArchiveRepo/files/a.txt": "first"}, false},
+		{"unknown commit", repodb.ArchiveZip, repodb.Hash{0x01, 0x23, 0x45}, nil, true},
+		{"unknown format", repodb.ArchiveFormat(0), repodb.ZeroHash, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := repo.Archive(buf, tt.format, tt.commit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repo.Archive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := archiveFiles(t, tt.format, buf.Bytes())
+			// meta-data files are archived too, only compare record files
+			for name := range got {
+				if !strings.HasPrefix(name, "ArchiveRepo/files/") || strings.Contains(name, repodb.MetaDir) {
+					delete(got, name)
+				}
+			}
+			if len(got) != len(tt.want) {
+				var names []string
+				for name := range got {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				t.Fatalf("Repo.Archive() files = %v, want %v", names, tt.want)
+			}
+			for name, content := range tt.want {
+				if got[name] != content {
+					t.Errorf("Repo.Archive() %s = %q, want %q", name, got[name], content)
+				}
+			}
+		})
+	}
+}
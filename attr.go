@@ -0,0 +1,188 @@
+package repodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileAttr are file attributes stored with a record, so files imported from other
+// systems round-trip faithfully. Git itself keeps neither.
+type FileAttr struct {
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mod_time"`
+}
+
+// attrSuffix ends the attribute file of a record in its meta-data directory. It is not
+// a .json file, so it is never taken for record meta-data.
+const attrSuffix = ".attr"
+
+// attrPath returns the repo relative path of the record attribute file
+func attrPath(rec Record) string {
+	return path.Join(rec.Folder(), MetaDir, rec.FileName()+attrSuffix)
+}
+
+// attrRecord returns the record file path of the repo relative attribute file path
+func attrRecord(name string) (string, bool) {
+	dir, file := path.Split(name)
+	if path.Base(dir) != MetaDir || !strings.HasSuffix(file, attrSuffix) {
+		return "", false
+	}
+	return path.Join(path.Dir(path.Clean(dir)), strings.TrimSuffix(file, attrSuffix)), true
+}
+
+// recordAttr is the attribute file of a record, the attributes stored by WriteFileAttr,
+// if any, the codec compressing the record file, if any, whether the record file is a
+// pointer to content in the blob store, and the size and sha256 of the content written
+type recordAttr struct {
+	*FileAttr
+	Codec  string `json:"codec,omitempty"`
+	Blob   bool   `json:"blob,omitempty"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// WriteFileAttr writes the record file like WriteFile, storing attr in the same commit
+// to be returned by Stat. Writing the record any other way, such as by WriteFile,
+// removes the stored attributes as they no longer describe the content.
+func (repo *Repo) WriteFileAttr(rec Record, r io.Reader, attr FileAttr, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("write_file_attr", time.Now(), &err)
+	return repo.writeRecord(rec, r, &attr, opts)
+}
+
+// WriteFileInfo is WriteFileAttr with the mode and modification time of fi, such as
+// from os.Stat when importing a file
+func (repo *Repo) WriteFileInfo(rec Record, r io.Reader, fi os.FileInfo, opts CommitOptions) error {
+	if fi.IsDir() {
+		return fmt.Errorf("WriteFileInfo requires a file, %s is a directory", fi.Name())
+	}
+	return repo.WriteFileAttr(rec, r, FileAttr{Mode: fi.Mode(), ModTime: fi.ModTime()}, opts)
+}
+
+// Stat returns the file info of the record. Mode and ModTime are the attributes stored
+// by WriteFileAttr, otherwise 0644 and the time of the commit last changing the file.
+// Size is that of the content read by ReadFile, so encrypted files and files in the
+// blob store are read to learn it. Returns ErrRecordNotExists if there is no record file.
+func (repo *Repo) Stat(rec Record) (os.FileInfo, error) {
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	key, err := repo.encKey()
+	if err != nil {
+		return nil, err
+	}
+	attr, err := repo.readAttr(rec)
+	if err != nil {
+		return nil, err
+	}
+	info := &recordInfo{name: rec.FileName(), mode: 0644}
+	fi, err := repo.DB.fs.Stat(path.Join(repo.Dir(), rec.Folder(), rec.FileName()))
+	if err == nil && key == nil && !attr.Blob && attr.Codec == "" {
+		info.size = fi.Size()
+	} else {
+		f, err := repo.openFile(rec)
+		if err != nil {
+			return nil, notExist(ErrRecordNotExists, rec, err)
+		}
+		defer f.Close()
+		if info.size, err = io.Copy(ioutil.Discard, f); err != nil {
+			return nil, err
+		}
+	}
+
+	if attr.FileAttr != nil {
+		info.mode, info.modTime = attr.Mode, attr.ModTime
+	} else if info.modTime, err = repo.committedAt(rec); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// loadAttr returns the stored attributes of the record, or nil if it has none. The
+// caller must hold the repo lock.
+func (repo *Repo) loadAttr(rec Record) (*FileAttr, error) {
+	attr, err := repo.readAttr(rec)
+	if err != nil {
+		return nil, err
+	}
+	return attr.FileAttr, nil
+}
+
+// readAttr reads the attribute file of the record, empty if it has none. The caller
+// must hold the repo lock.
+func (repo *Repo) readAttr(rec Record) (*recordAttr, error) {
+	var b []byte
+	var err error
+	if repo.isBare() {
+		var rc io.ReadCloser
+		if rc, err = repo.openBare(attrPath(rec)); err == nil {
+			b, err = ioutil.ReadAll(rc)
+			rc.Close()
+		}
+	} else {
+		b, err = repo.DB.readFile(path.Join(repo.Dir(), attrPath(rec)))
+	}
+	if os.IsNotExist(err) {
+		return &recordAttr{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read attributes of %s: %v", path.Join(rec.Folder(), rec.FileName()), err)
+	}
+	attr := &recordAttr{}
+	if err := json.Unmarshal(b, attr); err != nil {
+		return nil, fmt.Errorf("invalid attributes of %s: %v", path.Join(rec.Folder(), rec.FileName()), err)
+	}
+	return attr, nil
+}
+
+// writeAttr writes the record attributes to be committed with the record file, or
+// removes them if attr is nil. The caller must hold the repo lock.
+func (repo *Repo) writeAttr(rec Record, attr *recordAttr) error {
+	name := attrPath(rec)
+	if attr == nil {
+		if repo.staged != nil {
+			if _, err := repo.bareFile(name); err != nil {
+				return nil
+			}
+			return repo.stageRemove(name)
+		}
+		if err := repo.DB.fs.Remove(path.Join(repo.Dir(), name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	b, err := json.Marshal(attr)
+	if err != nil {
+		return err
+	}
+	if repo.staged != nil {
+		_, err = repo.stageFile(name, bytes.NewReader(b), nil)
+		return err
+	}
+	return repo.DB.writeFile(path.Join(repo.Dir(), name), b, 0600)
+}
+
+// recordInfo is the os.FileInfo of a record file returned by Stat
+type recordInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i *recordInfo) Name() string       { return i.name }
+func (i *recordInfo) Size() int64        { return i.size }
+func (i *recordInfo) Mode() os.FileMode  { return i.mode }
+func (i *recordInfo) ModTime() time.Time { return i.modTime }
+func (i *recordInfo) IsDir() bool        { return false }
+func (i *recordInfo) Sys() interface{}   { return nil }
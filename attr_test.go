@@ -0,0 +1,81 @@
+package repodb_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_WriteFileInfo(t *testing.T) {
+	imported := filepath.Join(t.TempDir(), "script.sh")
+	if err := ioutil.WriteFile(imported, []byte("#!/bin/sh\necho hello\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(imported, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+		{"encrypted", []repodb.Option{repodb.WithEncryption(repoKeys{"Attr": bytes.Repeat([]byte{3}, 32)})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), tt.opts...)
+			repo := &repodb.Repo{Name: "Attr", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(imported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			rec := &FileRecord{Name: "script.sh"}
+			if err := repo.WriteFileInfo(rec, f, fi, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := repo.Stat(rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name() != "script.sh" || got.Size() != fi.Size() || got.Mode() != fi.Mode() || !got.ModTime().Equal(modTime) {
+				t.Errorf("Repo.Stat() = %s %d %v %v, want %s %d %v %v", got.Name(), got.Size(), got.Mode(), got.ModTime(), fi.Name(), fi.Size(), fi.Mode(), modTime)
+			}
+
+			// attributes move with the record and are dropped by a plain write
+			if tt.name != "bare" {
+				if err := repo.RenameRecord(rec, "run.sh", repodb.DBRepoCommitOptions); err != nil {
+					t.Fatal(err)
+				}
+				rec = &FileRecord{Name: "run.sh"}
+				if got, err := repo.Stat(rec); err != nil || got.Mode() != fi.Mode() {
+					t.Errorf("Repo.Stat() after rename = %v, error = %v", got, err)
+				}
+			}
+			if err := repo.WriteFile(rec, strings.NewReader("echo changed"), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			got, err = repo.Stat(rec)
+			if err != nil || got.Mode() != 0644 || got.Size() != int64(len("echo changed")) || got.ModTime().Before(modTime) {
+				t.Errorf("Repo.Stat() after WriteFile = %v, error = %v", got, err)
+			}
+		})
+	}
+}
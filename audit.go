@@ -0,0 +1,138 @@
+package repodb
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// LogFormat is the output format of ExportAuditLog
+type LogFormat int
+
+// audit log formats
+const (
+	LogJSONL LogFormat = iota // one json AuditEntry per line
+	LogCEF                    // ArcSight Common Event Format, one event per line
+)
+
+// AuditEntry is an operation committed to a repo in the database, as exported by
+// ExportAuditLog
+type AuditEntry struct {
+	Repo string
+	Activity
+	ActorEmail string
+}
+
+// ExportAuditLog writes the operations committed to every repo in the database since
+// the time to w, oldest first, for ingestion by security information systems. The
+// entries are read from the commit history and trailers of each repo, so operations
+// of removed repos are not included.
+func (db *RepoDB) ExportAuditLog(since time.Time, w io.Writer, format LogFormat) (err error) {
+	defer db.metrics.observe("export_audit_log", time.Now(), &err)
+	if format != LogJSONL && format != LogCEF {
+		return fmt.Errorf("unknown audit log format %d", format)
+	}
+	repos, err := db.ListReposPage(ListOptions{SkipMeta: true})
+	if err != nil {
+		return err
+	}
+	entries := []AuditEntry{}
+	for _, repo := range repos {
+		e, err := repo.auditLog(since)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, e...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, e := range entries {
+		if format == LogCEF {
+			_, err = fmt.Fprintln(bw, e.cef())
+		} else {
+			err = enc.Encode(e)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write audit log: %v", err)
+		}
+	}
+	return bw.Flush()
+}
+
+// auditLog returns the operations committed to the repo since the time, oldest first
+func (repo *Repo) auditLog(since time.Time) ([]AuditEntry, error) {
+	repo.RLock()
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := r.Log(&git.LogOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read log of %s: %v", repo.Name, err)
+	}
+	entries := []AuditEntry{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.Author.When.Before(since) {
+			return nil
+		}
+		a := parseTrailers(c.Message)
+		a.Actor = c.Author.Name
+		a.Time = c.Author.When
+		a.Hash = c.Hash.String()
+		entries = append(entries, AuditEntry{Repo: repo.Name, Activity: a, ActorEmail: c.Author.Email})
+		return nil
+	})
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, err
+}
+
+// cefSeverity is the CEF severity of operations removing records, other operations
+// are reported with severity 3
+var cefSeverity = map[string]int{
+	OpRemoveFile:      5,
+	OpRemoveMeta:      5,
+	OpScheduledDelete: 5,
+}
+
+// cef formats the entry as a Common Event Format line
+func (e AuditEntry) cef() string {
+	header := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	ext := strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	severity, ok := cefSeverity[e.Operation]
+	if !ok {
+		severity = 3
+	}
+	fields := []string{
+		"rt=" + fmt.Sprint(e.Time.UnixNano()/int64(time.Millisecond)),
+		"suser=" + ext.Replace(e.Actor),
+		"suid=" + ext.Replace(e.ActorEmail),
+		"cs1Label=repo",
+		"cs1=" + ext.Replace(e.Repo),
+		"cs2Label=commit",
+		"cs2=" + e.Hash,
+	}
+	if e.Record != "" {
+		fields = append(fields, "fname="+ext.Replace(e.Record))
+	}
+	if e.IdempotencyKey != "" {
+		fields = append(fields, "cs3Label=idempotencyKey", "cs3="+ext.Replace(e.IdempotencyKey))
+	}
+	fields = append(fields, "msg="+ext.Replace(e.Message))
+	return fmt.Sprintf("CEF:0|readpe|repodb|1|%s|%s|%d|%s",
+		header.Replace(e.Operation), header.Replace(e.Operation+" "+e.Repo), severity, strings.Join(fields, " "))
+}
@@ -0,0 +1,86 @@
+package repodb_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_ExportAuditLog(t *testing.T) {
+	db := newTestDB(t)
+	a := &repodb.Repo{Name: "a", DB: db}
+	b := &repodb.Repo{Name: "b", DB: db}
+	for _, repo := range []*repodb.Repo{a, b} {
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// git commit times have second precision
+	time.Sleep(time.Second)
+	since := time.Now().Truncate(time.Second)
+
+	opts := repodb.DBRepoCommitOptions
+	opts.Msg = "import\nkey=value | pipe"
+	if err := a.WriteFile(&FileRecord{Name: "1.txt"}, strings.NewReader("1"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.WriteFile(&FileRecord{Name: "2.txt"}, strings.NewReader("2"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RemoveFile(&FileRecord{Name: "1.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := db.ExportAuditLog(since, buf, repodb.LogJSONL); err != nil {
+		t.Fatal(err)
+	}
+	// commits of different repos in the same second may be in either order
+	got := map[string][]string{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		e := repodb.AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		got[e.Repo] = append(got[e.Repo], e.Operation+" "+e.Record)
+	}
+	want := map[string][]string{
+		"a": {"write_file files/1.txt", "remove_file files/1.txt"},
+		"b": {"write_file files/2.txt"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("RepoDB.ExportAuditLog() JSONL = %v, want %v", got, want)
+	}
+
+	buf.Reset()
+	if err := db.ExportAuditLog(since, buf, repodb.LogCEF); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("RepoDB.ExportAuditLog() CEF lines = %d, want 3:\n%s", len(lines), buf.String())
+	}
+	cef := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"CEF:0|readpe|repodb|1|write_file|write_file a|3|",
+		"CEF:0|readpe|repodb|1|remove_file|remove_file a|5|",
+		"cs1=b",
+		"fname=files/1.txt",
+		`msg=import\nkey\=value | pipe`,
+	} {
+		if !strings.Contains(cef, want) {
+			t.Errorf("RepoDB.ExportAuditLog() CEF = %s, want %s", cef, want)
+		}
+	}
+
+	if err := db.ExportAuditLog(since, buf, repodb.LogFormat(10)); err == nil {
+		t.Errorf("RepoDB.ExportAuditLog() expected error for unknown format")
+	}
+}
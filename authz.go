@@ -0,0 +1,87 @@
+package repodb
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// ErrPermissionDenied is returned when the Authorizer denies an operation, it is also
+// os.ErrPermission
+var ErrPermissionDenied error = &errorKind{"permission denied", os.ErrPermission}
+
+// Action is the kind of access an operation needs
+type Action string
+
+// actions
+const (
+	ActionRead   Action = "read"   // read records, meta-data and listings
+	ActionWrite  Action = "write"  // write or change records and meta-data
+	ActionDelete Action = "delete" // remove records or meta-data
+)
+
+// Authorizer decides whether the subject may perform the action on the record of the
+// repo, the slash separated record path or folder, or "" for the whole repo. Returning
+// an error denies the operation.
+type Authorizer interface {
+	Authorize(subject string, action Action, repo, record string) error
+}
+
+// WithAuthorizer checks the record, history and maintenance operations of repos opened
+// by OpenRepoAs with a, before they are performed. Operations on the whole repo, such
+// as FS, Archive or GC, are checked with an empty record. Repos opened otherwise, such
+// as by the DB itself, have no subject and are not checked.
+func WithAuthorizer(a Authorizer) Option {
+	return func(db *RepoDB) {
+		db.authorizer = a
+	}
+}
+
+// OpenRepoAs opens the repo like OpenRepo, for the subject such as an application user.
+// The subject needs ActionRead on the repo to open it, and the Authorizer of the DB is
+// consulted before each record operation on the returned repo.
+func (db *RepoDB) OpenRepoAs(name, subject string) (_ *Repo, err error) {
+	defer db.metrics.observe("open_repo", time.Now(), &err)
+	repo, err := db.locateRepo(name)
+	if err != nil {
+		return nil, err
+	}
+	repo.subject = subject
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	if err := repo.loadRepoMeta(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Subject returns the subject the repo was opened for by OpenRepoAs, if any
+func (repo *Repo) Subject() string {
+	return repo.subject
+}
+
+// authorize checks the subject of the repo may perform the action on the record, nil
+// or the repo itself for the whole repo
+func (repo *Repo) authorize(action Action, rec Record) error {
+	if repo.DB.authorizer == nil || repo.subject == "" {
+		return nil
+	}
+	record := ""
+	if _, ok := rec.(*Repo); rec != nil && !ok {
+		record = path.Join(rec.Folder(), rec.FileName())
+	}
+	if err := repo.DB.authorizer.Authorize(repo.subject, action, repo.Name, record); err != nil {
+		return fmt.Errorf("%w: %s may not %s %s: %v", ErrPermissionDenied, repo.subject, action, path.Join(repo.Name, record), err)
+	}
+	return nil
+}
+
+// authorizeFolder checks the subject of the repo may perform the action on the folder
+func (repo *Repo) authorizeFolder(action Action, folder string) error {
+	if folder == "" {
+		return repo.authorize(action, nil)
+	}
+	return repo.authorize(action, &recordRef{folder: path.Dir(folder), name: path.Base(folder)})
+}
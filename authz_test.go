@@ -0,0 +1,285 @@
+package repodb_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+// grants authorizes the actions listed per subject
+type grants map[string][]repodb.Action
+
+func (g grants) Authorize(subject string, action repodb.Action, repo, record string) error {
+	for _, a := range g[subject] {
+		if a == action {
+			return nil
+		}
+	}
+	return fmt.Errorf("no %s grant", action)
+}
+
+func TestWithAuthorizer(t *testing.T) {
+	g := grants{
+		"reader":  {repodb.ActionRead},
+		"writer":  {repodb.ActionRead, repodb.ActionWrite},
+		"revoked": {repodb.ActionRead},
+	}
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithAuthorizer(g))
+	if err := db.CreateRepo(&repodb.Repo{Name: "AuthzRepo", DB: db}); err != nil {
+		t.Fatal(err)
+	}
+	rec := &FileRecord{Name: "a.txt"}
+
+	if _, err := db.OpenRepoAs("AuthzRepo", "nobody"); !errors.Is(err, repodb.ErrPermissionDenied) {
+		t.Errorf("RepoDB.OpenRepoAs() error = %v, want %v", err, repodb.ErrPermissionDenied)
+	}
+	writer, err := db.OpenRepoAs("AuthzRepo", "writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := db.OpenRepoAs("AuthzRepo", "reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := db.OpenRepoAs("AuthzRepo", "revoked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g["revoked"] = nil
+	if err := writer.WriteFile(rec, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fn      func() error
+		allowed bool
+	}{
+		{"read", func() error { _, err := reader.ReadFile(rec, &bytes.Buffer{}); return err }, true},
+		{"load meta", func() error { return reader.LoadMeta(&FileRecord{Name: "a.txt"}) }, true},
+		{"list", func() error { _, err := reader.ListRecords("files", false); return err }, true},
+		{"write", func() error { return reader.WriteFile(rec, strings.NewReader("b"), repodb.DBRepoCommitOptions) }, false},
+		{"write meta", func() error { return reader.WriteMeta(rec, repodb.DBRepoCommitOptions) }, false},
+		{"remove", func() error { return writer.RemoveFile(rec, repodb.DBRepoCommitOptions) }, false},
+		{"rename", func() error { return writer.RenameRecord(rec, "b.txt", repodb.DBRepoCommitOptions) }, false},
+		{"remove meta", func() error { return writer.RemoveMeta(rec, repodb.DBRepoCommitOptions) }, false},
+		{"fs", func() error { _, err := revoked.FS(); return err }, false},
+		{"fs at", func() error { _, err := revoked.FSAt(repodb.Hash{}); return err }, false},
+		{"archive", func() error { return revoked.Archive(&bytes.Buffer{}, repodb.ArchiveZip, repodb.Hash{}) }, false},
+		{"diff", func() error { _, err := revoked.DiffFile(rec, repodb.Hash{}, repodb.Hash{}); return err }, false},
+		{"blame", func() error { _, err := revoked.BlameFile(rec); return err }, false},
+		{"search", func() error { _, err := revoked.Search("a"); return err }, false},
+		{"history", func() error { _, err := revoked.History(1); return err }, false},
+		{"commit", func() error { _, err := revoked.Commit("master"); return err }, false},
+		{"branch", func() error { _, err := revoked.Branch(); return err }, false},
+		{"list branches", func() error { _, err := revoked.ListBranches(); return err }, false},
+		{"list snapshots", func() error { _, err := revoked.ListSnapshots(); return err }, false},
+		{"history allowed", func() error { _, err := reader.History(1); return err }, true},
+		{"squash", func() error { _, err := reader.SquashHistory(repodb.SquashOptions{}); return err }, false},
+		{"merge", func() error { return reader.Merge("draft", "master", repodb.MergeOurs) }, false},
+		{"create branch", func() error { return reader.CreateBranch("draft") }, false},
+		{"checkout branch", func() error { return reader.CheckoutBranch("master") }, false},
+		{"snapshot", func() error { return reader.Snapshot("v1", "") }, false},
+		{"schedule delete", func() error { return writer.ScheduleDelete(rec, time.Now()) }, false},
+		{"run deletes", func() error { _, err := writer.RunDeletes(repodb.DBRepoCommitOptions); return err }, false},
+		{"gc", func() error { _, err := reader.GC(repodb.GCOptions{}); return err }, false},
+		{"vacuum meta", func() error { _, err := reader.VacuumMeta(false, repodb.DBRepoCommitOptions); return err }, false},
+		{"vacuum meta repair", func() error { _, err := writer.VacuumMeta(true, repodb.DBRepoCommitOptions); return err }, false},
+		{"repair meta", func() error {
+			_, err := reader.RepairMeta(func(folder, name string) repodb.Record { return nil }, repodb.DBRepoCommitOptions)
+			return err
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+			if tt.allowed && err != nil {
+				t.Errorf("%s error = %v, want allowed", tt.name, err)
+			}
+			if !tt.allowed && (!errors.Is(err, repodb.ErrPermissionDenied) || !errors.Is(err, os.ErrPermission)) {
+				t.Errorf("%s error = %v, want %v", tt.name, err, repodb.ErrPermissionDenied)
+			}
+		})
+	}
+
+	// repos opened without a subject are not checked
+	repo, err := db.OpenRepo("AuthzRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RemoveFile(rec, repodb.DBRepoCommitOptions); err != nil {
+		t.Errorf("Repo.RemoveFile() without subject error = %v", err)
+	}
+}
+
+// TestWithAuthorizer_denyAll calls every exported Repo method for a subject denied every
+// action, each must be refused before it is performed
+func TestWithAuthorizer_denyAll(t *testing.T) {
+	g := grants{"denied": {repodb.ActionRead}}
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithAuthorizer(g), repodb.WithSearch(), repodb.WithContentAddressed("blobs"))
+	if err := db.CreateRepo(&repodb.Repo{Name: "AuthzRepo", DB: db}); err != nil {
+		t.Fatal(err)
+	}
+	rec := &FileRecord{Name: "a.txt"}
+	owner, err := db.OpenRepo("AuthzRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := owner.WriteFile(rec, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := db.OpenRepoAs("AuthzRepo", "denied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g["denied"] = nil
+
+	opts := repodb.DBRepoCommitOptions
+	fi, err := os.Stat(repo.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := func() *strings.Reader { return strings.NewReader("b") }
+	methods := map[string]func() error{
+		"Archive":        func() error { return repo.Archive(&bytes.Buffer{}, repodb.ArchiveZip, repodb.Hash{}) },
+		"BlameFile":      func() error { _, err := repo.BlameFile(rec); return err },
+		"Branch":         func() error { _, err := repo.Branch(); return err },
+		"CancelDelete":   func() error { return repo.CancelDelete(rec) },
+		"CheckoutBranch": func() error { return repo.CheckoutBranch("master") },
+		"Commit":         func() error { _, err := repo.Commit("master"); return err },
+		"CommitAll":      func() error { return repo.CommitAll(opts) },
+		"CreateBranch":   func() error { return repo.CreateBranch("draft") },
+		"DeleteWhere": func() error {
+			_, err := repo.DeleteWhere("files", func(repodb.Meta) bool { return true }, opts)
+			return err
+		},
+		"DiffFile":       func() error { _, err := repo.DiffFile(rec, repodb.Hash{}, repodb.Hash{}); return err },
+		"EnsureMeta":     func() error { return repo.EnsureMeta() },
+		"External":       func() error { _, err := repo.External(rec); return err },
+		"FS":             func() error { _, err := repo.FS(); return err },
+		"FSAt":           func() error { _, err := repo.FSAt(repodb.Hash{}); return err },
+		"FetchExternal":  func() error { _, err := repo.FetchExternal(rec, &bytes.Buffer{}); return err },
+		"Flush":          func() error { return repo.Flush(opts) },
+		"GC":             func() error { _, err := repo.GC(repodb.GCOptions{}); return err },
+		"Head":           func() error { _, err := repo.Head(); return err },
+		"History":        func() error { _, err := repo.History(1); return err },
+		"Holds":          func() error { _, err := repo.Holds(); return err },
+		"Incidents":      func() error { _, err := repo.Incidents(); return err },
+		"LegalHold":      func() error { return repo.LegalHold(rec, "case") },
+		"ListBranches":   func() error { _, err := repo.ListBranches(); return err },
+		"ListRecords":    func() error { _, err := repo.ListRecords("files", false); return err },
+		"ListSnapshots":  func() error { _, err := repo.ListSnapshots(); return err },
+		"LoadMeta":       func() error { return repo.LoadMeta(&FileRecord{Name: "a.txt"}) },
+		"Merge":          func() error { return repo.Merge("draft", "master", repodb.MergeOurs) },
+		"OpenRecord":     func() error { _, err := repo.OpenRecord(rec); return err },
+		"Preview":        func() error { _, _, err := repo.Preview(rec, 10); return err },
+		"Protect":        func() error { return repo.Protect() },
+		"PurgeDeleted":   func() error { _, err := repo.PurgeDeleted(0, opts); return err },
+		"QueryRecords":   func() error { _, err := repo.QueryRecords("files", nil); return err },
+		"ReadFile":       func() error { _, err := repo.ReadFile(rec, &bytes.Buffer{}); return err },
+		"RecentActivity": func() error { _, err := repo.RecentActivity(1); return err },
+		"ReindexFolder":  func() error { return repo.ReindexFolder("files") },
+		"ReleaseHold":    func() error { return repo.ReleaseHold(rec, "case") },
+		"RemoveFile":     func() error { return repo.RemoveFile(rec, opts) },
+		"RemoveMeta":     func() error { return repo.RemoveMeta(rec, opts) },
+		"RenameRecord":   func() error { return repo.RenameRecord(rec, "b.txt", opts) },
+		"Repair":         func() error { return repo.Repair(opts) },
+		"RepairMeta": func() error {
+			_, err := repo.RepairMeta(func(folder, name string) repodb.Record { return nil }, opts)
+			return err
+		},
+		"RevertFile":        func() error { return repo.RevertFile(rec, repodb.Hash{}, opts) },
+		"RollbackTo":        func() error { return repo.RollbackTo("master", opts) },
+		"RunDeletes":        func() error { _, err := repo.RunDeletes(opts); return err },
+		"ScheduleDelete":    func() error { return repo.ScheduleDelete(rec, time.Now()) },
+		"ScheduledDeletes":  func() error { _, err := repo.ScheduledDeletes(); return err },
+		"Search":            func() error { _, err := repo.Search("a"); return err },
+		"SetBackend":        func() error { return repo.SetBackend(repodb.BackendGoGit) },
+		"Snapshot":          func() error { return repo.Snapshot("v1", "") },
+		"SquashHistory":     func() error { _, err := repo.SquashHistory(repodb.SquashOptions{}); return err },
+		"Stat":              func() error { _, err := repo.Stat(rec); return err },
+		"Stats":             func() error { _, err := repo.Stats(); return err },
+		"TailFile":          func() error { return repo.TailFile(context.Background(), rec, &bytes.Buffer{}) },
+		"VacuumMeta":        func() error { _, err := repo.VacuumMeta(false, opts); return err },
+		"VerifyHistory":     func() error { return repo.VerifyHistory("") },
+		"VerifyRecord":      func() error { return repo.VerifyRecord(rec) },
+		"VerifySearchIndex": func() error { _, err := repo.VerifySearchIndex(); return err },
+		"WriteContent":      func() error { _, err := repo.WriteContent("blobs", content(), opts); return err },
+		"WriteExternal": func() error {
+			return repo.WriteExternal(rec, repodb.External{URL: "https://example.com/a", SHA256: strings.Repeat("0", 64), Size: 1}, opts)
+		},
+		"WriteFile":     func() error { return repo.WriteFile(rec, content(), opts) },
+		"WriteFileAttr": func() error { return repo.WriteFileAttr(rec, content(), repodb.FileAttr{Mode: 0600}, opts) },
+		"WriteFileCAS":  func() error { return repo.WriteFileCAS(rec, content(), repodb.Hash{}, opts) },
+		"WriteFileInfo": func() error { return repo.WriteFileInfo(rec, content(), fileInfo{fi}, opts) },
+		"WriteFileWithProgress": func() error {
+			return repo.WriteFileWithProgress(rec, content(), func(int64) error { return nil }, opts)
+		},
+		"ReadFileWithProgress": func() error {
+			_, err := repo.ReadFileWithProgress(rec, &bytes.Buffer{}, func(int64) error { return nil })
+			return err
+		},
+		"WriteFiles": func() error {
+			return repo.WriteFiles([]repodb.RecordData{{Record: rec, Content: content()}}, opts)
+		},
+		"WriteMeta":  func() error { return repo.WriteMeta(rec, opts) },
+		"WriteStats": func() error { _, err := repo.WriteStats(opts); return err },
+		// methods without errors hide what the subject may not read
+		"FileExists": func() error {
+			if repo.FileExists(rec) {
+				return errors.New("record exists")
+			}
+			return repodb.ErrPermissionDenied
+		},
+		"IsHeld": func() error {
+			if err := owner.LegalHold(rec, "case"); err != nil {
+				return err
+			}
+			if repo.IsHeld(rec) {
+				return errors.New("record held")
+			}
+			return repodb.ErrPermissionDenied
+		},
+	}
+	// methods of the repo value itself, and background loops of checked methods
+	unchecked := map[string]bool{
+		"Backend": true, "Dir": true, "FileName": true, "Folder": true, "Subject": true,
+		"Timestamps": true, "SetTimestamps": true, "StartDeletes": true, "StartStats": true,
+		"Lock": true, "Unlock": true, "RLock": true, "RUnlock": true, "RLocker": true,
+		"TryLock": true, "TryRLock": true,
+	}
+
+	typ := reflect.TypeOf(repo)
+	for i := 0; i < typ.NumMethod(); i++ {
+		name := typ.Method(i).Name
+		if _, ok := methods[name]; !ok && !unchecked[name] {
+			t.Errorf("Repo.%s is not checked for a denied subject", name)
+		}
+	}
+	for name, fn := range methods {
+		t.Run(name, func(t *testing.T) {
+			if err := fn(); !errors.Is(err, repodb.ErrPermissionDenied) {
+				t.Errorf("Repo.%s() error = %v, want %v", name, err, repodb.ErrPermissionDenied)
+			}
+		})
+	}
+	if _, err := owner.ReadFile(rec, &bytes.Buffer{}); err != nil {
+		t.Errorf("Repo.ReadFile() after denied calls error = %v", err)
+	}
+}
+
+// fileInfo is the file info of a regular file, for WriteFileInfo
+type fileInfo struct{ os.FileInfo }
+
+func (fileInfo) IsDir() bool { return false }
@@ -0,0 +1,245 @@
+package repodb
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// ErrBackupCorrupt is returned by Restore when the backup fails integrity verification
+var ErrBackupCorrupt = errors.New("backup is corrupt")
+
+// backupManifest is the final entry of a backup, listing the repos and the sha256 of
+// every file for verification on Restore
+type backupManifest struct {
+	Version int               `json:"version"`
+	Created time.Time         `json:"created"`
+	Repos   []string          `json:"repos"`
+	Files   map[string]string `json:"files"`
+}
+
+// backupManifestName is the archive path of the manifest, it cannot clash with a repo
+// file as all repo files are under the repo directory.
+const backupManifestName = "repodb-backup.json"
+
+// Backup writes a tar.gz archive of every repo in the database and its namespaces to
+// w, including its full git history, followed by a manifest of the repos and file
+// checksums. Each repo is archived under its read lock. Restore the archive with
+// Restore.
+func (db *RepoDB) Backup(w io.Writer) (err error) {
+	defer db.metrics.observe("backup", time.Now(), &err)
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	manifest := &backupManifest{Version: 1, Created: time.Now(), Repos: []string{}, Files: map[string]string{}}
+	if err := db.backupRepos(tw, manifest, db.dir); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: backupManifestName, Size: int64(len(b)), Mode: 0644, ModTime: manifest.Created}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	db.debug("wrote backup", "dir", db.dir, "repos", len(manifest.Repos), "files", len(manifest.Files))
+	return gw.Close()
+}
+
+// backupRepos writes the repos of the database, and of its namespaces, to tw with
+// paths relative to the root directory of the backup
+func (db *RepoDB) backupRepos(tw *tar.Writer, manifest *backupManifest, root string) error {
+	fileInfos, err := db.readDir(db.dir)
+	if err != nil {
+		return fmt.Errorf("unable to backup %s: %v", db.dir, err)
+	}
+	for _, fi := range fileInfos {
+		if !fi.IsDir() {
+			continue
+		}
+		repo, err := db.openRepo(fi.Name(), false)
+		switch {
+		case errors.Is(err, ErrRepoNotExists):
+			continue
+		case err != nil:
+			return fmt.Errorf("unable to backup %s: %v", fi.Name(), err)
+		}
+		if err := repo.backup(tw, manifest, root); err != nil {
+			return fmt.Errorf("unable to backup %s: %v", repo.Name, err)
+		}
+		manifest.Repos = append(manifest.Repos, relPath(root, repo.Dir()))
+	}
+	return db.eachNamespace(func(ns *RepoDB, dir string) error {
+		return ns.backupRepos(tw, manifest, root)
+	})
+}
+
+// backup writes the repo directory to tw, adding file checksums to the manifest
+func (repo *Repo) backup(tw *tar.Writer, manifest *backupManifest, root string) error {
+	repo.RLock()
+	defer repo.RUnlock()
+
+	return repo.DB.walk(repo.Dir(), func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := relPath(root, name)
+		if !fi.IsDir() && !fi.Mode().IsRegular() {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if fi.IsDir() {
+			hdr.Name += "/"
+			return tw.WriteHeader(hdr)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		f, err := repo.DB.fs.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(tw, h), f); err != nil {
+			return err
+		}
+		manifest.Files[rel] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+}
+
+// Restore recreates a database in dir from a backup written by Backup, verifying the
+// checksum of every file and that every repo opens. dir must not exist or be empty.
+// Returns an error wrapping ErrBackupCorrupt, and removes the restored files, if the
+// backup fails verification.
+func Restore(dir string, r io.Reader) (err error) {
+	if fileInfos, err := ioutil.ReadDir(dir); err == nil && len(fileInfos) > 0 {
+		return fmt.Errorf("unable to restore to %s: directory is not empty", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("unable to restore to %s: %v", dir, err)
+	}
+	defer func() {
+		if err != nil {
+			removeContents(dir)
+		}
+	}()
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+	}
+	tr := tar.NewReader(gr)
+	files := map[string]string{}
+	var manifest *backupManifest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+		}
+		if manifest != nil {
+			return fmt.Errorf("%w: entry %s after manifest", ErrBackupCorrupt, hdr.Name)
+		}
+		if hdr.Name == backupManifestName {
+			manifest = &backupManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return fmt.Errorf("%w: invalid manifest: %v", ErrBackupCorrupt, err)
+			}
+			continue
+		}
+		if err := restoreEntry(dir, hdr, tr, files); err != nil {
+			return err
+		}
+	}
+	if manifest == nil {
+		return fmt.Errorf("%w: missing manifest", ErrBackupCorrupt)
+	}
+
+	if len(files) != len(manifest.Files) {
+		return fmt.Errorf("%w: restored %d files, manifest lists %d", ErrBackupCorrupt, len(files), len(manifest.Files))
+	}
+	for name, sum := range manifest.Files {
+		if files[name] != sum {
+			return fmt.Errorf("%w: checksum mismatch for %s", ErrBackupCorrupt, name)
+		}
+	}
+	for _, name := range manifest.Repos {
+		if _, err := git.PlainOpen(path.Join(dir, name)); err != nil {
+			return fmt.Errorf("%w: unable to open repo %s: %v", ErrBackupCorrupt, name, err)
+		}
+	}
+	return nil
+}
+
+// restoreEntry extracts the tar entry under dir, recording the checksum of files
+func restoreEntry(dir string, hdr *tar.Header, r io.Reader, files map[string]string) error {
+	name := path.Clean(hdr.Name)
+	if path.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("%w: invalid entry %s", ErrBackupCorrupt, hdr.Name)
+	}
+	// names are checked in the platform form too, as on Windows \ is a separator and
+	// C: a volume
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(dir, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("%w: invalid entry %s", ErrBackupCorrupt, hdr.Name)
+	}
+	mode := os.FileMode(hdr.Mode).Perm()
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, mode|0700)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode|0600)
+		if err != nil {
+			return fmt.Errorf("unable to restore %s: %v", name, err)
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+			return fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+		}
+		files[name] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported entry type %c for %s", ErrBackupCorrupt, hdr.Typeflag, hdr.Name)
+}
+
+// removeContents removes everything in dir, leaving dir in place
+func removeContents(dir string) {
+	fileInfos, _ := ioutil.ReadDir(dir)
+	for _, fi := range fileInfos {
+		os.RemoveAll(filepath.Join(dir, fi.Name()))
+	}
+}
@@ -0,0 +1,118 @@
+package repodb_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_Backup(t *testing.T) {
+	db := newTestDB(t)
+	for _, name := range []string{"a", "b"} {
+		repo := &repodb.Repo{Name: name, DB: db, Description: name}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteFile(&FileRecord{Name: "f.txt"}, strings.NewReader("content of "+name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backup := &bytes.Buffer{}
+	if err := db.Backup(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := newTestDB(t).Dir()
+	if err := repodb.Restore(dir, bytes.NewReader(backup.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	restored := repodb.NewDB(dir)
+	for _, name := range []string{"a", "b"} {
+		repo, err := restored.OpenRepo(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if repo.Description != name {
+			t.Errorf("restored %s Description = %q, want %q", name, repo.Description, name)
+		}
+		buf := &bytes.Buffer{}
+		if _, err := repo.ReadFile(&FileRecord{Name: "f.txt"}, buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "content of "+name {
+			t.Errorf("restored %s file = %q", name, buf.String())
+		}
+		if activity, err := repo.RecentActivity(10); err != nil || len(activity) != 2 {
+			t.Errorf("restored %s history = %v, %v, want 2 commits", name, activity, err)
+		}
+	}
+	if err := repodb.Restore(dir, bytes.NewReader(backup.Bytes())); err == nil {
+		t.Error("Restore() expected error restoring to non-empty dir")
+	}
+
+	tests := []struct {
+		name   string
+		backup []byte
+	}{
+		{"truncated", backup.Bytes()[:backup.Len()/2]},
+		{"tampered", tamper(t, backup.Bytes(), "a/files/f.txt", "tampered")},
+		{"missing manifest", tamper(t, backup.Bytes(), "repodb-backup.json", "")},
+		{"not gzip", []byte("not a backup")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestDB(t).Dir()
+			err := repodb.Restore(dir, bytes.NewReader(tt.backup))
+			if !errors.Is(err, repodb.ErrBackupCorrupt) {
+				t.Errorf("Restore() error = %v, want %v", err, repodb.ErrBackupCorrupt)
+			}
+			if fileInfos, _ := ioutil.ReadDir(dir); len(fileInfos) != 0 {
+				t.Errorf("Restore() left %d files after failure", len(fileInfos))
+			}
+		})
+	}
+}
+
+// tamper returns a copy of the backup with the named entry content replaced, or the
+// entry dropped if content is empty
+func tamper(t *testing.T, backup []byte, name, content string) []byte {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(backup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	out := &bytes.Buffer{}
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(tr)
+		if path.Clean(hdr.Name) == name {
+			if content == "" {
+				continue
+			}
+			b = []byte(content)
+			hdr.Size = int64(len(b))
+		}
+		tw.WriteHeader(hdr)
+		tw.Write(b)
+	}
+	tw.Close()
+	gw.Close()
+	return out.Bytes()
+}
@@ -0,0 +1,307 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrBareRepo is returned by operations that need a worktree, when used on a bare repo
+var ErrBareRepo = errors.New("operation not supported for bare repo")
+
+// WithBareRepos creates new repos as bare git repositories, without a worktree. Record
+// files and meta-data are written directly as git objects and read from the HEAD
+// commit, halving disk usage. WriteFile, ReadFile, RemoveFile, the meta-data methods,
+// legal holds, scheduled deletions, queries, DeleteWhere, PurgeDeleted, stats,
+// snapshots, branches and history work on bare repos, other write operations return
+// ErrBareRepo. Search does not see the records of bare repos.
+func WithBareRepos() Option {
+	return func(db *RepoDB) {
+		db.bare = true
+	}
+}
+
+// isBare reports if the repo has no worktree
+func (repo *Repo) isBare() bool {
+	r, err := repo.git()
+	if err != nil {
+		return false
+	}
+	_, err = r.Worktree()
+	return errors.Is(err, git.ErrIsBareRepository)
+}
+
+// stageBare marks the operation as supported on bare repos, its changes are staged as
+// git objects and committed by commit. The returned func ends the operation, the caller
+// must hold the repo lock until then.
+func (repo *Repo) stageBare() (done func()) {
+	if !repo.isBare() {
+		return func() {}
+	}
+	repo.staged = make(map[string]*treeEntry)
+	return func() { repo.staged = nil }
+}
+
+// checkBare returns ErrBareRepo for operations on a bare repo not started with stageBare
+func (repo *Repo) checkBare() error {
+	if repo.staged == nil && repo.isBare() {
+		return fmt.Errorf("%w: %s", ErrBareRepo, repo.Name)
+	}
+	return nil
+}
+
+// stageFile stores the contents as a blob and stages it at the repo relative name,
+// encrypting with key if not nil. Returns the number of bytes read.
+func (repo *Repo) stageFile(name string, rd io.Reader, key repoKey) (int64, error) {
+	r, err := repo.git()
+	if err != nil {
+		return 0, err
+	}
+
+	obj := r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	ow, err := obj.Writer()
+	if err != nil {
+		return 0, err
+	}
+	var w io.Writer = ow
+	var ew *encryptWriter
+	if key != nil {
+		if ew, err = newEncryptWriter(ow, key, repo.fileAAD(name)[0]); err != nil {
+			return 0, fmt.Errorf("unable to encrypt %s: %v", name, err)
+		}
+		w = ew
+	}
+	n, err := io.Copy(w, rd)
+	if err == nil && ew != nil {
+		err = ew.Close()
+	}
+	if err == nil {
+		err = ow.Close()
+	}
+	if err != nil {
+		return n, fmt.Errorf("copy failed to %s: %v", name, err)
+	}
+	hash, err := r.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return n, fmt.Errorf("unable to store %s: %v", name, err)
+	}
+	repo.staged[name] = &treeEntry{hash: hash, mode: filemode.Regular}
+	return n, nil
+}
+
+// stageRemove stages the removal of the repo relative name. Returns an *os.PathError if
+// it is not committed.
+func (repo *Repo) stageRemove(name string) error {
+	if _, err := repo.bareFile(name); err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	repo.staged[name] = nil
+	return nil
+}
+
+// bareFile returns the repo relative name from the HEAD commit, or os.ErrNotExist
+func (repo *Repo) bareFile(name string) (*object.File, error) {
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := repo.headTree(r)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, os.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := tree.File(name)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, os.ErrNotExist
+	}
+	return f, err
+}
+
+// openBare opens the repo relative name from the HEAD commit, or returns os.ErrNotExist
+func (repo *Repo) openBare(name string) (io.ReadCloser, error) {
+	f, err := repo.bareFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.Reader()
+}
+
+// readBareMeta decodes the record meta-data from the HEAD commit
+func (repo *Repo) readBareMeta(rec Record) error {
+	rd, err := repo.openBare(repo.DB.metaPath(rec))
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	b, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+	return repo.DB.metaStore(repo.Dir()).decode(b, rec)
+}
+
+// reloadMeta reads the repo meta-data again, after HEAD moved
+func (repo *Repo) reloadMeta() error {
+	if repo.isBare() {
+		return repo.readBareMeta(repo)
+	}
+	return repo.DB.metaStore(repo.Dir()).read(repo.FileName(), repo)
+}
+
+// bareMeta returns the record names and contents of all meta-data files of the folder
+// from the HEAD commit, like metaStore.readAll
+func (repo *Repo) bareMeta(folder string) (names []string, records [][]byte, err error) {
+	r, err := repo.git()
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := repo.headTree(r)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, err := tree.Tree(path.Join(folder, MetaDir))
+	if errors.Is(err, object.ErrDirectoryNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range dir.Entries {
+		if !e.Mode.IsFile() || !strings.HasSuffix(e.Name, repo.DB.metaExt()) {
+			continue
+		}
+		f, err := dir.TreeEntryFile(&e)
+		if err != nil {
+			return nil, nil, err
+		}
+		rd, err := f.Reader()
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := ioutil.ReadAll(rd)
+		rd.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		names = append(names, strings.TrimSuffix(e.Name, repo.DB.metaExt()))
+		records = append(records, b)
+	}
+	return names, records, nil
+}
+
+// removeFiles removes the repo relative names, staging the removals in bare repos.
+// Names that do not exist are skipped. The caller must hold the repo lock.
+func (repo *Repo) removeFiles(names ...string) error {
+	for _, name := range names {
+		if repo.staged == nil {
+			if err := repo.DB.fs.Remove(path.Join(repo.Dir(), name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if _, err := repo.bareFile(name); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := repo.stageRemove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metaPath returns the repo relative name of the record meta-data file
+func (db *RepoDB) metaPath(rec Record) string {
+	if _, ok := rec.(*Repo); ok {
+		return path.Join(MetaDir, rec.FileName()) + db.metaExt()
+	}
+	return path.Join(rec.Folder(), MetaDir, rec.FileName()) + db.metaExt()
+}
+
+// commitStaged commits the staged changes on top of HEAD, and moves the checked out
+// branch to the commit. Returns the zero hash if nothing changed.
+func (repo *Repo) commitStaged(r *git.Repository, msg string, opts *git.CommitOptions) (plumbing.Hash, error) {
+	if err := opts.Validate(r); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	files := map[string]treeEntry{}
+	var parents []plumbing.Hash
+	head, err := r.Head()
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		// no commits yet
+	case err != nil:
+		return plumbing.ZeroHash, err
+	default:
+		c, err := r.CommitObject(head.Hash())
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if files, err = commitFiles(c); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		parents = []plumbing.Hash{head.Hash()}
+	}
+
+	changed := false
+	for name, e := range repo.staged {
+		old, ok := files[name]
+		switch {
+		case e == nil && ok:
+			delete(files, name)
+		case e != nil && (!ok || old != *e):
+			files[name] = *e
+		default:
+			continue
+		}
+		changed = true
+	}
+	if !changed {
+		return plumbing.ZeroHash, nil
+	}
+	tree, err := buildTree(r.Storer, files)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	c := &object.Commit{
+		Author:       *opts.Author,
+		Committer:    *opts.Committer,
+		Message:      msg,
+		TreeHash:     tree,
+		ParentHashes: parents,
+	}
+	hash, err := storeCommit(r.Storer, c, opts.SignKey)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	ref, err := r.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	name := plumbing.HEAD
+	if ref.Type() == plumbing.SymbolicReference {
+		name = ref.Target()
+	}
+	if err := r.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	repo.staged = make(map[string]*treeEntry)
+	return hash, nil
+}
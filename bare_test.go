@@ -0,0 +1,190 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_Bare(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithBareRepos())
+	repo := &repodb.Repo{Name: "BareRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	rec := &FileRecord{Name: "bare.txt", SoftDeleted: true}
+
+	tests := []struct {
+		name    string
+		op      func() error
+		exists  bool
+		content string
+		wantErr error
+	}{
+		{"write", func() error {
+			return repo.WriteFile(rec, strings.NewReader("hello"), repodb.DBRepoCommitOptions)
+		}, true, "hello", nil},
+		{"overwrite", func() error {
+			return repo.WriteFile(rec, strings.NewReader("world"), repodb.DBRepoCommitOptions)
+		}, true, "world", nil},
+		{"write meta", func() error {
+			return repo.WriteMeta(rec, repodb.DBRepoCommitOptions)
+		}, true, "world", nil},
+		{"held", func() error {
+			if err := repo.LegalHold(rec, "case"); err != nil {
+				return err
+			}
+			return repo.RemoveFile(rec, repodb.DBRepoCommitOptions)
+		}, true, "world", repodb.ErrLegalHold},
+		{"released", func() error {
+			if err := repo.ReleaseHold(rec, "case"); err != nil {
+				return err
+			}
+			return repo.RemoveFile(rec, repodb.DBRepoCommitOptions)
+		}, false, "", nil},
+		{"needs worktree", func() error {
+			return repo.RenameRecord(rec, "renamed.txt", repodb.DBRepoCommitOptions)
+		}, false, "", repodb.ErrBareRepo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.op(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := repo.FileExists(rec); got != tt.exists {
+				t.Fatalf("Repo.FileExists() = %v, want %v", got, tt.exists)
+			}
+			if !tt.exists {
+				return
+			}
+			b := &bytes.Buffer{}
+			if _, err := repo.ReadFile(rec, b); err != nil || b.String() != tt.content {
+				t.Errorf("Repo.ReadFile() = %q, error = %v, want %q", b.String(), err, tt.content)
+			}
+		})
+	}
+
+	got := &FileRecord{Name: "bare.txt"}
+	if err := repo.LoadMeta(got); err != nil || !got.SoftDeleted {
+		t.Errorf("Repo.LoadMeta() = %+v, error = %v", got, err)
+	}
+	if _, err := os.Stat(path.Join(repo.Dir(), rec.Folder())); !os.IsNotExist(err) {
+		t.Errorf("record folder written to disk, error = %v", err)
+	}
+	if _, err := os.Stat(path.Join(repo.Dir(), "objects")); err != nil {
+		t.Errorf("repo is not bare: %v", err)
+	}
+}
+
+func TestRepo_Bare_meta(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithBareRepos())
+	newRepo := func(t *testing.T, name string) *repodb.Repo {
+		t.Helper()
+		repo := &repodb.Repo{Name: name, DB: db, Labels: map[string]string{"kind": "bare"}}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		for _, rec := range []*FileRecord{
+			{Name: "kept.txt"},
+			{Name: "deleted.txt", SoftDeleted: true, DeletedOn: time.Now().Add(-time.Hour)},
+		} {
+			if err := repo.WriteFile(rec, strings.NewReader(rec.Name), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return repo
+	}
+	deleted := func(m repodb.Meta) bool { return m.Bool("softdeleted") }
+	remaining := func(t *testing.T, repo *repodb.Repo) []string {
+		t.Helper()
+		names, err := repo.ListRecords("files", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+
+	t.Run("stats", func(t *testing.T) {
+		stats, err := newRepo(t, "Stats").Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Records != 2 || stats.Folders != 1 || stats.FolderRecords["files"] != 2 || stats.Size != int64(len("kept.txtdeleted.txt")) {
+			t.Errorf("Repo.Stats() = %+v, want 2 records in files", stats)
+		}
+	})
+	t.Run("query records", func(t *testing.T) {
+		names, err := newRepo(t, "Query").QueryRecords("files", deleted)
+		if err != nil || !reflect.DeepEqual(names, []string{"deleted.txt"}) {
+			t.Errorf("Repo.QueryRecords() = %v, error = %v, want [deleted.txt]", names, err)
+		}
+	})
+	t.Run("delete where", func(t *testing.T) {
+		repo := newRepo(t, "DeleteWhere")
+		if n, err := repo.DeleteWhere("files", deleted, repodb.DBRepoCommitOptions); err != nil || n != 1 {
+			t.Fatalf("Repo.DeleteWhere() = %d, error = %v, want 1", n, err)
+		}
+		if got := remaining(t, repo); !reflect.DeepEqual(got, []string{"files/kept.txt"}) {
+			t.Errorf("records after Repo.DeleteWhere() = %v, want [files/kept.txt]", got)
+		}
+	})
+	t.Run("purge deleted", func(t *testing.T) {
+		repo := newRepo(t, "Purge")
+		if n, err := repo.PurgeDeleted(time.Minute, repodb.DBRepoCommitOptions); err != nil || n != 1 {
+			t.Fatalf("Repo.PurgeDeleted() = %d, error = %v, want 1", n, err)
+		}
+		if got := remaining(t, repo); !reflect.DeepEqual(got, []string{"files/kept.txt"}) {
+			t.Errorf("records after Repo.PurgeDeleted() = %v, want [files/kept.txt]", got)
+		}
+	})
+	t.Run("run deletes", func(t *testing.T) {
+		repo := newRepo(t, "Deletes")
+		if err := repo.ScheduleDelete(&FileRecord{Name: "kept.txt"}, time.Now().Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if scheduled, err := repo.ScheduledDeletes(); err != nil || len(scheduled) != 1 {
+			t.Fatalf("Repo.ScheduledDeletes() = %v, error = %v, want 1", scheduled, err)
+		}
+		if n, err := repo.RunDeletes(repodb.DBRepoCommitOptions); err != nil || n != 1 {
+			t.Fatalf("Repo.RunDeletes() = %d, error = %v, want 1", n, err)
+		}
+		if got := remaining(t, repo); !reflect.DeepEqual(got, []string{"files/deleted.txt"}) {
+			t.Errorf("records after Repo.RunDeletes() = %v, want [files/deleted.txt]", got)
+		}
+		if scheduled, err := repo.ScheduledDeletes(); err != nil || len(scheduled) != 0 {
+			t.Errorf("Repo.ScheduledDeletes() after Repo.RunDeletes() = %v, error = %v, want none", scheduled, err)
+		}
+	})
+	t.Run("list repos", func(t *testing.T) {
+		newRepo(t, "Listed")
+		repoNames := func(repos []*repodb.Repo, err error) string {
+			if err != nil {
+				return err.Error()
+			}
+			names := []string{}
+			for _, repo := range repos {
+				names = append(names, repo.Name)
+			}
+			return strings.Join(names, ",")
+		}
+		if got := repoNames(db.ListReposFiltered(repodb.ListReposFilter{NamePrefix: "List"})); got != "Listed" {
+			t.Errorf("RepoDB.ListReposFiltered() = %v, want Listed", got)
+		}
+		if got := repoNames(db.ListReposByLabel("kind", "bare")); !strings.Contains(got, "Listed") {
+			t.Errorf("RepoDB.ListReposByLabel() = %v, want Listed", got)
+		}
+		if got := repoNames(db.QueryRepos(func(m repodb.Meta) bool { return m.String("Name") == "Listed" })); got != "Listed" {
+			t.Errorf("RepoDB.QueryRepos() = %v, want Listed", got)
+		}
+	})
+}
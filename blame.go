@@ -0,0 +1,133 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// BlameLine is a line of a record file, with the commit that last changed it
+type BlameLine struct {
+	Line   int // 1-based line number
+	Text   string
+	Commit Hash
+	Author Signature
+}
+
+// BlameFile returns each line of the record file at HEAD with the commit and author
+// that last changed it, following the first parent of merge commits. Returns an error
+// satisfying errors.Is(err, os.ErrNotExist) if the record file is not committed.
+// Encrypted records cannot be blamed.
+func (repo *Repo) BlameFile(rec Record) ([]BlameLine, error) {
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	if key, err := repo.encKey(); err != nil || key != nil {
+		return nil, fmt.Errorf("unable to blame encrypted repo %s", repo.Name)
+	}
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.head()
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.CommitObject(head)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read head of %s: %v", repo.Name, err)
+	}
+	name := path.Join(rec.Folder(), rec.FileName())
+	f, content, err := repo.revisionFile(name, HashFromGit(head))
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, fmt.Errorf("%s not committed: %w", name, os.ErrNotExist)
+	}
+
+	text := splitLines(string(content))
+	owners := make([]*object.Commit, len(text))
+	// pos is the line index in the file at c of each HEAD line not yet attributed, or -1
+	pos := make([]int, len(text))
+	for i := range pos {
+		pos[i] = i
+	}
+	hash := f.hash
+	for remaining := len(text); remaining > 0; {
+		var parent *object.Commit
+		var pf *object.File
+		if c.NumParents() > 0 {
+			if parent, err = c.Parent(0); err != nil {
+				return nil, fmt.Errorf("unable to read parent of %s: %v", c.Hash, err)
+			}
+			if pf, err = parent.File(name); err != nil && !errors.Is(err, object.ErrFileNotFound) {
+				return nil, err
+			}
+		}
+		if pf == nil {
+			// the file was added by c, which wrote all remaining lines
+			for i, p := range pos {
+				if p >= 0 {
+					owners[i] = c
+				}
+			}
+			break
+		}
+		if pf.Hash != hash {
+			_, parentContent, err := repo.revisionFile(name, HashFromGit(parent.Hash))
+			if err != nil {
+				return nil, err
+			}
+			parentLines := mapLines(parentContent, content)
+			for i, p := range pos {
+				if p < 0 {
+					continue
+				}
+				if pos[i] = parentLines[p]; pos[i] < 0 {
+					owners[i] = c
+					remaining--
+				}
+			}
+			content = parentContent
+		}
+		c, hash = parent, pf.Hash
+	}
+
+	lines := make([]BlameLine, len(text))
+	for i, l := range text {
+		lines[i] = BlameLine{Line: i + 1, Text: l, Commit: HashFromGit(owners[i].Hash), Author: SignatureFromGit(owners[i].Author)}
+	}
+	return lines, nil
+}
+
+// mapLines returns the index of each line of to in from, or -1 for added lines
+func mapLines(from, to []byte) []int {
+	var m []int
+	fromLine := 0
+	for _, c := range diff.Do(string(from), string(to)) {
+		n := len(splitLines(c.Text))
+		switch c.Type {
+		case diffmatchpatch.DiffEqual:
+			for i := 0; i < n; i++ {
+				m = append(m, fromLine+i)
+			}
+			fromLine += n
+		case diffmatchpatch.DiffInsert:
+			for i := 0; i < n; i++ {
+				m = append(m, -1)
+			}
+		case diffmatchpatch.DiffDelete:
+			fromLine += n
+		}
+	}
+	return m
+}
@@ -0,0 +1,66 @@
+package repodb_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_BlameFile(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "BlameRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	write := func(content, author string) repodb.Hash {
+		t.Helper()
+		sig := &object.Signature{Name: author, Email: author + "@example.com", When: time.Now()}
+		opts := repodb.CommitOptions{Msg: "edit", Opts: git.CommitOptions{Author: sig, Committer: sig}}
+		if err := repo.WriteFile(&FileRecord{Name: "doc.txt"}, strings.NewReader(content), opts); err != nil {
+			t.Fatal(err)
+		}
+		head, err := repo.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return head
+	}
+	first := write("one\ntwo\nthree\n", "alice")
+	second := write("one\nTWO\nthree\n", "bob")
+	third := write("zero\none\nTWO\nthree\n", "carol")
+
+	got, err := repo.BlameFile(&FileRecord{Name: "doc.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		text   string
+		commit repodb.Hash
+		author string
+	}{
+		{"zero", third, "carol"},
+		{"one", first, "alice"},
+		{"TWO", second, "bob"},
+		{"three", first, "alice"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Repo.BlameFile() = %v, want %d lines", got, len(want))
+	}
+	for i, w := range want {
+		l := got[i]
+		if l.Line != i+1 || l.Text != w.text || l.Commit != w.commit || l.Author.Name != w.author || l.Author.Email != w.author+"@example.com" {
+			t.Errorf("Repo.BlameFile() line %d = %+v, want %s by %s in %s", i+1, l, w.text, w.author, w.commit)
+		}
+	}
+
+	if _, err := repo.BlameFile(&FileRecord{Name: "missing.txt"}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Repo.BlameFile() missing error = %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,221 @@
+package repodb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// BlobStore stores large record contents outside of git, such as in an S3 or GCS
+// bucket. Keys are slash separated paths, and the content of a key never changes once
+// put.
+type BlobStore interface {
+	Put(key string, r io.Reader, size int64) error
+	Get(key string) (io.ReadCloser, error)
+}
+
+// DirBlobStore is a BlobStore keeping blobs as files in a directory, such as a mounted
+// network share
+type DirBlobStore string
+
+// Put writes the blob to a temp file and renames it into place. Satisfies BlobStore.
+func (d DirBlobStore) Put(key string, r io.Reader, size int64) error {
+	filename := filepath.Join(string(d), filepath.FromSlash(path.Clean("/"+key)))
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "blob-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("wrote %d bytes to blob %s, want %d", n, key, size)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Get opens the blob. Satisfies BlobStore.
+func (d DirBlobStore) Get(key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(path.Clean("/"+key))))
+}
+
+// WithBlobStore stores record contents larger than threshold bytes in the blob store,
+// committing only a small pointer file to git, for databases holding large artifacts.
+// ReadFile fetches pointed to content from the store transparently, verifying its
+// checksum. Encrypted repos store encrypted blobs. History functions, such as DiffFile
+// and BlameFile, see the pointer files.
+func WithBlobStore(store BlobStore, threshold int64) Option {
+	return func(db *RepoDB) {
+		db.blobs = store
+		db.blobThreshold = threshold
+	}
+}
+
+// maxPointerSize limits the pointer files read
+const maxPointerSize = 1024
+
+// blobPointer is committed in place of content kept in the blob store
+type blobPointer struct {
+	Key    string `json:"repodb-blob"`
+	SHA256 string `json:"sha256"` // hex encoded sha256 of the plaintext
+	Size   int64  `json:"size"`
+}
+
+// offload puts content larger than the blob threshold in the blob store, returning a
+// reader of the pointer to write in its place and the content size. Smaller content is
+// returned unchanged with size -1. The caller must hold the repo lock.
+func (repo *Repo) offload(r io.Reader, key repoKey) (io.Reader, int64, error) {
+	buf := &bytes.Buffer{}
+	_, err := io.CopyN(buf, r, repo.DB.blobThreshold+1)
+	if err == io.EOF {
+		return buf, -1, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// spool the content to learn its checksum, the blob key
+	tmp, tmpName, err := repo.DB.tempFile(repo.spoolDir(), "repodb-blob-")
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to spool blob for %s: %v", repo.Name, err)
+	}
+	defer repo.DB.fs.Remove(tmpName)
+	defer tmp.Close()
+	var w io.Writer = tmp
+	var ew *encryptWriter
+	if key != nil {
+		// blobs are bound to the repo, their key is only known once written
+		if ew, err = newEncryptWriter(tmp, key, repo.fileAAD("")[0]); err != nil {
+			return nil, 0, fmt.Errorf("unable to encrypt blob for %s: %v", repo.Name, err)
+		}
+		w = ew
+	}
+	h := sha256.New()
+	size, err := io.Copy(w, io.TeeReader(io.MultiReader(buf, r), h))
+	if err == nil && ew != nil {
+		err = ew.Close()
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to spool blob for %s: %v", repo.Name, err)
+	}
+	fi, err := repo.DB.fs.Stat(tmpName)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	p := blobPointer{Key: path.Join(repo.Name, sum), SHA256: sum, Size: size}
+	if err := repo.DB.blobs.Put(p.Key, tmp, fi.Size()); err != nil {
+		return nil, 0, fmt.Errorf("unable to store blob %s: %v", p.Key, err)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(append(b, '\n')), size, nil
+}
+
+// resolveBlob returns the content from the blob store if the attributes of the record
+// file f mark it a pointer, otherwise f itself. Only the blob store keys of the repo, by
+// its name or one of its FormerNames, are fetched, so a pointer copied from another repo
+// cannot read that repo's content.
+func (repo *Repo) resolveBlob(f io.ReadCloser, attr *recordAttr, key repoKey) (io.ReadCloser, error) {
+	if !attr.Blob {
+		return f, nil
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(f, maxPointerSize))
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	if repo.DB.blobs == nil {
+		return nil, fmt.Errorf("unable to fetch blob of %s: no blob store", repo.Name)
+	}
+	p := &blobPointer{}
+	if err := json.Unmarshal(b, p); err != nil || p.Key == "" {
+		return nil, fmt.Errorf("invalid blob pointer in %s: %v", repo.Name, err)
+	}
+	if !repo.ownsBlob(p) {
+		return nil, fmt.Errorf("invalid blob pointer in %s: key %s is not of the repo", repo.Name, p.Key)
+	}
+
+	rc, err := repo.DB.blobs.Get(p.Key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch blob %s: %v", p.Key, err)
+	}
+	var r io.Reader = rc
+	if key != nil {
+		if r, err = newDecryptReader(rc, key, repo.fileAAD("")...); err != nil {
+			rc.Close()
+			return nil, err
+		}
+	}
+	return &blobReader{Reader: r, Closer: rc, ptr: p, hasher: sha256.New()}, nil
+}
+
+// ownsBlob reports whether the pointer key is that offload gives the content in the repo,
+// by its name or one of its FormerNames
+func (repo *Repo) ownsBlob(p *blobPointer) bool {
+	for _, name := range append([]string{repo.Name}, repo.FormerNames...) {
+		if p.Key == path.Join(name, p.SHA256) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendName returns a copy of the names with name added, unless already present
+func appendName(names []string, name string) []string {
+	out := []string{}
+	for _, n := range names {
+		if n == name {
+			name = ""
+		}
+		out = append(out, n)
+	}
+	if name != "" {
+		out = append(out, name)
+	}
+	return out
+}
+
+// blobReader hashes the blob content read, comparing it to the pointer at EOF
+type blobReader struct {
+	io.Reader
+	io.Closer
+	ptr    *blobPointer
+	hasher hash.Hash
+	n      int64
+}
+
+func (b *blobReader) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.hasher.Write(p[:n])
+	b.n += int64(n)
+	if err == io.EOF {
+		if got := hex.EncodeToString(b.hasher.Sum(nil)); got != b.ptr.SHA256 || b.n != b.ptr.Size {
+			return n, fmt.Errorf("%w: blob %s sha256 %s size %d, want %s size %d", ErrChecksumMismatch, b.ptr.Key, got, b.n, b.ptr.SHA256, b.ptr.Size)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,137 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithBlobStore(t *testing.T) {
+	store := repodb.DirBlobStore(t.TempDir())
+	key := bytes.Repeat([]byte{7}, 32)
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithBlobStore(store, 8), repodb.WithEncryption(repoKeys{"Secret": key}))
+
+	tests := []struct {
+		name    string
+		repo    string
+		content string
+		pointer bool
+	}{
+		{"small inline", "Plain", "small", false},
+		{"at threshold inline", "Plain", "12345678", false},
+		{"large offloaded", "Plain", "larger than the threshold", true},
+		{"encrypted offloaded", "Secret", "larger than the threshold", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := db.OpenRepo(tt.repo)
+			if errors.Is(err, repodb.ErrRepoNotExists) {
+				repo = &repodb.Repo{Name: tt.repo, DB: db}
+				err = db.CreateRepo(repo)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := &FileRecord{Name: strings.ReplaceAll(tt.name, " ", "_")}
+			if err := repo.WriteFile(rec, strings.NewReader(tt.content), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			b := &bytes.Buffer{}
+			if _, err := repo.ReadFile(rec, b); err != nil || b.String() != tt.content {
+				t.Errorf("Repo.ReadFile() = %q, error = %v, want %q", b.String(), err, tt.content)
+			}
+			onDisk, err := ioutil.ReadFile(path.Join(repo.Dir(), rec.Folder(), rec.FileName()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bytes.Contains(onDisk, []byte("repodb-blob")); got != (tt.pointer && tt.repo == "Plain") {
+				t.Errorf("pointer committed = %v, want %v: %q", got, tt.pointer, onDisk)
+			}
+		})
+	}
+
+	// blobs are verified against the pointer when read
+	repo, err := db.OpenRepo("Plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &FileRecord{Name: "large_offloaded"}
+	blobs, err := ioutil.ReadDir(path.Join(string(store), "Plain"))
+	if err != nil || len(blobs) != 1 {
+		t.Fatalf("blob store has %d blobs for Plain, error = %v", len(blobs), err)
+	}
+	if err := ioutil.WriteFile(path.Join(string(store), "Plain", blobs[0].Name()), []byte("tampered content!!"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ReadFile(rec, ioutil.Discard); !errors.Is(err, repodb.ErrChecksumMismatch) {
+		t.Errorf("Repo.ReadFile() tampered blob error = %v, want %v", err, repodb.ErrChecksumMismatch)
+	}
+}
+
+func TestWithBlobStore_pointers(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithBlobStore(repodb.DirBlobStore(t.TempDir()), 64))
+	open := func(name string) *repodb.Repo {
+		t.Helper()
+		repo, err := db.OpenRepo(name)
+		if errors.Is(err, repodb.ErrRepoNotExists) {
+			repo = &repodb.Repo{Name: name, DB: db}
+			err = db.CreateRepo(repo)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}
+	read := func(repo *repodb.Repo, rec repodb.Record) (string, error) {
+		b := &bytes.Buffer{}
+		_, err := repo.ReadFile(rec, b)
+		return b.String(), err
+	}
+	large := strings.Repeat("large content ", 10)
+
+	// content looking like a pointer is only a pointer if written as one
+	alice, bob := open("Alice"), open("Bob")
+	rec := &FileRecord{Name: "secret.txt"}
+	if err := bob.WriteFile(rec, strings.NewReader(large), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	pointer, err := ioutil.ReadFile(path.Join(bob.Dir(), rec.Folder(), rec.FileName()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.WriteFile(rec, bytes.NewReader(pointer), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := read(alice, rec); err != nil || got != string(pointer) {
+		t.Errorf("Repo.ReadFile() pointer content = %q, error = %v, want %q", got, err, pointer)
+	}
+
+	// a pointer to a blob of another repo is rejected
+	if err := alice.WriteFile(rec, strings.NewReader(large+"of alice"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(alice.Dir(), rec.Folder(), rec.FileName()), pointer, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := read(alice, rec); err == nil || strings.Contains(got, large) {
+		t.Errorf("Repo.ReadFile() pointer to Bob = %q, error = %v, want error", got, err)
+	}
+
+	// renamed repos and forks read the blobs written under the former name
+	if _, err := db.RenameRepo("Bob", "Robert"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ForkRepo("Robert", "Fork"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Robert", "Fork"} {
+		if got, err := read(open(name), rec); err != nil || got != large {
+			t.Errorf("Repo.ReadFile() in %s = %q, error = %v, want %q", name, got, err, large)
+		}
+	}
+}
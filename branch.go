@@ -0,0 +1,146 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// HookCheckout is the HookEvent type sent when a repo checks out another branch, as its
+// records may have changed without a commit.
+const HookCheckout = "checkout"
+
+// CreateBranch creates a branch at the current HEAD of the repo. Writes stay on the
+// current branch until the new branch is checked out with CheckoutBranch.
+func (repo *Repo) CreateBranch(name string) error {
+	if !validRefName(name) {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	head, err := repo.head()
+	if err != nil {
+		return err
+	}
+	refName := plumbing.NewBranchReferenceName(name)
+	if _, err := r.Reference(refName, false); err == nil {
+		return fmt.Errorf("branch %s already exists in %s", name, repo.Name)
+	}
+	if err := r.Storer.SetReference(plumbing.NewHashReference(refName, head)); err != nil {
+		return fmt.Errorf("unable to create branch %s in %s: %v", name, repo.Name, err)
+	}
+	repo.DB.debug("created branch", "repo", repo.Name, "branch", name, "commit", head.String())
+	return nil
+}
+
+// CheckoutBranch switches the repo worktree to the branch, later writes are committed
+// to it. The repo meta-data is reloaded from the branch.
+func (repo *Repo) CheckoutBranch(name string) error {
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	refName := plumbing.NewBranchReferenceName(name)
+	ref, err := r.Reference(refName, false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return fmt.Errorf("branch %s does not exist in %s", name, repo.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if repo.staged != nil {
+		// bare repo, only HEAD moves
+		err = r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refName))
+	} else {
+		var w *git.Worktree
+		if w, err = r.Worktree(); err == nil {
+			err = w.Checkout(&git.CheckoutOptions{Branch: refName})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to checkout branch %s in %s: %v", name, repo.Name, err)
+	}
+	if err := repo.heartbeat(r, ref.Hash()); err != nil {
+		return err
+	}
+
+	repo.DB.debug("checked out branch", "repo", repo.Name, "branch", name, "commit", ref.Hash().String())
+	repo.DB.runHooks(HookEvent{
+		Type:    HookCheckout,
+		Repo:    repo.Name,
+		Hash:    ref.Hash().String(),
+		Message: fmt.Sprintf("checked out branch %s", name),
+		Time:    time.Now(),
+	})
+	return repo.reloadMeta()
+}
+
+// Branch returns the name of the checked out branch
+func (repo *Repo) Branch() (string, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return "", err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return "", err
+	}
+	head, err := r.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", fmt.Errorf("unable to read head of %s: %v", repo.Name, err)
+	}
+	return head.Target().Short(), nil
+}
+
+// ListBranches returns the branch names of the repo, sorted by name
+func (repo *Repo) ListBranches() ([]string, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := r.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list branches of %s: %v", repo.Name, err)
+	}
+	branches := []string{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		branches = append(branches, ref.Name().Short())
+		return nil
+	})
+	sort.Strings(branches)
+	return branches, err
+}
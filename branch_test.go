@@ -0,0 +1,81 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_CheckoutBranch(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithSearch())
+	repo := &repodb.Repo{Name: "BranchRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("alpha"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	createTests := []struct {
+		name    string
+		branch  string
+		wantErr bool
+	}{
+		{"created", "draft", false},
+		{"exists", "draft", true},
+		{"invalid", "bad name", true},
+	}
+	for _, tt := range createTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.CreateBranch(tt.branch); (err != nil) != tt.wantErr {
+				t.Errorf("Repo.CreateBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := repo.CheckoutBranch("missing"); err == nil {
+		t.Errorf("Repo.CheckoutBranch() expected error for missing branch")
+	}
+
+	if err := repo.CheckoutBranch("draft"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "b.txt"}, strings.NewReader("bravo"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := repo.Search("bravo"); err != nil || len(results) != 1 {
+		t.Fatalf("Repo.Search() on draft = %v, error = %v", results, err)
+	}
+
+	checkoutTests := []struct {
+		branch string
+		wantB  bool
+	}{
+		{"master", false},
+		{"draft", true},
+	}
+	for _, tt := range checkoutTests {
+		t.Run(tt.branch, func(t *testing.T) {
+			if err := repo.CheckoutBranch(tt.branch); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := repo.Branch(); err != nil || got != tt.branch {
+				t.Errorf("Repo.Branch() = %s, error = %v, want %s", got, err, tt.branch)
+			}
+			if got := repo.FileExists(&FileRecord{Name: "b.txt"}); got != tt.wantB {
+				t.Errorf("Repo.FileExists() = %v, want %v", got, tt.wantB)
+			}
+			if results, err := repo.Search("bravo"); err != nil || (len(results) == 1) != tt.wantB {
+				t.Errorf("Repo.Search() = %v, error = %v, want found %v", results, err, tt.wantB)
+			}
+		})
+	}
+
+	branches, err := repo.ListBranches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(branches, ",") != "draft,master" {
+		t.Errorf("Repo.ListBranches() = %v, want [draft master]", branches)
+	}
+}
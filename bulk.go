@@ -0,0 +1,175 @@
+package repodb
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+// RecordData is a record written by WriteFiles
+type RecordData struct {
+	Record  Record
+	Content io.Reader // content of the record file, nil to leave the file unchanged
+	Meta    bool      // write the record meta-data, as WriteMeta
+}
+
+// WriteFiles writes the files and meta-data of the records in a single commit, for bulk
+// imports without the overhead of a commit per record. Writes are as WriteFile and
+// WriteMeta, and if any fails nothing is committed and the written records are restored.
+func (repo *Repo) WriteFiles(records []RecordData, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("write_files", time.Now(), &err)
+	for _, d := range records {
+		if err := repo.authorize(ActionWrite, d.Record); err != nil {
+			return err
+		}
+	}
+	for _, d := range records {
+		if d.Content == nil && !d.Meta {
+			return fmt.Errorf("WriteFiles requires content or meta-data: %s", d.Record.FileName())
+		}
+		if err := repo.DB.validateRecord(d.Record); err != nil {
+			return err
+		}
+		if err := repo.DB.checkMutable(d.Record); err != nil {
+			return err
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	if replayed, err := repo.replayed(OpWriteFiles, nil, opts); err != nil || replayed {
+		return err
+	}
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+
+	written := make([]*writtenFile, len(records))
+	var n int64
+	for i, d := range records {
+		if written[i], err = repo.writeRecordData(d); err != nil {
+			if repo.staged == nil {
+				repo.restoreRecords(records[:i+1])
+			}
+			return err
+		}
+		if written[i] != nil {
+			n += written[i].n
+		}
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpWriteFiles,
+		Bytes:     n,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("wrote %d records", len(records)),
+	})
+	if err := repo.commit(OpWriteFiles, nil, opts); err != nil {
+		return err
+	}
+	for i, w := range written {
+		if w == nil {
+			continue
+		}
+		if err := w.setComputed(repo, records[i].Record); err != nil {
+			return err
+		}
+		w.checkQuota(repo, records[i].Record)
+	}
+	return nil
+}
+
+// writeRecordData writes the file and meta-data of the record to be committed,
+// returning the written file if any. The caller must hold the repo lock.
+func (repo *Repo) writeRecordData(d RecordData) (w *writtenFile, err error) {
+	if d.Content != nil {
+		if w, err = repo.writeContent(d.Record, d.Content, nil); err != nil {
+			return nil, err
+		}
+	}
+	if d.Meta {
+		if err := repo.writeMeta(d.Record); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// restoreRecords returns the files of the records to their HEAD commit contents after a
+// failed WriteFiles, the caller must hold the repo lock
+func (repo *Repo) restoreRecords(records []RecordData) {
+	for _, d := range records {
+		rec := d.Record
+		expiry := &Expiry{RecordFolder: rec.Folder(), RecordName: rec.FileName()}
+		err := repo.restore(path.Join(rec.Folder(), rec.FileName()), attrPath(rec), repo.DB.metaPath(rec), repo.DB.metaPath(expiry))
+		if err != nil {
+			repo.DB.warn("unable to restore record after failed write", "repo", repo.Name, "record", rec.FileName(), "err", err)
+		}
+	}
+}
+
+// DeleteWhere removes the file and meta-data of every record in folder whose meta-data
+// matches the filter, in a single commit. Records under legal hold, or content still
+// referenced by other records, are skipped. Returns the number of records deleted, or
+// that would be deleted if opts.DryRun is set.
+func (repo *Repo) DeleteWhere(folder string, f Filter, opts CommitOptions) (deleted int, err error) {
+	defer repo.DB.metrics.observe("delete_where", time.Now(), &err)
+	if err := repo.authorizeFolder(ActionDelete, folder); err != nil {
+		return 0, err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	if replayed, err := repo.replayed(OpDeleteWhere, nil, opts); err != nil || replayed {
+		return 0, err
+	}
+	defer repo.stageBare()()
+	names, err := repo.queryRecords(folder, f)
+	if err != nil {
+		return 0, err
+	}
+	matched := make([]*recordRef, 0, len(names))
+	for _, name := range names {
+		rec := &recordRef{folder: folder, name: name}
+		if repo.isHeld(rec) || repo.checkReferences(rec) != nil {
+			continue
+		}
+		matched = append(matched, rec)
+	}
+	if opts.DryRun || len(matched) == 0 {
+		return len(matched), nil
+	}
+	if err := repo.prepareWrite(); err != nil {
+		return 0, err
+	}
+
+	if err := repo.removeRecords(matched); err != nil {
+		return 0, err
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpDeleteWhere,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("deleted %d records from folder %s", len(matched), folder),
+	})
+	if err := repo.commit(OpDeleteWhere, nil, opts); err != nil {
+		return 0, err
+	}
+	return len(matched), nil
+}
+
+// removeRecords removes the file, meta-data and attributes of the records, to be
+// committed by the caller holding the repo lock
+func (repo *Repo) removeRecords(recs []*recordRef) error {
+	for _, rec := range recs {
+		if err := repo.removeFiles(path.Join(rec.folder, rec.name), repo.DB.metaPath(rec), attrPath(rec)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
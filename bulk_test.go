@@ -0,0 +1,156 @@
+package repodb_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_DeleteWhere(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "BulkRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, fr := range []*FileRecord{
+		{Name: "a.txt", SoftDeleted: true},
+		{Name: "b.txt", SoftDeleted: true},
+		{Name: "c.txt"},
+		{Name: "d.txt", SoftDeleted: true},
+	} {
+		if err := repo.WriteFile(fr, strings.NewReader(fr.Name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.LegalHold(&FileRecord{Name: "d.txt"}, "case"); err != nil {
+		t.Fatal(err)
+	}
+	softDeleted := repodb.Match(map[string]interface{}{"softdeleted": true})
+
+	tests := []struct {
+		name       string
+		dryRun     bool
+		want       int
+		wantCommit bool
+		remaining  []string
+	}{
+		{"dry run", true, 2, false, []string{"a.txt", "b.txt", "d.txt"}},
+		{"deleted", false, 2, true, []string{"d.txt"}},
+		{"none left", false, 0, false, []string{"d.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := repo.Head()
+			opts := repodb.DBRepoCommitOptions
+			opts.DryRun = tt.dryRun
+			got, err := repo.DeleteWhere("files", softDeleted, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Repo.DeleteWhere() = %d, want %d", got, tt.want)
+			}
+			after, _ := repo.Head()
+			if (before != after) != tt.wantCommit {
+				t.Errorf("Repo.DeleteWhere() committed = %v, want %v", before != after, tt.wantCommit)
+			}
+			remaining, err := repo.QueryRecords("files", softDeleted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(remaining, ",") != strings.Join(tt.remaining, ",") {
+				t.Errorf("Repo.QueryRecords() after DeleteWhere = %v, want %v", remaining, tt.remaining)
+			}
+		})
+	}
+
+	for name, want := range map[string]bool{"a.txt": false, "c.txt": true, "d.txt": true} {
+		if got := repo.FileExists(&FileRecord{Name: name}); got != want {
+			t.Errorf("Repo.FileExists(%s) = %v, want %v", name, got, want)
+		}
+		for _, file := range []string{"files/" + name, "files/meta-data/" + name + ".json"} {
+			if got := committed(t, repo, file); got != want {
+				t.Errorf("%s committed = %v, want %v", file, got, want)
+			}
+		}
+	}
+	activity, err := repo.RecentActivity(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity[0].Operation != repodb.OpDeleteWhere || !strings.Contains(activity[0].Message, "deleted 2 records from folder files") {
+		t.Errorf("Repo.RecentActivity() = %+v, want delete_where of 2 records", activity[0])
+	}
+}
+
+func TestRepo_WriteFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), tt.opts...)
+			repo := &repodb.Repo{Name: "BatchRepo", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			records := []repodb.RecordData{
+				{Record: &FileRecord{Name: "a.txt"}, Content: strings.NewReader("a"), Meta: true},
+				{Record: &FileRecord{Name: "b.txt"}, Content: strings.NewReader("bb")},
+				{Record: &FileRecord{Name: "c.txt", SoftDeleted: true}, Meta: true},
+			}
+			if err := repo.WriteFiles(records, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			activity, err := repo.RecentActivity(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(activity) == 0 || activity[0].Operation != repodb.OpWriteFiles || len(activity) > 1 && activity[1].Operation == repodb.OpWriteFiles {
+				t.Errorf("Repo.WriteFiles() activity = %+v, want a single commit", activity)
+			}
+			for name, want := range map[string]string{"a.txt": "a", "b.txt": "bb"} {
+				buf := &strings.Builder{}
+				if _, err := repo.ReadFile(&FileRecord{Name: name}, buf); err != nil || buf.String() != want {
+					t.Errorf("Repo.ReadFile(%s) = %q, error = %v, want %q", name, buf, err, want)
+				}
+			}
+			c := &FileRecord{Name: "c.txt"}
+			if err := repo.LoadMeta(c); err != nil || !c.SoftDeleted {
+				t.Errorf("Repo.LoadMeta() = %+v, error = %v", c, err)
+			}
+
+			// a failed write commits and leaves nothing
+			failing := []repodb.RecordData{
+				{Record: &FileRecord{Name: "d.txt"}, Content: strings.NewReader("d"), Meta: true},
+				{Record: &FileRecord{Name: "a.txt"}, Content: &failingReader{}},
+			}
+			if err := repo.WriteFiles(failing, repodb.DBRepoCommitOptions); err == nil {
+				t.Fatal("Repo.WriteFiles() error = nil, want read error")
+			}
+			if repo.FileExists(&FileRecord{Name: "d.txt"}) || repo.LoadMeta(&FileRecord{Name: "d.txt"}) == nil {
+				t.Errorf("Repo.WriteFiles() left d.txt after failing")
+			}
+			buf := &strings.Builder{}
+			if _, err := repo.ReadFile(&FileRecord{Name: "a.txt"}, buf); err != nil || buf.String() != "a" {
+				t.Errorf("Repo.ReadFile() after failed write = %q, error = %v", buf, err)
+			}
+		})
+	}
+}
+
+// failingReader fails every read
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
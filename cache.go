@@ -0,0 +1,104 @@
+package repodb
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// DefaultRepoCacheSize is the number of open git repositories kept by a RepoDB
+const DefaultRepoCacheSize = 32
+
+// WithRepoCache sets the number of open git repositories cached by the RepoDB, reused
+// across operations rather than re-opening the repository each time. Zero disables
+// the cache.
+func WithRepoCache(size int) Option {
+	return func(db *RepoDB) {
+		db.gitCache = newGitCache(size)
+	}
+}
+
+// gitCache is a least recently used cache of open git repositories keyed by directory.
+// A nil *gitCache is valid and caches nothing.
+type gitCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of *gitCacheEntry, most recently used first
+	items map[string]*list.Element
+}
+
+type gitCacheEntry struct {
+	dir  string
+	repo *git.Repository
+}
+
+func newGitCache(size int) *gitCache {
+	return &gitCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached repository for dir, or nil
+func (c *gitCache) get(dir string) *git.Repository {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[dir]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*gitCacheEntry).repo
+}
+
+// put adds the repository for dir, evicting the least recently used if full
+func (c *gitCache) put(dir string, repo *git.Repository) {
+	if c == nil || c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[dir]; ok {
+		e.Value.(*gitCacheEntry).repo = repo
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[dir] = c.order.PushFront(&gitCacheEntry{dir: dir, repo: repo})
+	if c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(*gitCacheEntry).dir)
+	}
+}
+
+// remove invalidates the cached repository for dir
+func (c *gitCache) remove(dir string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[dir]; ok {
+		c.order.Remove(e)
+		delete(c.items, dir)
+	}
+}
+
+// git returns the open git repository for the repo, from the DB cache if present
+func (repo *Repo) git() (*git.Repository, error) {
+	dir := repo.Dir()
+	if r := repo.DB.gitCache.get(dir); r != nil {
+		return r, nil
+	}
+	r, err := repo.DB.openGit(dir)
+	if err != nil {
+		return nil, err
+	}
+	repo.DB.gitCache.put(dir, r)
+	return r, nil
+}
@@ -0,0 +1,59 @@
+package repodb_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithRepoCache_removeRepo(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithRepoCache(1))
+	for i := 0; i < 2; i++ {
+		repo := &repodb.Repo{Name: "CachedRepo", DB: db}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatalf("RepoDB.CreateRepo() iteration %d error = %v", i, err)
+		}
+		if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		// a second repo evicts the first from the cache
+		other := &repodb.Repo{Name: fmt.Sprintf("OtherRepo%d", i), DB: db}
+		if err := db.CreateRepo(other); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteFile(&FileRecord{Name: "b.txt"}, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		activity, err := repo.RecentActivity(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activity) != 3 {
+			t.Errorf("Repo.RecentActivity() iteration %d len = %v, want %v", i, len(activity), 3)
+		}
+		if err := db.RemoveRepo("CachedRepo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRepo_WriteFile(b *testing.B) {
+	for _, size := range []int{0, repodb.DefaultRepoCacheSize} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			db := repodb.NewDB(newTestDB(b).Dir(), repodb.WithRepoCache(size))
+			repo := &repodb.Repo{Name: "BenchRepo", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				b.Fatal(err)
+			}
+			fr := &FileRecord{Name: "bench.txt"}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := repo.WriteFile(fr, strings.NewReader(fmt.Sprint(i)), repodb.DBRepoCommitOptions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ErrConflict is returned by compare-and-swap writes when the repo HEAD has moved since
+// the expected commit. ErrMergeConflict is an ErrConflict too.
+var ErrConflict = errors.New("repo head has changed")
+
+// Head returns the hash of the repo HEAD commit, for use with WriteFileCAS
+func (repo *Repo) Head() (Hash, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return Hash{}, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	head, err := repo.head()
+	return HashFromGit(head), err
+}
+
+// head returns the HEAD commit hash, the caller must hold the repo lock
+func (repo *Repo) head() (plumbing.Hash, error) {
+	r, err := repo.git()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	ref, err := r.Head()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("unable to read head of %s: %v", repo.Name, err)
+	}
+	return ref.Hash(), nil
+}
+
+// commitLock returns the lock serializing the commits of all Repo values of the repo in
+// dir, so a compare-and-swap write checks HEAD and commits atomically
+func (db *RepoDB) commitLock(dir string) *sync.Mutex {
+	db.commitMu.Lock()
+	defer db.commitMu.Unlock()
+	if db.commitLocks == nil {
+		db.commitLocks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := db.commitLocks[dir]
+	if !ok {
+		lock = &sync.Mutex{}
+		db.commitLocks[dir] = lock
+	}
+	return lock
+}
+
+// checkHead returns ErrConflict if expected is set and HEAD is at another commit, the
+// caller must hold the repo lock
+func (repo *Repo) checkHead(expected *plumbing.Hash) error {
+	if expected == nil {
+		return nil
+	}
+	head, err := repo.head()
+	if err != nil {
+		return err
+	}
+	if head != *expected {
+		return fmt.Errorf("%w: expected %s, found %s", ErrConflict, HashFromGit(*expected), head)
+	}
+	return nil
+}
+
+// WriteFileCAS writes the record like WriteFile, only if the repo HEAD is still
+// expectedHead. Returns ErrConflict without writing if another write has been committed
+// since, the caller should re-read the record and retry. HEAD is checked again when
+// committing, under a lock shared by the Repo values of the same RepoDB, so writes
+// through separate OpenRepo values conflict too.
+func (repo *Repo) WriteFileCAS(rec Record, r io.Reader, expectedHead Hash, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("write_file_cas", time.Now(), &err)
+	if err := repo.authorize(ActionWrite, rec); err != nil {
+		return err
+	}
+	if r == nil {
+		return fmt.Errorf("WriteFileCAS requires non-nil reader: %s", rec.FileName())
+	}
+	if err := repo.DB.validateRecord(rec); err != nil {
+		return err
+	}
+	if err := repo.DB.checkMutable(rec); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	if replayed, err := repo.replayed(OpWriteFile, rec, opts); err != nil || replayed {
+		return err
+	}
+	head := expectedHead.Git()
+	opts.expectedHead = &head
+	if err := repo.checkHead(opts.expectedHead); err != nil {
+		return err
+	}
+	defer repo.stageBare()()
+	err = repo.writeFile(OpWriteFile, rec, r, nil, opts)
+	if errors.Is(err, ErrConflict) && !repo.isBare() {
+		// HEAD moved while writing, discard the write in the worktree
+		if rerr := repo.restore(path.Join(rec.Folder(), rec.FileName()), attrPath(rec)); rerr != nil {
+			repo.DB.warn("unable to restore file after conflicting write", "repo", repo.Name, "record", rec.FileName(), "err", rerr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package repodb_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_WriteFileCAS(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "CASRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "cas.txt"}
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFileCAS(fr, strings.NewReader("first"), head, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.WriteFileCAS() error = %v", err)
+	}
+
+	// a second writer holding the stale head conflicts
+	err = repo.WriteFileCAS(fr, strings.NewReader("second"), head, repodb.DBRepoCommitOptions)
+	if !errors.Is(err, repodb.ErrConflict) {
+		t.Errorf("Repo.WriteFileCAS() error = %v, want %v", err, repodb.ErrConflict)
+	}
+
+	buf := &strings.Builder{}
+	if _, err := repo.ReadFile(fr, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "first" {
+		t.Errorf("Repo.ReadFile() = %q, want %q", buf.String(), "first")
+	}
+}
+
+// writeOnRead runs write on its first Read, before reading from r
+type writeOnRead struct {
+	r     io.Reader
+	write func()
+}
+
+func (w *writeOnRead) Read(p []byte) (int, error) {
+	if w.write != nil {
+		w.write()
+		w.write = nil
+	}
+	return w.r.Read(p)
+}
+
+func TestRepo_WriteFileCAS_handles(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), tt.opts...)
+			repo := &repodb.Repo{Name: "CASRepo", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			other, err := db.OpenRepo(repo.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fr := &FileRecord{Name: "cas.txt"}
+			head, err := repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the other value commits with the same head while the first is writing
+			r := &writeOnRead{r: strings.NewReader("first"), write: func() {
+				if err := other.WriteFileCAS(fr, strings.NewReader("second"), head, repodb.DBRepoCommitOptions); err != nil {
+					t.Errorf("Repo.WriteFileCAS() of other value error = %v", err)
+				}
+			}}
+			err = repo.WriteFileCAS(fr, r, head, repodb.DBRepoCommitOptions)
+			if !errors.Is(err, repodb.ErrConflict) {
+				t.Errorf("Repo.WriteFileCAS() error = %v, want %v", err, repodb.ErrConflict)
+			}
+
+			buf := &strings.Builder{}
+			if _, err := repo.ReadFile(fr, buf); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != "second" {
+				t.Errorf("Repo.ReadFile() = %q, want %q", buf.String(), "second")
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package repodb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrChecksumMismatch is returned when content read does not match its recorded checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrNoChecksum is returned by VerifyRecord for record files written without a checksum
+var ErrNoChecksum = errors.New("no checksum")
+
+// WithVerifiedReads verifies record files as they are read against the git hash of the
+// record committed at HEAD, detecting corruption of the worktree without a separate
+// pass. The hash is compared once the whole file is read, ReadFile returns
+// ErrChecksumMismatch after copying corrupt content. Records not yet committed, such as
+// writes pending in a degraded repo, are not verified.
+func WithVerifiedReads() Option {
+	return func(db *RepoDB) {
+		db.verifyReads = true
+	}
+}
+
+// verifyFile wraps the opened record file to verify it against the committed hash
+func (repo *Repo) verifyFile(rec Record, f io.ReadCloser) (io.ReadCloser, error) {
+	name := path.Join(rec.Folder(), rec.FileName())
+	want, err := repo.committedHash(name)
+	switch {
+	case errors.Is(err, object.ErrFileNotFound):
+		return f, nil
+	case err != nil:
+		f.Close()
+		return nil, fmt.Errorf("unable to verify %s: %v", name, err)
+	}
+	fi, err := repo.DB.fs.Stat(path.Join(repo.Dir(), name))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &verifyingReader{
+		ReadCloser: f,
+		name:       name,
+		hasher:     plumbing.NewHasher(plumbing.BlobObject, fi.Size()),
+		want:       want,
+	}, nil
+}
+
+// committedHash returns the git hash of the named file at HEAD
+func (repo *Repo) committedHash(name string) (plumbing.Hash, error) {
+	r, err := repo.git()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	tree, err := repo.headTree(r)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	file, err := tree.File(name)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return file.Hash, nil
+}
+
+// verifyingReader hashes the content read, comparing it to the expected hash at EOF
+type verifyingReader struct {
+	io.ReadCloser
+	name   string
+	hasher plumbing.Hasher
+	want   plumbing.Hash
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.ReadCloser.Read(p)
+	v.hasher.Write(p[:n])
+	if err == io.EOF {
+		if got := v.hasher.Sum(); got != v.want {
+			return n, fmt.Errorf("%w: %s hash %s, committed %s", ErrChecksumMismatch, v.name, got, v.want)
+		}
+	}
+	return n, err
+}
+
+// VerifyRecord reads the record file as ReadFile does and compares its size and sha256
+// to those stored in the record meta-data directory when it was written, returning
+// ErrChecksumMismatch if they differ. Files written before checksums were stored
+// return ErrNoChecksum.
+func (repo *Repo) VerifyRecord(rec Record) (err error) {
+	defer repo.DB.metrics.observe("verify_record", time.Now(), &err)
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo.RLocker())
+	defer repo.RUnlock()
+
+	name := path.Join(rec.Folder(), rec.FileName())
+	attr, err := repo.readAttr(rec)
+	if err != nil {
+		return err
+	}
+	if attr.SHA256 == "" {
+		return fmt.Errorf("%w: %s", ErrNoChecksum, name)
+	}
+	f, err := repo.openFile(rec)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sum := newContentSum()
+	if _, err := io.Copy(sum, f); err != nil {
+		return fmt.Errorf("unable to verify %s: %v", name, err)
+	}
+	if sum.n != attr.Size || sum.String() != attr.SHA256 {
+		return fmt.Errorf("%w: %s sha256 %s size %d, stored %s size %d", ErrChecksumMismatch, name, sum, sum.n, attr.SHA256, attr.Size)
+	}
+	return nil
+}
+
+// contentSum is the sha256 and size of the content written to it
+type contentSum struct {
+	h hash.Hash
+	n int64
+}
+
+func newContentSum() *contentSum {
+	return &contentSum{h: sha256.New()}
+}
+
+func (s *contentSum) Write(p []byte) (int, error) {
+	s.n += int64(len(p))
+	return s.h.Write(p)
+}
+
+// String returns the hex encoded sha256
+func (s *contentSum) String() string {
+	return hex.EncodeToString(s.h.Sum(nil))
+}
@@ -0,0 +1,121 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithVerifiedReads(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithVerifiedReads(), repodb.WithEncryption(repoKeys{"Secret": key}))
+	content := bytes.Repeat([]byte("0123456789"), 20*1024)
+
+	tests := []struct {
+		name     string
+		repo     string
+		corrupt  func(b []byte) []byte
+		wantErr  bool
+		mismatch bool
+	}{
+		{"intact", "Plain", nil, false, false},
+		{"flipped byte", "Plain", func(b []byte) []byte { b[len(b)/2] ^= 1; return b }, true, true},
+		{"truncated", "Plain", func(b []byte) []byte { return b[:len(b)-1] }, true, true},
+		{"uncommitted", "Plain", func(b []byte) []byte { return b }, false, false},
+		{"encrypted intact", "Secret", nil, false, false},
+		// decryption fails authentication before the whole file is hashed
+		{"encrypted flipped byte", "Secret", func(b []byte) []byte { b[len(b)-1] ^= 1; return b }, true, false},
+	}
+	for _, name := range []string{"Plain", "Secret"} {
+		if err := db.CreateRepo(&repodb.Repo{Name: name, DB: db}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := db.OpenRepo(tt.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := &FileRecord{Name: tt.name}
+			filename := path.Join(repo.Dir(), rec.Folder(), rec.FileName())
+			if tt.name == "uncommitted" {
+				if err := ioutil.WriteFile(filename, content, 0600); err != nil {
+					t.Fatal(err)
+				}
+			} else if err := repo.WriteFile(rec, bytes.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if tt.corrupt != nil {
+				b, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(filename, tt.corrupt(b), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			buf := &bytes.Buffer{}
+			_, err = repo.ReadFile(rec, buf)
+			if (err != nil) != tt.wantErr || errors.Is(err, repodb.ErrChecksumMismatch) != tt.mismatch {
+				t.Fatalf("Repo.ReadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(buf.Bytes(), content) {
+				t.Errorf("Repo.ReadFile() read %d bytes, want %d", buf.Len(), len(content))
+			}
+		})
+	}
+}
+
+func TestRepo_VerifyRecord(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir())
+	content := bytes.Repeat([]byte("0123456789"), 100)
+
+	tests := []struct {
+		name        string
+		compression string
+		change      func(filename, attrname string) error
+		want        error
+	}{
+		{"intact", "", nil, nil},
+		{"compressed", "gzip", nil, nil},
+		{"flipped byte", "", func(filename, _ string) error {
+			b, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			b[len(b)/2] ^= 1
+			return ioutil.WriteFile(filename, b, 0600)
+		}, repodb.ErrChecksumMismatch},
+		{"no checksum", "", func(_, attrname string) error {
+			return ioutil.WriteFile(attrname, []byte("{}"), 0600)
+		}, repodb.ErrNoChecksum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &repodb.Repo{Name: tt.name, DB: db, Compression: tt.compression}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			rec := &FileRecord{Name: "a.txt"}
+			if err := repo.WriteFile(rec, bytes.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if tt.change != nil {
+				filename := path.Join(repo.Dir(), rec.Folder(), rec.FileName())
+				attrname := path.Join(repo.Dir(), rec.Folder(), repodb.MetaDir, rec.FileName()+".attr")
+				if err := tt.change(filename, attrname); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.VerifyRecord(rec); !errors.Is(err, tt.want) {
+				t.Errorf("Repo.VerifyRecord() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
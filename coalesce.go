@@ -0,0 +1,179 @@
+package repodb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithCommitCoalescing defers the commits of record writes like WithDeferredCommits,
+// and folds them into a commit per repo once interval has passed since the first
+// pending write, or maxWrites are pending. The commit message lists the messages of the
+// writes, and it is authored as the first. A zero interval or maxWrites disables that
+// trigger. Writes return once on disk, RepoDB.Sync commits all pending writes.
+func WithCommitCoalescing(interval time.Duration, maxWrites int) Option {
+	return func(db *RepoDB) {
+		db.deferCommits = true
+		db.coalescer = &coalescer{
+			interval:  interval,
+			maxWrites: maxWrites,
+			pending:   make(map[string]*pendingWrites),
+		}
+	}
+}
+
+// coalescer tracks the pending writes of repos for WithCommitCoalescing
+type coalescer struct {
+	interval  time.Duration
+	maxWrites int
+
+	mu      sync.Mutex
+	pending map[string]*pendingWrites // by repo directory
+}
+
+// pendingWrites are the uncommitted writes of a repo
+type pendingWrites struct {
+	repo  *Repo // last writer, committing the writes on its lock
+	opts  CommitOptions
+	msgs  []string
+	timer *time.Timer
+}
+
+// add records a deferred write to the repo, and reports if maxWrites are pending so it
+// should commit them now. The caller must hold the repo lock.
+func (c *coalescer) add(repo *Repo, opts CommitOptions) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dir := repo.Dir()
+	p, ok := c.pending[dir]
+	if !ok {
+		p = &pendingWrites{opts: opts}
+		c.pending[dir] = p
+		if c.interval > 0 {
+			p.timer = time.AfterFunc(c.interval, func() { c.flush(dir) })
+		}
+	}
+	p.repo = repo
+	p.msgs = append(p.msgs, strings.TrimSpace(opts.Msg))
+	return c.maxWrites > 0 && len(p.msgs) >= c.maxWrites
+}
+
+// take removes and returns the pending writes of the repo directory, nil if none
+func (c *coalescer) take(dir string) *pendingWrites {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.pending[dir]
+	if p == nil {
+		return nil
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	delete(c.pending, dir)
+	return p
+}
+
+// detail describes the pending writes in the commit message
+func (p *pendingWrites) detail() string {
+	return fmt.Sprintf("coalesced %d writes\n\n%s", len(p.msgs), strings.Join(p.msgs, "\n"))
+}
+
+// flush commits the pending writes of the repo directory, once the interval has passed
+func (c *coalescer) flush(dir string) {
+	c.mu.Lock()
+	p := c.pending[dir]
+	c.mu.Unlock()
+	if p == nil {
+		return
+	}
+	repo := p.repo
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	if err := repo.commitPending(CommitOptions{Opts: p.opts.Opts, Actor: p.opts.Actor}, ""); err != nil {
+		repo.DB.warn("unable to commit coalesced writes", "repo", repo.Name, "err", err)
+	}
+}
+
+// commitPending commits the changes of deferred writes with opts, described by detail
+// and the coalesced writes, if any. If the commit fails the writes remain pending. The
+// caller must hold the repo lock.
+func (repo *Repo) commitPending(opts CommitOptions, detail string) error {
+	c := repo.DB.coalescer
+	var p *pendingWrites
+	if c != nil {
+		p = c.take(repo.Dir())
+	}
+	if p != nil {
+		detail = strings.TrimPrefix(detail+"\n\n"+p.detail(), "\n\n")
+	}
+	opts.Msg = repo.DB.commitMessage(CommitMessage{Operation: OpFlush, Message: opts.Msg, Detail: detail})
+	err := repo.commit(OpFlush, nil, opts)
+	if err != nil && p != nil {
+		c.requeue(repo.Dir(), p)
+	}
+	return err
+}
+
+// requeue returns writes that failed to commit to pending, to be retried after the
+// interval
+func (c *coalescer) requeue(dir string, p *pendingWrites) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if q, ok := c.pending[dir]; ok {
+		// writes since are committed together
+		q.msgs = append(p.msgs, q.msgs...)
+		return
+	}
+	p.timer = nil
+	if c.interval > 0 {
+		p.timer = time.AfterFunc(c.interval, func() { c.flush(dir) })
+	}
+	c.pending[dir] = p
+}
+
+// Sync commits the pending writes of every repo, including those of the namespaces
+// returned by Namespace, see WithCommitCoalescing
+func (db *RepoDB) Sync() (err error) {
+	defer db.metrics.observe("sync", time.Now(), &err)
+	db.nsMu.Lock()
+	namespaces := make([]*RepoDB, 0, len(db.namespaces))
+	for _, ns := range db.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	db.nsMu.Unlock()
+	for _, ns := range namespaces {
+		if err := ns.Sync(); err != nil {
+			return err
+		}
+	}
+	if db.coalescer == nil {
+		return nil
+	}
+	c := db.coalescer
+	c.mu.Lock()
+	dirs := make([]string, 0, len(c.pending))
+	for dir := range c.pending {
+		dirs = append(dirs, dir)
+	}
+	c.mu.Unlock()
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		c.mu.Lock()
+		p := c.pending[dir]
+		c.mu.Unlock()
+		if p == nil {
+			continue
+		}
+		repo := p.repo
+		repo.DB.metrics.lock("repo", repo)
+		err := repo.commitPending(CommitOptions{Opts: p.opts.Opts, Actor: p.opts.Actor}, "")
+		repo.Unlock()
+		if err != nil {
+			return fmt.Errorf("unable to sync %s: %v", repo.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repodb_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithCommitCoalescing(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		maxWrites int
+		writes    int
+		want      int // coalesced commits before Sync
+	}{
+		{"count", 0, 3, 7, 2},
+		{"interval", 50 * time.Millisecond, 0, 4, 1},
+		{"sync", time.Hour, 0, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithCommitCoalescing(tt.interval, tt.maxWrites))
+			repo := &repodb.Repo{Name: "Coalesced", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.writes; i++ {
+				rec := &FileRecord{Name: fmt.Sprintf("%d.txt", i)}
+				if err := repo.WriteFile(rec, strings.NewReader("data"), repodb.DBRepoCommitOptions); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.interval > 0 && tt.interval < time.Second {
+				time.Sleep(4 * tt.interval)
+			}
+			if got := flushes(t, repo); got != tt.want {
+				t.Errorf("coalesced commits = %d, want %d", got, tt.want)
+			}
+
+			if err := db.Sync(); err != nil {
+				t.Fatal(err)
+			}
+			activity, err := repo.RecentActivity(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if activity[0].Operation != repodb.OpFlush || !strings.Contains(activity[0].Message, fmt.Sprintf("%d.txt", tt.writes-1)) {
+				t.Errorf("RepoDB.Sync() last commit = %+v, want the last write", activity[0])
+			}
+			want := flushes(t, repo)
+			if err := db.Sync(); err != nil || flushes(t, repo) != want {
+				t.Errorf("RepoDB.Sync() with nothing pending committed, error = %v", err)
+			}
+		})
+	}
+}
+
+// flushes returns the number of flush commits of the repo
+func flushes(t *testing.T, repo *repodb.Repo) int {
+	t.Helper()
+	activity, err := repo.RecentActivity(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, a := range activity {
+		if a.Operation == repodb.OpFlush {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,164 @@
+package repodb
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Hash is a commit or object hash. History and versioning APIs use the repodb types
+// Hash, Signature and CommitInfo, so callers need not import go-git; convert to and
+// from go-git types with HashFromGit, Hash.Git and similar.
+type Hash [20]byte
+
+// ZeroHash is the empty Hash
+var ZeroHash Hash
+
+// ParseHash parses a 40 character hex hash
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(h) {
+		return h, fmt.Errorf("invalid hash %q", s)
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// HashFromGit converts a go-git hash
+func HashFromGit(h plumbing.Hash) Hash {
+	return Hash(h)
+}
+
+// Git converts the hash to a go-git hash
+func (h Hash) Git() plumbing.Hash {
+	return plumbing.Hash(h)
+}
+
+// IsZero reports if the hash is the ZeroHash
+func (h Hash) IsZero() bool {
+	return h == ZeroHash
+}
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// MarshalText encodes the hash as hex, for json
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText decodes a hex hash
+func (h *Hash) UnmarshalText(b []byte) error {
+	parsed, err := ParseHash(string(b))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// Signature identifies the author or committer of a commit
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// SignatureFromGit converts a go-git signature
+func SignatureFromGit(s object.Signature) Signature {
+	return Signature{Name: s.Name, Email: s.Email, When: s.When}
+}
+
+// Git converts the signature to a go-git signature
+func (s Signature) Git() *object.Signature {
+	return &object.Signature{Name: s.Name, Email: s.Email, When: s.When}
+}
+
+// CommitInfo describes a commit to a repo
+type CommitInfo struct {
+	Hash      Hash
+	Author    Signature
+	Committer Signature
+	Message   string
+	Parents   []Hash
+}
+
+// CommitInfoFromGit converts a go-git commit
+func CommitInfoFromGit(c *object.Commit) CommitInfo {
+	info := CommitInfo{
+		Hash:      HashFromGit(c.Hash),
+		Author:    SignatureFromGit(c.Author),
+		Committer: SignatureFromGit(c.Committer),
+		Message:   c.Message,
+		Parents:   make([]Hash, 0, len(c.ParentHashes)),
+	}
+	for _, p := range c.ParentHashes {
+		info.Parents = append(info.Parents, HashFromGit(p))
+	}
+	return info
+}
+
+// Commit returns the commit at rev, a snapshot name, tag, branch or commit hash
+func (repo *Repo) Commit(rev string) (CommitInfo, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return CommitInfo{}, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	c, err := resolveCommit(r, rev)
+	if err != nil {
+		return CommitInfo{}, fmt.Errorf("unable to resolve %s in %s: %v", rev, repo.Name, err)
+	}
+	return CommitInfoFromGit(c), nil
+}
+
+// History returns up to the last n commits of the repo, newest first
+func (repo *Repo) History(n int) ([]CommitInfo, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := r.Log(&git.LogOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read log of %s: %v", repo.Name, err)
+	}
+	history := []CommitInfo{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if len(history) >= n {
+			return storer.ErrStop
+		}
+		history = append(history, CommitInfoFromGit(c))
+		return nil
+	})
+	return history, err
+}
+
+// resolveCommit returns the commit at rev, resolving annotated tags to their commit
+func resolveCommit(r *git.Repository, rev string) (*object.Commit, error) {
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, err
+	}
+	if tag, err := r.TagObject(*hash); err == nil {
+		return tag.Commit()
+	}
+	return r.CommitObject(*hash)
+}
@@ -0,0 +1,86 @@
+package repodb_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestParseHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		wantErr bool
+	}{
+		{"normal", "0123456789abcdef0123456789abcdef01234567", false},
+		{"short", "0123", true},
+		{"not hex", "z123456789abcdef0123456789abcdef01234567", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := repodb.ParseHash(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && h.String() != tt.s {
+				t.Errorf("ParseHash() = %v, want %v", h, tt.s)
+			}
+			if err == nil && repodb.HashFromGit(h.Git()) != h {
+				t.Errorf("HashFromGit(h.Git()) = %v, want %v", repodb.HashFromGit(h.Git()), h)
+			}
+		})
+	}
+}
+
+func TestRepo_History(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "HistoryRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Snapshot("v1", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := repo.History(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || len(history[0].Parents) != 1 || history[0].Parents[0] != history[1].Hash {
+		t.Fatalf("Repo.History() = %v, want 2 linked commits", history)
+	}
+	if history[0].Author.Name != "repodb" || !strings.Contains(history[0].Message, "wrote 1 bytes") {
+		t.Errorf("Repo.History()[0] = %+v, want write by repodb", history[0])
+	}
+
+	for _, rev := range []string{"v1", "HEAD", history[0].Hash.String()} {
+		c, err := repo.Commit(rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Hash != history[0].Hash {
+			t.Errorf("Repo.Commit(%s) = %v, want %v", rev, c.Hash, history[0].Hash)
+		}
+	}
+	if _, err := repo.Commit("v2"); err == nil {
+		t.Errorf("Repo.Commit() expected error for unknown revision")
+	}
+
+	b, err := json.Marshal(history[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := repodb.CommitInfo{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hash != history[0].Hash || !strings.Contains(string(b), history[0].Hash.String()) {
+		t.Errorf("CommitInfo json = %s, want hex hash %v", b, history[0].Hash)
+	}
+}
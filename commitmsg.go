@@ -0,0 +1,40 @@
+package repodb
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// CommitMessage is the data of a commit message template, see WithCommitTemplate
+type CommitMessage struct {
+	Operation string // operation committed, such as OpWriteFile
+	Record    string // slash separated path of the changed record, if any
+	Bytes     int64  // bytes written, if any
+	Message   string // message of the CommitOptions
+	Detail    string // description of the change, such as "wrote 5 bytes to file files/a.txt"
+}
+
+// WithCommitTemplate renders the messages of commits made by repodb operations with the
+// template, executed with a CommitMessage, in place of the caller message followed by a
+// blank line and the detail, for messages that downstream tools can parse. The
+// operation trailers read by RecentActivity are appended to the rendered message.
+func WithCommitTemplate(t *template.Template) Option {
+	return func(db *RepoDB) {
+		db.commitTemplate = t
+	}
+}
+
+// commitMessage renders the commit message of a change
+func (db *RepoDB) commitMessage(m CommitMessage) string {
+	if db.commitTemplate != nil {
+		var b strings.Builder
+		err := db.commitTemplate.Execute(&b, m)
+		if err == nil {
+			return b.String()
+		}
+		// the default message is committed rather than failing the operation
+		db.warn("unable to render commit message template", "operation", m.Operation, "err", err)
+	}
+	return fmt.Sprintf("%s\n\n%s", m.Message, m.Detail)
+}
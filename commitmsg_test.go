@@ -0,0 +1,42 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithCommitTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"structured", "op={{.Operation}} record={{.Record}} bytes={{.Bytes}}\n\n{{.Message}}", "op=write_file record=files/a.txt bytes=5\n\nimport"},
+		{"invalid field falls back", "{{.Missing}}", "import\n\nwrote 5 bytes to file files/a.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("commit").Parse(tt.template))
+			db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithCommitTemplate(tmpl))
+			repo := &repodb.Repo{Name: "Templated", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			opts := repodb.DBRepoCommitOptions
+			opts.Msg = "import"
+			if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("hello"), opts); err != nil {
+				t.Fatal(err)
+			}
+			activity, err := repo.RecentActivity(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if activity[0].Message != tt.want || activity[0].Operation != repodb.OpWriteFile {
+				t.Errorf("commit message = %q, operation %s, want %q", activity[0].Message, activity[0].Operation, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package repodb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrUnknownCodec is returned for a compression codec that is not registered
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// Codec compresses record file contents, see Repo.Compression
+type Codec interface {
+	Name() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Gzip is the gzip Codec, always available as "gzip"
+var Gzip Codec = gzipCodec{}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// WithCodecs registers compression codecs by name in addition to Gzip, such as a zstd
+// codec, for repos to name in Compression
+func WithCodecs(codecs ...Codec) Option {
+	return func(db *RepoDB) {
+		if db.codecs == nil {
+			db.codecs = map[string]Codec{}
+		}
+		for _, c := range codecs {
+			db.codecs[c.Name()] = c
+		}
+	}
+}
+
+// codec returns the named codec, or nil for no compression
+func (db *RepoDB) codec(name string) (Codec, error) {
+	switch c, ok := db.codecs[name]; {
+	case name == "":
+		return nil, nil
+	case ok:
+		return c, nil
+	case name == Gzip.Name():
+		return Gzip, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCodec, name)
+}
+
+// compressReader reads the compressed contents of r. Closing it stops compressing and
+// waits for r to no longer be read.
+type compressReader struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+func newCompressReader(r io.Reader, c Codec) *compressReader {
+	pr, pw := io.Pipe()
+	cr := &compressReader{PipeReader: pr, done: make(chan struct{})}
+	go func() {
+		defer close(cr.done)
+		cw, err := c.NewWriter(pw)
+		if err == nil {
+			_, err = io.Copy(cw, r)
+			if cerr := cw.Close(); err == nil {
+				err = cerr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	return cr
+}
+
+func (cr *compressReader) Close() error {
+	err := cr.PipeReader.Close()
+	<-cr.done
+	return err
+}
+
+// decompress returns the decompressed contents of rc, closing rc when closed
+func decompress(rc io.ReadCloser, c Codec) (io.ReadCloser, error) {
+	if c == nil {
+		return rc, nil
+	}
+	dr, err := c.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("unable to decompress %s: %v", c.Name(), err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{dr, rc}, nil
+}
+
+// treeAttr returns the attributes of the named record file in the tree, empty if it has
+// none
+func (repo *Repo) treeAttr(tree *object.Tree, name string) (*recordAttr, error) {
+	attr := &recordAttr{}
+	f, err := tree.File(path.Join(path.Dir(name), MetaDir, path.Base(name)+attrSuffix))
+	if errors.Is(err, object.ErrFileNotFound) {
+		return attr, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(s), attr); err != nil {
+		return nil, fmt.Errorf("invalid attributes of %s: %v", name, err)
+	}
+	return attr, nil
+}
@@ -0,0 +1,118 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_Compression(t *testing.T) {
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog\n", 200)
+	tests := []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+		{"encrypted", []repodb.Option{repodb.WithEncryption(repoKeys{"Docs": bytes.Repeat([]byte{5}, 32)})}},
+		{"blob store", []repodb.Option{repodb.WithLargeFiles(64)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), tt.opts...)
+			repo := &repodb.Repo{Name: "Docs", DB: db, Compression: "gzip"}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			rec := &FileRecord{Name: "a.txt"}
+			if err := repo.WriteFile(rec, strings.NewReader(text), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			first, err := repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.name == "worktree" {
+				b, err := ioutil.ReadFile(filepath.Join(repo.Dir(), rec.Folder(), rec.FileName()))
+				if err != nil || len(b) >= len(text)/4 {
+					t.Errorf("stored file is %d bytes, %v, want compressed below %d", len(b), err, len(text)/4)
+				}
+			}
+
+			// files are read back after compression is turned off
+			repo.Compression = ""
+			if err := repo.WriteMeta(repo, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if _, err := repo.ReadFile(rec, &buf); err != nil || buf.String() != text {
+				t.Fatalf("Repo.ReadFile() = %d bytes, %v, want %d bytes", buf.Len(), err, len(text))
+			}
+			if fi, err := repo.Stat(rec); err != nil || fi.Size() != int64(len(text)) {
+				t.Errorf("Repo.Stat() = %v, %v, want size %d", fi, err, len(text))
+			}
+
+			if err := repo.WriteFile(rec, strings.NewReader("plain\n"), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			second, err := repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff, err := repo.DiffFile(rec, first, second)
+			switch {
+			case err != nil:
+				t.Errorf("Repo.DiffFile() error = %v", err)
+			case tt.name == "blob store":
+				// pointer files are diffed
+			case diff.Added != 1 || diff.Removed != 200:
+				t.Errorf("Repo.DiffFile() = +%d -%d, want +1 -200", diff.Added, diff.Removed)
+			}
+		})
+	}
+}
+
+func TestWithCodecs(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithCodecs(namedCodec{repodb.Gzip, "gz"}))
+	tests := []struct {
+		codec   string
+		wantErr error
+	}{
+		{"gz", nil},
+		{"gzip", nil},
+		{"zstd", repodb.ErrUnknownCodec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.codec, func(t *testing.T) {
+			repo := &repodb.Repo{Name: "Codec " + tt.codec, DB: db, Compression: tt.codec}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			rec := &FileRecord{Name: "a.txt"}
+			err := repo.WriteFile(rec, strings.NewReader("content"), repodb.DBRepoCommitOptions)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Repo.WriteFile() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var buf bytes.Buffer
+			if _, err := repo.ReadFile(rec, &buf); err != nil || buf.String() != "content" {
+				t.Errorf("Repo.ReadFile() = %q, %v, want %q", buf.String(), err, "content")
+			}
+		})
+	}
+}
+
+// namedCodec renames a codec
+type namedCodec struct {
+	repodb.Codec
+	name string
+}
+
+func (c namedCodec) Name() string { return c.name }
@@ -0,0 +1,177 @@
+package repodb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Computed field tags. Record struct fields tagged `repodb:"<name>"` are filled by
+// WriteFile, and by LoadMeta from the record file, so they cannot drift from the
+// content:
+//
+//	Size      int64     `repodb:"size"`         // content size in bytes
+//	SHA256    string    `repodb:"sha256"`       // hex sha256 of the content
+//	Committed time.Time `repodb:"committed_at"` // time of the last commit changing the file
+//
+// Sizes and checksums are of the decrypted content. Fields of records without a file
+// are left as loaded.
+const (
+	FieldSize        = "size"
+	FieldSHA256      = "sha256"
+	FieldCommittedAt = "committed_at"
+)
+
+// computedField is a struct field of a Record filled by the library
+type computedField struct {
+	index int
+	name  string
+}
+
+// computedCache caches the computed fields of Record types
+var computedCache sync.Map
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// computedFields returns the computed fields of the record type, or an error if a
+// field of the wrong type is tagged
+func computedFields(rec Record) ([]computedField, error) {
+	t := reflect.TypeOf(rec)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, nil
+	}
+	t = t.Elem()
+	if v, ok := computedCache.Load(t); ok {
+		return v.([]computedField), nil
+	}
+
+	fields := []computedField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := f.Tag.Lookup("repodb")
+		if !ok {
+			continue
+		}
+		var valid bool
+		switch name {
+		case FieldSize:
+			switch f.Type.Kind() {
+			case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+				valid = true
+			}
+		case FieldSHA256:
+			valid = f.Type.Kind() == reflect.String
+		case FieldCommittedAt:
+			valid = f.Type == timeType
+		default:
+			return nil, fmt.Errorf("unknown repodb tag %q on %s.%s", name, t.Name(), f.Name)
+		}
+		if !valid || f.PkgPath != "" {
+			return nil, fmt.Errorf("invalid field %s.%s for repodb tag %q", t.Name(), f.Name, name)
+		}
+		fields = append(fields, computedField{index: i, name: name})
+	}
+	computedCache.Store(t, fields)
+	return fields, nil
+}
+
+// computedValues are the values of the computed fields of a record
+type computedValues struct {
+	size        int64
+	sha256      string
+	committedAt time.Time
+}
+
+// setComputed sets the computed fields of the record
+func setComputed(rec Record, fields []computedField, values computedValues) {
+	v := reflect.ValueOf(rec).Elem()
+	for _, f := range fields {
+		field := v.Field(f.index)
+		switch f.name {
+		case FieldSize:
+			if k := field.Kind(); k == reflect.Uint || k == reflect.Uint64 {
+				field.SetUint(uint64(values.size))
+			} else {
+				field.SetInt(values.size)
+			}
+		case FieldSHA256:
+			field.SetString(values.sha256)
+		case FieldCommittedAt:
+			field.Set(reflect.ValueOf(values.committedAt))
+		}
+	}
+}
+
+// loadComputed fills the computed fields of the record from its file, the caller must
+// hold the repo lock
+func (repo *Repo) loadComputed(rec Record, fields []computedField) error {
+	f, err := repo.openFile(rec)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	values := computedValues{size: n, sha256: hex.EncodeToString(h.Sum(nil))}
+	if values.committedAt, err = repo.committedAt(rec); err != nil {
+		return err
+	}
+	setComputed(rec, fields, values)
+	return nil
+}
+
+// committedAt returns the committer time of the last commit on the first parent
+// history of HEAD changing the record file, or the zero time if it is not committed.
+// The caller must hold the repo lock.
+func (repo *Repo) committedAt(rec Record) (time.Time, error) {
+	r, err := repo.git()
+	if err != nil {
+		return time.Time{}, err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return time.Time{}, err
+	}
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return time.Time{}, err
+	}
+	name := path.Join(rec.Folder(), rec.FileName())
+	f, err := c.File(name)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	for c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return time.Time{}, err
+		}
+		pf, err := parent.File(name)
+		if errors.Is(err, object.ErrFileNotFound) || (err == nil && pf.Hash != f.Hash) {
+			break
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		c = parent
+	}
+	return c.Committer.When, nil
+}
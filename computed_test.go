@@ -0,0 +1,90 @@
+package repodb_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+type computedRecord struct {
+	Name      string
+	Size      int64     `repodb:"size"`
+	SHA256    string    `repodb:"sha256"`
+	Committed time.Time `repodb:"committed_at"`
+}
+
+func (r *computedRecord) FileName() string { return r.Name }
+func (r *computedRecord) Folder() string   { return "computed" }
+
+type badComputedRecord struct {
+	Name string
+	Size string `repodb:"size"`
+}
+
+func (r *badComputedRecord) FileName() string { return r.Name }
+func (r *badComputedRecord) Folder() string   { return "computed" }
+
+func TestComputedFields(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "ComputedRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	content := "hello world"
+	sum := sha256.Sum256([]byte(content))
+	want := computedRecord{Name: "a.txt", Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])}
+
+	rec := &computedRecord{Name: "a.txt", Size: 1, SHA256: "stale"}
+	if err := repo.WriteFile(rec, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Size != want.Size || rec.SHA256 != want.SHA256 || rec.Committed.IsZero() {
+		t.Errorf("Repo.WriteFile() computed fields = %+v, want %+v", rec, want)
+	}
+	committed := rec.Committed
+	if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is changed outside of the record, LoadMeta reports the actual content
+	time.Sleep(time.Second)
+	if err := ioutil.WriteFile(path.Join(repo.Dir(), rec.Folder(), rec.FileName()), []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &computedRecord{Name: "a.txt"}
+	if err := repo.LoadMeta(loaded); err != nil {
+		t.Fatal(err)
+	}
+	changed := sha256.Sum256([]byte("changed"))
+	if loaded.Size != 7 || loaded.SHA256 != hex.EncodeToString(changed[:]) || !loaded.Committed.Equal(committed) {
+		t.Errorf("Repo.LoadMeta() computed fields = %+v, want size 7 committed %v", loaded, committed)
+	}
+	if err := repo.CommitAll(repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.LoadMeta(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Committed.After(committed) {
+		t.Errorf("Repo.LoadMeta() Committed = %v, want after %v", loaded.Committed, committed)
+	}
+
+	// meta-data without a file is loaded as written
+	if err := repo.WriteMeta(&computedRecord{Name: "meta-only", Size: 3}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	metaOnly := &computedRecord{Name: "meta-only"}
+	if err := repo.LoadMeta(metaOnly); err != nil || metaOnly.Size != 3 {
+		t.Errorf("Repo.LoadMeta() meta only = %+v, error = %v", metaOnly, err)
+	}
+
+	if err := repo.WriteFile(&badComputedRecord{Name: "b.txt"}, strings.NewReader(content), repodb.DBRepoCommitOptions); err == nil {
+		t.Errorf("Repo.WriteFile() expected error for invalid computed field type")
+	}
+}
@@ -0,0 +1,181 @@
+package repodb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var (
+	// ErrContentAddressed is returned when writing or renaming a record by name in a
+	// content addressed folder, use WriteContent instead.
+	ErrContentAddressed = errors.New("folder is content addressed")
+	// ErrContentReferenced is returned when removing content that is still referenced
+	// by record meta-data.
+	ErrContentReferenced = errors.New("content is referenced")
+)
+
+// WithContentAddressed makes the folders content addressed. Record files in them are
+// written with WriteContent, named by the sha256 of their plaintext, and cannot be
+// changed once written. Their meta-data remains mutable.
+func WithContentAddressed(folders ...string) Option {
+	return func(db *RepoDB) {
+		if db.contentFolders == nil {
+			db.contentFolders = make(map[string]bool)
+		}
+		for _, f := range folders {
+			db.contentFolders[f] = true
+		}
+	}
+}
+
+// WriteContent writes the content to the content addressed folder and returns its
+// name, the hex sha256 of the content. Writing content already in the folder is a no-op
+// and does not commit.
+func (repo *Repo) WriteContent(folder string, r io.Reader, opts CommitOptions) (name string, err error) {
+	defer repo.DB.metrics.observe("write_content", time.Now(), &err)
+	if err := repo.authorizeFolder(ActionWrite, folder); err != nil {
+		return "", err
+	}
+	if r == nil {
+		return "", fmt.Errorf("WriteContent requires non-nil reader: %s", folder)
+	}
+	if !repo.DB.contentFolders[folder] {
+		return "", fmt.Errorf("folder %s is not content addressed", folder)
+	}
+
+	// spool the content to learn its name before taking the repo lock
+	tmp, tmpName, err := repo.DB.tempFile(repo.spoolDir(), "repodb-content-")
+	if err != nil {
+		return "", fmt.Errorf("unable to spool content for %s: %v", repo.Name, err)
+	}
+	defer repo.DB.fs.Remove(tmpName)
+	defer tmp.Close()
+	h := sha256.New()
+	if _, err := io.Copy(tmp, io.TeeReader(r, h)); err != nil {
+		return "", fmt.Errorf("unable to spool content for %s: %v", repo.Name, err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	rec := &recordRef{folder: folder, name: hex.EncodeToString(h.Sum(nil))}
+	if err := repo.DB.validateRecord(rec); err != nil {
+		return "", err
+	}
+
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+	if repo.recordExists(rec) {
+		return rec.name, nil
+	}
+	return rec.name, repo.writeFile(OpWriteFile, rec, tmp, nil, opts)
+}
+
+// spoolDir returns the directory for temp files of the repo, inside the git directory
+// so they are never committed
+func (repo *Repo) spoolDir() string {
+	if repo.isBare() {
+		return repo.Dir()
+	}
+	return path.Join(repo.Dir(), ".git")
+}
+
+// checkMutable returns ErrContentAddressed if the record is in a content addressed folder
+func (db *RepoDB) checkMutable(rec Record) error {
+	if db.contentFolders[rec.Folder()] {
+		return fmt.Errorf("%w: %s", ErrContentAddressed, rec.Folder())
+	}
+	return nil
+}
+
+// checkReferences returns ErrContentReferenced if the content addressed record name is
+// in the meta-data of any other record, or of the repo. The caller must hold the repo
+// lock.
+func (repo *Repo) checkReferences(rec Record) error {
+	if !repo.DB.contentFolders[rec.Folder()] {
+		return nil
+	}
+	if repo.isBare() {
+		return repo.checkBareReferences(rec)
+	}
+	folders, err := repo.recordFolders()
+	if err != nil {
+		return fmt.Errorf("unable to read references of %s: %v", repo.Name, err)
+	}
+	dirs := []string{repo.Dir()}
+	for _, folder := range folders {
+		dirs = append(dirs, path.Join(repo.Dir(), folder))
+	}
+
+	quoted := []byte(`"` + rec.FileName() + `"`)
+	own := repo.DB.metaStore(path.Join(repo.Dir(), rec.Folder())).filename(rec.FileName())
+	for _, dir := range dirs {
+		metaDir := path.Join(dir, MetaDir)
+		metas, err := repo.DB.readDir(metaDir)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to read references of %s: %v", repo.Name, err)
+		}
+		for _, m := range metas {
+			filename := path.Join(metaDir, m.Name())
+			// meta-data of the content itself is not a reference
+			if m.IsDir() || !strings.HasSuffix(m.Name(), repo.DB.metaExt()) || filename == own {
+				continue
+			}
+			b, err := repo.DB.readFile(filename)
+			if err != nil {
+				return fmt.Errorf("unable to read references of %s: %v", repo.Name, err)
+			}
+			if bytes.Contains(b, quoted) {
+				return fmt.Errorf("%w: %s", ErrContentReferenced, path.Join(rec.Folder(), rec.FileName()))
+			}
+		}
+	}
+	return nil
+}
+
+// checkBareReferences is checkReferences for bare repos, reading meta-data from HEAD
+func (repo *Repo) checkBareReferences(rec Record) error {
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	tree, err := repo.headTree(r)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read references of %s: %v", repo.Name, err)
+	}
+
+	quoted := []byte(`"` + rec.FileName() + `"`)
+	own := repo.DB.metaPath(rec)
+	err = tree.Files().ForEach(func(f *object.File) error {
+		dir, name := path.Split(f.Name)
+		if path.Base(dir) != MetaDir || !strings.HasSuffix(name, repo.DB.metaExt()) || f.Name == own {
+			return nil
+		}
+		s, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		if bytes.Contains([]byte(s), quoted) {
+			return fmt.Errorf("%w: %s", ErrContentReferenced, path.Join(rec.Folder(), rec.FileName()))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrContentReferenced) {
+		return fmt.Errorf("unable to read references of %s: %v", repo.Name, err)
+	}
+	return err
+}
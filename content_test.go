@@ -0,0 +1,77 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_WriteContent(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithContentAddressed("other"))
+	repo := &repodb.Repo{Name: "ContentRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	const hello = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	tests := []struct {
+		name       string
+		folder     string
+		content    string
+		want       string
+		wantCommit bool
+		wantErr    bool
+	}{
+		{"written", "other", "hello", hello, true, false},
+		{"duplicate", "other", "hello", hello, false, false},
+		{"not content addressed", "files", "hello", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := repo.Head()
+			got, err := repo.WriteContent(tt.folder, strings.NewReader(tt.content), repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repo.WriteContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Repo.WriteContent() = %s, want %s", got, tt.want)
+			}
+			after, _ := repo.Head()
+			if (before != after) != tt.wantCommit {
+				t.Errorf("Repo.WriteContent() committed = %v, want %v", before != after, tt.wantCommit)
+			}
+		})
+	}
+
+	b := &bytes.Buffer{}
+	if _, err := repo.ReadFile(&otherRecord{Name: hello}, b); err != nil || b.String() != "hello" {
+		t.Errorf("Repo.ReadFile() = %q, error = %v", b.String(), err)
+	}
+	if err := repo.WriteFile(&otherRecord{Name: hello}, strings.NewReader("changed"), repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrContentAddressed) {
+		t.Errorf("Repo.WriteFile() error = %v, want %v", err, repodb.ErrContentAddressed)
+	}
+	if err := repo.RenameRecord(&otherRecord{Name: hello}, "renamed", repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrContentAddressed) {
+		t.Errorf("Repo.RenameRecord() error = %v, want %v", err, repodb.ErrContentAddressed)
+	}
+
+	// meta-data of the content is not a reference to it, meta-data of other records is
+	if err := repo.WriteMeta(&otherRecord{Name: hello}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	ref := &FileRecord{Name: hello}
+	if err := repo.WriteMeta(ref, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RemoveFile(&otherRecord{Name: hello}, repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrContentReferenced) {
+		t.Errorf("Repo.RemoveFile() referenced error = %v, want %v", err, repodb.ErrContentReferenced)
+	}
+	if err := repo.RemoveMeta(ref, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RemoveFile(&otherRecord{Name: hello}, repodb.DBRepoCommitOptions); err != nil {
+		t.Errorf("Repo.RemoveFile() unreferenced error = %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package repodb
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// ConflictPolicy decides how CopyRecord handles a record already in the destination
+type ConflictPolicy int
+
+// conflict policies
+const (
+	ConflictFail      ConflictPolicy = iota // return ErrRecordAlreadyExists
+	ConflictOverwrite                       // replace the destination record
+)
+
+// CopyRecord copies the record file and meta-data from the src repo to the dst repo,
+// such as when promoting records from a staging to a production repo, in a single
+// commit to dst. Content is re-encrypted for dst if encryption is enabled. If the
+// record exists in dst the policy decides if ErrRecordAlreadyExists is returned or it
+// is overwritten, a held record cannot be overwritten. Returns an error satisfying
+// errors.Is(err, os.ErrNotExist) if src has neither file nor meta-data for the record.
+func (db *RepoDB) CopyRecord(src, dst *Repo, rec Record, policy ConflictPolicy, opts CommitOptions) (err error) {
+	defer db.metrics.observe("copy_record", time.Now(), &err)
+	if err := src.authorize(ActionRead, rec); err != nil {
+		return err
+	}
+	if err := dst.authorize(ActionWrite, rec); err != nil {
+		return err
+	}
+	if src.Dir() == dst.Dir() {
+		return fmt.Errorf("CopyRecord source and destination are the same repo %s", src.Name)
+	}
+	if err := dst.DB.validateRecord(rec); err != nil {
+		return err
+	}
+
+	// repos are locked in directory order, so concurrent copies in both directions
+	// cannot deadlock
+	if src.Dir() < dst.Dir() {
+		db.metrics.lock("repo", src.RLocker())
+		db.metrics.lock("repo", dst)
+	} else {
+		db.metrics.lock("repo", dst)
+		db.metrics.lock("repo", src.RLocker())
+	}
+	defer src.RUnlock()
+	defer dst.Unlock()
+
+	if replayed, err := dst.replayed(OpCopyRecord, rec, opts); err != nil || replayed {
+		return err
+	}
+	if err := dst.prepareWrite(); err != nil {
+		return err
+	}
+	name := path.Join(rec.Folder(), rec.FileName())
+	if dst.FileExists(rec) || dst.DB.fileExists(dst.DB.metaStore(path.Join(dst.Dir(), rec.Folder())).filename(rec.FileName())) {
+		if policy != ConflictOverwrite {
+			return fmt.Errorf("%w: %s in %s", ErrRecordAlreadyExists, name, dst.Name)
+		}
+		if dst.isHeld(rec) {
+			return ErrLegalHold
+		}
+	}
+
+	meta, err := src.DB.readFile(src.DB.metaStore(path.Join(src.Dir(), rec.Folder())).filename(rec.FileName()))
+	switch {
+	case os.IsNotExist(err):
+		meta = nil
+	case err != nil:
+		return fmt.Errorf("unable to copy %s: %v", name, err)
+	}
+	attr, err := src.loadAttr(rec)
+	if err != nil {
+		return err
+	}
+	f, err := src.openFile(rec)
+	switch {
+	case os.IsNotExist(err) && meta == nil:
+		return fmt.Errorf("unable to copy %s: %w", name, os.ErrNotExist)
+	case os.IsNotExist(err):
+		f = nil
+	case err != nil:
+		return fmt.Errorf("unable to copy %s: %v", name, err)
+	default:
+		defer f.Close()
+	}
+
+	// meta-data is written first, so it is committed with the file by writeFile
+	if meta != nil {
+		store := dst.DB.metaStore(path.Join(dst.Dir(), rec.Folder()))
+		var err error
+		if src.DB.metaExt() == dst.DB.metaExt() {
+			err = store.writeEncoded(rec.FileName(), meta)
+		} else {
+			// re-encode for a destination DB of another meta-data codec
+			m := Meta{}
+			if err = src.DB.MetaCodec().Unmarshal(meta, &m); err == nil {
+				err = store.write(rec.FileName(), m)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("unable to copy meta-data of %s: %v", name, err)
+		}
+	}
+	opts.Msg = dst.DB.commitMessage(CommitMessage{
+		Operation: OpCopyRecord,
+		Record:    name,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("copied record %s from %s", name, src.Name),
+	})
+	if f == nil {
+		return dst.commit(OpCopyRecord, rec, opts)
+	}
+	return dst.writeFile(OpCopyRecord, rec, f, attr, opts)
+}
+
+// fileExists reports if the named file exists
+func (db *RepoDB) fileExists(name string) bool {
+	_, err := db.fs.Stat(name)
+	return err == nil
+}
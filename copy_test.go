@@ -0,0 +1,104 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_CopyRecord(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithEncryption(repoKeys{"production": key}))
+	staging := &repodb.Repo{Name: "staging", DB: db}
+	production := &repodb.Repo{Name: "production", DB: db}
+	for _, repo := range []*repodb.Repo{staging, production} {
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.txt", "b.txt", "held.txt"} {
+		fr := &FileRecord{Name: name}
+		if err := staging.WriteFile(fr, strings.NewReader("staged "+name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		if err := staging.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := staging.WriteMeta(&FileRecord{Name: "meta.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b.txt", "held.txt"} {
+		if err := production.WriteFile(&FileRecord{Name: name}, strings.NewReader("old"), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := production.LegalHold(&FileRecord{Name: "held.txt"}, "case"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		policy  repodb.ConflictPolicy
+		src     *repodb.Repo
+		want    string
+		wantErr bool
+		is      error
+	}{
+		{"copied", "a.txt", repodb.ConflictFail, staging, "staged a.txt", false, nil},
+		{"conflict", "b.txt", repodb.ConflictFail, staging, "old", true, repodb.ErrRecordAlreadyExists},
+		{"overwrite", "b.txt", repodb.ConflictOverwrite, staging, "staged b.txt", false, nil},
+		{"held", "held.txt", repodb.ConflictOverwrite, staging, "old", true, repodb.ErrLegalHold},
+		{"meta only", "meta.txt", repodb.ConflictFail, staging, "", false, nil},
+		{"missing", "missing.txt", repodb.ConflictFail, staging, "", true, os.ErrNotExist},
+		{"same repo", "a.txt", repodb.ConflictOverwrite, production, "staged a.txt", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := &FileRecord{Name: tt.file}
+			err := db.CopyRecord(tt.src, production, fr, tt.policy, repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("RepoDB.CopyRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want == "" {
+				return
+			}
+			b := &bytes.Buffer{}
+			if _, err := production.ReadFile(fr, b); err != nil || b.String() != tt.want {
+				t.Errorf("copied record = %q, error = %v, want %q", b.String(), err, tt.want)
+			}
+		})
+	}
+
+	if err := production.LoadMeta(&FileRecord{Name: "meta.txt"}); err != nil {
+		t.Errorf("copied meta-data error = %v", err)
+	}
+	activity, err := production.RecentActivity(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity[0].Operation != repodb.OpCopyRecord || activity[0].Record != "files/meta.txt" {
+		t.Errorf("RecentActivity() = %v, want copy of files/meta.txt", activity[0])
+	}
+
+	// copies in both directions at once do not deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			db.CopyRecord(staging, production, &FileRecord{Name: "a.txt"}, repodb.ConflictOverwrite, repodb.DBRepoCommitOptions)
+		}()
+		go func() {
+			defer wg.Done()
+			db.CopyRecord(production, staging, &FileRecord{Name: "a.txt"}, repodb.ConflictOverwrite, repodb.DBRepoCommitOptions)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,129 @@
+package repodb
+
+import (
+	"path"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LargestRepos is the number of repos listed in DBStats.Largest
+var LargestRepos = 10
+
+// DBStats is a capacity report of the whole database
+type DBStats struct {
+	Dir      string    `json:"dir"`
+	Time     time.Time `json:"time"`
+	Repos    int       `json:"repos"`
+	Skipped  int       `json:"skipped"`   // repos whose stats could not be computed
+	Size     int64     `json:"size"`      // bytes of record files in all repos
+	DiskSize int64     `json:"disk_size"` // bytes of all repo directories
+	Records  int       `json:"records"`
+	Commits  int       `json:"commits"`
+
+	Largest        []*Stats  `json:"largest"`         // repos with the largest DiskSize, largest first
+	OldestActivity time.Time `json:"oldest_activity"` // earliest last commit of any repo
+	NewestActivity time.Time `json:"newest_activity"` // latest last commit of any repo
+}
+
+// Stats computes a capacity report of all repos in the database and its namespaces.
+// Repo stats are computed concurrently, one worker per CPU. Repos that fail are logged
+// to the DB logger and counted as skipped. Repos of namespaces are listed in Largest by
+// their path under NamespacesDir.
+func (db *RepoDB) Stats() (_ *DBStats, err error) {
+	defer db.metrics.observe("db_stats", time.Now(), &err)
+	repos, err := db.ListReposPage(ListOptions{SkipMeta: true})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(chan *Repo)
+	results := make(chan *Stats)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range jobs {
+				stats, err := repo.Stats()
+				if err != nil {
+					db.warn("unable to compute repo stats", "repo", repo.Name, "err", err)
+				}
+				results <- stats
+			}
+		}()
+	}
+	go func() {
+		for _, repo := range repos {
+			jobs <- repo
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	report := &DBStats{Dir: db.dir, Time: time.Now(), Largest: []*Stats{}}
+	for stats := range results {
+		if stats == nil {
+			report.Skipped++
+			continue
+		}
+		report.Repos++
+		report.Size += stats.Size
+		report.DiskSize += stats.DiskSize
+		report.Records += stats.Records
+		report.Commits += stats.Commits
+		if !stats.LastCommit.IsZero() {
+			if report.OldestActivity.IsZero() || stats.LastCommit.Before(report.OldestActivity) {
+				report.OldestActivity = stats.LastCommit
+			}
+			if stats.LastCommit.After(report.NewestActivity) {
+				report.NewestActivity = stats.LastCommit
+			}
+		}
+		report.Largest = append(report.Largest, stats)
+	}
+	err = db.eachNamespace(func(ns *RepoDB, dir string) error {
+		nsReport, err := ns.Stats()
+		if err != nil {
+			return err
+		}
+		report.merge(nsReport, dir)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Largest, func(i, j int) bool {
+		if report.Largest[i].DiskSize != report.Largest[j].DiskSize {
+			return report.Largest[i].DiskSize > report.Largest[j].DiskSize
+		}
+		return report.Largest[i].Repo < report.Largest[j].Repo
+	})
+	if len(report.Largest) > LargestRepos {
+		report.Largest = report.Largest[:LargestRepos]
+	}
+	return report, nil
+}
+
+// merge adds the report of the namespace in dir to the report
+func (r *DBStats) merge(ns *DBStats, dir string) {
+	r.Repos += ns.Repos
+	r.Skipped += ns.Skipped
+	r.Size += ns.Size
+	r.DiskSize += ns.DiskSize
+	r.Records += ns.Records
+	r.Commits += ns.Commits
+	if !ns.OldestActivity.IsZero() && (r.OldestActivity.IsZero() || ns.OldestActivity.Before(r.OldestActivity)) {
+		r.OldestActivity = ns.OldestActivity
+	}
+	if ns.NewestActivity.After(r.NewestActivity) {
+		r.NewestActivity = ns.NewestActivity
+	}
+	for _, stats := range ns.Largest {
+		stats.Repo = path.Join(dir, stats.Repo)
+		r.Largest = append(r.Largest, stats)
+	}
+}
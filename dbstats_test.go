@@ -0,0 +1,45 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_Stats(t *testing.T) {
+	db := newTestDB(t)
+	for i, r := range []struct {
+		name string
+		size int
+	}{{"small", 10}, {"large", 100000}, {"medium", 1000}} {
+		if i == 1 {
+			time.Sleep(1100 * time.Millisecond) // commit times have second precision
+		}
+		name, size := r.name, r.size
+		repo := &repodb.Repo{Name: name, DB: db}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader(strings.Repeat("x", size)), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(n int) { repodb.LargestRepos = n }(repodb.LargestRepos)
+	repodb.LargestRepos = 2
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Repos != 3 || stats.Skipped != 0 || stats.Records != 3 || stats.Commits != 6 || stats.Size != 101010 {
+		t.Errorf("RepoDB.Stats() = %+v", stats)
+	}
+	if len(stats.Largest) != 2 || stats.Largest[0].Repo != "large" || stats.Largest[1].Repo != "medium" {
+		t.Errorf("RepoDB.Stats() Largest = %v, want large and medium", stats.Largest)
+	}
+	if !stats.OldestActivity.Before(stats.NewestActivity) {
+		t.Errorf("RepoDB.Stats() OldestActivity %v not before NewestActivity %v", stats.OldestActivity, stats.NewestActivity)
+	}
+}
@@ -0,0 +1,46 @@
+package repodb
+
+import (
+	"time"
+)
+
+// OpFlush is the operation of commits made by Repo.Flush
+const OpFlush = "flush"
+
+// WithDeferredCommits makes record writes and removals of worktree repos leave their
+// changes uncommitted, for high frequency writers, until Repo.Flush commits everything
+// accumulated in a single commit. Other operations, and writes of repo meta-data,
+// commit the pending changes with their own. Deferred writes run no commit hooks and
+// record no idempotency keys until flushed, and bare repos commit every write.
+func WithDeferredCommits() Option {
+	return func(db *RepoDB) {
+		db.deferCommits = true
+	}
+}
+
+// deferred reports if the commit of the operation on the record is deferred to Flush
+func (repo *Repo) deferred(op string, rec Record) bool {
+	if !repo.DB.deferCommits || repo.isBare() {
+		return false
+	}
+	if _, ok := rec.(*Repo); ok {
+		return false
+	}
+	switch op {
+	case OpWriteFile, OpWriteFiles, OpWriteMeta, OpRemoveFile, OpRemoveMeta:
+		return true
+	}
+	return false
+}
+
+// Flush commits the changes of deferred writes to the repo, see WithDeferredCommits.
+// Nothing is committed if there are none.
+func (repo *Repo) Flush(opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("flush", time.Now(), &err)
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	return repo.commitPending(opts, "flushed deferred writes")
+}
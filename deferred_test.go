@@ -0,0 +1,71 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithDeferredCommits(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithDeferredCommits(), repodb.WithStrictIntegrity())
+	repo := &repodb.Repo{Name: "Deferred", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	before, err := repo.RecentActivity(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := repo.WriteFile(&FileRecord{Name: name}, strings.NewReader(name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.WriteMeta(&FileRecord{Name: "a.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.RecentActivity(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(before) {
+		t.Errorf("deferred writes committed %d times", len(got)-len(before))
+	}
+	buf := &strings.Builder{}
+	if _, err := repo.ReadFile(&FileRecord{Name: "b.txt"}, buf); err != nil || buf.String() != "b.txt" {
+		t.Errorf("Repo.ReadFile() before Flush = %q, error = %v", buf, err)
+	}
+
+	if err := repo.Flush(repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	got, err = repo.RecentActivity(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(before)+1 || got[0].Operation != repodb.OpFlush {
+		t.Errorf("Repo.Flush() activity = %+v, want a single flush commit", got)
+	}
+	if err := repo.Flush(repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := repo.RecentActivity(10); err != nil || len(again) != len(got) {
+		t.Errorf("Repo.Flush() with nothing pending committed, error = %v", err)
+	}
+
+	// removals are deferred too, until flushed from the HEAD commit
+	if err := repo.RemoveFile(&FileRecord{Name: "c.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if !committed(t, repo, "files/c.txt") {
+		t.Error("deferred Repo.RemoveFile() committed before Flush")
+	}
+	if err := repo.Flush(repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if committed(t, repo, "files/c.txt") {
+		t.Error("Repo.Flush() left removed files/c.txt committed")
+	}
+}
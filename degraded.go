@@ -0,0 +1,192 @@
+package repodb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// HookCommitDeferred is the HookEvent type sent when a commit fails in degraded mode
+// and the write is kept on disk for a later repair commit.
+const HookCommitDeferred = "commit_deferred"
+
+// OpRepair is the operation of commits made by Repo.Repair
+const OpRepair = "repair"
+
+// incidentsFile is kept in the .git directory so it is never committed itself
+const incidentsFile = "repodb-incidents.json"
+
+// Incident records a write whose commit failed in degraded mode
+type Incident struct {
+	Operation string
+	Record    string
+	Message   string
+	Error     string
+	Time      time.Time
+}
+
+// WithDegradedCommits keeps writes on disk when their commit fails, for example due
+// to a corrupted index, rather than returning the error. An incident is recorded and
+// the changes are committed by a background repair every interval until it succeeds,
+// ctx is done, or the repo no longer exists. For applications prioritizing
+// availability over immediate versioning.
+func WithDegradedCommits(ctx context.Context, interval time.Duration) Option {
+	return func(db *RepoDB) {
+		db.repairCtx = ctx
+		db.repairInterval = interval
+		db.repairing = make(map[string]bool)
+	}
+}
+
+// Incidents returns the writes whose commits have failed and not yet been repaired
+func (repo *Repo) Incidents() ([]Incident, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.incidents()
+}
+
+// Repair commits all changes left uncommitted by failed commits in a single commit,
+// and clears the recorded incidents.
+func (repo *Repo) Repair(opts CommitOptions) error {
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	incidents, err := repo.incidents()
+	if err != nil || len(incidents) == 0 {
+		return err
+	}
+	if err := repo.DB.checkFrozen(); err != nil {
+		return err
+	}
+
+	msgs := make([]string, 0, len(incidents))
+	for _, inc := range incidents {
+		msgs = append(msgs, fmt.Sprintf("%s %s %s: %s", inc.Time.Format(time.RFC3339), inc.Operation, inc.Record, inc.Message))
+	}
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpRepair,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("recovered %d deferred commits:\n%s", len(incidents), strings.Join(msgs, "\n")),
+	})
+	if err := repo.commitOnce(OpRepair, nil, opts); err != nil {
+		return fmt.Errorf("unable to repair %s: %v", repo.Name, err)
+	}
+	return repo.DB.fs.Remove(repo.incidentsPath())
+}
+
+// degrade records the failed commit as an incident and schedules a repair, the caller
+// must hold the repo lock. The commit error is returned if the incident cannot be
+// recorded.
+func (repo *Repo) degrade(op string, rec Record, opts CommitOptions, commitErr error) error {
+	incidents, err := repo.incidents()
+	if err != nil {
+		return commitErr
+	}
+	inc := Incident{
+		Operation: op,
+		Message:   strings.TrimSpace(opts.Msg),
+		Error:     commitErr.Error(),
+		Time:      time.Now(),
+	}
+	if rec != nil {
+		inc.Record = path.Join(rec.Folder(), rec.FileName())
+	}
+	b, err := json.MarshalIndent(append(incidents, inc), "", "\t")
+	if err != nil {
+		return commitErr
+	}
+	if err := repo.DB.writeFile(repo.incidentsPath(), b, 0644); err != nil {
+		return commitErr
+	}
+
+	repo.DB.warn("commit failed, write kept for repair", "repo", repo.Name, "record", inc.Record, "err", commitErr)
+	repo.DB.runHooks(HookEvent{
+		Type:      HookCommitDeferred,
+		Operation: op,
+		Repo:      repo.Name,
+		Record:    inc.Record,
+		Message:   inc.Message,
+		Time:      inc.Time,
+	})
+	repo.scheduleRepair()
+	return nil
+}
+
+// scheduleRepair starts the background repair of the repo, if not already running.
+// The repair stops once the repair ctx is done, or gives up once the incidents are
+// gone with the repo, for example after it is removed or renamed by another handle.
+func (repo *Repo) scheduleRepair() {
+	db := repo.DB
+	db.repairMu.Lock()
+	defer db.repairMu.Unlock()
+	if db.repairing[repo.Name] {
+		return
+	}
+	db.repairing[repo.Name] = true
+
+	go func() {
+		t := time.NewTicker(db.repairInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-db.repairCtx.Done():
+				db.stopRepair(repo.Name)
+				return
+			case <-t.C:
+			}
+			if !db.fileExists(repo.incidentsPath()) {
+				db.warn("repair abandoned, repo no longer exists", "repo", repo.Name)
+				db.stopRepair(repo.Name)
+				return
+			}
+			if err := repo.Repair(CommitOptions{Msg: DBRepoName}); err != nil {
+				db.warn("repair failed", "repo", repo.Name, "err", err)
+				continue
+			}
+			db.stopRepair(repo.Name)
+
+			// incidents recorded while finishing the repair need a new schedule
+			if incidents, err := repo.Incidents(); err == nil && len(incidents) > 0 {
+				repo.scheduleRepair()
+			}
+			return
+		}
+	}()
+}
+
+// stopRepair marks the background repair of the named repo as no longer running
+func (db *RepoDB) stopRepair(name string) {
+	db.repairMu.Lock()
+	delete(db.repairing, name)
+	db.repairMu.Unlock()
+}
+
+// incidents reads the recorded incidents, the caller must hold the repo lock
+func (repo *Repo) incidents() ([]Incident, error) {
+	b, err := repo.DB.readFile(repo.incidentsPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var incidents []Incident
+	if err := json.Unmarshal(b, &incidents); err != nil {
+		return nil, fmt.Errorf("unable to read incidents for %s: %v", repo.Name, err)
+	}
+	return incidents, nil
+}
+
+func (repo *Repo) incidentsPath() string {
+	return path.Join(repo.Dir(), ".git", incidentsFile)
+}
@@ -0,0 +1,129 @@
+package repodb_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithDegradedCommits(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithDegradedCommits(context.Background(), time.Hour))
+	var deferred int
+	db.AddHook(func(ev repodb.HookEvent) {
+		if ev.Type == repodb.HookCommitDeferred {
+			deferred++
+		}
+	})
+	repo := &repodb.Repo{Name: "DegradedRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt the index so commits fail
+	index := path.Join(repo.Dir(), ".git", "index")
+	if err := ioutil.WriteFile(index, []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "degraded.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("kept"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.WriteFile() in degraded mode error = %v", err)
+	}
+	if !repo.FileExists(fr) || deferred != 1 {
+		t.Errorf("Repo.WriteFile() exists = %v, deferred = %v", repo.FileExists(fr), deferred)
+	}
+	incidents, err := repo.Incidents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 1 || incidents[0].Record != "files/degraded.txt" {
+		t.Errorf("Repo.Incidents() = %+v", incidents)
+	}
+
+	if err := repo.Repair(repodb.DBRepoCommitOptions); err == nil {
+		t.Errorf("Repo.Repair() with corrupt index expected error")
+	}
+	if err := os.Remove(index); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Repair(repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if incidents, _ := repo.Incidents(); len(incidents) != 0 {
+		t.Errorf("Repo.Incidents() after repair = %+v", incidents)
+	}
+	activity, err := repo.RecentActivity(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity[0].Operation != repodb.OpRepair {
+		t.Errorf("Repo.RecentActivity() after repair = %+v", activity[0])
+	}
+}
+
+func TestWithDegradedCommits_stop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	buf := &syncBuffer{}
+	db := repodb.NewDB(newTestDB(t).Dir(),
+		repodb.WithDegradedCommits(ctx, 10*time.Millisecond),
+		repodb.WithLogger(slog.New(slog.NewTextHandler(buf, nil))))
+
+	// degrade writes a record with a corrupt index, so the repair keeps failing
+	degrade := func(name string) *repodb.Repo {
+		t.Helper()
+		repo := &repodb.Repo{Name: name, DB: db}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(repo.Dir(), ".git", "index"), []byte("corrupt"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteFile(&FileRecord{Name: "degraded.txt"}, strings.NewReader("kept"), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}
+	waitLog := func(want string) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !strings.Contains(buf.String(), want); {
+			if time.Now().After(deadline) {
+				t.Fatalf("WithDegradedCommits() log missing %q:\n%s", want, buf)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// the repair gives up once the repo is removed, here by another process
+	removed := degrade("RemovedRepo")
+	waitLog("msg=\"repair failed\" repo=RemovedRepo")
+	if err := os.RemoveAll(removed.Dir()); err != nil {
+		t.Fatal(err)
+	}
+	failures := func() int { return strings.Count(buf.String(), "msg=\"repair failed\" repo=RemovedRepo") }
+	time.Sleep(50 * time.Millisecond)
+	n := failures()
+	time.Sleep(50 * time.Millisecond)
+	if failures() != n {
+		t.Errorf("WithDegradedCommits() repair retried after the repo was removed:\n%s", buf)
+	}
+
+	// the repair stops once ctx is done, leaving the incident in place
+	canceled := degrade("CanceledRepo")
+	waitLog("msg=\"repair failed\" repo=CanceledRepo")
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	if err := os.Remove(path.Join(canceled.Dir(), ".git", "index")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if incidents, err := canceled.Incidents(); err != nil || len(incidents) != 1 {
+		t.Errorf("Repo.Incidents() after cancel = %+v, %v", incidents, err)
+	}
+}
@@ -0,0 +1,222 @@
+package repodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DeliveryRepoName is the internal repo of a DeliveryQueue. Commits to it are not
+// delivered to webhooks.
+const DeliveryRepoName = "repodb-deliveries"
+
+// Delivery is a failed webhook delivery waiting to be retried. The webhook secret is
+// not stored, it is looked up from the dispatcher when retrying.
+type Delivery struct {
+	ID          string
+	URL         string
+	Event       HookEvent
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	FailedOn    time.Time
+}
+
+// FileName returns the delivery id. Implements Record interface
+func (d *Delivery) FileName() string {
+	return d.ID
+}
+
+// Folder is the record folder for deliveries. Implements Record interface
+func (d *Delivery) Folder() string {
+	return "deliveries"
+}
+
+// deliverySeq makes delivery ids unique within the process
+var deliverySeq uint64
+
+// DeliveryQueue persists failed webhook deliveries to an internal repo and retries
+// them with exponential backoff until delivered, so notifications survive endpoint
+// outages and process restarts.
+type DeliveryQueue struct {
+	Backoff    time.Duration // wait before the first retry, doubled for each retry
+	MaxBackoff time.Duration // maximum wait between retries
+
+	repo       *Repo
+	dispatcher *WebhookDispatcher
+}
+
+// NewDeliveryQueue returns a queue of the failed deliveries of the dispatcher, creating
+// its internal repo in the DB if needed. Failed deliveries are queued in addition to
+// calling any existing OnError. Retry queued deliveries with Retry or Start.
+func NewDeliveryQueue(db *RepoDB, d *WebhookDispatcher) (*DeliveryQueue, error) {
+	repo, err := db.OpenRepo(DeliveryRepoName)
+	if errors.Is(err, ErrRepoNotExists) {
+		repo = &Repo{Name: DeliveryRepoName, DB: db, Description: "failed webhook deliveries", Protected: true}
+		err = db.CreateRepo(repo)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open delivery queue: %v", err)
+	}
+
+	q := &DeliveryQueue{
+		Backoff:    time.Minute,
+		MaxBackoff: time.Hour,
+		repo:       repo,
+		dispatcher: d,
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.skipRepo = DeliveryRepoName
+	onError := d.OnError
+	d.OnError = func(wh Webhook, ev HookEvent, err error) {
+		q.Enqueue(wh, ev, err)
+		if onError != nil {
+			onError(wh, ev, err)
+		}
+	}
+	return q, nil
+}
+
+// Enqueue persists a failed delivery for retry. Errors are logged to the DB logger.
+func (q *DeliveryQueue) Enqueue(wh Webhook, ev HookEvent, err error) {
+	now := time.Now()
+	d := &Delivery{
+		ID:          fmt.Sprintf("%d-%d", now.UnixNano(), atomic.AddUint64(&deliverySeq, 1)),
+		URL:         wh.URL,
+		Event:       ev,
+		Attempts:    1,
+		NextAttempt: now.Add(q.Backoff),
+		LastError:   err.Error(),
+		FailedOn:    now,
+	}
+	if err := q.repo.WriteMeta(d, CommitOptions{
+		Msg: fmt.Sprintf("queued failed delivery to %s", wh.URL),
+	}); err != nil {
+		q.repo.DB.warn("unable to queue failed delivery", "url", wh.URL, "err", err)
+	}
+}
+
+// Pending returns the queued deliveries, ordered by next attempt
+func (q *DeliveryQueue) Pending() ([]*Delivery, error) {
+	q.repo.RLock()
+	defer q.repo.RUnlock()
+
+	dir := path.Join(q.repo.Dir(), (&Delivery{}).Folder())
+	records, err := q.repo.DB.metaStore(dir).readAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read queued deliveries: %v", err)
+	}
+	deliveries := make([]*Delivery, 0, len(records))
+	for _, r := range records {
+		d := &Delivery{}
+		if err := q.repo.DB.MetaCodec().Unmarshal(r, d); err != nil {
+			return nil, fmt.Errorf("cannot read queued delivery: %v", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttempt.Before(deliveries[j].NextAttempt)
+	})
+	return deliveries, nil
+}
+
+// Retry attempts each queued delivery that is due, returning the number delivered.
+// Delivered and unregistered webhooks are removed from the queue, failed deliveries
+// are rescheduled with backoff.
+func (q *DeliveryQueue) Retry() (delivered int, err error) {
+	deliveries, err := q.Pending()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for _, d := range deliveries {
+		if d.NextAttempt.After(now) {
+			break
+		}
+		wh, ok := q.webhook(d.URL)
+		if !ok {
+			q.repo.DB.warn("dropped queued delivery to unregistered webhook", "url", d.URL, "id", d.ID)
+			if err := q.remove(d, "dropped"); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		payload, err := json.Marshal(d.Event)
+		if err != nil {
+			return delivered, err
+		}
+		if err := q.dispatcher.post(wh, payload); err != nil {
+			d.Attempts++
+			d.LastError = err.Error()
+			d.NextAttempt = now.Add(q.backoff(d.Attempts))
+			if err := q.repo.WriteMeta(d, CommitOptions{
+				Msg: fmt.Sprintf("delivery to %s failed %d times", d.URL, d.Attempts),
+			}); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err := q.remove(d, "delivered"); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// Start retries queued deliveries every interval until ctx is done. Errors are logged
+// to the DB logger and do not stop the schedule.
+func (q *DeliveryQueue) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if _, err := q.Retry(); err != nil {
+					q.repo.DB.warn("unable to retry queued deliveries", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// backoff returns the wait after the delivery has failed attempts times
+func (q *DeliveryQueue) backoff(attempts int) time.Duration {
+	wait := q.Backoff
+	for i := 1; i < attempts && wait < q.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > q.MaxBackoff {
+		wait = q.MaxBackoff
+	}
+	return wait
+}
+
+// webhook returns the registered webhook for the url
+func (q *DeliveryQueue) webhook(url string) (Webhook, bool) {
+	q.dispatcher.mu.RLock()
+	defer q.dispatcher.mu.RUnlock()
+	for _, wh := range q.dispatcher.webhooks {
+		if wh.URL == url {
+			return wh, true
+		}
+	}
+	return Webhook{}, false
+}
+
+// remove removes the delivery from the queue
+func (q *DeliveryQueue) remove(d *Delivery, reason string) error {
+	return q.repo.RemoveMeta(d, CommitOptions{
+		Msg: fmt.Sprintf("%s queued delivery to %s", reason, d.URL),
+	})
+}
@@ -0,0 +1,102 @@
+package repodb_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestDeliveryQueue(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		down = true
+		got  []repodb.HookEvent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var ev repodb.HookEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			t.Error(err)
+		}
+		got = append(got, ev)
+	}))
+	defer srv.Close()
+
+	d := repodb.NewWebhookDispatcher()
+	d.Retries = 0
+	d.Register(srv.URL, "secret")
+	db := newTestDB(t)
+	q, err := repodb.NewDeliveryQueue(db, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Backoff = 0
+	db.AddHook(d.Hook)
+
+	repo := &repodb.Repo{Name: "QueuedRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	d.Wait()
+
+	pending, err := q.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("DeliveryQueue.Pending() len = %d, want 2", len(pending))
+	}
+	if delivered, err := q.Retry(); err != nil || delivered != 0 {
+		t.Errorf("DeliveryQueue.Retry() while down = %d, %v, want 0", delivered, err)
+	}
+	pending, _ = q.Pending()
+	if len(pending) != 2 || pending[0].Attempts != 2 || pending[0].LastError == "" {
+		t.Errorf("DeliveryQueue.Pending() after failed retry = %+v", pending)
+	}
+
+	// a new queue, as after a restart, retries the persisted deliveries
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	restarted, err := repodb.NewDeliveryQueue(repodb.NewDB(db.Dir()), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.Backoff = 0
+	time.Sleep(time.Millisecond)
+	delivered, err := restarted.Retry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 2 {
+		t.Errorf("DeliveryQueue.Retry() = %d, want 2", delivered)
+	}
+	if pending, _ := restarted.Pending(); len(pending) != 0 {
+		t.Errorf("DeliveryQueue.Pending() after delivery len = %d, want 0", len(pending))
+	}
+	d.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, ev := range got {
+		if ev.Repo != "QueuedRepo" {
+			t.Errorf("delivered event for %s, want only QueuedRepo", ev.Repo)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package repodb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContextLines is the number of unchanged lines around each hunk of a FileDiff patch
+const diffContextLines = 3
+
+// FileDiff is the change to a record file between two commits
+type FileDiff struct {
+	Patch   string // unified diff, empty if the file is unchanged
+	Binary  bool   // either version is binary, Changes is empty
+	Added   int    // number of added lines
+	Removed int    // number of removed lines
+	Changes []LineChange
+}
+
+// LineChange is a line added or removed between two versions of a record file
+type LineChange struct {
+	Added bool   // true if the line was added, false if removed
+	Line  int    // 1-based line number in the new version if added, the old version if removed
+	Text  string // line contents, without the line ending
+}
+
+// DiffFile returns the change to the record file from one commit to another. A record
+// file missing from one of the commits is diffed as created or deleted, an error
+// satisfying errors.Is(err, os.ErrNotExist) is returned if it is in neither.
+// Encrypted and compressed records are diffed in plaintext.
+func (repo *Repo) DiffFile(rec Record, from, to Hash) (FileDiff, error) {
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return FileDiff{}, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	name := path.Join(rec.Folder(), rec.FileName())
+	fromFile, fromContent, err := repo.revisionFile(name, from)
+	if err != nil {
+		return FileDiff{}, err
+	}
+	toFile, toContent, err := repo.revisionFile(name, to)
+	if err != nil {
+		return FileDiff{}, err
+	}
+	if fromFile == nil && toFile == nil {
+		return FileDiff{}, fmt.Errorf("%s not in commits %s or %s: %w", name, from, to, os.ErrNotExist)
+	}
+
+	d := FileDiff{}
+	fp := &filePatch{from: fromFile, to: toFile}
+	if fromFile != nil && toFile != nil && fromFile.hash == toFile.hash {
+		return d, nil
+	}
+	if !isText(fromContent) || !isText(toContent) {
+		d.Binary, fp.binary = true, true
+	} else {
+		fromLine, toLine := 1, 1
+		for _, c := range diff.Do(string(fromContent), string(toContent)) {
+			lines := splitLines(c.Text)
+			switch c.Type {
+			case diffmatchpatch.DiffEqual:
+				fp.chunks = append(fp.chunks, &textChunk{c.Text, fdiff.Equal})
+				fromLine += len(lines)
+				toLine += len(lines)
+			case diffmatchpatch.DiffInsert:
+				fp.chunks = append(fp.chunks, &textChunk{c.Text, fdiff.Add})
+				for _, l := range lines {
+					d.Changes = append(d.Changes, LineChange{Added: true, Line: toLine, Text: l})
+					toLine++
+				}
+				d.Added += len(lines)
+			case diffmatchpatch.DiffDelete:
+				fp.chunks = append(fp.chunks, &textChunk{c.Text, fdiff.Delete})
+				for _, l := range lines {
+					d.Changes = append(d.Changes, LineChange{Line: fromLine, Text: l})
+					fromLine++
+				}
+				d.Removed += len(lines)
+			}
+		}
+	}
+
+	buf := &strings.Builder{}
+	if err := fdiff.NewUnifiedEncoder(buf, diffContextLines).Encode(&patch{fp}); err != nil {
+		return FileDiff{}, err
+	}
+	d.Patch = buf.String()
+	return d, nil
+}
+
+// revisionFile returns the named file and its decrypted and decompressed contents at
+// the commit, or a nil file if it is not in the commit. The caller must hold the repo
+// lock.
+func (repo *Repo) revisionFile(name string, hash Hash) (*patchFile, []byte, error) {
+	r, err := repo.git()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := r.CommitObject(hash.Git())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read commit %s of %s: %v", hash, repo.Name, err)
+	}
+	f, err := c.File(name)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := f.Reader()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+
+	var content io.Reader = rc
+	key, err := repo.encKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	if key != nil {
+		if content, err = newDecryptReader(rc, key, repo.fileAAD(name)...); err != nil {
+			return nil, nil, err
+		}
+	}
+	buf := &bytes.Buffer{}
+	if _, err := io.Copy(buf, content); err != nil {
+		return nil, nil, fmt.Errorf("unable to read %s at %s: %v", name, hash, err)
+	}
+
+	// blob pointers are diffed as stored, the content they point to is compressed
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+	attr, err := repo.treeAttr(tree, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	codec, err := repo.DB.codec(attr.Codec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if codec != nil && !attr.Blob {
+		dr, err := codec.NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to decompress %s at %s: %v", name, hash, err)
+		}
+		defer dr.Close()
+		b, err := ioutil.ReadAll(dr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to decompress %s at %s: %v", name, hash, err)
+		}
+		buf = bytes.NewBuffer(b)
+	}
+	return &patchFile{hash: f.Hash, mode: f.Mode, path: name}, buf.Bytes(), nil
+}
+
+// splitLines splits s after each line ending, without the line endings
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(strings.TrimSuffix(l, "\n"), "\r")
+	}
+	return lines
+}
+
+// patch, filePatch, patchFile and textChunk implement the go-git diff interfaces for
+// encoding a single file patch
+type patch struct {
+	fp *filePatch
+}
+
+func (p *patch) FilePatches() []fdiff.FilePatch { return []fdiff.FilePatch{p.fp} }
+func (p *patch) Message() string                { return "" }
+
+type filePatch struct {
+	from, to *patchFile
+	chunks   []fdiff.Chunk
+	binary   bool
+}
+
+func (fp *filePatch) IsBinary() bool        { return fp.binary }
+func (fp *filePatch) Chunks() []fdiff.Chunk { return fp.chunks }
+func (fp *filePatch) Files() (from, to fdiff.File) {
+	// nil files must be returned as nil interfaces
+	if fp.from != nil {
+		from = fp.from
+	}
+	if fp.to != nil {
+		to = fp.to
+	}
+	return from, to
+}
+
+type patchFile struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+	path string
+}
+
+func (f *patchFile) Hash() plumbing.Hash     { return f.hash }
+func (f *patchFile) Mode() filemode.FileMode { return f.mode }
+func (f *patchFile) Path() string            { return f.path }
+
+type textChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c *textChunk) Content() string       { return c.content }
+func (c *textChunk) Type() fdiff.Operation { return c.op }
@@ -0,0 +1,129 @@
+package repodb_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_DiffFile(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithEncryption(repoKeys{"DiffRepo": make([]byte, 32)}))
+	repo := &repodb.Repo{Name: "DiffRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	commits := []repodb.Hash{}
+	write := func(rec *FileRecord, content string) {
+		t.Helper()
+		if err := repo.WriteFile(rec, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		head, err := repo.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, head)
+	}
+	write(&FileRecord{Name: "a.txt"}, "one\ntwo\nthree\n")
+	write(&FileRecord{Name: "a.txt"}, "one\n2\nthree\nfour\n")
+	write(&FileRecord{Name: "b.bin"}, "\x00\x01")
+	if err := repo.RemoveFile(&FileRecord{Name: "a.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits = append(commits, head)
+
+	tests := []struct {
+		name    string
+		file    string
+		from    repodb.Hash
+		to      repodb.Hash
+		want    repodb.FileDiff
+		patch   string
+		wantErr bool
+		is      error
+	}{
+		{
+			name: "changed",
+			file: "a.txt",
+			from: commits[0],
+			to:   commits[1],
+			want: repodb.FileDiff{Added: 2, Removed: 1, Changes: []repodb.LineChange{
+				{Line: 2, Text: "two"},
+				{Added: true, Line: 2, Text: "2"},
+				{Added: true, Line: 4, Text: "four"},
+			}},
+			patch: "@@ -1,3 +1,4 @@\n one\n-two\n+2\n three\n+four\n",
+		},
+		{
+			name:  "unchanged",
+			file:  "a.txt",
+			from:  commits[1],
+			to:    commits[2],
+			want:  repodb.FileDiff{},
+			patch: "",
+		},
+		{
+			name:  "deleted",
+			file:  "a.txt",
+			from:  commits[2],
+			to:    commits[3],
+			want:  repodb.FileDiff{Removed: 4},
+			patch: "+++ /dev/null\n@@ -1,4 +0,0 @@\n-one\n-2\n-three\n-four\n",
+		},
+		{
+			name:  "binary",
+			file:  "b.bin",
+			from:  commits[1],
+			to:    commits[2],
+			want:  repodb.FileDiff{Binary: true},
+			patch: "Binary files /dev/null and b/files/b.bin differ\n",
+		},
+		{
+			name:    "in neither",
+			file:    "b.bin",
+			from:    commits[0],
+			to:      commits[1],
+			wantErr: true,
+			is:      os.ErrNotExist,
+		},
+		{
+			name:    "unknown commit",
+			file:    "a.txt",
+			from:    repodb.ZeroHash,
+			to:      commits[1],
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.DiffFile(&FileRecord{Name: tt.file}, tt.from, tt.to)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("Repo.DiffFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Added != tt.want.Added || got.Removed != tt.want.Removed || got.Binary != tt.want.Binary {
+				t.Errorf("Repo.DiffFile() = +%d -%d binary %v, want +%d -%d binary %v", got.Added, got.Removed, got.Binary, tt.want.Added, tt.want.Removed, tt.want.Binary)
+			}
+			if tt.want.Changes != nil && len(got.Changes) != len(tt.want.Changes) {
+				t.Fatalf("Repo.DiffFile() Changes = %v, want %v", got.Changes, tt.want.Changes)
+			}
+			for i := range tt.want.Changes {
+				if got.Changes[i] != tt.want.Changes[i] {
+					t.Errorf("Repo.DiffFile() Changes[%d] = %v, want %v", i, got.Changes[i], tt.want.Changes[i])
+				}
+			}
+			if !strings.HasSuffix(got.Patch, tt.patch) || (tt.patch == "") != (got.Patch == "") {
+				t.Errorf("Repo.DiffFile() Patch = %q, want suffix %q", got.Patch, tt.patch)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package repodb
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// encryption stream format: magic, salt, then chunks of
+// [final flag byte][uint32 sealed length][sealed chunk]
+const (
+	encMagic       = "RDE2"
+	encSaltSize    = 32
+	encChunkSize   = 64 * 1024
+	encChunkHeader = 5
+)
+
+// ErrDecrypt is returned when reading an encrypted record fails authentication, which
+// may be due to the wrong key or a corrupt or truncated file.
+var ErrDecrypt = errors.New("unable to decrypt record")
+
+// KeyProvider supplies the AES-256 key used to encrypt record files in a repo. A nil
+// key with nil error leaves the repo unencrypted, allowing per-repo encryption.
+type KeyProvider interface {
+	Key(repo string) ([]byte, error)
+}
+
+// StaticKey is a KeyProvider using the same key for every repo in the database
+type StaticKey []byte
+
+// Key returns the static key for any repo. Satisfies KeyProvider.
+func (k StaticKey) Key(repo string) ([]byte, error) {
+	return k, nil
+}
+
+// WithEncryption encrypts record files at rest using AES-GCM with keys from kp.
+// WriteFile encrypts the stream before it is written to disk and ReadFile decrypts
+// it transparently. Each file is encrypted under its own key, derived from the repo key
+// and a random salt stored with the file, and is bound to its path in the repo, so
+// files cannot be swapped between records or repos sharing a key. Meta-data is not
+// encrypted.
+func WithEncryption(kp KeyProvider) Option {
+	return func(db *RepoDB) {
+		db.keys = kp
+	}
+}
+
+// repoKey is the key of an encrypted repo, from which the key of each file is derived
+type repoKey []byte
+
+// encKey returns the key of the repo, or nil if the repo is not encrypted
+func (repo *Repo) encKey() (repoKey, error) {
+	if repo.DB.keys == nil {
+		return nil, nil
+	}
+	key, err := repo.DB.keys.Key(repo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get key for repo %s: %v", repo.Name, err)
+	}
+	if key == nil {
+		return nil, nil
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, fmt.Errorf("invalid key for repo %s: %v", repo.Name, err)
+	}
+	return key, nil
+}
+
+// aead returns the cipher of the file with the salt, its key derived from the repo key
+func (k repoKey) aead(salt []byte) (cipher.AEAD, error) {
+	fileKey := make([]byte, len(k))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, k, salt, []byte("repodb file")), fileKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// fileAAD returns the additional data binding an encrypted file to its repo relative
+// name, by the repo name and then each of its FormerNames, under which files written
+// before the repo was renamed or forked are found
+func (repo *Repo) fileAAD(name string) [][]byte {
+	aad := [][]byte{[]byte(path.Join(repo.Name, name))}
+	for _, former := range repo.FormerNames {
+		aad = append(aad, []byte(path.Join(former, name)))
+	}
+	return aad
+}
+
+// chunkNonce returns the nonce for chunk i, the final flag is part of the nonce so a
+// truncated stream cannot pass as complete. Each file has its own key, so the chunk
+// index is unique to the key.
+func chunkNonce(i uint32, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[7:], i)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptWriter encrypts a stream in fixed size chunks, Close must be called to write
+// the final chunk.
+type encryptWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+	aad  []byte
+	buf  []byte
+	i    uint32
+}
+
+// newEncryptWriter writes the stream header to w and returns the writer encrypting under
+// a new key derived from the repo key, binding the chunks to aad
+func newEncryptWriter(w io.Writer, key repoKey, aad []byte) (*encryptWriter, error) {
+	salt := make([]byte, encSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := key.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, encMagic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(salt); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, aad: aad, buf: make([]byte, 0, encChunkSize)}, nil
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		// only flush full chunks once more data arrives, the last chunk is written on Close
+		if len(ew.buf) == encChunkSize {
+			if err := ew.flush(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(ew.buf[len(ew.buf):encChunkSize], p)
+		ew.buf = ew.buf[:len(ew.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// Close writes the final chunk, it does not close the underlying writer
+func (ew *encryptWriter) Close() error {
+	return ew.flush(true)
+}
+
+func (ew *encryptWriter) flush(final bool) error {
+	sealed := ew.aead.Seal(nil, chunkNonce(ew.i, final), ew.buf, ew.aad)
+	header := make([]byte, encChunkHeader)
+	if final {
+		header[0] = 1
+	}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(sealed)))
+	if _, err := ew.w.Write(header); err != nil {
+		return err
+	}
+	if _, err := ew.w.Write(sealed); err != nil {
+		return err
+	}
+	ew.buf = ew.buf[:0]
+	ew.i++
+	return nil
+}
+
+// decryptReader decrypts a stream written by encryptWriter
+type decryptReader struct {
+	r     *bufio.Reader
+	aead  cipher.AEAD
+	aad   [][]byte // candidates until the first chunk is opened, then the one opening it
+	buf   []byte
+	i     uint32
+	final bool
+}
+
+// newDecryptReader reads the stream header from r and returns the decrypting reader. The
+// stream must have been written with one of the aad.
+func newDecryptReader(r io.Reader, key repoKey, aad ...[]byte) (*decryptReader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(encMagic)+encSaltSize)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(encMagic)]) != encMagic {
+		return nil, ErrDecrypt
+	}
+	aead, err := key.aead(header[len(encMagic):])
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: br, aead: aead, aad: aad}, nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.buf) == 0 {
+		if dr.final {
+			return 0, io.EOF
+		}
+		if err := dr.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+	return n, nil
+}
+
+// next reads and opens the next chunk
+func (dr *decryptReader) next() error {
+	header := make([]byte, encChunkHeader)
+	if _, err := io.ReadFull(dr.r, header); err != nil {
+		return ErrDecrypt
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > encChunkSize+uint32(dr.aead.Overhead()) {
+		return ErrDecrypt
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(dr.r, sealed); err != nil {
+		return ErrDecrypt
+	}
+	final := header[0] == 1
+	nonce := chunkNonce(dr.i, final)
+	for _, aad := range dr.aad {
+		plain, err := dr.aead.Open(nil, nonce, sealed, aad)
+		if err != nil {
+			continue
+		}
+		dr.aad = [][]byte{aad}
+		dr.buf = plain
+		dr.final = final
+		dr.i++
+		return nil
+	}
+	return ErrDecrypt
+}
+
+// reseal encrypts the file from again as the file to, for the repo relative name of to,
+// and removes from. The caller must hold the repo lock.
+func (repo *Repo) reseal(key repoKey, from, to string) error {
+	src, err := repo.DB.fs.Open(from)
+	if err != nil {
+		return err
+	}
+	err = repo.resealTo(key, src, from, to)
+	src.Close()
+	if err != nil {
+		return err
+	}
+	return repo.DB.fs.Remove(from)
+}
+
+// resealTo writes the encrypted file src, read from the file from, to the new file to,
+// which is removed if not written in full
+func (repo *Repo) resealTo(key repoKey, src io.Reader, from, to string) (err error) {
+	dr, err := newDecryptReader(src, key, repo.fileAAD(relPath(repo.Dir(), from))...)
+	if err != nil {
+		return err
+	}
+	dst, err := repo.DB.fs.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		dst.Close()
+		if err != nil {
+			repo.DB.fs.Remove(to)
+		}
+	}()
+	ew, err := newEncryptWriter(dst, key, repo.fileAAD(relPath(repo.Dir(), to))[0])
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(ew, dr); err != nil {
+		return err
+	}
+	if err := ew.Close(); err != nil {
+		return err
+	}
+	return dst.Close()
+}
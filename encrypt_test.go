@@ -0,0 +1,170 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"path"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+// repoKeys encrypts only the repos it has keys for
+type repoKeys map[string][]byte
+
+func (k repoKeys) Key(repo string) ([]byte, error) {
+	return k[repo], nil
+}
+
+func TestWithEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithEncryption(repoKeys{"Secret": key}))
+
+	tests := []struct {
+		name      string
+		repo      string
+		size      int
+		encrypted bool
+	}{
+		{"empty", "Secret", 0, true},
+		{"small", "Secret", 100, true},
+		{"chunk boundary", "Secret", 64 * 1024, true},
+		{"multi chunk", "Secret", 200 * 1024, true},
+		{"plain repo", "Plain", 100, false},
+	}
+	for _, name := range []string{"Secret", "Plain"} {
+		if err := db.CreateRepo(&repodb.Repo{Name: name, DB: db}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := db.OpenRepo(tt.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := make([]byte, tt.size)
+			rand.Read(body)
+			fr := &FileRecord{Name: tt.name}
+			if err := repo.WriteFile(fr, bytes.NewReader(body), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+
+			onDisk, err := ioutil.ReadFile(path.Join(repo.Dir(), fr.Folder(), fr.FileName()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := !bytes.Equal(onDisk, body); got != tt.encrypted {
+				t.Errorf("WriteFile() encrypted = %v, want %v", got, tt.encrypted)
+			}
+
+			buf := &bytes.Buffer{}
+			n, err := repo.ReadFile(fr, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(tt.size) || !bytes.Equal(buf.Bytes(), body) {
+				t.Errorf("ReadFile() read %d bytes, does not match written %d bytes", n, tt.size)
+			}
+		})
+	}
+
+	// wrong key fails authentication
+	wrongDB := repodb.NewDB(db.Dir(), repodb.WithEncryption(repodb.StaticKey(bytes.Repeat([]byte{2}, 32))))
+	repo, err := wrongDB.OpenRepo("Secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ReadFile(&FileRecord{Name: "small"}, &bytes.Buffer{}); !errors.Is(err, repodb.ErrDecrypt) {
+		t.Errorf("ReadFile() with wrong key error = %v, want %v", err, repodb.ErrDecrypt)
+	}
+}
+
+func TestWithEncryption_bound(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithEncryption(repodb.StaticKey(bytes.Repeat([]byte{1}, 32))))
+	write := func(repoName, name, content string) *repodb.Repo {
+		t.Helper()
+		repo, err := db.OpenRepo(repoName)
+		if errors.Is(err, repodb.ErrRepoNotExists) {
+			repo = &repodb.Repo{Name: repoName, DB: db}
+			err = db.CreateRepo(repo)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteFile(&FileRecord{Name: name}, bytes.NewBufferString(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}
+	read := func(repo *repodb.Repo, name string) (string, error) {
+		buf := &bytes.Buffer{}
+		_, err := repo.ReadFile(&FileRecord{Name: name}, buf)
+		return buf.String(), err
+	}
+	file := func(repo *repodb.Repo, name string) string {
+		return path.Join(repo.Dir(), "files", name)
+	}
+
+	// files of the same content are encrypted under different keys
+	a := write("A", "a.txt", "content of a")
+	write("A", "copy.txt", "content of a")
+	first, err := ioutil.ReadFile(file(a, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ioutil.ReadFile(file(a, "copy.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("WriteFile() of the same content wrote the same ciphertext")
+	}
+
+	// files swapped between records or repos sharing the key fail authentication
+	write("A", "b.txt", "content of b")
+	b := write("B", "a.txt", "content of a in B")
+	for _, swap := range []struct{ from, to string }{
+		{file(a, "b.txt"), file(a, "a.txt")},
+		{file(a, "copy.txt"), file(b, "a.txt")},
+	} {
+		content, err := ioutil.ReadFile(swap.from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(swap.to, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := read(a, "a.txt"); !errors.Is(err, repodb.ErrDecrypt) {
+		t.Errorf("Repo.ReadFile() of swapped record = %q, error = %v, want %v", got, err, repodb.ErrDecrypt)
+	}
+	if got, err := read(b, "a.txt"); !errors.Is(err, repodb.ErrDecrypt) {
+		t.Errorf("Repo.ReadFile() of record of another repo = %q, error = %v, want %v", got, err, repodb.ErrDecrypt)
+	}
+
+	// renamed records, renamed repos and forks stay readable
+	if err := a.RenameRecord(&FileRecord{Name: "b.txt"}, "renamed.txt", repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := read(a, "renamed.txt"); err != nil || got != "content of b" {
+		t.Errorf("Repo.ReadFile() of renamed record = %q, error = %v, want %q", got, err, "content of b")
+	}
+	if _, err := db.RenameRepo("A", "C"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ForkRepo("C", "D"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"C", "D"} {
+		repo, err := db.OpenRepo(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := read(repo, "renamed.txt"); err != nil || got != "content of b" {
+			t.Errorf("Repo.ReadFile() in %s = %q, error = %v, want %q", name, got, err, "content of b")
+		}
+	}
+}
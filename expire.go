@@ -0,0 +1,183 @@
+package repodb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// OpExpire is the operation of commits made by RepoDB.ExpireSweep
+const OpExpire = "expire"
+
+// ExpiringRecord is a Record which expires, for using repodb as a cache with history.
+// WriteMeta records the expiry time in the expiries folder of the repo, and
+// RepoDB.ExpireSweep soft deletes or removes the record once it has passed. A zero
+// time never expires.
+type ExpiringRecord interface {
+	Record
+	ExpiresOn() time.Time
+}
+
+// Expiry is the expiry time of a record, written by WriteMeta of an ExpiringRecord.
+// Expiries are stored as meta-data in the expiries folder of the repo.
+type Expiry struct {
+	RecordFolder string
+	RecordName   string
+	ExpiresOn    time.Time
+}
+
+// FileName returns the expiry file name, unique per record. Implements Record interface
+func (e *Expiry) FileName() string {
+	return recordKey(e.RecordFolder, e.RecordName)
+}
+
+// Folder is the record folder for expiries. Implements Record interface
+func (e *Expiry) Folder() string {
+	return "expiries"
+}
+
+// ExpireMode decides what ExpireSweep does with expired records
+type ExpireMode int
+
+// expire modes
+const (
+	ExpireSoftDelete ExpireMode = iota // set SoftDeleted and DeletedOn in the meta-data
+	ExpireRemove                       // remove the file, meta-data and attributes
+)
+
+// ExpireSweep soft deletes or removes the expired records of every repo, in a commit
+// per repo, and returns the number expired. Soft deletion sets the SoftDeleted and
+// DeletedOn meta-data fields, found by name as PurgeDeleted does and added if missing,
+// so PurgeDeleted can remove them later. Records under legal hold are kept until their
+// holds are released.
+func (db *RepoDB) ExpireSweep(mode ExpireMode, opts CommitOptions) (expired int, err error) {
+	defer db.metrics.observe("expire_sweep", time.Now(), &err)
+	repos, err := db.ListReposPage(ListOptions{SkipMeta: true})
+	if err != nil {
+		return 0, err
+	}
+	for _, repo := range repos {
+		n, err := repo.expire(mode, opts)
+		expired += n
+		if err != nil {
+			return expired, fmt.Errorf("unable to expire records in %s: %v", repo.Name, err)
+		}
+	}
+	return expired, nil
+}
+
+// expire sweeps the expired records of the repo
+func (repo *Repo) expire(mode ExpireMode, opts CommitOptions) (int, error) {
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	dir := path.Join(repo.Dir(), (&Expiry{}).Folder())
+	records, err := repo.DB.metaStore(dir).readAll()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	var due []*Expiry
+	for _, r := range records {
+		e := &Expiry{}
+		if err := repo.DB.MetaCodec().Unmarshal(r, e); err != nil {
+			return 0, fmt.Errorf("invalid expiry: %v", err)
+		}
+		rec := &recordRef{folder: e.RecordFolder, name: e.RecordName}
+		if e.ExpiresOn.IsZero() || e.ExpiresOn.After(now) || repo.isHeld(rec) {
+			continue
+		}
+		due = append(due, e)
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+	if err := repo.prepareWrite(); err != nil {
+		return 0, err
+	}
+
+	for _, e := range due {
+		rec := &recordRef{folder: e.RecordFolder, name: e.RecordName}
+		if mode == ExpireRemove {
+			err = repo.removeRecords([]*recordRef{rec})
+		} else {
+			err = repo.softDelete(rec, now)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err := repo.DB.fs.Remove(repo.DB.metaStore(dir).filename(e.FileName())); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpExpire,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("expired %d records", len(due)),
+	})
+	if err := repo.commit(OpExpire, nil, opts); err != nil {
+		return 0, err
+	}
+	return len(due), nil
+}
+
+// softDelete sets the soft deletion fields of the record meta-data, the caller must hold
+// the repo lock
+func (repo *Repo) softDelete(rec *recordRef, now time.Time) error {
+	store := repo.DB.metaStore(path.Join(repo.Dir(), rec.folder))
+	m := Meta{}
+	if err := store.read(rec.name, &m); err != nil {
+		if os.IsNotExist(err) {
+			// records without meta-data cannot be marked deleted
+			return nil
+		}
+		return err
+	}
+	set := func(name, field string, v interface{}) {
+		for k := range m {
+			if normalizedField(k) == name {
+				m[k] = v
+				return
+			}
+		}
+		m[field] = v
+	}
+	set("softdeleted", "SoftDeleted", true)
+	set("deletedon", "DeletedOn", now)
+	return store.write(rec.name, m)
+}
+
+// writeExpiry records the expiry of an ExpiringRecord, to be committed with its
+// meta-data. The caller must hold the repo lock.
+func (repo *Repo) writeExpiry(rec Record) error {
+	er, ok := rec.(ExpiringRecord)
+	if !ok {
+		return nil
+	}
+	e := &Expiry{RecordFolder: rec.Folder(), RecordName: rec.FileName(), ExpiresOn: er.ExpiresOn()}
+	store := repo.DB.metaStore(path.Join(repo.Dir(), e.Folder()))
+	if e.ExpiresOn.IsZero() {
+		if repo.staged != nil {
+			if _, err := repo.bareFile(repo.DB.metaPath(e)); err != nil {
+				return nil
+			}
+			return repo.stageRemove(repo.DB.metaPath(e))
+		}
+		if err := repo.DB.fs.Remove(store.filename(e.FileName())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if repo.staged != nil {
+		b, err := store.encode(e)
+		if err != nil {
+			return err
+		}
+		_, err = repo.stageFile(repo.DB.metaPath(e), bytes.NewReader(b), nil)
+		return err
+	}
+	return store.write(e.FileName(), e)
+}
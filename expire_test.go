@@ -0,0 +1,114 @@
+package repodb_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+// cachedRecord is an ExpiringRecord
+type cachedRecord struct {
+	Name    string
+	Expires time.Time
+}
+
+func (r *cachedRecord) FileName() string     { return r.Name }
+func (r *cachedRecord) Folder() string       { return "cache" }
+func (r *cachedRecord) ExpiresOn() time.Time { return r.Expires }
+
+// expiringRecord is an ExpiringRecord in an arbitrary folder
+type expiringRecord struct {
+	folder, name string
+	expires      time.Time
+}
+
+func (r *expiringRecord) FileName() string     { return r.name }
+func (r *expiringRecord) Folder() string       { return r.folder }
+func (r *expiringRecord) ExpiresOn() time.Time { return r.expires }
+
+func TestRepoDB_ExpireSweep(t *testing.T) {
+	tests := []struct {
+		name string
+		mode repodb.ExpireMode
+		want []string
+	}{
+		{"soft delete", repodb.ExpireSoftDelete, []string{"cache/expired", "cache/fresh", "cache/held", "cache/unexpiring"}},
+		{"remove", repodb.ExpireRemove, []string{"cache/fresh", "cache/held", "cache/unexpiring"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := &repodb.Repo{Name: "Cache", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			past := time.Now().Add(-time.Minute)
+			for _, rec := range []*cachedRecord{
+				{Name: "expired", Expires: past},
+				{Name: "fresh", Expires: time.Now().Add(time.Hour)},
+				{Name: "held", Expires: past},
+				{Name: "unexpiring", Expires: past},
+			} {
+				if err := repo.WriteFile(rec, strings.NewReader(rec.Name), repodb.DBRepoCommitOptions); err != nil {
+					t.Fatal(err)
+				}
+				if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// rewriting with a zero time cancels the expiry
+			if err := repo.WriteMeta(&cachedRecord{Name: "unexpiring"}, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.LegalHold(&cachedRecord{Name: "held"}, "case"); err != nil {
+				t.Fatal(err)
+			}
+
+			if n, err := db.ExpireSweep(tt.mode, repodb.DBRepoCommitOptions); err != nil || n != 1 {
+				t.Fatalf("RepoDB.ExpireSweep() = %d, error = %v, want 1", n, err)
+			}
+			if n, err := db.ExpireSweep(tt.mode, repodb.DBRepoCommitOptions); err != nil || n != 0 {
+				t.Errorf("RepoDB.ExpireSweep() again = %d, error = %v, want 0", n, err)
+			}
+			if got, err := repo.ListRecords("cache", false); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repo.ListRecords() = %v, error = %v, want %v", got, err, tt.want)
+			}
+			if tt.mode == repodb.ExpireSoftDelete {
+				deleted, err := repo.QueryRecords("cache", func(m repodb.Meta) bool { return m.Bool("SoftDeleted") })
+				if err != nil || !reflect.DeepEqual(deleted, []string{"expired"}) {
+					t.Errorf("soft deleted records = %v, error = %v", deleted, err)
+				}
+				if n, err := repo.PurgeDeleted(0, repodb.DBRepoCommitOptions); err != nil || n != 1 {
+					t.Errorf("Repo.PurgeDeleted() = %d, error = %v, want 1", n, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRepoDB_ExpireSweep_distinct(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "Cache", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	expired := &expiringRecord{folder: "a_b", name: "c", expires: time.Now().Add(-time.Minute)}
+	fresh := &expiringRecord{folder: "a", name: "b_c", expires: time.Now().Add(time.Hour)}
+	for _, rec := range []*expiringRecord{expired, fresh} {
+		if err := repo.WriteFile(rec, strings.NewReader(rec.name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := db.ExpireSweep(repodb.ExpireRemove, repodb.DBRepoCommitOptions); err != nil || n != 1 {
+		t.Fatalf("RepoDB.ExpireSweep() = %d, error = %v, want 1", n, err)
+	}
+	if repo.FileExists(expired) || !repo.FileExists(fresh) {
+		t.Errorf("Repo.FileExists() after ExpireSweep = %v %v, want false true", repo.FileExists(expired), repo.FileExists(fresh))
+	}
+}
@@ -0,0 +1,155 @@
+package repodb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ExternalClient is the http client used by FetchExternal. It has no timeout as
+// external content may be large, cancel slow downloads using the client transport.
+var ExternalClient = &http.Client{}
+
+// externalDir caches fetched external content by checksum, it is kept in the .git
+// directory so it is never committed itself
+const externalDir = "repodb-external"
+
+// External is a reference to content hosted outside of the repo, for data too large to
+// store whose provenance should still be versioned. It is stored as the content of its
+// record, written with WriteExternal and materialized with FetchExternal.
+type External struct {
+	URL    string
+	SHA256 string // hex encoded sha256 of the content
+	Size   int64
+}
+
+// validate checks the reference is complete
+func (ext External) validate() error {
+	if ext.URL == "" {
+		return fmt.Errorf("external reference url cannot be empty")
+	}
+	if b, err := hex.DecodeString(ext.SHA256); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("invalid external reference sha256 %q", ext.SHA256)
+	}
+	if ext.Size < 0 {
+		return fmt.Errorf("invalid external reference size %d", ext.Size)
+	}
+	return nil
+}
+
+// WriteExternal writes and commits the external reference as the record content
+func (repo *Repo) WriteExternal(rec Record, ext External, opts CommitOptions) error {
+	if err := ext.validate(); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(ext, "", "  ")
+	if err != nil {
+		return err
+	}
+	return repo.WriteFile(rec, bytes.NewReader(b), opts)
+}
+
+// External returns the external reference stored as the record content
+func (repo *Repo) External(rec Record) (*External, error) {
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.external(rec)
+}
+
+// external reads the record external reference, the caller must hold the repo lock
+func (repo *Repo) external(rec Record) (*External, error) {
+	buf := &bytes.Buffer{}
+	if _, err := repo.readFile(rec, buf); err != nil {
+		return nil, err
+	}
+	ext := &External{}
+	if err := json.Unmarshal(buf.Bytes(), ext); err != nil {
+		return nil, fmt.Errorf("%s is not an external reference: %v", path.Join(rec.Folder(), rec.FileName()), err)
+	}
+	if err := ext.validate(); err != nil {
+		return nil, err
+	}
+	return ext, nil
+}
+
+// FetchExternal copies the content referenced by the external record to w. Content is
+// downloaded on first use and cached by checksum in the repo .git directory, only once
+// its size and checksum are verified, so w never receives unverified content. Returns
+// ErrChecksumMismatch if the downloaded content does not match the reference.
+func (repo *Repo) FetchExternal(rec Record, w io.Writer) (written int64, err error) {
+	defer repo.DB.metrics.observe("fetch_external", time.Now(), &err)
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return 0, err
+	}
+	repo.RLock()
+	ext, err := repo.external(rec)
+	repo.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	cached := path.Join(repo.Dir(), ".git", externalDir, strings.ToLower(ext.SHA256))
+	f, err := repo.DB.fs.Open(cached)
+	if os.IsNotExist(err) {
+		if err := repo.download(ext, cached); err != nil {
+			return 0, err
+		}
+		f, err = repo.DB.fs.Open(cached)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
+
+// download fetches the external content to filename, verifying it first in a temporary
+// file so concurrent or failed downloads never leave partial content behind
+func (repo *Repo) download(ext *External, filename string) error {
+	if err := repo.DB.fs.MkdirAll(path.Dir(filename), 0700); err != nil {
+		return err
+	}
+	resp, err := ExternalClient.Get(ext.URL)
+	if err != nil {
+		return fmt.Errorf("unable to fetch %s: %v", ext.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to fetch %s: %s", ext.URL, resp.Status)
+	}
+
+	tmp, tmpName, err := repo.DB.tempFile(path.Dir(filename), "download-")
+	if err != nil {
+		return err
+	}
+	defer repo.DB.fs.Remove(tmpName)
+	defer tmp.Close()
+
+	h := sha256.New()
+	// read one byte past the size to detect longer content
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(resp.Body, ext.Size+1))
+	if err != nil {
+		return fmt.Errorf("unable to fetch %s: %v", ext.URL, err)
+	}
+	if n != ext.Size {
+		return fmt.Errorf("%w: fetched %d bytes from %s, want %d", ErrChecksumMismatch, n, ext.URL, ext.Size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, ext.SHA256) {
+		return fmt.Errorf("%w: fetched sha256 %s from %s, want %s", ErrChecksumMismatch, sum, ext.URL, ext.SHA256)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return repo.DB.fs.Rename(tmpName, filename)
+}
@@ -0,0 +1,101 @@
+package repodb_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_FetchExternal(t *testing.T) {
+	content := "a large dataset"
+	sum := sha256.Sum256([]byte(content))
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/data":
+			w.Write([]byte(content))
+		case "/changed":
+			w.Write([]byte(strings.ToUpper(content)))
+		case "/longer":
+			w.Write([]byte(content + "!"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "ExternalRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	ref := func(p string) repodb.External {
+		return repodb.External{URL: srv.URL + p, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+	}
+	refs := map[string]repodb.External{
+		"data.ref":    ref("/data"),
+		"changed.ref": ref("/changed"),
+		"longer.ref":  ref("/longer"),
+		"missing.ref": ref("/missing"),
+	}
+	for name, ext := range refs {
+		if err := repo.WriteExternal(&FileRecord{Name: name}, ext, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "plain.txt"}, strings.NewReader("plain"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		want     string
+		wantErr  bool
+		mismatch bool
+	}{
+		{"changed content", "changed.ref", "", true, true},
+		{"longer content", "longer.ref", "", true, true},
+		{"not found", "missing.ref", "", true, false},
+		{"fetched", "data.ref", content, false, false},
+		{"cached", "data.ref", content, false, false},
+		{"not external", "plain.txt", "", true, false},
+		{"missing record", "none.ref", "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			_, err := repo.FetchExternal(&FileRecord{Name: tt.file}, buf)
+			if (err != nil) != tt.wantErr || errors.Is(err, repodb.ErrChecksumMismatch) != tt.mismatch {
+				t.Fatalf("Repo.FetchExternal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Repo.FetchExternal() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+	// each failed fetch is attempted, data.ref is downloaded once
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Errorf("FetchExternal made %d requests, want 4", n)
+	}
+
+	ext, err := repo.External(&FileRecord{Name: "data.ref"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *ext != refs["data.ref"] {
+		t.Errorf("Repo.External() = %v, want %v", ext, refs["data.ref"])
+	}
+	if err := repo.WriteExternal(&FileRecord{Name: "bad.ref"}, repodb.External{URL: srv.URL, SHA256: "abc"}, repodb.DBRepoCommitOptions); err == nil {
+		t.Errorf("Repo.WriteExternal() expected error for invalid checksum")
+	}
+}
@@ -0,0 +1,144 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// RepoStore is the repo management interface implemented by both RepoDB and
+// Federation, so applications can move between one and many database roots.
+type RepoStore interface {
+	CreateRepo(repo *Repo) error
+	OpenRepo(name string) (*Repo, error)
+	RemoveRepo(name string) error
+	ListRepos() []*Repo
+}
+
+var (
+	_ RepoStore = (*RepoDB)(nil)
+	_ RepoStore = (*Federation)(nil)
+)
+
+// Router returns the index of the database in dbs a new repo named name is created in
+type Router func(name string, dbs []*RepoDB) int
+
+// RouteFewestRepos creates new repos in the database with the fewest repos, the first
+// of those with equal counts.
+func RouteFewestRepos(name string, dbs []*RepoDB) int {
+	best, fewest := 0, -1
+	for i, db := range dbs {
+		repos, err := db.ListReposPage(ListOptions{SkipMeta: true})
+		if err != nil {
+			continue
+		}
+		if fewest < 0 || len(repos) < fewest {
+			best, fewest = i, len(repos)
+		}
+	}
+	return best
+}
+
+// RouteHash creates new repos in a database chosen by a hash of the repo name, spreading
+// repos evenly while keeping the placement of a name stable.
+func RouteHash(name string, dbs []*RepoDB) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % uint32(len(dbs)))
+}
+
+// Federation is a view of several RepoDB roots, such as on different disks, as one
+// database. Repos are opened from whichever database holds them, and new repos are
+// created in the database chosen by the Router. Repo names are unique across the
+// federation.
+type Federation struct {
+	mu    sync.Mutex // serializes CreateRepo, so names stay unique
+	dbs   []*RepoDB
+	route Router
+}
+
+// NewFederation returns a Federation of the databases, creating new repos where route
+// chooses. A nil route uses RouteFewestRepos.
+func NewFederation(route Router, dbs ...*RepoDB) *Federation {
+	if route == nil {
+		route = RouteFewestRepos
+	}
+	return &Federation{dbs: dbs, route: route}
+}
+
+// DBs returns the databases of the federation
+func (f *Federation) DBs() []*RepoDB {
+	return append([]*RepoDB(nil), f.dbs...)
+}
+
+// CreateRepo creates the repo in the database chosen by the Router, setting repo.DB.
+// Will return ErrRepoAlreadyExists if any database has a repo with the name.
+func (f *Federation) CreateRepo(repo *Repo) error {
+	if repo == nil {
+		return fmt.Errorf("CreateRepo repo pointer cannot be nil")
+	}
+	if len(f.dbs) == 0 {
+		return fmt.Errorf("CreateRepo federation has no databases")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.Locate(repo.Name); err == nil {
+		return ErrRepoAlreadyExists
+	}
+	i := f.route(repo.Name, f.dbs)
+	if i < 0 || i >= len(f.dbs) {
+		return fmt.Errorf("CreateRepo router returned invalid database %d", i)
+	}
+	repo.DB = f.dbs[i]
+	return repo.DB.CreateRepo(repo)
+}
+
+// OpenRepo opens the named repo from the database holding it. Will return
+// ErrRepoNotExists if no database has the repo.
+func (f *Federation) OpenRepo(name string) (*Repo, error) {
+	db, err := f.Locate(name)
+	if err != nil {
+		return nil, err
+	}
+	return db.OpenRepo(name)
+}
+
+// RemoveRepo removes the named repo from the database holding it
+func (f *Federation) RemoveRepo(name string) error {
+	db, err := f.Locate(name)
+	if err != nil {
+		return err
+	}
+	return db.RemoveRepo(name)
+}
+
+// ListRepos returns the repos of all databases sorted by name. Repos that cannot be
+// opened are skipped.
+func (f *Federation) ListRepos() []*Repo {
+	var repos []*Repo
+	for _, db := range f.dbs {
+		repos = append(repos, db.ListRepos()...)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Name < repos[j].Name
+	})
+	return repos
+}
+
+// Locate returns the database holding the named repo, or ErrRepoNotExists
+func (f *Federation) Locate(name string) (*RepoDB, error) {
+	for _, db := range f.dbs {
+		_, err := db.openRepo(name, false)
+		switch {
+		case errors.Is(err, ErrRepoNotExists):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		return db, nil
+	}
+	return nil, ErrRepoNotExists
+}
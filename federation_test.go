@@ -0,0 +1,89 @@
+package repodb_test
+
+import (
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestFederation(t *testing.T) {
+	dbs := []*repodb.RepoDB{newTestDB(t), newTestDB(t)}
+	if err := dbs[0].CreateRepo(&repodb.Repo{Name: "existing", DB: dbs[0]}); err != nil {
+		t.Fatal(err)
+	}
+	fed := repodb.NewFederation(nil, dbs...)
+
+	tests := []struct {
+		name    string
+		repo    string
+		wantDB  int
+		wantErr error
+	}{
+		{"fewest repos", "a", 1, nil},
+		{"tie goes to first", "b", 0, nil},
+		{"exists in other db", "existing", 0, repodb.ErrRepoAlreadyExists},
+		{"exists", "a", 0, repodb.ErrRepoAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &repodb.Repo{Name: tt.repo}
+			err := fed.CreateRepo(repo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Federation.CreateRepo() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if repo.DB != dbs[tt.wantDB] {
+				t.Errorf("Federation.CreateRepo() created in %s, want %s", repo.DB.Dir(), dbs[tt.wantDB].Dir())
+			}
+		})
+	}
+
+	repo, err := fed.OpenRepo("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Dir() != path.Join(dbs[1].Dir(), "a") {
+		t.Errorf("Federation.OpenRepo() dir = %v, want in %v", repo.Dir(), dbs[1].Dir())
+	}
+	if _, err := fed.OpenRepo("missing"); !errors.Is(err, repodb.ErrRepoNotExists) {
+		t.Errorf("Federation.OpenRepo() error = %v, want %v", err, repodb.ErrRepoNotExists)
+	}
+
+	var store repodb.RepoStore = fed
+	var names []string
+	for _, r := range store.ListRepos() {
+		names = append(names, r.Name)
+	}
+	if len(names) != 3 || names[0] != "a" || names[1] != "b" || names[2] != "existing" {
+		t.Errorf("Federation.ListRepos() = %v, want [a b existing]", names)
+	}
+
+	if err := store.RemoveRepo("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbs[1].OpenRepo("a"); !errors.Is(err, repodb.ErrRepoNotExists) {
+		t.Errorf("Federation.RemoveRepo() repo still exists: %v", err)
+	}
+}
+
+func TestRouteHash(t *testing.T) {
+	dbs := []*repodb.RepoDB{newTestDB(t), newTestDB(t), newTestDB(t)}
+	counts := make([]int, len(dbs))
+	for i := 0; i < 300; i++ {
+		name := string(rune('a'+i%26)) + string(rune('a'+i/26))
+		n := repodb.RouteHash(name, dbs)
+		if n != repodb.RouteHash(name, dbs) {
+			t.Fatalf("RouteHash(%s) not stable", name)
+		}
+		counts[n]++
+	}
+	for i, c := range counts {
+		if c < 50 {
+			t.Errorf("RouteHash() routed %d of 300 repos to db %d", c, i)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// ErrFileTooLarge is returned when a record file is larger than the maximum file size
+var ErrFileTooLarge = errors.New("file too large")
+
+// FileTooLargeError is returned by writes of content larger than WithMaxFileSize
+type FileTooLargeError struct {
+	Record string // slash separated record path in the repo
+	Limit  int64  // maximum file size in bytes
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("%v: %s exceeds %d bytes", ErrFileTooLarge, e.Record, e.Limit)
+}
+
+// Unwrap returns ErrFileTooLarge
+func (e *FileTooLargeError) Unwrap() error {
+	return ErrFileTooLarge
+}
+
+// WithMaxFileSize limits the content of each record file write to n bytes. The limit is
+// enforced while copying, so an oversized write is aborted without reading it all, and
+// the record file is left as it was.
+func WithMaxFileSize(n int64) Option {
+	return func(db *RepoDB) {
+		db.maxFileSize = n
+	}
+}
+
+// sizeLimitReader reads from r, failing with a FileTooLargeError once more than limit
+// bytes are read
+type sizeLimitReader struct {
+	r     io.Reader
+	n     int64 // bytes read
+	limit int64
+	rec   Record
+	err   error
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if rem := l.limit - l.n + 1; int64(len(p)) > rem {
+		p = p[:rem]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.limit {
+		l.err = &FileTooLargeError{Record: path.Join(l.rec.Folder(), l.rec.FileName()), Limit: l.limit}
+		return 0, l.err
+	}
+	return n, err
+}
+
+// restore returns the repo relative files to their HEAD commit contents, removing those
+// not committed, after a failed write. The caller must hold the repo lock.
+func (repo *Repo) restore(names ...string) error {
+	for _, name := range names {
+		f, err := repo.bareFile(name)
+		if os.IsNotExist(err) {
+			if err := repo.DB.fs.Remove(path.Join(repo.Dir(), name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := repo.checkoutFile(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithMaxFileSize(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+		{"blob store", []repodb.Option{repodb.WithBlobStore(repodb.DirBlobStore(t.TempDir()), 4)}},
+		{"quota", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), append(tt.opts, repodb.WithMaxFileSize(8))...)
+			repo := &repodb.Repo{Name: "Limited", DB: db}
+			if tt.name == "quota" {
+				repo.MaxSize = 100
+			}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			rec := &FileRecord{Name: "a.txt"}
+			if err := repo.WriteFile(rec, strings.NewReader("12345678"), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatalf("Repo.WriteFile() at the limit error = %v", err)
+			}
+
+			// an oversized write leaves the existing file unchanged
+			err := repo.WriteFile(rec, strings.NewReader("123456789"), repodb.DBRepoCommitOptions)
+			var tooLarge *repodb.FileTooLargeError
+			if !errors.Is(err, repodb.ErrFileTooLarge) || !errors.As(err, &tooLarge) || tooLarge.Limit != 8 || tooLarge.Record != "files/a.txt" {
+				t.Fatalf("Repo.WriteFile() error = %v, want FileTooLargeError for files/a.txt", err)
+			}
+			var buf bytes.Buffer
+			if _, err := repo.ReadFile(rec, &buf); err != nil || buf.String() != "12345678" {
+				t.Errorf("Repo.ReadFile() = %q, %v after oversized write, want %q", buf.String(), err, "12345678")
+			}
+
+			// and no partial file is created
+			big := &FileRecord{Name: "big.txt"}
+			if err := repo.WriteFile(big, strings.NewReader(strings.Repeat("x", 1<<20)), repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrFileTooLarge) {
+				t.Fatalf("Repo.WriteFile() error = %v, want ErrFileTooLarge", err)
+			}
+			if repo.FileExists(big) {
+				t.Error("Repo.FileExists() = true after oversized write")
+			}
+			if err := repo.WriteFile(big, strings.NewReader("small"), repodb.DBRepoCommitOptions); err != nil {
+				t.Errorf("Repo.WriteFile() after oversized writes error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// checkFolder returns ErrInvalidName unless the record folder is a relative, slash
+// separated path of one or more directories, such as "invoices/2024/06". Components
+// may not be empty, dot directories, .git or the meta-data directory.
+func checkFolder(folder string) error {
+	invalid := func(reason string) error {
+		return &NameError{Kind: FolderName, Name: folder, Err: errors.New(reason)}
+	}
+	switch {
+	case folder == "":
+		return invalid("empty folder")
+	case strings.ContainsAny(folder, `\`+"\x00"):
+		return invalid("contains a backslash or nul character")
+	case strings.HasPrefix(folder, "/"):
+		return invalid("absolute path")
+	}
+	for _, p := range strings.Split(folder, "/") {
+		switch p {
+		case "":
+			return invalid("empty path component")
+		case ".", "..":
+			return invalid("relative path component")
+		case ".git", MetaDir:
+			return invalid("reserved directory " + p)
+		}
+	}
+	return nil
+}
+
+// isRecordPath reports if the repo relative path is a record file, rather than
+// meta-data or other repo files
+func isRecordPath(name string) bool {
+	parts := strings.Split(name, "/")
+	for _, p := range parts[:len(parts)-1] {
+		if ignoredDir(p) {
+			return false
+		}
+	}
+	return len(parts) >= 2
+}
+
+// ListRecords returns the folder/name of every record in folder, sorted, including the
+// records of sub-folders if recursive is set. Records with only a file or only
+// meta-data are included. The repo root holds no records itself, so all records of the
+// repo are listed with folder "" and recursive set. Bare repos are listed at HEAD.
+func (repo *Repo) ListRecords(folder string, recursive bool) ([]string, error) {
+	if err := repo.authorizeFolder(ActionRead, folder); err != nil {
+		return nil, err
+	}
+	if folder != "" {
+		if err := checkFolder(folder); err != nil {
+			return nil, err
+		}
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.listRecords(folder, recursive)
+}
+
+// listRecords lists the records of folder, the caller must hold the repo lock
+func (repo *Repo) listRecords(folder string, recursive bool) ([]string, error) {
+	names, subs, err := repo.readFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	records := []string{}
+	for _, name := range names {
+		records = append(records, path.Join(folder, name))
+	}
+	if recursive {
+		for _, sub := range subs {
+			more, err := repo.listRecords(path.Join(folder, sub), true)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, more...)
+		}
+	}
+	sort.Strings(records)
+	return records, nil
+}
+
+// recordFolders returns every record folder of the repo, at any depth, sorted. The
+// caller must hold the repo lock.
+func (repo *Repo) recordFolders() ([]string, error) {
+	var folders []string
+	var walk func(folder string) error
+	walk = func(folder string) error {
+		_, subs, err := repo.readFolder(folder)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			sub = path.Join(folder, sub)
+			folders = append(folders, sub)
+			if err := walk(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	sort.Strings(folders)
+	return folders, nil
+}
+
+// readFolder returns the sorted record names and sub-folders of the repo relative
+// folder, read from HEAD in bare repos. A missing folder is empty. The caller must hold
+// the repo lock.
+func (repo *Repo) readFolder(folder string) (names, subs []string, err error) {
+	if repo.isBare() {
+		names, subs, err = repo.readBareFolder(folder)
+	} else {
+		names, subs, err = repo.readWorktreeFolder(folder)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to list %s: %v", path.Join(repo.Name, folder), err)
+	}
+	// records with both a file and meta-data are listed once
+	sort.Strings(names)
+	unique := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique, subs, nil
+}
+
+// readWorktreeFolder is readFolder for repos with a worktree
+func (repo *Repo) readWorktreeFolder(folder string) (names, subs []string, err error) {
+	dir := path.Join(repo.Dir(), folder)
+	fileInfos, err := repo.DB.readDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, fi := range fileInfos {
+		switch {
+		case fi.IsDir() && fi.Name() == MetaDir && folder != "":
+			metas, err := repo.DB.readDir(path.Join(dir, MetaDir))
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, m := range metas {
+				if !m.IsDir() && strings.HasSuffix(m.Name(), repo.DB.metaExt()) {
+					names = append(names, strings.TrimSuffix(m.Name(), repo.DB.metaExt()))
+				}
+			}
+		case fi.IsDir() && !ignoredDir(fi.Name()):
+			subs = append(subs, fi.Name())
+		case !fi.IsDir() && folder != "":
+			names = append(names, fi.Name())
+		}
+	}
+	return names, subs, nil
+}
+
+// readBareFolder is readFolder for bare repos, reading the HEAD tree
+func (repo *Repo) readBareFolder(folder string) (names, subs []string, err error) {
+	r, err := repo.git()
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := repo.headTree(r)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if folder != "" {
+		tree, err = tree.Tree(folder)
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, e := range tree.Entries {
+		switch {
+		case e.Mode == filemode.Dir && e.Name == MetaDir && folder != "":
+			metas, err := tree.Tree(MetaDir)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, m := range metas.Entries {
+				if m.Mode.IsFile() && strings.HasSuffix(m.Name, repo.DB.metaExt()) {
+					names = append(names, strings.TrimSuffix(m.Name, repo.DB.metaExt()))
+				}
+			}
+		case e.Mode == filemode.Dir && !ignoredDir(e.Name):
+			subs = append(subs, e.Name)
+		case e.Mode.IsFile() && folder != "":
+			names = append(names, e.Name)
+		}
+	}
+	return names, subs, nil
+}
@@ -0,0 +1,108 @@
+package repodb_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+// nestedRecord is a record in an arbitrary folder
+type nestedRecord struct {
+	folder, name string
+	SoftDeleted  bool
+}
+
+func (r *nestedRecord) FileName() string { return r.name }
+func (r *nestedRecord) Folder() string   { return r.folder }
+
+func TestNestedFolders(t *testing.T) {
+	invalid := []string{"", "/abs", "a//b", "a/", "a/../b", "./a", `a\b`, "a/.git", "a/" + repodb.MetaDir}
+	for _, opts := range [][]repodb.Option{nil, {repodb.WithBareRepos()}} {
+		db := repodb.NewDB(newTestDB(t).Dir(), opts...)
+		repo := &repodb.Repo{Name: "Nested", DB: db}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		for _, folder := range invalid {
+			rec := &nestedRecord{folder: folder, name: "a.txt"}
+			if err := repo.WriteFile(rec, strings.NewReader("x"), repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrInvalidName) {
+				t.Errorf("Repo.WriteFile() folder %q error = %v, want %v", folder, err, repodb.ErrInvalidName)
+			}
+		}
+
+		for _, rec := range []*nestedRecord{
+			{folder: "invoices", name: "index.txt"},
+			{folder: "invoices/2024/06", name: "a.txt"},
+			{folder: "invoices/2024/06", name: "b.txt", SoftDeleted: true},
+			{folder: "invoices/2024/07", name: "c.txt"},
+		} {
+			if err := repo.WriteFile(rec, strings.NewReader(rec.name), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !repo.FileExists(&nestedRecord{folder: "invoices/2024/06", name: "a.txt"}) {
+			t.Error("Repo.FileExists() = false for nested record")
+		}
+		if len(opts) == 0 {
+			if _, err := os.Stat(path.Join(repo.Dir(), "invoices/2024/06", repodb.MetaDir, "b.txt.json")); err != nil {
+				t.Errorf("meta-data not stored in the leaf folder: %v", err)
+			}
+		}
+		loaded := &nestedRecord{folder: "invoices/2024/06", name: "b.txt"}
+		if err := repo.LoadMeta(loaded); err != nil || !loaded.SoftDeleted {
+			t.Errorf("Repo.LoadMeta() = %+v, error = %v", loaded, err)
+		}
+
+		tests := []struct {
+			name      string
+			folder    string
+			recursive bool
+			want      []string
+		}{
+			{"folder", "invoices", false, []string{"invoices/index.txt"}},
+			{"leaf", "invoices/2024/06", false, []string{"invoices/2024/06/a.txt", "invoices/2024/06/b.txt"}},
+			{"recursive", "invoices/2024", true, []string{"invoices/2024/06/a.txt", "invoices/2024/06/b.txt", "invoices/2024/07/c.txt"}},
+			{"all", "", true, []string{"invoices/2024/06/a.txt", "invoices/2024/06/b.txt", "invoices/2024/07/c.txt", "invoices/index.txt"}},
+			{"missing", "receipts", true, []string{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := repo.ListRecords(tt.folder, tt.recursive)
+				if err != nil || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Repo.ListRecords() = %v, error = %v, want %v", got, err, tt.want)
+				}
+			})
+		}
+		if _, err := repo.ListRecords("../x", true); !errors.Is(err, repodb.ErrInvalidName) {
+			t.Errorf("Repo.ListRecords() error = %v, want %v", err, repodb.ErrInvalidName)
+		}
+	}
+}
+
+func TestRepo_QueryRecords_nested(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "NestedQuery", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range []*nestedRecord{
+		{folder: "invoices/2024/06", name: "a.txt"},
+		{folder: "invoices/2024/06", name: "b.txt", SoftDeleted: true},
+	} {
+		if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := repo.QueryRecords("invoices/2024/06", func(m repodb.Meta) bool { return m.Bool("SoftDeleted") })
+	if err != nil || !reflect.DeepEqual(got, []string{"b.txt"}) {
+		t.Errorf("Repo.QueryRecords() = %v, error = %v", got, err)
+	}
+}
@@ -0,0 +1,96 @@
+package repodb
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// ForkRepo clones the src repo into a new repo dst in the database, preserving all of
+// its commits, and records the source repo in the ForkedFrom field of the fork
+// meta-data. The fork is independent of the source once created. Will return
+// ErrRepoNotExists if there is no repo src, or ErrRepoAlreadyExists if dst is taken.
+// Encrypted repos can only be forked if dst has the same key.
+func (db *RepoDB) ForkRepo(src, dst string) (_ *Repo, err error) {
+	defer db.metrics.observe("fork_repo", time.Now(), &err)
+
+	source, err := db.OpenRepo(src)
+	if err != nil {
+		return nil, err
+	}
+	// don't allow .. or Pathseparator in repo Name
+	if dst, err = db.cleanName(RepoName, dst); err != nil {
+		return nil, err
+	}
+	fork := &Repo{Name: dst, DB: db}
+	if fork.Name == "" {
+		return nil, fmt.Errorf("ForkRepo repo name cannot be empty")
+	}
+	if err := db.checkFrozen(); err != nil {
+		return nil, err
+	}
+	if err := db.validateName(RepoName, fork.Name); err != nil {
+		return nil, err
+	}
+	if db.keys != nil {
+		srcKey, err := db.keys.Key(source.Name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get key for repo %s: %v", source.Name, err)
+		}
+		dstKey, err := db.keys.Key(fork.Name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get key for repo %s: %v", fork.Name, err)
+		}
+		if !bytes.Equal(srcKey, dstKey) {
+			return nil, fmt.Errorf("unable to fork %s: %s has a different encryption key", source.Name, fork.Name)
+		}
+	}
+
+	if err := db.checkOS("ForkRepo"); err != nil {
+		return nil, err
+	}
+
+	db.metrics.lock("db", db)
+	if _, err := db.fs.Stat(fork.Dir()); err == nil {
+		db.Unlock()
+		return nil, ErrRepoAlreadyExists
+	}
+	r, err := git.PlainClone(fork.Dir(), false, &git.CloneOptions{URL: path.Join(source.Dir(), ".git")})
+	db.Unlock()
+	if err != nil {
+		db.removeAll(fork.Dir())
+		return nil, fmt.Errorf("unable to fork %s: %v", source.Name, err)
+	}
+	defer func() {
+		if err != nil {
+			db.gitCache.remove(fork.Dir())
+			db.removeAll(fork.Dir())
+		}
+	}()
+	if err := r.DeleteRemote(git.DefaultRemoteName); err != nil {
+		return nil, fmt.Errorf("unable to fork %s: %v", source.Name, err)
+	}
+	db.gitCache.put(fork.Dir(), r)
+
+	// the fork meta-data replaces the meta-data of the source
+	if err := db.fs.Remove(db.metaStore(fork.Dir()).filename(source.Name)); err != nil {
+		return nil, fmt.Errorf("unable to fork %s: %v", source.Name, err)
+	}
+	fork.Description = source.Description
+	fork.Protected = source.Protected
+	fork.ForkedFrom = source.Name
+	fork.FormerNames = appendName(source.FormerNames, source.Name)
+	fork.CreatedOn = time.Now()
+	fork.UpdatedOn = fork.CreatedOn
+	err = fork.WriteMeta(fork, CommitOptions{
+		Msg: fmt.Sprintf("forked %s from %s", fork.Name, source.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	db.debug("forked repo", "repo", source.Name, "fork", fork.Name)
+	return fork, nil
+}
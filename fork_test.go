@@ -0,0 +1,85 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_ForkRepo(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithEncryption(repoKeys{"secret": key, "secret-fork": key}))
+	for _, name := range []string{"upstream", "taken", "secret"} {
+		repo := &repodb.Repo{Name: name, DB: db, Description: name}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader(name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		src     string
+		dst     string
+		wantErr bool
+		is      error
+	}{
+		{"forked", "upstream", "fork", false, nil},
+		{"encrypted", "secret", "secret-fork", false, nil},
+		{"different key", "secret", "plain-fork", true, nil},
+		{"taken", "upstream", "taken", true, repodb.ErrRepoAlreadyExists},
+		{"missing", "missing", "other", true, repodb.ErrRepoNotExists},
+		{"empty", "upstream", "", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fork, err := db.ForkRepo(tt.src, tt.dst)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("RepoDB.ForkRepo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			opened, err := db.OpenRepo(tt.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opened.ForkedFrom != tt.src || opened.Description != tt.src {
+				t.Errorf("fork meta-data ForkedFrom = %q, Description = %q, want %q", opened.ForkedFrom, opened.Description, tt.src)
+			}
+			b := &bytes.Buffer{}
+			if _, err := fork.ReadFile(&FileRecord{Name: "a.txt"}, b); err != nil || b.String() != tt.src {
+				t.Errorf("fork ReadFile() = %q, error = %v, want %q", b.String(), err, tt.src)
+			}
+			// history of the source, then the fork meta-data
+			activity, err := fork.RecentActivity(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(activity) != 3 || activity[1].Operation != repodb.OpWriteFile {
+				t.Errorf("fork history = %v, want source commits and fork commit", activity)
+			}
+		})
+	}
+
+	// the fork is independent of the source
+	fork, err := db.OpenRepo("fork")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fork.WriteFile(&FileRecord{Name: "b.txt"}, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	upstream, err := db.OpenRepo("upstream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upstream.FileExists(&FileRecord{Name: "b.txt"}) {
+		t.Errorf("write to fork changed the source repo")
+	}
+}
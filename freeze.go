@@ -0,0 +1,77 @@
+package repodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// ErrDBFrozen is returned by operations modifying the database while it is frozen
+var ErrDBFrozen = errors.New("database is frozen")
+
+// frozenFile marks a frozen database, it is dot-prefixed so it is not listed as a repo
+const frozenFile = ".repodb-frozen.json"
+
+// frozen is the content of the frozenFile
+type frozen struct {
+	Reason string
+	Since  time.Time
+}
+
+// Freeze makes all operations modifying the database return ErrDBFrozen until Unfreeze
+// is called, for maintenance such as backups, migrations and GC, which are still
+// allowed. The freeze is stored in the database directory so it survives restarts and
+// applies to every RepoDB opened on the directory and to its namespaces. Operations
+// already running when the database is frozen are completed.
+func (db *RepoDB) Freeze(reason string) error {
+	b, err := json.MarshalIndent(frozen{Reason: reason, Since: time.Now()}, "", "\t")
+	if err != nil {
+		return err
+	}
+	filename := path.Join(db.dir, frozenFile)
+	if err := db.writeFile(filename+".tmp", b, 0644); err != nil {
+		return fmt.Errorf("unable to freeze %s: %v", db.dir, err)
+	}
+	if err := db.fs.Rename(filename+".tmp", filename); err != nil {
+		return fmt.Errorf("unable to freeze %s: %v", db.dir, err)
+	}
+	db.debug("froze database", "dir", db.dir, "reason", reason)
+	return nil
+}
+
+// Unfreeze allows modifying the database again after Freeze, it is a no-op if the
+// database is not frozen
+func (db *RepoDB) Unfreeze() error {
+	err := db.fs.Remove(path.Join(db.dir, frozenFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to unfreeze %s: %v", db.dir, err)
+	}
+	db.debug("unfroze database", "dir", db.dir)
+	return nil
+}
+
+// Frozen reports if the database, or the database of a namespace, is frozen, and the
+// reason given to Freeze
+func (db *RepoDB) Frozen() (reason string, ok bool) {
+	b, err := db.readFile(path.Join(db.dir, frozenFile))
+	if os.IsNotExist(err) {
+		if db.parent != nil {
+			return db.parent.Frozen()
+		}
+		return "", false
+	}
+	f := frozen{}
+	json.Unmarshal(b, &f)
+	return f.Reason, true
+}
+
+// checkFrozen returns ErrDBFrozen if the database is frozen
+func (db *RepoDB) checkFrozen() error {
+	if reason, ok := db.Frozen(); ok {
+		return fmt.Errorf("%w: %s", ErrDBFrozen, reason)
+	}
+	return nil
+}
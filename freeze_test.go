@@ -0,0 +1,85 @@
+package repodb_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_Freeze(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "FrozenRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "a.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Freeze("nightly backup"); err != nil {
+		t.Fatal(err)
+	}
+	// the freeze is seen by other RepoDB values, such as after a restart
+	restarted := repodb.NewDB(db.Dir())
+	if reason, ok := restarted.Frozen(); !ok || reason != "nightly backup" {
+		t.Errorf("RepoDB.Frozen() = %q, %v, want nightly backup, true", reason, ok)
+	}
+	frozenRepo, err := restarted.OpenRepo("FrozenRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		op   func() error
+	}{
+		{"CreateRepo", func() error { return restarted.CreateRepo(&repodb.Repo{Name: "new", DB: restarted}) }},
+		{"RemoveRepo", func() error { return restarted.RemoveRepo("FrozenRepo") }},
+		{"RenameRepo", func() error { _, err := restarted.RenameRepo("FrozenRepo", "renamed"); return err }},
+		{"WriteFile", func() error {
+			return frozenRepo.WriteFile(fr, strings.NewReader("b"), repodb.DBRepoCommitOptions)
+		}},
+		{"WriteMeta", func() error { return frozenRepo.WriteMeta(fr, repodb.DBRepoCommitOptions) }},
+		{"RemoveFile", func() error { return frozenRepo.RemoveFile(fr, repodb.DBRepoCommitOptions) }},
+		{"RenameRecord", func() error { return frozenRepo.RenameRecord(fr, "b.txt", repodb.DBRepoCommitOptions) }},
+		{"CommitAll", func() error { return frozenRepo.CommitAll(repodb.DBRepoCommitOptions) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.op(); !errors.Is(err, repodb.ErrDBFrozen) {
+				t.Errorf("%s() error = %v, want %v", tt.name, err, repodb.ErrDBFrozen)
+			}
+		})
+	}
+
+	// reads, backups and GC are allowed
+	if _, err := frozenRepo.ReadFile(fr, ioutil.Discard); err != nil {
+		t.Errorf("Repo.ReadFile() frozen error = %v", err)
+	}
+	if err := restarted.Backup(ioutil.Discard); err != nil {
+		t.Errorf("RepoDB.Backup() frozen error = %v", err)
+	}
+	if _, err := frozenRepo.GC(repodb.GCOptions{}); err != nil {
+		t.Errorf("Repo.GC() frozen error = %v", err)
+	}
+	if got := len(restarted.ListRepos()); got != 1 {
+		t.Errorf("RepoDB.ListRepos() frozen = %d repos, want 1", got)
+	}
+
+	if err := db.Unfreeze(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Unfreeze(); err != nil {
+		t.Errorf("RepoDB.Unfreeze() not frozen error = %v", err)
+	}
+	if _, ok := restarted.Frozen(); ok {
+		t.Errorf("RepoDB.Frozen() after Unfreeze = true")
+	}
+	if err := frozenRepo.WriteFile(fr, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+		t.Errorf("Repo.WriteFile() after Unfreeze error = %v", err)
+	}
+}
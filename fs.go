@@ -0,0 +1,205 @@
+package repodb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// osFS is the default filesystem, paths are used as given
+var osFS = osfs.New("")
+
+// WithFilesystem stores the database in fs instead of the OS filesystem, such as a
+// chroot-ed, in-memory or custom billy.Filesystem. The DB directory is a path in fs.
+// The system git backend is only used on the OS filesystem, and Watch and ForkRepo
+// return an error for other filesystems.
+func WithFilesystem(fs billy.Filesystem) Option {
+	return func(db *RepoDB) {
+		db.fs = fs
+	}
+}
+
+// cleanDir returns the DB directory as used internally. Paths in the database
+// filesystem are slash separated on every platform, so OS directories are converted from
+// the platform form, and made absolute as relative paths above the working directory,
+// such as ../data, are rejected by the OS filesystem.
+func (db *RepoDB) cleanDir(dir string) string {
+	if !db.onOS() {
+		return dir
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return filepath.ToSlash(dir)
+}
+
+// relPath returns the slash separated name relative to root, name must be root or a
+// path under it, as given by walk
+func relPath(root, name string) string {
+	root, name = path.Clean(root), path.Clean(name)
+	switch {
+	case name == root:
+		return "."
+	case root == ".":
+		return name
+	}
+	return strings.TrimPrefix(name, strings.TrimSuffix(root, "/")+"/")
+}
+
+// onOS reports if the database is stored in the OS filesystem
+func (db *RepoDB) onOS() bool {
+	return db.fs == osFS
+}
+
+// checkOS returns an error if the database is not stored in the OS filesystem
+func (db *RepoDB) checkOS(op string) error {
+	if !db.onOS() {
+		return fmt.Errorf("%s requires the OS filesystem", op)
+	}
+	return nil
+}
+
+// openGit opens the git repository in dir, with or without a worktree
+func (db *RepoDB) openGit(dir string) (*git.Repository, error) {
+	if db.onOS() {
+		return git.PlainOpen(dir)
+	}
+	_, err := db.fs.Stat(path.Join(dir, git.GitDirName))
+	s, wt, err := db.gitFS(dir, os.IsNotExist(err))
+	if err != nil {
+		return nil, err
+	}
+	return git.Open(s, wt)
+}
+
+// initGit creates a git repository in dir, bare repos have no worktree
+func (db *RepoDB) initGit(dir string, bare bool) (*git.Repository, error) {
+	if db.onOS() {
+		return git.PlainInit(dir, bare)
+	}
+	s, wt, err := db.gitFS(dir, bare)
+	if err != nil {
+		return nil, err
+	}
+	return git.Init(s, wt)
+}
+
+// cloneGit clones the repository into dir
+func (db *RepoDB) cloneGit(dir string, opts *git.CloneOptions) (*git.Repository, error) {
+	if db.onOS() {
+		return git.PlainClone(dir, false, opts)
+	}
+	s, wt, err := db.gitFS(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	return git.Clone(s, wt, opts)
+}
+
+// gitFS returns the git storage and worktree of the repo in dir in db.fs, the worktree
+// is nil for bare repos
+func (db *RepoDB) gitFS(dir string, bare bool) (*filesystem.Storage, billy.Filesystem, error) {
+	wt, err := db.fs.Chroot(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bare {
+		return filesystem.NewStorage(wt, cache.NewObjectLRUDefault()), nil, nil
+	}
+	dot, err := wt.Chroot(git.GitDirName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return filesystem.NewStorage(dot, cache.NewObjectLRUDefault()), wt, nil
+}
+
+// readFile reads the named file, like ioutil.ReadFile
+func (db *RepoDB) readFile(name string) ([]byte, error) {
+	f, err := db.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// writeFile writes the named file, like ioutil.WriteFile
+func (db *RepoDB) writeFile(name string, b []byte, perm os.FileMode) error {
+	return util.WriteFile(db.fs, name, b, perm)
+}
+
+// readDir returns the directory entries sorted by name, like ioutil.ReadDir
+func (db *RepoDB) readDir(name string) ([]os.FileInfo, error) {
+	fileInfos, err := db.fs.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(fileInfos, func(i, j int) bool { return fileInfos[i].Name() < fileInfos[j].Name() })
+	return fileInfos, nil
+}
+
+// tempFile creates a new file in dir named prefix and a random suffix, returning it
+// and its name
+func (db *RepoDB) tempFile(dir, prefix string) (billy.File, string, error) {
+	for {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		name := path.Join(dir, prefix+hex.EncodeToString(b))
+		f, err := db.fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if !os.IsExist(err) {
+			return f, name, err
+		}
+	}
+}
+
+// removeAll removes the named path and any children, like os.RemoveAll
+func (db *RepoDB) removeAll(name string) error {
+	return util.RemoveAll(db.fs, name)
+}
+
+// walk walks the file tree rooted at root in lexical order, like filepath.Walk
+func (db *RepoDB) walk(root string, fn filepath.WalkFunc) error {
+	fi, err := db.fs.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = db.walkDir(root, fi, fn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walkDir walks the named file or directory for walk
+func (db *RepoDB) walkDir(name string, fi os.FileInfo, fn filepath.WalkFunc) error {
+	if !fi.IsDir() {
+		return fn(name, fi, nil)
+	}
+	fileInfos, err := db.readDir(name)
+	if err := fn(name, fi, err); err != nil || fileInfos == nil {
+		return err
+	}
+	for _, child := range fileInfos {
+		err := db.walkDir(path.Join(name, child.Name()), child, fn)
+		if err != nil && (!child.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
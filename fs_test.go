@@ -0,0 +1,95 @@
+package repodb_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/readpe/repodb"
+)
+
+func TestWithFilesystem(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := memfs.New()
+			db := repodb.NewDB("/repodb-memfs", append(tt.opts, repodb.WithFilesystem(fs))...)
+			repo := &repodb.Repo{Name: "MemRepo", DB: db, Description: "in memory"}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			rec := &FileRecord{Name: "mem.txt", SoftDeleted: true}
+			if err := repo.WriteFile(rec, strings.NewReader("hello"), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+
+			opened, err := db.OpenRepo("MemRepo")
+			if err != nil || opened.Description != "in memory" {
+				t.Fatalf("RepoDB.OpenRepo() = %+v, error = %v", opened, err)
+			}
+			if repos := db.ListRepos(); len(repos) != 1 {
+				t.Errorf("RepoDB.ListRepos() = %d repos, want 1", len(repos))
+			}
+			b := &bytes.Buffer{}
+			if _, err := opened.ReadFile(rec, b); err != nil || b.String() != "hello" {
+				t.Errorf("Repo.ReadFile() = %q, error = %v", b.String(), err)
+			}
+			got := &FileRecord{Name: "mem.txt"}
+			if err := opened.LoadMeta(got); err != nil || !got.SoftDeleted {
+				t.Errorf("Repo.LoadMeta() = %+v, error = %v", got, err)
+			}
+			if err := opened.RemoveFile(rec, repodb.DBRepoCommitOptions); err != nil {
+				t.Errorf("Repo.RemoveFile() error = %v", err)
+			}
+			if opened.FileExists(rec) {
+				t.Error("Repo.FileExists() = true after RemoveFile")
+			}
+
+			if _, err := db.Watch(context.Background()); err == nil {
+				t.Error("RepoDB.Watch() error = nil, want error for memory filesystem")
+			}
+			if _, err := os.Stat(db.Dir()); !os.IsNotExist(err) {
+				t.Errorf("database written to the OS filesystem, error = %v", err)
+			}
+			if _, err := fs.Stat(repo.Dir()); err != nil {
+				t.Errorf("repo not in memory filesystem: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewDB_relativeDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		t.Skipf("no relative path to %s: %v", dir, err)
+	}
+	db := repodb.NewDB(rel)
+	if want := filepath.ToSlash(dir); db.Dir() != want {
+		t.Errorf("RepoDB.Dir() = %s, want %s", db.Dir(), want)
+	}
+	repo := &repodb.Repo{Name: `Back\slash`, DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Backslash")); err != nil {
+		t.Errorf("repo not created in the DB directory: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// FsckReport is the result of RepoDB.Fsck. Paths are slash separated and start with
+// the repo name, and each list is sorted.
+type FsckReport struct {
+	Repos          []string // repos checked
+	CorruptObjects []string // git objects missing, unreadable or not matching their hash, with the reason
+	MissingMeta    []string // record files without meta-data
+	OrphanMeta     []string // meta-data files without a record file
+	Dirty          []string // repos with uncommitted changes in their worktree
+}
+
+// OK reports if no problems were found
+func (r *FsckReport) OK() bool {
+	return len(r.CorruptObjects) == 0 && len(r.MissingMeta) == 0 && len(r.OrphanMeta) == 0 && len(r.Dirty) == 0
+}
+
+// Fsck checks the integrity of every repo in the database. Every git object reachable
+// from the repo refs is read and its hash verified, record files and meta-data are
+// checked to be in pairs, and worktrees are checked for uncommitted changes. Records
+// kept as meta-data only by the library, such as legal holds, and content addressed
+// folders are not paired. Problems are listed in the report, an error is returned only
+// if a repo cannot be checked. Repos of namespaces are checked too, and reported by
+// their path under NamespacesDir.
+func (db *RepoDB) Fsck() (report *FsckReport, err error) {
+	defer db.metrics.observe("fsck", time.Now(), &err)
+	repos, err := db.ListReposPage(ListOptions{SkipMeta: true})
+	if err != nil {
+		return nil, err
+	}
+	report = &FsckReport{Repos: []string{}}
+	for _, repo := range repos {
+		if err := repo.fsck(report); err != nil {
+			return nil, fmt.Errorf("unable to check %s: %v", repo.Name, err)
+		}
+		report.Repos = append(report.Repos, repo.Name)
+	}
+	err = db.eachNamespace(func(ns *RepoDB, dir string) error {
+		nsReport, err := ns.Fsck()
+		if err != nil {
+			return err
+		}
+		report.Repos = append(report.Repos, joinAll(dir, nsReport.Repos)...)
+		report.CorruptObjects = append(report.CorruptObjects, joinAll(dir, nsReport.CorruptObjects)...)
+		report.MissingMeta = append(report.MissingMeta, joinAll(dir, nsReport.MissingMeta)...)
+		report.OrphanMeta = append(report.OrphanMeta, joinAll(dir, nsReport.OrphanMeta)...)
+		report.Dirty = append(report.Dirty, joinAll(dir, nsReport.Dirty)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range [][]string{report.CorruptObjects, report.MissingMeta, report.OrphanMeta, report.Dirty} {
+		sort.Strings(list)
+	}
+	return report, nil
+}
+
+// joinAll joins dir to each of the names
+func joinAll(dir string, names []string) []string {
+	joined := make([]string, len(names))
+	for i, name := range names {
+		joined[i] = path.Join(dir, name)
+	}
+	return joined
+}
+
+// fsck adds the problems of the repo to the report
+func (repo *Repo) fsck(report *FsckReport) error {
+	repo.DB.metrics.lock("repo", repo.RLocker())
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	corrupt, err := repo.checkObjects(r)
+	if err != nil {
+		return err
+	}
+	report.CorruptObjects = append(report.CorruptObjects, corrupt...)
+
+	folders, err := repo.recordFolders()
+	if err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		if !repo.isPairedFolder(folder) {
+			continue
+		}
+		names, _, err := repo.readFolder(folder)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			rec := &recordRef{folder: folder, name: name}
+			hasFile, hasMeta := repo.FileExists(rec), repo.metaExists(rec)
+			switch {
+			case hasFile && !hasMeta:
+				report.MissingMeta = append(report.MissingMeta, path.Join(repo.Name, folder, name))
+			case hasMeta && !hasFile:
+				report.OrphanMeta = append(report.OrphanMeta, path.Join(repo.Name, repo.DB.metaPath(rec)))
+			}
+		}
+	}
+
+	if repo.isBare() || repo.checkSparse() != nil {
+		// no worktree, or one missing folders by design
+		return nil
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := w.Status()
+	if err != nil {
+		return err
+	}
+	if !status.IsClean() {
+		report.Dirty = append(report.Dirty, repo.Name)
+	}
+	return nil
+}
+
+// isPairedFolder reports if the records of the folder are expected to have both a file
+// and meta-data. Records kept as meta-data only by the library, such as legal holds,
+// and content addressed records are not.
+func (repo *Repo) isPairedFolder(folder string) bool {
+	switch folder {
+	case (&Hold{}).Folder(), (&Stats{}).Folder(), (&Deletion{}).Folder(), (&Expiry{}).Folder(), (&Delivery{}).Folder():
+		return false
+	}
+	return !repo.DB.contentFolders[folder]
+}
+
+// metaExists reports if the record has meta-data, the caller must hold the repo lock
+func (repo *Repo) metaExists(rec Record) bool {
+	if repo.isBare() {
+		_, err := repo.bareFile(repo.DB.metaPath(rec))
+		return err == nil
+	}
+	return repo.DB.fileExists(path.Join(repo.Dir(), repo.DB.metaPath(rec)))
+}
+
+// checkObjects reads every object reachable from the refs of the repo, verifying its
+// hash, and returns the objects that are missing or corrupt
+func (repo *Repo) checkObjects(r *git.Repository) ([]string, error) {
+	refs, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+	var pending []plumbing.Hash
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			pending = append(pending, ref.Hash())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var corrupt []string
+	seen := map[plumbing.Hash]bool{}
+	for len(pending) > 0 {
+		h := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		links, err := checkObject(r, h)
+		if err != nil {
+			corrupt = append(corrupt, fmt.Sprintf("%s/%s: %v", repo.Name, h, err))
+			continue
+		}
+		pending = append(pending, links...)
+	}
+	return corrupt, nil
+}
+
+// checkObject reads the object and verifies its hash, returning the hashes of the
+// objects it links to
+func checkObject(r *git.Repository, h plumbing.Hash) ([]plumbing.Hash, error) {
+	o, err := r.Storer.EncodedObject(plumbing.AnyObject, h)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := o.Reader()
+	if err != nil {
+		return nil, err
+	}
+	hasher := plumbing.NewHasher(o.Type(), o.Size())
+	_, err = io.Copy(hasher, rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	if got := hasher.Sum(); got != h {
+		return nil, fmt.Errorf("content hashes to %s", got)
+	}
+
+	var links []plumbing.Hash
+	switch o.Type() {
+	case plumbing.CommitObject:
+		c, err := object.DecodeCommit(r.Storer, o)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, c.TreeHash)
+		links = append(links, c.ParentHashes...)
+	case plumbing.TreeObject:
+		t, err := object.DecodeTree(r.Storer, o)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range t.Entries {
+			if e.Mode != filemode.Submodule {
+				links = append(links, e.Hash)
+			}
+		}
+	case plumbing.TagObject:
+		t, err := object.DecodeTag(r.Storer, o)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, t.Target)
+	case plumbing.BlobObject:
+	default:
+		return nil, errors.New("unknown object type")
+	}
+	return links, nil
+}
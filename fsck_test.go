@@ -0,0 +1,99 @@
+package repodb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_Fsck(t *testing.T) {
+	db := newTestDB(t)
+	write := func(repo *repodb.Repo, name string, file, meta bool) {
+		t.Helper()
+		rec := &FileRecord{Name: name}
+		if file {
+			if err := repo.WriteFile(rec, strings.NewReader(name), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if meta {
+			if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, name := range []string{"Clean", "Corrupt", "Dirty", "Unpaired"} {
+		if err := db.CreateRepo(&repodb.Repo{Name: name, DB: db}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clean, _ := db.OpenRepo("Clean")
+	write(clean, "a.txt", true, true)
+	if err := clean.LegalHold(&FileRecord{Name: "a.txt"}, "audit"); err != nil {
+		t.Fatal(err)
+	}
+
+	unpaired, _ := db.OpenRepo("Unpaired")
+	write(unpaired, "file-only.txt", true, false)
+	write(unpaired, "meta-only.txt", false, true)
+
+	dirty, _ := db.OpenRepo("Dirty")
+	write(dirty, "a.txt", true, true)
+	if err := ioutil.WriteFile(filepath.Join(dirty.Dir(), "files", "a.txt"), []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt, _ := db.OpenRepo("Corrupt")
+	write(corrupt, "a.txt", true, true)
+	head, err := corrupt.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := git.PlainOpen(corrupt.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.CommitObject(head.Git())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := c.File("files/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := filepath.Join(corrupt.Dir(), ".git", "objects", f.Hash.String()[:2], f.Hash.String()[2:])
+	if err := os.Chmod(object, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(object, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new handle, so no objects are cached
+	report, err := repodb.NewDB(db.Dir()).Fsck()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Error("FsckReport.OK() = true")
+	}
+	want := &repodb.FsckReport{
+		Repos:       []string{"Clean", "Corrupt", "Dirty", "Unpaired"},
+		MissingMeta: []string{"Unpaired/files/file-only.txt"},
+		OrphanMeta:  []string{"Unpaired/files/meta-data/meta-only.txt.json"},
+		Dirty:       []string{"Dirty"},
+	}
+	if len(report.CorruptObjects) != 1 || !strings.HasPrefix(report.CorruptObjects[0], "Corrupt/"+f.Hash.String()) {
+		t.Errorf("FsckReport.CorruptObjects = %v, want Corrupt/%s", report.CorruptObjects, f.Hash)
+	}
+	report.CorruptObjects = nil
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("RepoDB.Fsck() = %+v, want %+v", report, want)
+	}
+}
@@ -0,0 +1,267 @@
+//go:build go1.16
+// +build go1.16
+
+package repodb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// FS returns a read-only io/fs view of the repo contents at the current HEAD, for
+// standard library consumers such as http.FileServer, fs.WalkDir or template loading.
+// Record files are decrypted and fetched from the blob store as by ReadFile, meta-data
+// files are included as stored. Later commits do not change the view. Files are read
+// into memory when opened.
+func (repo *Repo) FS() (fs.FS, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	head, err := repo.head()
+	if err != nil {
+		return nil, err
+	}
+	return repo.fsAt(HashFromGit(head))
+}
+
+// FSAt returns a read-only io/fs view of the repo contents at the commit, see FS
+func (repo *Repo) FSAt(commit Hash) (fs.FS, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.fsAt(commit)
+}
+
+// fsAt returns the view of the commit, the caller must hold the repo lock
+func (repo *Repo) fsAt(commit Hash) (fs.FS, error) {
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.CommitObject(commit.Git())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read commit %s of %s: %v", commit, repo.Name, err)
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read commit %s of %s: %v", commit, repo.Name, err)
+	}
+	key, err := repo.encKey()
+	if err != nil {
+		return nil, err
+	}
+	return &repoFS{repo: repo, tree: tree, key: key, modTime: c.Committer.When}, nil
+}
+
+// repoFS is the fs.FS of a commit tree
+type repoFS struct {
+	repo    *Repo
+	tree    *object.Tree
+	key     repoKey
+	modTime time.Time
+}
+
+// Open opens the named file or directory. Implements fs.FS.
+func (f *repoFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return f.openDir(name, f.tree)
+	}
+	if dir, err := f.tree.Tree(name); err == nil {
+		return f.openDir(name, dir)
+	}
+	file, err := f.tree.File(name)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	b, err := f.read(file)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	info := &fsInfo{name: path.Base(name), size: int64(len(b)), modTime: f.modTime}
+	return &fsFile{Reader: bytes.NewReader(b), info: info}, nil
+}
+
+// openDir lists the directory entries, sorted by name
+func (f *repoFS) openDir(name string, tree *object.Tree) (fs.File, error) {
+	entries := make([]fs.DirEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		if e.Mode == filemode.Submodule {
+			continue
+		}
+		entries = append(entries, &fsEntry{fsys: f, name: path.Join(name, e.Name), entry: e})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	info := &fsInfo{name: path.Base(name), dir: true, modTime: f.modTime}
+	return &fsDir{info: info, entries: entries}, nil
+}
+
+// read returns the file content, record files are decrypted, resolved and decompressed
+// like ReadFile
+func (f *repoFS) read(file *object.File) ([]byte, error) {
+	f.repo.RLock()
+	defer f.repo.RUnlock()
+	rc, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	if !isRecordPath(file.Name) {
+		defer rc.Close()
+		return ioutil.ReadAll(rc)
+	}
+	if f.key != nil {
+		dr, err := newDecryptReader(rc, f.key, f.repo.fileAAD(file.Name)...)
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+		rc = struct {
+			io.Reader
+			io.Closer
+		}{dr, rc}
+	}
+	attr, err := f.repo.treeAttr(f.tree, file.Name)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	codec, err := f.repo.DB.codec(attr.Codec)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	if rc, err = f.repo.resolveBlob(rc, attr, f.key); err != nil {
+		return nil, err
+	}
+	if rc, err = decompress(rc, codec); err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// size returns the size of the file content, reading it unless stored as is
+func (f *repoFS) size(file *object.File) (int64, error) {
+	if !isRecordPath(file.Name) {
+		return file.Size, nil
+	}
+	if f.key == nil {
+		if attr, err := f.repo.treeAttr(f.tree, file.Name); err != nil || (attr.Codec == "" && !attr.Blob) {
+			return file.Size, err
+		}
+	}
+	b, err := f.read(file)
+	return int64(len(b)), err
+}
+
+// fsInfo is the fs.FileInfo of repoFS files and directories
+type fsInfo struct {
+	name    string
+	size    int64
+	dir     bool
+	modTime time.Time
+}
+
+func (i *fsInfo) Name() string       { return i.name }
+func (i *fsInfo) Size() int64        { return i.size }
+func (i *fsInfo) ModTime() time.Time { return i.modTime }
+func (i *fsInfo) IsDir() bool        { return i.dir }
+func (i *fsInfo) Sys() interface{}   { return nil }
+
+func (i *fsInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// fsEntry is the fs.DirEntry of a tree entry
+type fsEntry struct {
+	fsys  *repoFS
+	name  string // path in the repo
+	entry object.TreeEntry
+}
+
+func (e *fsEntry) Name() string      { return e.entry.Name }
+func (e *fsEntry) IsDir() bool       { return e.entry.Mode == filemode.Dir }
+func (e *fsEntry) Type() fs.FileMode { return e.info(0).Mode().Type() }
+
+// Info returns the entry info, record files may be read to learn their size
+func (e *fsEntry) Info() (fs.FileInfo, error) {
+	if e.IsDir() {
+		return e.info(0), nil
+	}
+	file, err := e.fsys.tree.File(e.name)
+	if err != nil {
+		return nil, err
+	}
+	size, err := e.fsys.size(file)
+	if err != nil {
+		return nil, err
+	}
+	return e.info(size), nil
+}
+
+func (e *fsEntry) info(size int64) *fsInfo {
+	return &fsInfo{name: e.entry.Name, size: size, dir: e.IsDir(), modTime: e.fsys.modTime}
+}
+
+// fsFile is an open repoFS file
+type fsFile struct {
+	*bytes.Reader
+	info *fsInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *fsFile) Close() error               { return nil }
+
+// fsDir is an open repoFS directory
+type fsDir struct {
+	info    *fsInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir returns the next n entries, or all remaining entries if n <= 0. Implements
+// fs.ReadDirFile.
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,73 @@
+//go:build go1.16
+// +build go1.16
+
+package repodb_test
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_FS(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithEncryption(repoKeys{"FSRepo": bytes.Repeat([]byte{3}, 32)}))
+	repo := &repodb.Repo{Name: "FSRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	rec := &FileRecord{Name: "a.txt"}
+	if err := repo.WriteFile(rec, strings.NewReader("first"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	first, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(rec, strings.NewReader("second"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&otherRecord{Name: "b.txt"}, strings.NewReader("other"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := repo.FS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(head, "files/a.txt", "other/b.txt", "meta-data/FSRepo.json"); err != nil {
+		t.Fatal(err)
+	}
+	old, err := repo.FSAt(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fsys    fs.FS
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{"head decrypted", head, "files/a.txt", "second", false},
+		{"head other folder", head, "other/b.txt", "other", false},
+		{"at commit", old, "files/a.txt", "first", false},
+		{"not at commit", old, "other/b.txt", "", true},
+		{"invalid path", head, "../a.txt", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fs.ReadFile(tt.fsys, tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fs.ReadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("fs.ReadFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package repodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// GCOptions configures garbage collection of repo objects
+type GCOptions struct {
+	// Grace protects unreachable objects newer than the duration from pruning
+	Grace time.Duration
+}
+
+// GCReport is the result of garbage collecting a repo
+type GCReport struct {
+	Repo       string
+	Pruned     int   // unreachable loose objects removed
+	Packed     int   // reachable loose objects moved into the pack
+	SizeBefore int64 // bytes of the .git directory before
+	SizeAfter  int64 // bytes of the .git directory after
+}
+
+// GC prunes unreachable objects and repacks the reachable objects of the repo into a
+// single pack, removing their loose copies. Every commit writes loose objects, GC keeps
+// the disk usage of long lived repos under control. Repos using BackendSystem are
+// collected by git gc, which does not report the Pruned and Packed counts.
+func (repo *Repo) GC(opts GCOptions) (report *GCReport, err error) {
+	defer repo.DB.metrics.observe("gc", time.Now(), &err)
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return nil, err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	los, ok := r.Storer.(storer.LooseObjectStorer)
+	if !ok {
+		return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, git.ErrLooseObjectsNotSupported)
+	}
+
+	report = &GCReport{Repo: repo.Name}
+	gitDir := path.Join(repo.Dir(), git.GitDirName)
+	if repo.isBare() {
+		gitDir = repo.Dir()
+	}
+	if report.SizeBefore, err = repo.DB.dirSize(gitDir); err != nil {
+		return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, err)
+	}
+	if repo.systemGit(r) {
+		if err := repo.execGC(opts); err != nil {
+			return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, err)
+		}
+		if report.SizeAfter, err = repo.DB.dirSize(gitDir); err != nil {
+			return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, err)
+		}
+		return report, nil
+	}
+
+	// find the unreachable loose objects, all others are reachable and packed below
+	unreachable := map[plumbing.Hash]bool{}
+	err = r.Prune(git.PruneOptions{Handler: func(h plumbing.Hash) error {
+		unreachable[h] = true
+		return nil
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, err)
+	}
+	var loose []plumbing.Hash
+	err = los.ForEachObjectHash(func(h plumbing.Hash) error {
+		loose = append(loose, h)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, err)
+	}
+
+	cutoff := time.Now().Add(-opts.Grace)
+	for h := range unreachable {
+		if opts.Grace > 0 {
+			if t, err := los.LooseObjectTime(h); err != nil || !t.Before(cutoff) {
+				continue
+			}
+		}
+		if err := los.DeleteLooseObject(h); err != nil {
+			return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, err)
+		}
+		report.Pruned++
+	}
+
+	if err := r.RepackObjects(&git.RepackConfig{}); err != nil {
+		return nil, fmt.Errorf("unable to repack %s: %v", repo.Name, err)
+	}
+	// some go-git versions remove the loose copies when repacking
+	for _, h := range loose {
+		if unreachable[h] {
+			continue
+		}
+		if err := los.DeleteLooseObject(h); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, err)
+		}
+		report.Packed++
+	}
+
+	if report.SizeAfter, err = repo.DB.dirSize(gitDir); err != nil {
+		return nil, fmt.Errorf("unable to gc %s: %v", repo.Name, err)
+	}
+	repo.DB.debug("garbage collected", "repo", repo.Name, "pruned", report.Pruned, "packed", report.Packed,
+		"before", report.SizeBefore, "after", report.SizeAfter)
+	return report, nil
+}
+
+// GCAll garbage collects every repo in the database and its namespaces, returning a
+// report for each repo collected. Repos of namespaces are reported by their path under
+// NamespacesDir. A repo failing does not stop the others, the first error is returned.
+func (db *RepoDB) GCAll(opts GCOptions) ([]*GCReport, error) {
+	repos, err := db.ListReposPage(ListOptions{SkipMeta: true})
+	if err != nil {
+		return nil, err
+	}
+	var firstErr error
+	reports := make([]*GCReport, 0, len(repos))
+	for _, repo := range repos {
+		report, err := repo.GC(opts)
+		if err != nil {
+			db.warn("unable to garbage collect repo", "repo", repo.Name, "err", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		reports = append(reports, report)
+	}
+	err = db.eachNamespace(func(ns *RepoDB, dir string) error {
+		nsReports, err := ns.GCAll(opts)
+		for _, report := range nsReports {
+			report.Repo = path.Join(dir, report.Repo)
+		}
+		reports = append(reports, nsReports...)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return nil
+	})
+	if err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return reports, firstErr
+}
+
+// StartGC garbage collects every repo in the database every interval until ctx is
+// done. Errors are logged to the DB logger and do not stop the schedule.
+func (db *RepoDB) StartGC(ctx context.Context, interval time.Duration, opts GCOptions) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				db.GCAll(opts)
+			}
+		}
+	}()
+}
+
+// dirSize returns the total size of the regular files under dir
+func (db *RepoDB) dirSize(dir string) (int64, error) {
+	var size int64
+	err := db.walk(dir, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
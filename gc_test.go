@@ -0,0 +1,70 @@
+package repodb_test
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_GC(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "GCRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		content := strings.Repeat(fmt.Sprintf("version %d\n", i), 200)
+		if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := db.GCAll(repodb.GCOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("RepoDB.GCAll() reports = %d, want 1", len(reports))
+	}
+	report := reports[0]
+	if report.Packed == 0 || report.SizeBefore == 0 || report.SizeAfter == 0 {
+		t.Errorf("Repo.GC() report = %+v, want packed objects", report)
+	}
+	if loose, _ := filepath.Glob(path.Join(repo.Dir(), ".git", "objects", "??", "*")); len(loose) != 0 {
+		t.Errorf("Repo.GC() left %d loose objects", len(loose))
+	}
+
+	// history and records are intact, and writes continue
+	if got, _ := repo.Head(); got != head {
+		t.Errorf("Repo.Head() after GC = %v, want %v", got, head)
+	}
+	activity, err := repo.RecentActivity(100)
+	if err != nil || len(activity) != 21 {
+		t.Errorf("Repo.RecentActivity() after GC = %d commits, %v, want 21", len(activity), err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("after gc"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := repo.ReadFile(&FileRecord{Name: "a.txt"}, buf); err != nil || buf.String() != "after gc" {
+		t.Errorf("Repo.ReadFile() after GC = %q, %v", buf.String(), err)
+	}
+
+	// a second GC only packs the new commit
+	report, err = repo.GC(repodb.GCOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pruned != 0 || report.Packed == 0 {
+		t.Errorf("Repo.GC() second report = %+v", report)
+	}
+}
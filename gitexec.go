@@ -0,0 +1,209 @@
+package repodb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Backend is the git implementation used to commit, garbage collect and clone a repo.
+// Reads always use go-git.
+type Backend string
+
+// git backends
+const (
+	// BackendGoGit uses the pure Go go-git library, the default
+	BackendGoGit Backend = "go-git"
+	// BackendSystem runs the system git binary, for very large repos where go-git is
+	// slow or uses too much memory. If git is not found in the PATH go-git is used.
+	BackendSystem Backend = "system"
+)
+
+// backend config section and key, stored in the repo .git/config
+const (
+	backendSection = "repodb"
+	backendKey     = "backend"
+)
+
+var (
+	gitPathOnce sync.Once
+	gitPath     string
+)
+
+// systemGitPath returns the path of the system git binary, or "" if not found
+func systemGitPath() string {
+	gitPathOnce.Do(func() {
+		gitPath, _ = exec.LookPath("git")
+	})
+	return gitPath
+}
+
+// SetBackend selects the git backend of the repo, stored in the repo git config so it
+// persists
+func (repo *Repo) SetBackend(b Backend) error {
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	if err := setBackend(r, b); err != nil {
+		return fmt.Errorf("unable to set backend of %s: %v", repo.Name, err)
+	}
+	return nil
+}
+
+// setBackend writes the backend to the repo config
+func setBackend(r *git.Repository, b Backend) error {
+	if b != BackendGoGit && b != BackendSystem {
+		return fmt.Errorf("unknown git backend %q", b)
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Raw.Section(backendSection).SetOption(backendKey, string(b))
+	return r.Storer.SetConfig(cfg)
+}
+
+// Backend returns the git backend selected for the repo
+func (repo *Repo) Backend() Backend {
+	repo.RLock()
+	defer repo.RUnlock()
+	r, err := repo.git()
+	if err != nil {
+		return BackendGoGit
+	}
+	return backend(r)
+}
+
+// backend returns the git backend selected in the repo config
+func backend(r *git.Repository) Backend {
+	cfg, err := r.Config()
+	if err != nil {
+		return BackendGoGit
+	}
+	if b := Backend(cfg.Raw.Section(backendSection).Option(backendKey)); b == BackendSystem {
+		return b
+	}
+	return BackendGoGit
+}
+
+// systemGit reports if the system git binary should be used for the repo
+func (repo *Repo) systemGit(r *git.Repository) bool {
+	return backend(r) == BackendSystem && repo.DB.onOS() && systemGitPath() != ""
+}
+
+// execGit runs the system git in dir, returning its output
+func execGit(dir string, stdin []byte, env []string, args ...string) (string, error) {
+	name := args[0]
+	// user configuration for signing and hooks must not change repodb commits, and
+	// automatic gc is left to Repo.GC
+	args = append([]string{
+		"-c", "commit.gpgsign=false",
+		"-c", "core.hooksPath=/dev/null",
+		"-c", "gc.auto=0",
+		"-c", "maintenance.auto=false",
+	}, args...)
+	cmd := exec.Command(systemGitPath(), args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// execStage stages all changes using the system git, reporting if there is nothing to
+// commit
+func (repo *Repo) execStage() (clean bool, err error) {
+	if _, err := execGit(repo.Dir(), nil, nil, "add", "-A"); err != nil {
+		return false, err
+	}
+	out, err := execGit(repo.Dir(), nil, nil, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "", nil
+}
+
+// execCommit commits the staged changes using the system git
+func (repo *Repo) execCommit(msg string, opts *git.CommitOptions) (plumbing.Hash, error) {
+	var env []string
+	if opts.Author != nil {
+		env = append(env, signatureEnv("AUTHOR", opts.Author)...)
+	}
+	committer := opts.Committer
+	if committer == nil {
+		committer = opts.Author
+	}
+	if committer != nil {
+		env = append(env, signatureEnv("COMMITTER", committer)...)
+	}
+	args := []string{"commit", "--quiet", "--no-verify", "--allow-empty-message", "--cleanup=verbatim", "-F", "-"}
+	if _, err := execGit(repo.Dir(), []byte(msg), env, args...); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	out, err := execGit(repo.Dir(), nil, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return plumbing.NewHash(strings.TrimSpace(out)), nil
+}
+
+// signatureEnv returns the git environment variables setting the signature
+func signatureEnv(role string, s *object.Signature) []string {
+	return []string{
+		"GIT_" + role + "_NAME=" + s.Name,
+		"GIT_" + role + "_EMAIL=" + s.Email,
+		"GIT_" + role + "_DATE=" + s.When.Format(time.RFC3339),
+	}
+}
+
+// execClone clones url into the repo directory using the system git
+func (repo *Repo) execClone(url string, opts CloneOptions) (*git.Repository, error) {
+	args := []string{"clone", "--quiet"}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.Folders != nil {
+		args = append(args, "--no-checkout")
+	}
+	args = append(args, "--", url, repo.Dir())
+	if _, err := execGit(repo.DB.dir, nil, nil, args...); err != nil {
+		return nil, err
+	}
+	return git.PlainOpen(repo.Dir())
+}
+
+// execGC garbage collects the repo using the system git, unreachable objects newer
+// than the grace period are kept
+func (repo *Repo) execGC(opts GCOptions) error {
+	prune := "now"
+	if opts.Grace > 0 {
+		prune = strconv.FormatInt(int64(opts.Grace/time.Second), 10) + ".seconds.ago"
+	}
+	_, err := execGit(repo.Dir(), nil, nil, "gc", "--quiet", "--prune="+prune)
+	// the cached go-git repository may still refer to the replaced packs
+	repo.DB.gitCache.remove(repo.Dir())
+	return err
+}
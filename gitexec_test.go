@@ -0,0 +1,85 @@
+package repodb_test
+
+import (
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestBackendSystem(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	db := newTestDB(t)
+	events := 0
+	db.AddHook(func(ev repodb.HookEvent) { events++ })
+	repo := &repodb.Repo{Name: "SystemRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.Backend(); got != repodb.BackendGoGit {
+		t.Errorf("Repo.Backend() default = %v, want %v", got, repodb.BackendGoGit)
+	}
+	if err := repo.SetBackend("svn"); err == nil {
+		t.Errorf("Repo.SetBackend() expected error for unknown backend")
+	}
+	if err := repo.SetBackend(repodb.BackendSystem); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := db.OpenRepo("SystemRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := opened.Backend(); got != repodb.BackendSystem {
+		t.Errorf("Repo.Backend() = %v, want %v", got, repodb.BackendSystem)
+	}
+
+	for _, content := range []string{"1", "2", "2"} {
+		if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	activity, err := repo.RecentActivity(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unchanged content is not committed
+	if len(activity) != 3 || activity[0].Operation != repodb.OpWriteFile || activity[0].Record != "files/a.txt" || activity[0].Actor != "repodb" {
+		t.Errorf("Repo.RecentActivity() = %v, want 2 writes by repodb", activity)
+	}
+	if events != 3 {
+		t.Errorf("hook events = %d, want 3", events)
+	}
+	out, err := exec.Command("git", "-C", repo.Dir(), "log", "-1", "--format=%B").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "Repodb-Operation: write_file") {
+		t.Errorf("system git commit message = %q, want trailers", out)
+	}
+
+	if _, err := repo.GC(repodb.GCOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	b := &strings.Builder{}
+	if _, err := repo.ReadFile(&FileRecord{Name: "a.txt"}, b); err != nil || b.String() != "2" {
+		t.Errorf("Repo.ReadFile() after gc = %q, error = %v", b.String(), err)
+	}
+
+	clone, err := newTestDB(t).CloneRepo("SystemRepo", path.Join(repo.Dir(), ".git"), repodb.CloneOptions{Backend: repodb.BackendSystem})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := clone.Backend(); got != repodb.BackendSystem {
+		t.Errorf("cloned Repo.Backend() = %v, want %v", got, repodb.BackendSystem)
+	}
+	if err := clone.WriteFile(&FileRecord{Name: "b.txt"}, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clone.History(10); err != nil {
+		t.Fatal(err)
+	}
+}
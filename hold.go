@@ -0,0 +1,146 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// ErrLegalHold is returned when removing a record, or its repo, while under legal hold
+var ErrLegalHold = errors.New("record is under legal hold")
+
+// Hold is a legal hold placed on a record. Held records cannot be removed until all of
+// their holds are released. Holds are stored as meta-data in the holds folder of the repo.
+type Hold struct {
+	CaseID       string
+	RecordFolder string
+	RecordName   string
+	PlacedOn     time.Time
+}
+
+// FileName returns the hold file name, unique per case and record. Implements Record interface
+func (h *Hold) FileName() string {
+	return recordKey(h.CaseID, h.RecordFolder, h.RecordName)
+}
+
+// Folder is the record folder for holds. Implements Record interface
+func (h *Hold) Folder() string {
+	return "holds"
+}
+
+// held reports if the hold applies to the record
+func (h *Hold) held(rec Record) bool {
+	return h.RecordFolder == rec.Folder() && h.RecordName == rec.FileName()
+}
+
+// LegalHold places a hold on the record for the case. The record cannot be removed
+// until the hold is released with ReleaseHold. Placing the same hold twice is a no-op.
+func (repo *Repo) LegalHold(rec Record, caseID string) error {
+	if caseID == "" {
+		return fmt.Errorf("LegalHold case id cannot be empty")
+	}
+	hold := &Hold{
+		CaseID:       caseID,
+		RecordFolder: rec.Folder(),
+		RecordName:   rec.FileName(),
+		PlacedOn:     time.Now(),
+	}
+	// the hold file is compared, not only found, so a hold is never taken for another
+	existing := *hold
+	err := repo.LoadMeta(&existing)
+	switch {
+	case err == nil && existing.CaseID == caseID && existing.held(rec):
+		return nil
+	case err == nil:
+		return fmt.Errorf("unable to place legal hold %s on %s, %s holds %s on %s", caseID, path.Join(rec.Folder(), rec.FileName()),
+			hold.FileName(), existing.CaseID, path.Join(existing.RecordFolder, existing.RecordName))
+	case !errors.Is(err, ErrMetaNotExists):
+		return err
+	}
+	return repo.WriteMeta(hold, CommitOptions{
+		Msg: fmt.Sprintf("placed legal hold %s on %s", caseID, path.Join(rec.Folder(), rec.FileName())),
+	})
+}
+
+// ReleaseHold releases the case hold on the record. Other holds on the record remain active.
+func (repo *Repo) ReleaseHold(rec Record, caseID string) error {
+	hold := &Hold{
+		CaseID:       caseID,
+		RecordFolder: rec.Folder(),
+		RecordName:   rec.FileName(),
+	}
+	err := repo.RemoveMeta(hold, CommitOptions{
+		Msg: fmt.Sprintf("released legal hold %s on %s", caseID, path.Join(rec.Folder(), rec.FileName())),
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no legal hold %s on %s", caseID, path.Join(rec.Folder(), rec.FileName()))
+	}
+	return err
+}
+
+// Holds returns a report of all active legal holds in the repo
+func (repo *Repo) Holds() ([]*Hold, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.holds()
+}
+
+// IsHeld reports if the record has any active legal holds, false if the subject of the
+// repo may not read it
+func (repo *Repo) IsHeld(rec Record) bool {
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return false
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.isHeld(rec)
+}
+
+// holds reads all hold meta-data, the caller must hold the repo lock
+func (repo *Repo) holds() ([]*Hold, error) {
+	dir := path.Join(repo.Dir(), (&Hold{}).Folder())
+	var records [][]byte
+	var err error
+	if repo.isBare() {
+		_, records, err = repo.bareMeta((&Hold{}).Folder())
+	} else {
+		records, err = repo.DB.metaStore(dir).readAll()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read legal holds for %s: %v", repo.Name, err)
+	}
+
+	holds := make([]*Hold, 0, len(records))
+	for _, r := range records {
+		hold := &Hold{}
+		if err := repo.DB.MetaCodec().Unmarshal(r, hold); err != nil {
+			return nil, fmt.Errorf("cannot read legal hold for %s: %v", repo.Name, err)
+		}
+		holds = append(holds, hold)
+	}
+	return holds, nil
+}
+
+// isHeld reports if the record has any active legal holds, the caller must hold the
+// repo lock. Holds that cannot be read are treated as active.
+func (repo *Repo) isHeld(rec Record) bool {
+	if _, ok := rec.(*Hold); ok {
+		return false
+	}
+	holds, err := repo.holds()
+	if err != nil {
+		repo.DB.warn("treating record as held, unable to read legal holds", "repo", repo.Name, "err", err)
+		return true
+	}
+	for _, h := range holds {
+		if h.held(rec) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package repodb_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_LegalHold(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "HoldRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "held.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("evidence"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, caseID := range []string{"case-1", "case-2", "case-2"} {
+		if err := repo.LegalHold(fr, caseID); err != nil {
+			t.Fatalf("Repo.LegalHold() error = %v", err)
+		}
+	}
+	holds, err := repo.Holds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holds) != 2 {
+		t.Errorf("Repo.Holds() len = %v, want %v", len(holds), 2)
+	}
+
+	if err := repo.RemoveFile(fr, repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrLegalHold) {
+		t.Errorf("Repo.RemoveFile() error = %v, want %v", err, repodb.ErrLegalHold)
+	}
+	if err := db.RemoveRepo("HoldRepo"); !errors.Is(err, repodb.ErrLegalHold) {
+		t.Errorf("RepoDB.RemoveRepo() error = %v, want %v", err, repodb.ErrLegalHold)
+	}
+
+	if err := repo.ReleaseHold(fr, "case-1"); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.IsHeld(fr) {
+		t.Errorf("Repo.IsHeld() = false after releasing one of two holds")
+	}
+	if err := repo.ReleaseHold(fr, "case-1"); err == nil {
+		t.Errorf("Repo.ReleaseHold() expected error releasing a hold twice")
+	}
+	if err := repo.ReleaseHold(fr, "case-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RemoveFile(fr, repodb.DBRepoCommitOptions); err != nil {
+		t.Errorf("Repo.RemoveFile() error = %v after releasing holds", err)
+	}
+}
+
+func TestRepo_LegalHold_distinct(t *testing.T) {
+	tests := []struct {
+		name         string
+		recA, recB   *nestedRecord
+		caseA, caseB string
+	}{
+		{"underscores", &nestedRecord{folder: "b_c", name: "d"}, &nestedRecord{folder: "c", name: "d"}, "a", "a_b"},
+		{"nested folders", &nestedRecord{folder: "x/y", name: "z"}, &nestedRecord{folder: "xy", name: "z"}, "case", "case"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := &repodb.Repo{Name: "HoldRepo", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			for _, rec := range []*nestedRecord{tt.recA, tt.recB} {
+				if err := repo.WriteFile(rec, strings.NewReader("evidence"), repodb.DBRepoCommitOptions); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.LegalHold(tt.recA, tt.caseA); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.LegalHold(tt.recB, tt.caseB); err != nil {
+				t.Fatal(err)
+			}
+			if holds, err := repo.Holds(); err != nil || len(holds) != 2 {
+				t.Errorf("Repo.Holds() = %d holds, error = %v, want 2", len(holds), err)
+			}
+			for _, rec := range []*nestedRecord{tt.recA, tt.recB} {
+				if !repo.IsHeld(rec) {
+					t.Errorf("Repo.IsHeld() %s/%s = false, want true", rec.folder, rec.name)
+				}
+				if err := repo.RemoveFile(rec, repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrLegalHold) {
+					t.Errorf("Repo.RemoveFile() %s/%s error = %v, want %v", rec.folder, rec.name, err, repodb.ErrLegalHold)
+				}
+			}
+			if err := repo.ReleaseHold(tt.recA, tt.caseA); err != nil {
+				t.Fatal(err)
+			}
+			if !repo.IsHeld(tt.recB) {
+				t.Error("Repo.IsHeld() = false after releasing the hold of another record")
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package repodb
+
+import "time"
+
+// HookEvent types
+const (
+	HookCommit       = "commit"
+	HookQuotaWarning = "quota_warning"
+)
+
+// HookEvent describes a commit made to a repo in the database, or a warning raised
+// by it. Usage, Limit and Threshold are only set for quota warnings.
+type HookEvent struct {
+	Type      string    `json:"type"`
+	Operation string    `json:"operation,omitempty"` // Op name of the committed operation
+	Repo      string    `json:"repo"`
+	Record    string    `json:"record,omitempty"` // folder/name of the changed record, if any
+	Hash      string    `json:"commit,omitempty"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"timestamp"`
+	Usage     int64     `json:"usage,omitempty"`
+	Limit     int64     `json:"limit,omitempty"`
+	Threshold float64   `json:"threshold,omitempty"`
+}
+
+// Hook is called after each commit to a repo in the database, and for warnings.
+// Hooks are run synchronously in the order they were added, long running work
+// should be done in a separate goroutine.
+type Hook func(ev HookEvent)
+
+// AddHook registers a hook to be run after each commit
+func (db *RepoDB) AddHook(h Hook) {
+	db.hookMu.Lock()
+	defer db.hookMu.Unlock()
+	db.hooks = append(db.hooks, h)
+}
+
+// runHooks runs all registered hooks for the event
+func (db *RepoDB) runHooks(ev HookEvent) {
+	db.hookMu.RLock()
+	hooks := db.hooks
+	db.hookMu.RUnlock()
+	for _, h := range hooks {
+		h(ev)
+	}
+}
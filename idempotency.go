@@ -0,0 +1,65 @@
+package repodb
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// IdempotencyWindow is the number of recent commits searched for a matching
+// CommitOptions.IdempotencyKey
+var IdempotencyWindow = 100
+
+// replayed reports if the operation on the record was already committed with the
+// idempotency key of opts, in which case it should be skipped. The caller must hold
+// the repo lock.
+func (repo *Repo) replayed(op string, rec Record, opts CommitOptions) (bool, error) {
+	key := strings.TrimSpace(opts.IdempotencyKey)
+	if key == "" {
+		return false, nil
+	}
+	if strings.ContainsAny(key, "\r\n") {
+		return false, fmt.Errorf("idempotency key cannot contain line breaks: %q", key)
+	}
+	record := ""
+	if rec != nil {
+		record = path.Join(rec.Folder(), rec.FileName())
+	}
+
+	r, err := repo.git()
+	if err != nil {
+		return false, err
+	}
+	if _, err := r.Head(); err != nil {
+		// no commits yet
+		return false, nil
+	}
+	iter, err := r.Log(&git.LogOptions{})
+	if err != nil {
+		return false, fmt.Errorf("unable to read log of %s: %v", repo.Name, err)
+	}
+	found, n := false, 0
+	err = iter.ForEach(func(c *object.Commit) error {
+		if n >= IdempotencyWindow {
+			return storer.ErrStop
+		}
+		n++
+		a := parseTrailers(c.Message)
+		if a.IdempotencyKey == key && a.Operation == op && a.Record == record {
+			found = true
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to read log of %s: %v", repo.Name, err)
+	}
+	if found {
+		repo.DB.debug("skipped replayed operation", "repo", repo.Name, "operation", op, "record", record, "key", key)
+	}
+	return found, nil
+}
@@ -0,0 +1,83 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_IdempotencyKey(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "IdempotentRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	withKey := func(key string) repodb.CommitOptions {
+		opts := repodb.DBRepoCommitOptions
+		opts.IdempotencyKey = key
+		return opts
+	}
+
+	tests := []struct {
+		name    string
+		op      func() error
+		commits int
+		wantErr bool
+	}{
+		{"write", func() error {
+			return repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("one"), withKey("req-1"))
+		}, 1, false},
+		{"retried write", func() error {
+			return repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("one"), withKey("req-1"))
+		}, 0, false},
+		{"same key other operation", func() error {
+			return repo.WriteMeta(&FileRecord{Name: "a.txt"}, withKey("req-1"))
+		}, 1, false},
+		{"same key other record", func() error {
+			return repo.WriteFile(&FileRecord{Name: "b.txt"}, strings.NewReader("two"), withKey("req-1"))
+		}, 1, false},
+		{"remove", func() error {
+			return repo.RemoveFile(&FileRecord{Name: "b.txt"}, withKey("req-2"))
+		}, 1, false},
+		{"retried remove", func() error {
+			return repo.RemoveFile(&FileRecord{Name: "b.txt"}, withKey("req-2"))
+		}, 0, false},
+		{"without key", func() error {
+			return repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("three"), repodb.DBRepoCommitOptions)
+		}, 1, false},
+		{"line break", func() error {
+			return repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("four"), withKey("req\n3"))
+		}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := repo.RecentActivity(1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.op(); (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			after, err := repo.RecentActivity(1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(after) - len(before); got != tt.commits {
+				t.Errorf("made %d commits, want %d", got, tt.commits)
+			}
+		})
+	}
+
+	activity, err := repo.RecentActivity(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity[0].IdempotencyKey != "" {
+		t.Errorf("Activity.IdempotencyKey = %q, want empty", activity[0].IdempotencyKey)
+	}
+	activity, _ = repo.RecentActivity(2)
+	if activity[1].IdempotencyKey != "req-2" {
+		t.Errorf("Activity.IdempotencyKey = %q, want %q", activity[1].IdempotencyKey, "req-2")
+	}
+}
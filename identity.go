@@ -0,0 +1,81 @@
+package repodb
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// WithDefaultSignatures sets the commit author and committer of the DB, used when no
+// signature is provided in the CommitOptions, including commits of the DB itself such
+// as of repo meta-data. It takes precedence over WithOSIdentity, so databases in one
+// process can commit under different identities.
+func WithDefaultSignatures(author, committer Signature) Option {
+	return func(db *RepoDB) {
+		db.author = author.Git()
+		db.committer = committer.Git()
+	}
+}
+
+// WithOSIdentity sets the commit author, and committer, from the current OS user and
+// hostname when no signature is provided in the CommitOptions. The email is
+// user@hostname. Useful for auditing CLI usage on shared servers.
+func WithOSIdentity() Option {
+	return func(db *RepoDB) {
+		db.osIdentity = true
+	}
+}
+
+// osSignature returns a signature for the current OS user
+func osSignature() (*object.Signature, error) {
+	u, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get current user: %v", err)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get hostname: %v", err)
+	}
+	name := u.Name
+	if name == "" {
+		name = u.Username
+	}
+	return &object.Signature{Name: name, Email: fmt.Sprintf("%s@%s", u.Username, host)}, nil
+}
+
+// defaultSignatures fills missing author and committer signatures in opts according to
+// the DB identity options, falling back to those of DBRepoCommitOptions. The Actor of
+// opts is the author if none is provided. The key of WithSignKey is the SignKey if none
+// is provided.
+func (db *RepoDB) defaultSignatures(opts *CommitOptions) error {
+	if opts.Opts.SignKey == nil {
+		opts.Opts.SignKey = db.signKey
+	}
+	if opts.Opts.Author == nil && opts.Actor != nil {
+		opts.Opts.Author = opts.Actor.Git()
+	}
+	if opts.Opts.Author != nil && opts.Opts.Committer != nil {
+		return nil
+	}
+	author, committer := db.author, db.committer
+	if author == nil && db.osIdentity {
+		sig, err := osSignature()
+		if err != nil {
+			return err
+		}
+		c := *sig
+		author, committer = sig, &c
+	}
+	if author == nil {
+		author, committer = DBRepoCommitOptions.Opts.Author, DBRepoCommitOptions.Opts.Committer
+	}
+	if opts.Opts.Author == nil {
+		opts.Opts.Author = author
+	}
+	if opts.Opts.Committer == nil {
+		opts.Opts.Committer = committer
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package repodb_test
+
+import (
+	"os/user"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithOSIdentity(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithOSIdentity())
+	repo := &repodb.Repo{Name: "IdentityRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "identity.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(fr, strings.NewReader("a"), repodb.CommitOptions{Msg: "no signature"}); err != nil {
+		t.Fatal(err)
+	}
+
+	activity, err := repo.RecentActivity(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := u.Name
+	if want == "" {
+		want = u.Username
+	}
+	if activity[0].Actor != want {
+		t.Errorf("Repo.RecentActivity() Actor = %q, want OS user %q", activity[0].Actor, want)
+	}
+	// provided signatures are kept
+	if activity[1].Actor != "repodb" {
+		t.Errorf("Repo.RecentActivity() Actor = %q, want %q", activity[1].Actor, "repodb")
+	}
+}
+
+func TestWithDefaultSignatures(t *testing.T) {
+	dir := newTestDB(t).Dir()
+	alice := repodb.Signature{Name: "alice", Email: "alice@example.com"}
+	bob := repodb.Signature{Name: "bob", Email: "bob@example.com"}
+	dbA := repodb.NewDB(dir, repodb.WithDefaultSignatures(alice, alice), repodb.WithOSIdentity())
+	dbB := repodb.NewDB(dir, repodb.WithDefaultSignatures(bob, bob))
+
+	tests := []struct {
+		name    string
+		db      *repodb.RepoDB
+		opts    repodb.CommitOptions
+		actor   string
+		creator string // actor of the CreateRepo commit
+	}{
+		{"DefaultA", dbA, repodb.CommitOptions{Msg: "a"}, "alice", "alice"},
+		{"DefaultB", dbB, repodb.CommitOptions{Msg: "b"}, "bob", "bob"},
+		{"Override", dbA, repodb.DBRepoCommitOptions, "repodb", "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &repodb.Repo{Name: "Signature" + tt.name, DB: tt.db}
+			if err := tt.db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			fr := &FileRecord{Name: "signature.txt"}
+			if err := repo.WriteFile(fr, strings.NewReader("a"), tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			activity, err := repo.RecentActivity(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if activity[0].Actor != tt.actor {
+				t.Errorf("Repo.RecentActivity() Actor = %q, want %q", activity[0].Actor, tt.actor)
+			}
+			if activity[1].Actor != tt.creator {
+				t.Errorf("Repo.RecentActivity() Actor = %q, want %q", activity[1].Actor, tt.creator)
+			}
+		})
+	}
+}
+
+func TestCommitOptions_Actor(t *testing.T) {
+	system := repodb.Signature{Name: "system", Email: "system@example.com"}
+	alice := &repodb.Signature{Name: "alice", Email: "alice@example.com"}
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithDefaultSignatures(system, system))
+	repo := &repodb.Repo{Name: "ActorRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      repodb.CommitOptions
+		author    string
+		committer string
+	}{
+		{"Actor", repodb.CommitOptions{Msg: "actor", Actor: alice}, "alice@example.com", "system@example.com"},
+		{"NoActor", repodb.CommitOptions{Msg: "no actor"}, "system@example.com", "system@example.com"},
+		{"Author", repodb.CommitOptions{Msg: "author", Actor: alice, Opts: repodb.DBRepoCommitOptions.Opts}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := &FileRecord{Name: tt.name + ".txt"}
+			if err := repo.WriteFile(fr, strings.NewReader(tt.name), tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			c, err := repo.Commit("HEAD")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Author.Email != tt.author || c.Committer.Email != tt.committer {
+				t.Errorf("Repo.Commit() Author = %q, Committer = %q, want %q, %q", c.Author.Email, c.Committer.Email, tt.author, tt.committer)
+			}
+		})
+	}
+}
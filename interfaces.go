@@ -0,0 +1,136 @@
+package repodb
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// DB is the public method set of RepoDB, for substituting mocks in unit tests of code
+// using repodb. Repos are returned as the concrete *Repo, code working with a single
+// repo should depend on Repository instead.
+type DB interface {
+	RepoStore
+	Dir() string
+	OpenRepoLazy(name string) (*Repo, error)
+	OpenRepoAs(name, subject string) (*Repo, error)
+	Namespace(name string, opts ...Option) (*RepoDB, error)
+	Namespaces() ([]string, error)
+	CreateRepoFromTemplate(repo *Repo, tmpl RepoTemplate) error
+	AdoptRepo(name string) (*Repo, error)
+	CloneRepo(name, url string, opts CloneOptions) (*Repo, error)
+	RenameRepo(oldName, newName string) (*Repo, error)
+	ForkRepo(src, dst string) (*Repo, error)
+	CopyRecord(src, dst *Repo, rec Record, policy ConflictPolicy, opts CommitOptions) error
+	ListReposPage(opts ListOptions) ([]*Repo, error)
+	QueryRepos(f Filter) ([]*Repo, error)
+	ListReposByLabel(key, value string) ([]*Repo, error)
+	ListReposFiltered(f ListReposFilter) ([]*Repo, error)
+	AddHook(h Hook)
+	Freeze(reason string) error
+	Unfreeze() error
+	Frozen() (string, bool)
+	Watch(ctx context.Context) (<-chan Event, error)
+	Backup(w io.Writer) error
+	ExportAuditLog(since time.Time, w io.Writer, format LogFormat) error
+	Stats() (*DBStats, error)
+	Fsck() (*FsckReport, error)
+	GCAll(opts GCOptions) ([]*GCReport, error)
+	StartGC(ctx context.Context, interval time.Duration, opts GCOptions)
+	ExpireSweep(mode ExpireMode, opts CommitOptions) (int, error)
+	Migrate(opts CommitOptions) (int, error)
+	Sync() error
+}
+
+// Repository is the public method set of Repo, for substituting mocks in unit tests of
+// code using repodb.
+type Repository interface {
+	Record
+	Dir() string
+	Protect() error
+	EnsureMeta() error
+	Subject() string
+
+	// records
+	FileExists(rec Record) bool
+	WriteFile(rec Record, r io.Reader, opts CommitOptions) error
+	WriteFileCAS(rec Record, r io.Reader, expectedHead Hash, opts CommitOptions) error
+	ReadFile(rec Record, w io.Writer) (int64, error)
+	OpenRecord(rec Record) (io.ReadSeekCloser, error)
+	WriteFileWithProgress(rec Record, r io.Reader, progress Progress, opts CommitOptions) error
+	ReadFileWithProgress(rec Record, w io.Writer, progress Progress) (int64, error)
+	Preview(rec Record, maxBytes int) ([]byte, bool, error)
+	RemoveFile(rec Record, opts CommitOptions) error
+	RenameRecord(rec Record, newName string, opts CommitOptions) error
+	WriteFileAttr(rec Record, r io.Reader, attr FileAttr, opts CommitOptions) error
+	WriteFileInfo(rec Record, r io.Reader, fi os.FileInfo, opts CommitOptions) error
+	WriteFiles(records []RecordData, opts CommitOptions) error
+	Stat(rec Record) (os.FileInfo, error)
+	VerifyRecord(rec Record) error
+	WriteExternal(rec Record, ext External, opts CommitOptions) error
+	External(rec Record) (*External, error)
+	FetchExternal(rec Record, w io.Writer) (int64, error)
+	WriteMeta(rec Record, opts CommitOptions) error
+	LoadMeta(rec Record) error
+	RemoveMeta(rec Record, opts CommitOptions) error
+	ListRecords(folder string, recursive bool) ([]string, error)
+	QueryRecords(folder string, f Filter) ([]string, error)
+	DeleteWhere(folder string, f Filter, opts CommitOptions) (int, error)
+	PurgeDeleted(olderThan time.Duration, opts CommitOptions) (int, error)
+	Search(query string) ([]SearchResult, error)
+	ReindexFolder(folder string) error
+	VerifySearchIndex() ([]string, error)
+	CommitAll(opts CommitOptions) error
+	Flush(opts CommitOptions) error
+
+	// history
+	Head() (Hash, error)
+	Commit(rev string) (CommitInfo, error)
+	History(n int) ([]CommitInfo, error)
+	RecentActivity(n int) ([]Activity, error)
+	Archive(w io.Writer, format ArchiveFormat, commit Hash) error
+	Snapshot(name, message string) error
+	ListSnapshots() ([]Snapshot, error)
+	RollbackTo(ref string, opts CommitOptions) error
+	RevertFile(rec Record, hash Hash, opts CommitOptions) error
+	DiffFile(rec Record, from, to Hash) (FileDiff, error)
+	BlameFile(rec Record) ([]BlameLine, error)
+	TailFile(ctx context.Context, rec Record, w io.Writer) error
+	CreateBranch(name string) error
+	CheckoutBranch(name string) error
+	Branch() (string, error)
+	ListBranches() ([]string, error)
+	Merge(src, dst string, strategy MergeStrategy) error
+	WriteContent(folder string, r io.Reader, opts CommitOptions) (string, error)
+	VerifyHistory(armoredKeyRing string) error
+
+	// retention
+	LegalHold(rec Record, caseID string) error
+	ReleaseHold(rec Record, caseID string) error
+	Holds() ([]*Hold, error)
+	IsHeld(rec Record) bool
+	ScheduleDelete(rec Record, at time.Time) error
+	CancelDelete(rec Record) error
+	ScheduledDeletes() ([]*Deletion, error)
+	RunDeletes(opts CommitOptions) (int, error)
+	StartDeletes(ctx context.Context, interval time.Duration, opts CommitOptions)
+
+	// maintenance
+	Stats() (*Stats, error)
+	WriteStats(opts CommitOptions) (*Stats, error)
+	StartStats(ctx context.Context, interval time.Duration, opts CommitOptions)
+	VacuumMeta(repair bool, opts CommitOptions) (*VacuumReport, error)
+	RepairMeta(newRecord func(folder, name string) Record, opts CommitOptions) ([]string, error)
+	GC(opts GCOptions) (*GCReport, error)
+	SquashHistory(opts SquashOptions) (int, error)
+	SetBackend(b Backend) error
+	Backend() Backend
+	Incidents() ([]Incident, error)
+	Repair(opts CommitOptions) error
+}
+
+var (
+	_ DB         = (*RepoDB)(nil)
+	_ Repository = (*Repo)(nil)
+)
@@ -0,0 +1,43 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+// failingRepo is a mock Repository failing every read, other methods panic
+type failingRepo struct {
+	repodb.Repository
+}
+
+func (failingRepo) ReadFile(rec repodb.Record, w io.Writer) (int64, error) {
+	return 0, errors.New("disk failure")
+}
+
+// readRecord is application code depending on the Repository interface
+func readRecord(repo repodb.Repository, name string) (string, error) {
+	buf := &bytes.Buffer{}
+	if _, err := repo.ReadFile(&FileRecord{Name: name}, buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func TestRepository_Mock(t *testing.T) {
+	if _, err := readRecord(failingRepo{}, "a.txt"); err == nil {
+		t.Error("readRecord() with failing mock expected error")
+	}
+
+	var db repodb.DB = newTestDB(t)
+	repo := &repodb.Repo{Name: "MockedRepo", DB: db.(*repodb.RepoDB)}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readRecord(repo, "missing.txt"); err == nil {
+		t.Error("readRecord() of missing record expected error")
+	}
+}
@@ -0,0 +1,70 @@
+package repodb
+
+import (
+	"fmt"
+	"io"
+	"path"
+)
+
+// LargeFilesDir is the directory of the database holding the content of large files
+// written with WithLargeFiles. It is never a repo.
+const LargeFilesDir = ".large-files"
+
+// WithLargeFiles stores record contents larger than threshold bytes in the large files
+// directory of the database, outside of git history, committing only a small pointer
+// file in their place. It is WithBlobStore with a store kept beside the repos, content
+// addressed by repo and sha256 so unchanged content is stored once. Contents are kept
+// when their records or repos are removed.
+func WithLargeFiles(threshold int64) Option {
+	return func(db *RepoDB) {
+		db.blobs = &largeFileStore{db: db}
+		db.blobThreshold = threshold
+	}
+}
+
+// largeFileStore is the BlobStore of WithLargeFiles, in the database filesystem
+type largeFileStore struct {
+	db *RepoDB
+}
+
+func (s *largeFileStore) filename(key string) string {
+	return path.Join(s.db.dir, LargeFilesDir, path.Clean("/"+key))
+}
+
+// Put writes the blob to a temp file and renames it into place, unless it is already
+// stored. Satisfies BlobStore.
+func (s *largeFileStore) Put(key string, r io.Reader, size int64) error {
+	filename := s.filename(key)
+	if fi, err := s.db.fs.Stat(filename); err == nil && fi.Size() == size {
+		return nil
+	}
+	if err := s.db.fs.MkdirAll(path.Dir(filename), 0700); err != nil {
+		return err
+	}
+	tmp, tmpName, err := s.db.tempFile(path.Dir(filename), "blob-")
+	if err != nil {
+		return err
+	}
+	defer s.db.fs.Remove(tmpName)
+	defer tmp.Close()
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("wrote %d bytes to blob %s, want %d", n, key, size)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return s.db.fs.Rename(tmpName, filename)
+}
+
+// Get opens the blob. Satisfies BlobStore.
+func (s *largeFileStore) Get(key string) (io.ReadCloser, error) {
+	f, err := s.db.fs.Open(s.filename(key))
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
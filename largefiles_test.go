@@ -0,0 +1,60 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithLargeFiles(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithLargeFiles(16))
+	repo := &repodb.Repo{Name: "Artifacts", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		pointer bool
+	}{
+		{"small.txt", "small", false},
+		{"large.bin", strings.Repeat("large", 1000), true},
+		{"copy.bin", strings.Repeat("large", 1000), true},
+	}
+	for _, tt := range tests {
+		rec := &FileRecord{Name: tt.name}
+		if err := repo.WriteFile(rec, strings.NewReader(tt.content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := repo.ReadFile(rec, &buf); err != nil || buf.String() != tt.content {
+			t.Errorf("Repo.ReadFile(%s) = %d bytes, %v, want %d bytes", tt.name, buf.Len(), err, len(tt.content))
+		}
+		b, err := ioutil.ReadFile(filepath.Join(repo.Dir(), rec.Folder(), rec.FileName()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(b) < len(tt.content); got != tt.pointer {
+			t.Errorf("committed %s is %d bytes, want pointer %v", tt.name, len(b), tt.pointer)
+		}
+	}
+
+	// identical contents are stored once, beside the repos
+	blobs, err := ioutil.ReadDir(filepath.Join(db.Dir(), repodb.LargeFilesDir, "Artifacts"))
+	if err != nil || len(blobs) != 1 {
+		t.Errorf("large files = %v, %v, want 1", blobs, err)
+	}
+	if repos, err := db.ListReposPage(repodb.ListOptions{}); err != nil || len(repos) != 1 {
+		t.Errorf("RepoDB.ListReposPage() = %v, %v, want only Artifacts", repos, err)
+	}
+	err = db.CreateRepo(&repodb.Repo{Name: repodb.LargeFilesDir, DB: db})
+	if !errors.Is(err, repodb.ErrInvalidName) {
+		t.Errorf("RepoDB.CreateRepo(%s) error = %v, want ErrInvalidName", repodb.LargeFilesDir, err)
+	}
+}
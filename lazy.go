@@ -0,0 +1,48 @@
+package repodb
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpenRepoLazy opens the repo like OpenRepo without loading its meta-data, for hot
+// paths reading records which don't need it. Fields of the repo are zero until
+// EnsureMeta loads them, which the first write to the repo does as writes depend on
+// MaxSize and Compression.
+func (db *RepoDB) OpenRepoLazy(name string) (_ *Repo, err error) {
+	defer db.metrics.observe("open_repo_lazy", time.Now(), &err)
+	repo, err := db.locateRepo(name)
+	if err != nil {
+		return nil, err
+	}
+	repo.lazy = true
+	return repo, nil
+}
+
+// EnsureMeta loads the meta-data of a repo opened by OpenRepoLazy, if not yet loaded.
+// Call it before changing fields of the repo to write with WriteMeta.
+func (repo *Repo) EnsureMeta() error {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	return repo.ensureMeta()
+}
+
+// ensureMeta loads the meta-data of a lazily opened repo, the caller must hold the
+// repo lock
+func (repo *Repo) ensureMeta() error {
+	if !repo.lazy {
+		return nil
+	}
+	if err := repo.reloadMeta(); err != nil {
+		return fmt.Errorf("cannot read meta-data for %s: %v", repo.Name, err)
+	}
+	// UpdatedOn is not written by every commit, as in OpenRepo
+	if t, err := repo.headTime(); err == nil && t.After(repo.UpdatedOn) {
+		repo.UpdatedOn = t
+	}
+	repo.lazy = false
+	return nil
+}
@@ -0,0 +1,56 @@
+package repodb_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_OpenRepoLazy(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.CreateRepo(&repodb.Repo{Name: "LazyRepo", DB: db, Description: "lazy", Compression: "gzip"}); err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Repeat("lazy ", 100)
+	rec := &FileRecord{Name: "a.txt"}
+
+	repo, err := db.OpenRepoLazy("LazyRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Description != "" {
+		t.Errorf("RepoDB.OpenRepoLazy() Description = %q, want not loaded", repo.Description)
+	}
+	// meta-data is loaded by the first write, which compresses as configured
+	if err := repo.WriteFile(rec, strings.NewReader(text), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if repo.Description != "lazy" || repo.Compression != "gzip" {
+		t.Errorf("Repo.WriteFile() lazy repo Description = %q, Compression = %q, want loaded", repo.Description, repo.Compression)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := repo.ReadFile(rec, buf); err != nil || buf.String() != text {
+		t.Errorf("Repo.ReadFile() = %q, %v, want %q", buf.String(), err, text)
+	}
+
+	// writing the meta-data of a lazy repo requires it loaded
+	lazy, err := db.OpenRepoLazy("LazyRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lazy.WriteMeta(lazy, repodb.DBRepoCommitOptions); err == nil {
+		t.Errorf("Repo.WriteMeta() of lazy repo expected error")
+	}
+	if err := lazy.Protect(); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := db.OpenRepo("LazyRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opened.Protected || opened.Description != "lazy" {
+		t.Errorf("Repo.Protect() lazy repo Protected = %v, Description = %q, want true, %q", opened.Protected, opened.Description, "lazy")
+	}
+}
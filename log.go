@@ -0,0 +1,32 @@
+package repodb
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger sets the logger used for debug logging of commit activity and meta-data
+// writes, and warnings for errors that are recovered from rather than returned. By
+// default nothing is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(db *RepoDB) {
+		db.logger = l
+	}
+}
+
+// debug logs at debug level if a logger is set
+func (db *RepoDB) debug(msg string, args ...interface{}) {
+	db.log(slog.LevelDebug, msg, args...)
+}
+
+// warn logs at warn level if a logger is set
+func (db *RepoDB) warn(msg string, args ...interface{}) {
+	db.log(slog.LevelWarn, msg, args...)
+}
+
+func (db *RepoDB) log(level slog.Level, msg string, args ...interface{}) {
+	if db.logger == nil {
+		return
+	}
+	db.logger.Log(context.Background(), level, msg, args...)
+}
@@ -0,0 +1,39 @@
+package repodb_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithLogger(logger))
+
+	if err := db.CreateRepo(&repodb.Repo{Name: "LogRepo", DB: db}); err != nil {
+		t.Fatal(err)
+	}
+	// a directory that is not a repo is skipped with a warning
+	if err := os.Mkdir(path.Join(db.Dir(), "NotARepo"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(db.ListRepos()); got != 1 {
+		t.Errorf("RepoDB.ListRepos() len = %v, want %v", got, 1)
+	}
+
+	for _, want := range []string{
+		"level=DEBUG msg=\"wrote meta-data\" repo=LogRepo",
+		"level=DEBUG msg=committed repo=LogRepo",
+		"level=WARN msg=\"skipping repo in list\" repo=NotARepo",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WithLogger() log missing %q:\n%s", want, buf)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package repodb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// ErrMergeConflict is matched by a *MergeConflictError with errors.Is, and is also
+// ErrConflict
+var ErrMergeConflict error = &errorKind{"merge conflict", ErrConflict}
+
+// MergeConflictError is returned by Merge when both branches changed the same files
+type MergeConflictError struct {
+	Paths []string // conflicting file paths, sorted
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrMergeConflict, strings.Join(e.Paths, ", "))
+}
+
+// Unwrap returns ErrMergeConflict
+func (e *MergeConflictError) Unwrap() error {
+	return ErrMergeConflict
+}
+
+// MergeStrategy decides how Merge resolves branches that have diverged
+type MergeStrategy int
+
+// merge strategies
+const (
+	MergeAuto            MergeStrategy = iota // fast-forward, or merge returning a *MergeConflictError on conflicts
+	MergeFastForwardOnly                      // fast-forward, or fail if the branches have diverged
+	MergeOurs                                 // conflicting files keep the dst version
+	MergeTheirs                               // conflicting files take the src version
+)
+
+// OpMerge is the operation of merge commits made by Repo.Merge
+const OpMerge = "merge"
+
+// Merge merges the src branch into the dst branch, e.g. an approved draft into master.
+// Branches are merged file by file, a file changed on only one branch since they
+// diverged takes that version, files changed on both are resolved by the strategy. If
+// dst is checked out the worktree is updated. Merging a branch already in dst is a
+// no-op.
+func (repo *Repo) Merge(src, dst string, strategy MergeStrategy) error {
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	theirs, err := branchCommit(r, src)
+	if err != nil {
+		return fmt.Errorf("unable to merge %s in %s: %v", src, repo.Name, err)
+	}
+	ours, err := branchCommit(r, dst)
+	if err != nil {
+		return fmt.Errorf("unable to merge into %s in %s: %v", dst, repo.Name, err)
+	}
+	bases, err := theirs.MergeBase(ours)
+	if err != nil || len(bases) == 0 {
+		return fmt.Errorf("branches %s and %s of %s have no common history", src, dst, repo.Name)
+	}
+	base := bases[0]
+
+	var hash plumbing.Hash
+	switch {
+	case base.Hash == theirs.Hash:
+		return nil
+	case base.Hash == ours.Hash:
+		hash = theirs.Hash
+	case strategy == MergeFastForwardOnly:
+		return fmt.Errorf("cannot fast-forward %s to %s in %s, branches have diverged", dst, src, repo.Name)
+	default:
+		tree, err := mergeTrees(r, base, ours, theirs, strategy)
+		if err != nil {
+			return err
+		}
+		if hash, err = repo.mergeCommit(r, tree, ours, theirs, src, dst); err != nil {
+			return err
+		}
+	}
+
+	head, err := r.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return fmt.Errorf("unable to read head of %s: %v", repo.Name, err)
+	}
+	if head.Target() == plumbing.NewBranchReferenceName(dst) && repo.staged == nil {
+		w, err := r.Worktree()
+		if err != nil {
+			return err
+		}
+		if err := w.Reset(&git.ResetOptions{Commit: hash, Mode: git.MergeReset}); err != nil {
+			return fmt.Errorf("unable to update worktree of %s: %v", repo.Name, err)
+		}
+		if err := repo.heartbeat(r, hash); err != nil {
+			return err
+		}
+	} else if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(dst), hash)); err != nil {
+		return fmt.Errorf("unable to update branch %s of %s: %v", dst, repo.Name, err)
+	}
+
+	msg := fmt.Sprintf("merged branch %s into %s", src, dst)
+	repo.DB.debug(msg, "repo", repo.Name, "commit", hash.String())
+	repo.DB.runHooks(HookEvent{
+		Type:      HookCommit,
+		Operation: OpMerge,
+		Repo:      repo.Name,
+		Hash:      hash.String(),
+		Message:   msg,
+		Time:      time.Now(),
+	})
+	return repo.reloadMeta()
+}
+
+// mergeCommit stores a merge commit of the tree with ours and theirs as parents
+func (repo *Repo) mergeCommit(r *git.Repository, tree plumbing.Hash, ours, theirs *object.Commit, src, dst string) (plumbing.Hash, error) {
+	opts := CommitOptions{}
+	if err := repo.DB.defaultSignatures(&opts); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	sig := *opts.Opts.Author
+	sig.When = time.Now()
+
+	c := &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      fmt.Sprintf("merged branch %s into %s\n\n%s", src, dst, trailers(OpMerge, "", "")),
+		TreeHash:     tree,
+		ParentHashes: []plumbing.Hash{ours.Hash, theirs.Hash},
+	}
+	return storeCommit(r.Storer, c, opts.Opts.SignKey)
+}
+
+// branchCommit returns the commit at the head of the branch
+func branchCommit(r *git.Repository, branch string) (*object.Commit, error) {
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil, err
+	}
+	return r.CommitObject(ref.Hash())
+}
+
+// treeEntry is a file of a merged tree
+type treeEntry struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+}
+
+// mergeTrees merges the files of ours and theirs changed since base and stores the
+// merged tree. Returns a *MergeConflictError for the MergeAuto strategy if both
+// changed the same file differently.
+func mergeTrees(r *git.Repository, base, ours, theirs *object.Commit, strategy MergeStrategy) (plumbing.Hash, error) {
+	baseFiles, err := commitFiles(base)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	ourFiles, err := commitFiles(ours)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	theirFiles, err := commitFiles(theirs)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	paths := map[string]bool{}
+	for _, files := range []map[string]treeEntry{baseFiles, ourFiles, theirFiles} {
+		for p := range files {
+			paths[p] = true
+		}
+	}
+	merged := map[string]treeEntry{}
+	conflicts := []string{}
+	for p := range paths {
+		b, bok := baseFiles[p]
+		o, ook := ourFiles[p]
+		t, tok := theirFiles[p]
+		e, ok := o, ook
+		switch {
+		case o == t && ook == tok:
+		case b == t && bok == tok:
+		case b == o && bok == ook:
+			e, ok = t, tok
+		case strategy == MergeTheirs:
+			e, ok = t, tok
+		case strategy == MergeAuto:
+			conflicts = append(conflicts, p)
+		}
+		if ok {
+			merged[p] = e
+		}
+	}
+	if len(conflicts) > 0 {
+		// attribute files conflict with their record file, only the record is reported
+		conflicting := map[string]bool{}
+		for _, p := range conflicts {
+			conflicting[p] = true
+		}
+		reported := []string{}
+		for _, p := range conflicts {
+			if rec, ok := attrRecord(p); !ok || !conflicting[rec] {
+				reported = append(reported, p)
+			}
+		}
+		sort.Strings(reported)
+		return plumbing.ZeroHash, &MergeConflictError{Paths: reported}
+	}
+	return buildTree(r.Storer, merged)
+}
+
+// commitFiles returns the files of the commit tree by path
+func commitFiles(c *object.Commit) (map[string]treeEntry, error) {
+	iter, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]treeEntry{}
+	err = iter.ForEach(func(f *object.File) error {
+		files[f.Name] = treeEntry{hash: f.Hash, mode: f.Mode}
+		return nil
+	})
+	return files, err
+}
+
+// buildTree stores the trees holding the files, and returns the hash of the root tree
+func buildTree(s storer.EncodedObjectStorer, files map[string]treeEntry) (plumbing.Hash, error) {
+	entries := []object.TreeEntry{}
+	dirs := map[string]map[string]treeEntry{}
+	for p, e := range files {
+		i := strings.IndexByte(p, '/')
+		if i < 0 {
+			entries = append(entries, object.TreeEntry{Name: p, Mode: e.mode, Hash: e.hash})
+			continue
+		}
+		if dirs[p[:i]] == nil {
+			dirs[p[:i]] = map[string]treeEntry{}
+		}
+		dirs[p[:i]][p[i+1:]] = e
+	}
+	for name, files := range dirs {
+		hash, err := buildTree(s, files)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// git orders tree entries by name, with directories compared as if ending in a slash
+	key := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(entries, func(i, j int) bool { return key(entries[i]) < key(entries[j]) })
+	return storeObject(s, &object.Tree{Entries: entries})
+}
+
+// storeObject encodes and stores the git object
+func storeObject(s storer.EncodedObjectStorer, o interface {
+	Encode(plumbing.EncodedObject) error
+}) (plumbing.Hash, error) {
+	obj := s.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.SetEncodedObject(obj)
+}
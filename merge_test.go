@@ -0,0 +1,114 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_Merge(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "MergeRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := repo.WriteFile(&FileRecord{Name: name}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkout := func(branch string) {
+		t.Helper()
+		if err := repo.CheckoutBranch(branch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		b := &bytes.Buffer{}
+		if _, err := repo.ReadFile(&FileRecord{Name: name}, b); err != nil {
+			return ""
+		}
+		return b.String()
+	}
+
+	// fast-forward of master to a draft
+	write("a.txt", "base")
+	if err := repo.CreateBranch("draft"); err != nil {
+		t.Fatal(err)
+	}
+	checkout("draft")
+	write("b.txt", "draft")
+	checkout("master")
+	if err := repo.Merge("draft", "master", repodb.MergeFastForwardOnly); err != nil {
+		t.Fatalf("Repo.Merge() fast-forward error = %v", err)
+	}
+	if got := read("b.txt"); got != "draft" {
+		t.Fatalf("Repo.Merge() fast-forward b.txt = %q, want %q", got, "draft")
+	}
+
+	// diverged branches, both changing a.txt
+	write("a.txt", "master")
+	write("c.txt", "master")
+	if err := repo.CreateBranch("release"); err != nil {
+		t.Fatal(err)
+	}
+	checkout("draft")
+	write("a.txt", "draft")
+	write("d.txt", "draft")
+	checkout("master")
+
+	tests := []struct {
+		name     string
+		dst      string
+		strategy repodb.MergeStrategy
+		wantErr  bool
+		is       error
+		wantA    string
+	}{
+		{"fast-forward only", "master", repodb.MergeFastForwardOnly, true, nil, "master"},
+		{"conflict", "master", repodb.MergeAuto, true, repodb.ErrMergeConflict, "master"},
+		{"conflict is ErrConflict", "master", repodb.MergeAuto, true, repodb.ErrConflict, "master"},
+		{"ours", "release", repodb.MergeOurs, false, nil, "master"},
+		{"theirs", "master", repodb.MergeTheirs, false, nil, "draft"},
+		{"already merged", "master", repodb.MergeAuto, false, nil, "draft"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.Merge("draft", tt.dst, tt.strategy)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("Repo.Merge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var conflict *repodb.MergeConflictError
+			if errors.As(err, &conflict) && strings.Join(conflict.Paths, ",") != "files/a.txt" {
+				t.Errorf("Repo.Merge() conflict paths = %v, want [files/a.txt]", conflict.Paths)
+			}
+			if tt.dst != "master" {
+				checkout(tt.dst)
+				defer checkout("master")
+			}
+			if got := read("a.txt"); got != tt.wantA {
+				t.Errorf("Repo.Merge() a.txt = %q, want %q", got, tt.wantA)
+			}
+			if err != nil {
+				return
+			}
+			for _, name := range []string{"b.txt", "c.txt", "d.txt"} {
+				if !repo.FileExists(&FileRecord{Name: name}) {
+					t.Errorf("Repo.Merge() %s missing from %s", name, tt.dst)
+				}
+			}
+			head, err := repo.Commit(tt.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(head.Parents) != 2 {
+				t.Errorf("Repo.Merge() head of %s has %d parents, want merge commit", tt.dst, len(head.Parents))
+			}
+		})
+	}
+}
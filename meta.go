@@ -0,0 +1,185 @@
+package repodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+)
+
+// MetaCodec encodes meta-data files, such as with YAML or TOML for records edited by
+// hand in the repos, see WithMetaCodec. Unmarshal into a Meta must decode objects as
+// map[string]interface{} and other values as encoding/json does, for filters to work.
+type MetaCodec interface {
+	Ext() string // file name extension including the dot, e.g. ".yaml"
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+// JSONMeta is the default MetaCodec, indented json
+var JSONMeta MetaCodec = jsonMeta{indent: true}
+
+// jsonMeta is the json MetaCodec, compact unless indent is set
+type jsonMeta struct {
+	indent bool
+}
+
+func (jsonMeta) Ext() string { return ".json" }
+
+func (c jsonMeta) Marshal(v interface{}) ([]byte, error) {
+	if c.indent {
+		return json.MarshalIndent(v, "", "\t")
+	}
+	return json.Marshal(v)
+}
+
+func (jsonMeta) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+// WithMetaCodec encodes meta-data files with the codec rather than json. Files of
+// another encoding are not read, so the codec is chosen when the database is created.
+func WithMetaCodec(c MetaCodec) Option {
+	return func(db *RepoDB) {
+		db.meta = c
+	}
+}
+
+// MetaCodec returns the codec of the meta-data files, see WithMetaCodec
+func (db *RepoDB) MetaCodec() MetaCodec {
+	switch {
+	case db.meta != nil:
+		return db.meta
+	case db.compactMeta:
+		return jsonMeta{}
+	}
+	return JSONMeta
+}
+
+// metaExt returns the file name extension of meta-data files
+func (db *RepoDB) metaExt() string {
+	return db.MetaCodec().Ext()
+}
+
+// MetaMarshaler is a Record encoding its own meta-data in WriteMeta, in place of the DB
+// codec, such as to redact secrets or format times. The encoding should still decode
+// with the codec into a Meta for QueryRecords and VacuumMeta.
+type MetaMarshaler interface {
+	MarshalMeta() ([]byte, error)
+}
+
+// MetaUnmarshaler is a Record decoding its own meta-data in LoadMeta, in place of the
+// DB codec, such as to set unexported fields
+type MetaUnmarshaler interface {
+	UnmarshalMeta(b []byte) error
+}
+
+// metaStore reads and writes meta-data files in the MetaDir sub-directory of dir.
+// Files are written to a temp file and renamed into place, so an interrupted write
+// leaves the previous version intact, see VacuumMeta.
+type metaStore struct {
+	db    *RepoDB
+	dir   string
+	codec MetaCodec
+}
+
+// metaStore returns the meta-data store for dir using the DB serialization options
+func (db *RepoDB) metaStore(dir string) *metaStore {
+	return &metaStore{db: db, dir: path.Join(dir, MetaDir), codec: db.MetaCodec()}
+}
+
+// filename returns the meta-data file name for the resource
+func (m *metaStore) filename(name string) string {
+	return path.Join(m.dir, name) + m.codec.Ext()
+}
+
+// write v encoded to the named meta-data file
+func (m *metaStore) write(name string, v interface{}) error {
+	b, err := m.encode(v)
+	if err != nil {
+		return err
+	}
+	return m.writeEncoded(name, b)
+}
+
+// writeEncoded writes the encoded meta-data b to the named meta-data file
+func (m *metaStore) writeEncoded(name string, b []byte) error {
+	if err := m.db.fs.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+	filename := m.filename(name)
+	if err := m.db.writeFile(filename+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return m.db.fs.Rename(filename+".tmp", filename)
+}
+
+// encode v with the meta-data codec, or its MarshalMeta method, ending in a newline
+func (m *metaStore) encode(v interface{}) ([]byte, error) {
+	var b []byte
+	var err error
+	if mm, ok := v.(MetaMarshaler); ok {
+		b, err = mm.MarshalMeta()
+	} else {
+		b, err = m.codec.Marshal(v)
+	}
+	if rec, ok := v.(Record); ok && err == nil {
+		b, err = m.stampVersion(rec, b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(b, []byte("\n")) {
+		b = append(b, '\n')
+	}
+	return b, nil
+}
+
+// read the named meta-data file into v, for json either compact or indented is accepted
+func (m *metaStore) read(name string, v interface{}) error {
+	b, err := m.db.readFile(m.filename(name))
+	if err != nil {
+		return err
+	}
+	return m.decode(b, v)
+}
+
+// decode b into v with the meta-data codec, or its UnmarshalMeta method, migrating the
+// meta-data of a record first
+func (m *metaStore) decode(b []byte, v interface{}) error {
+	if rec, ok := v.(Record); ok {
+		var err error
+		if b, err = m.migrateEncoded(rec, b); err != nil {
+			return err
+		}
+	}
+	if mu, ok := v.(MetaUnmarshaler); ok {
+		return mu.UnmarshalMeta(b)
+	}
+	return m.codec.Unmarshal(b, v)
+}
+
+// readAll returns the contents of all meta-data files, ignoring temp files. Returns an
+// empty list if the meta-data directory does not exist.
+func (m *metaStore) readAll() ([][]byte, error) {
+	fileInfos, err := m.db.readDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records [][]byte
+	for _, f := range fileInfos {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), m.codec.Ext()) {
+			continue
+		}
+		b, err := m.db.readFile(path.Join(m.dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, b)
+	}
+	return records, nil
+}
@@ -0,0 +1,191 @@
+package repodb_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithCompactMeta(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithCompactMeta())
+	repo := &repodb.Repo{Name: "CompactRepo", DB: db, Description: "compact"}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path.Join(repo.Dir(), repodb.MetaDir, "CompactRepo.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(b, []byte("\n")) != 1 {
+		t.Errorf("WithCompactMeta() wrote indented meta-data:\n%s", b)
+	}
+
+	// indented and compact meta-data are both readable by either DB
+	indentedDB := repodb.NewDB(db.Dir())
+	repo, err = indentedDB.OpenRepo("CompactRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Description != "compact" {
+		t.Errorf("RepoDB.OpenRepo() Description = %v, want %v", repo.Description, "compact")
+	}
+	if err := repo.Protect(); err != nil {
+		t.Fatal(err)
+	}
+	repo, err = db.OpenRepo("CompactRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !repo.Protected {
+		t.Errorf("RepoDB.OpenRepo() Protected = %v, want %v", repo.Protected, true)
+	}
+}
+
+// headerMeta is a MetaCodec of json after a header line, which plain json readers reject
+type headerMeta struct{}
+
+const metaHeader = "# meta\n"
+
+func (headerMeta) Ext() string { return ".meta" }
+
+func (headerMeta) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return append([]byte(metaHeader), b...), err
+}
+
+func (headerMeta) Unmarshal(b []byte, v interface{}) error {
+	if !bytes.HasPrefix(b, []byte(metaHeader)) {
+		return errors.New("missing meta header")
+	}
+	return json.Unmarshal(b[len(metaHeader):], v)
+}
+
+func TestWithMetaCodec(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithMetaCodec(headerMeta{}))
+	repo := &repodb.Repo{Name: "CodecRepo", DB: db, Description: "codec"}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := repo.WriteFile(&FileRecord{Name: name}, strings.NewReader(name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.WriteMeta(&FileRecord{Name: "b.txt", SoftDeleted: true}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path.Join(repo.Dir(), "files", repodb.MetaDir, "b.txt.meta"))
+	if err != nil || !bytes.HasPrefix(b, []byte(metaHeader)) {
+		t.Fatalf("WithMetaCodec() meta-data = %q, error = %v", b, err)
+	}
+	if _, err := os.Stat(path.Join(repo.Dir(), "files", repodb.MetaDir, "b.txt.json")); !os.IsNotExist(err) {
+		t.Errorf("WithMetaCodec() wrote json meta-data, error = %v", err)
+	}
+
+	opened, err := db.OpenRepo("CodecRepo")
+	if err != nil || opened.Description != "codec" {
+		t.Fatalf("RepoDB.OpenRepo() = %+v, error = %v", opened, err)
+	}
+	loaded := &FileRecord{Name: "b.txt"}
+	if err := repo.LoadMeta(loaded); err != nil || !loaded.SoftDeleted {
+		t.Errorf("Repo.LoadMeta() = %+v, error = %v", loaded, err)
+	}
+	got, err := repo.QueryRecords("files", repodb.Match(map[string]interface{}{"softdeleted": true}))
+	if err != nil || !reflect.DeepEqual(got, []string{"b.txt"}) {
+		t.Errorf("Repo.QueryRecords() = %v, error = %v", got, err)
+	}
+	report, err := repo.VacuumMeta(false, repodb.DBRepoCommitOptions)
+	if err != nil || len(report.Invalid) != 0 {
+		t.Errorf("Repo.VacuumMeta() = %+v, error = %v", report, err)
+	}
+
+	// copies keep the codec of the destination DB
+	dst := &repodb.Repo{Name: "CodecCopy", DB: db}
+	if err := db.CreateRepo(dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CopyRecord(repo, dst, &FileRecord{Name: "b.txt"}, repodb.ConflictFail, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	copied := &FileRecord{Name: "b.txt"}
+	if err := dst.LoadMeta(copied); err != nil || !copied.SoftDeleted {
+		t.Errorf("RepoDB.CopyRecord() meta-data = %+v, error = %v", copied, err)
+	}
+	jsonDB := repodb.NewDB(newTestDB(t).Dir())
+	jsonRepo := &repodb.Repo{Name: "JSONCopy", DB: jsonDB}
+	if err := jsonDB.CreateRepo(jsonRepo); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CopyRecord(repo, jsonRepo, &FileRecord{Name: "b.txt"}, repodb.ConflictFail, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	copied = &FileRecord{Name: "b.txt"}
+	if err := jsonRepo.LoadMeta(copied); err != nil || !copied.SoftDeleted {
+		t.Errorf("RepoDB.CopyRecord() to json DB meta-data = %+v, error = %v", copied, err)
+	}
+}
+
+// secretRecord redacts its secret from meta-data and keeps the raw encoding
+type secretRecord struct {
+	Name   string
+	Secret string
+	raw    string
+}
+
+func (r *secretRecord) FileName() string { return r.Name }
+func (r *secretRecord) Folder() string   { return "secrets" }
+
+func (r *secretRecord) MarshalMeta() ([]byte, error) {
+	return json.Marshal(map[string]string{"Name": r.Name, "Secret": "redacted"})
+}
+
+func (r *secretRecord) UnmarshalMeta(b []byte) error {
+	r.raw = string(b)
+	return json.Unmarshal(b, r)
+}
+
+func TestMetaMarshaler(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), tt.opts...)
+			repo := &repodb.Repo{Name: "Secrets", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteMeta(&secretRecord{Name: "key", Secret: "hunter2"}, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			got := &secretRecord{Name: "key"}
+			if err := repo.LoadMeta(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Secret != "redacted" || !strings.Contains(got.raw, `"redacted"`) {
+				t.Errorf("Repo.LoadMeta() = %+v, want redacted secret", got)
+			}
+			if tt.name == "bare" {
+				return
+			}
+			names, err := repo.QueryRecords("secrets", repodb.Match(map[string]interface{}{"Secret": "redacted"}))
+			if err != nil || !reflect.DeepEqual(names, []string{"key"}) {
+				t.Errorf("Repo.QueryRecords() = %v, error = %v", names, err)
+			}
+		})
+	}
+}
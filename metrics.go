@@ -0,0 +1,150 @@
+package repodb
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics are the optional prometheus collectors for a RepoDB. A nil *metrics is
+// valid and records nothing.
+type metrics struct {
+	ops          *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	bytesWritten prometheus.Counter
+	bytesRead    prometheus.Counter
+	lockWait     *prometheus.HistogramVec
+
+	reg prometheus.Registerer
+	err error // of registering the collectors, logged once the DB is created
+}
+
+// WithMetrics registers operation counts, latencies, bytes written and read, repo
+// count and lock wait time metrics with reg, under the repodb namespace. The repo count
+// is labeled with the DB directory, as namespaces of the DB are counted separately.
+// Registration errors are logged, and the metrics are then not exported.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(db *RepoDB) {
+		m := &metrics{
+			reg: reg,
+			ops: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: "repodb",
+				Name:      "operations_total",
+				Help:      "Number of database operations by operation and result.",
+			}, []string{"op", "result"}),
+			latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: "repodb",
+				Name:      "operation_duration_seconds",
+				Help:      "Duration of database operations, including lock wait and commit.",
+				Buckets:   prometheus.DefBuckets,
+			}, []string{"op"}),
+			bytesWritten: prometheus.NewCounter(prometheus.CounterOpts{
+				Namespace: "repodb",
+				Name:      "written_bytes_total",
+				Help:      "Number of record file bytes written.",
+			}),
+			bytesRead: prometheus.NewCounter(prometheus.CounterOpts{
+				Namespace: "repodb",
+				Name:      "read_bytes_total",
+				Help:      "Number of record file bytes read.",
+			}),
+			lockWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: "repodb",
+				Name:      "lock_wait_seconds",
+				Help:      "Time spent waiting to acquire database and repo locks.",
+				Buckets:   []float64{.0001, .001, .01, .1, 1, 10},
+			}, []string{"lock"}),
+		}
+		var errs []error
+		m.ops = m.register(m.ops, &errs).(*prometheus.CounterVec)
+		m.latency = m.register(m.latency, &errs).(*prometheus.HistogramVec)
+		m.bytesWritten = m.register(m.bytesWritten, &errs).(prometheus.Counter)
+		m.bytesRead = m.register(m.bytesRead, &errs).(prometheus.Counter)
+		m.lockWait = m.register(m.lockWait, &errs).(*prometheus.HistogramVec)
+		m.err = errors.Join(errs...)
+		db.metrics = m
+	}
+}
+
+// init registers the repo count of the DB, once its directory is set, and logs the
+// registration errors
+func (m *metrics) init(db *RepoDB) {
+	if m == nil {
+		return
+	}
+	repos := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   "repodb",
+		Name:        "repos",
+		Help:        "Number of repository directories in the database.",
+		ConstLabels: prometheus.Labels{"dir": db.dir},
+	}, func() float64 {
+		fileInfos, _ := db.readDir(db.dir)
+		n := 0
+		for _, f := range fileInfos {
+			if f.IsDir() && !reservedDir(f.Name()) {
+				n++
+			}
+		}
+		return float64(n)
+	})
+	errs := []error{m.err}
+	m.register(repos, &errs)
+	if err := errors.Join(errs...); err != nil {
+		db.warn("unable to register metrics", "dir", db.dir, "err", err)
+	}
+}
+
+// register the collector, returning the existing collector if one is already
+// registered. Other errors are appended to errs, and c is returned unregistered.
+func (m *metrics) register(c prometheus.Collector, errs *[]error) prometheus.Collector {
+	err := m.reg.Register(c)
+	var are prometheus.AlreadyRegisteredError
+	switch {
+	case errors.As(err, &are):
+		return are.ExistingCollector
+	case err != nil:
+		*errs = append(*errs, err)
+	}
+	return c
+}
+
+// observe records the count, result and duration of the operation started at start.
+// Use with defer and a named error result.
+func (m *metrics) observe(op string, start time.Time, err *error) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if *err != nil {
+		result = "error"
+	}
+	m.ops.WithLabelValues(op, result).Inc()
+	m.latency.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// lock acquires l, recording the time spent waiting under the lock name
+func (m *metrics) lock(name string, l sync.Locker) {
+	if m == nil {
+		l.Lock()
+		return
+	}
+	start := time.Now()
+	l.Lock()
+	m.lockWait.WithLabelValues(name).Observe(time.Since(start).Seconds())
+}
+
+// written records n record file bytes written
+func (m *metrics) written(n int64) {
+	if m != nil {
+		m.bytesWritten.Add(float64(n))
+	}
+}
+
+// read records n record file bytes read
+func (m *metrics) read(n int64) {
+	if m != nil {
+		m.bytesRead.Add(float64(n))
+	}
+}
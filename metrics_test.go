@@ -0,0 +1,96 @@
+package repodb_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithMetrics(reg))
+
+	repo := &repodb.Repo{Name: "MetricsRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "measured.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("12345"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.OpenRepo("NotExists"); err == nil {
+		t.Fatal("RepoDB.OpenRepo() expected error")
+	}
+	// namespaces count their own repos
+	ns, err := db.Namespace("tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"TenantRepo1", "TenantRepo2"} {
+		if err := ns.CreateRepo(&repodb.Repo{Name: name, DB: ns}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			name := mf.GetName()
+			for _, l := range m.GetLabel() {
+				name += "," + l.GetValue()
+			}
+			switch {
+			case m.Counter != nil:
+				got[name] = m.GetCounter().GetValue()
+			case m.Gauge != nil:
+				got[name] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		want float64
+	}{
+		{"repodb_operations_total,create_repo,ok", 3},
+		{"repodb_operations_total,write_file,ok", 1},
+		{"repodb_operations_total,open_repo,error", 1},
+		{"repodb_written_bytes_total", 5},
+		{"repodb_repos," + db.Dir(), 1},
+		{"repodb_repos," + ns.Dir(), 2},
+	}
+	for _, tt := range tests {
+		if got[tt.name] != tt.want {
+			t.Errorf("WithMetrics() %s = %v, want %v", tt.name, got[tt.name], tt.want)
+		}
+	}
+
+	// registering twice reuses the existing collectors
+	buf := &bytes.Buffer{}
+	logger := repodb.WithLogger(slog.New(slog.NewTextHandler(buf, nil)))
+	repodb.NewDB(db.Dir(), repodb.WithMetrics(reg), logger)
+	if buf.Len() != 0 {
+		t.Errorf("WithMetrics() registered twice logged:\n%s", buf)
+	}
+
+	// other registration errors are logged
+	conflicting := prometheus.NewRegistry()
+	conflicting.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "repodb",
+		Name:      "read_bytes_total",
+		Help:      "Another counter of the same name.",
+	}))
+	repodb.NewDB(db.Dir(), repodb.WithMetrics(conflicting), logger)
+	if want := "msg=\"unable to register metrics\""; !strings.Contains(buf.String(), want) {
+		t.Errorf("WithMetrics() log missing %q:\n%s", want, buf)
+	}
+}
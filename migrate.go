@@ -0,0 +1,214 @@
+package repodb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// OpMigrate is the operation of commits made by RepoDB.Migrate
+const OpMigrate = "migrate"
+
+// SchemaVersionField is the meta-data field holding the schema version of records in
+// folders with migrations. Meta-data without it is version 0.
+const SchemaVersionField = "schema_version"
+
+// Migration rewrites the meta-data of a record from one schema version to the next
+type Migration func(m Meta) error
+
+// WithMigrations registers the migrations of the records in folder, the first migrating
+// version 0 to 1, and so on. The schema version of the folder is the number of
+// migrations. WriteMeta stamps records with it, LoadMeta migrates older meta-data before
+// decoding, and RepoDB.Migrate rewrites older meta-data files.
+func WithMigrations(folder string, migrations ...Migration) Option {
+	return func(db *RepoDB) {
+		if db.migrations == nil {
+			db.migrations = make(map[string][]Migration)
+		}
+		db.migrations[folder] = append(db.migrations[folder], migrations...)
+	}
+}
+
+// schemaVersion returns the schema version of the meta-data, 0 if missing or invalid
+func schemaVersion(m Meta) int {
+	switch v := m[SchemaVersionField].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	}
+	return 0
+}
+
+// migrateMeta migrates the meta-data to the current schema version of the folder,
+// returning false if it is already current
+func (db *RepoDB) migrateMeta(folder string, m Meta) (bool, error) {
+	migrations := db.migrations[folder]
+	v := schemaVersion(m)
+	if v >= len(migrations) {
+		return false, nil
+	}
+	for ; v < len(migrations); v++ {
+		if err := migrations[v](m); err != nil {
+			return false, fmt.Errorf("unable to migrate %s meta-data from version %d: %v", folder, v, err)
+		}
+	}
+	m[SchemaVersionField] = len(migrations)
+	return true, nil
+}
+
+// Migrate rewrites the meta-data of every record older than the schema version of its
+// folder, in a commit per repo, and returns the number migrated, see WithMigrations.
+// Repos of namespaces are migrated with the migrations of their namespace.
+func (db *RepoDB) Migrate(opts CommitOptions) (migrated int, err error) {
+	defer db.metrics.observe("migrate", time.Now(), &err)
+	if len(db.migrations) > 0 {
+		repos, err := db.ListReposPage(ListOptions{SkipMeta: true})
+		if err != nil {
+			return 0, err
+		}
+		for _, repo := range repos {
+			n, err := repo.migrate(opts)
+			migrated += n
+			if err != nil {
+				return migrated, fmt.Errorf("unable to migrate records in %s: %v", repo.Name, err)
+			}
+		}
+	}
+	err = db.eachNamespace(func(ns *RepoDB, dir string) error {
+		n, err := ns.Migrate(opts)
+		migrated += n
+		if err != nil {
+			return fmt.Errorf("unable to migrate namespace %s: %v", dir, err)
+		}
+		return nil
+	})
+	return migrated, err
+}
+
+// migrate rewrites the outdated meta-data of the repo
+func (repo *Repo) migrate(opts CommitOptions) (int, error) {
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return 0, err
+	}
+
+	folders := make([]string, 0, len(repo.DB.migrations))
+	for folder := range repo.DB.migrations {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+
+	migrated := 0
+	for _, folder := range folders {
+		names, _, err := repo.readFolder(folder)
+		if err != nil {
+			return 0, err
+		}
+		for _, name := range names {
+			ok, err := repo.migrateRecord(&recordRef{folder: folder, name: name})
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				migrated++
+			}
+		}
+	}
+	if migrated == 0 {
+		return 0, nil
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpMigrate,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("migrated %d records", migrated),
+	})
+	if err := repo.commit(OpMigrate, nil, opts); err != nil {
+		return 0, err
+	}
+	return migrated, nil
+}
+
+// migrateRecord rewrites the meta-data of the record if outdated, returning false if
+// it is current or missing. The caller must hold the repo lock.
+func (repo *Repo) migrateRecord(rec *recordRef) (bool, error) {
+	var b []byte
+	var err error
+	if repo.isBare() {
+		var f *object.File
+		if f, err = repo.bareFile(repo.DB.metaPath(rec)); err == nil {
+			var s string
+			s, err = f.Contents()
+			b = []byte(s)
+		}
+	} else {
+		b, err = repo.DB.readFile(path.Join(repo.Dir(), repo.DB.metaPath(rec)))
+	}
+	if os.IsNotExist(err) || err != nil && repo.isBare() {
+		// records without meta-data have nothing to migrate
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	store := repo.DB.metaStore(path.Join(repo.Dir(), rec.folder))
+	m := Meta{}
+	if err := store.codec.Unmarshal(b, &m); err != nil {
+		return false, fmt.Errorf("invalid meta-data %s: %v", path.Join(rec.folder, rec.name), err)
+	}
+	ok, err := repo.DB.migrateMeta(rec.folder, m)
+	if err != nil || !ok {
+		return false, err
+	}
+	if b, err = store.encode(m); err != nil {
+		return false, err
+	}
+	if repo.staged != nil {
+		_, err = repo.stageFile(repo.DB.metaPath(rec), bytes.NewReader(b), nil)
+		return true, err
+	}
+	return true, store.writeEncoded(rec.name, b)
+}
+
+// migrateEncoded migrates the encoded meta-data of the record to the current schema
+// version of its folder, returning b unchanged if current
+func (m *metaStore) migrateEncoded(rec Record, b []byte) ([]byte, error) {
+	if len(m.db.migrations[rec.Folder()]) == 0 {
+		return b, nil
+	}
+	meta := Meta{}
+	if err := m.codec.Unmarshal(b, &meta); err != nil {
+		return nil, err
+	}
+	ok, err := m.db.migrateMeta(rec.Folder(), meta)
+	if err != nil || !ok {
+		return b, err
+	}
+	return m.codec.Marshal(meta)
+}
+
+// stampVersion sets the schema version of the folder in the encoded meta-data of the
+// record, if the folder has migrations
+func (m *metaStore) stampVersion(rec Record, b []byte) ([]byte, error) {
+	migrations := m.db.migrations[rec.Folder()]
+	if len(migrations) == 0 {
+		return b, nil
+	}
+	meta := Meta{}
+	if err := m.codec.Unmarshal(b, &meta); err != nil {
+		return nil, err
+	}
+	meta[SchemaVersionField] = len(migrations)
+	return m.codec.Marshal(meta)
+}
@@ -0,0 +1,94 @@
+package repodb_test
+
+import (
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+// oldArticle is the version 0 schema of article
+type oldArticle struct {
+	File  string `json:"-"`
+	Title string `json:"title"`
+}
+
+func (a *oldArticle) FileName() string { return a.File }
+func (a *oldArticle) Folder() string   { return "articles" }
+
+// article renames the title of oldArticle to headline in version 1
+type article struct {
+	File     string `json:"-"`
+	Headline string `json:"headline"`
+	Version  int    `json:"schema_version"`
+}
+
+func (a *article) FileName() string { return a.File }
+func (a *article) Folder() string   { return "articles" }
+
+func renameTitle(m repodb.Meta) error {
+	m["headline"] = m["title"]
+	delete(m, "title")
+	return nil
+}
+
+func TestRepoDB_Migrate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestDB(t).Dir()
+			old := repodb.NewDB(dir, tt.opts...)
+			repo := &repodb.Repo{Name: "Articles", DB: old}
+			if err := old.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteMeta(&oldArticle{File: "a", Title: "Hello"}, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+
+			db := repodb.NewDB(dir, append(tt.opts, repodb.WithMigrations("articles", renameTitle))...)
+			repo, err := db.OpenRepo("Articles")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// loading migrates without rewriting the file
+			loaded := &article{File: "a"}
+			if err := repo.LoadMeta(loaded); err != nil || loaded.Headline != "Hello" || loaded.Version != 1 {
+				t.Errorf("Repo.LoadMeta() = %+v, error = %v", loaded, err)
+			}
+			if n, err := db.Migrate(repodb.DBRepoCommitOptions); err != nil || n != 1 {
+				t.Fatalf("RepoDB.Migrate() = %d, error = %v, want 1", n, err)
+			}
+			if n, err := db.Migrate(repodb.DBRepoCommitOptions); err != nil || n != 0 {
+				t.Errorf("RepoDB.Migrate() again = %d, error = %v, want 0", n, err)
+			}
+			// the migrated meta-data reads without migrations
+			repo, err = old.OpenRepo("Articles")
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded = &article{File: "a"}
+			if err := repo.LoadMeta(loaded); err != nil || loaded.Headline != "Hello" || loaded.Version != 1 {
+				t.Errorf("Repo.LoadMeta() after Migrate = %+v, error = %v", loaded, err)
+			}
+
+			// writes are stamped with the current version
+			repo, err = db.OpenRepo("Articles")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteMeta(&article{File: "b", Headline: "New"}, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			loaded = &article{File: "b"}
+			if err := repo.LoadMeta(loaded); err != nil || loaded.Headline != "New" || loaded.Version != 1 {
+				t.Errorf("Repo.LoadMeta() of written = %+v, error = %v", loaded, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidName is returned when a repo, folder or record name is rejected by the
+// DB NameValidator, the strict name policy, or a record folder is not a valid path.
+var ErrInvalidName = errors.New("invalid name")
+
+// NameError is returned for rejected names, errors.Is(err, ErrInvalidName) is true
+type NameError struct {
+	Kind NameKind
+	Name string
+	Err  error // reason the name was rejected
+}
+
+func (e *NameError) Error() string {
+	return fmt.Sprintf("%v: %s %q: %v", ErrInvalidName, e.Kind, e.Name, e.Err)
+}
+
+// Unwrap returns ErrInvalidName
+func (e *NameError) Unwrap() error {
+	return ErrInvalidName
+}
+
+// NameKind is the kind of name being validated by a NameValidator
+type NameKind int
+
+// name kinds
+const (
+	RepoName NameKind = iota + 1
+	FolderName
+	RecordName
+)
+
+// String returns a human readable name for the kind
+func (k NameKind) String() string {
+	switch k {
+	case RepoName:
+		return "repo"
+	case FolderName:
+		return "folder"
+	case RecordName:
+		return "record"
+	}
+	return fmt.Sprintf("NameKind(%d)", int(k))
+}
+
+// NameValidator returns an error if the name is not allowed, for enforcing naming
+// rules such as case, prefixes or length.
+type NameValidator func(kind NameKind, s string) error
+
+// WithNameValidator validates names of repos on CreateRepo, and folders and records on
+// WriteFile and WriteMeta. Folders and records used internally by repodb, such as legal
+// holds and stats, are not validated.
+func WithNameValidator(v NameValidator) Option {
+	return func(db *RepoDB) {
+		db.validator = v
+	}
+}
+
+// validateName runs the validator, if any, for the name
+func (db *RepoDB) validateName(kind NameKind, s string) error {
+	if db.validator == nil {
+		return nil
+	}
+	if err := db.validator(kind, s); err != nil {
+		return &NameError{Kind: kind, Name: s, Err: err}
+	}
+	return nil
+}
+
+// WithStrictNames rejects repo, folder and record names outside the name policy with
+// ErrInvalidName, rather than removing ".." and path separators from repo names, which
+// can make different names collide. Names are 1 to 255 bytes of letters, digits, spaces
+// and the characters . - _ + @ ( ), and may not start with a dot or space, or end with
+// one. Folders are slash separated paths of such names.
+func WithStrictNames() Option {
+	return func(db *RepoDB) {
+		db.strictNames = true
+	}
+}
+
+// cleanName returns the repo name to use, rejecting names outside the policy in strict
+// mode, or removing .. and path separators otherwise
+func (db *RepoDB) cleanName(kind NameKind, s string) (string, error) {
+	if !db.strictNames {
+		s = cleanPath(s)
+		if kind == RepoName && reservedDir(s) {
+			return "", &NameError{Kind: kind, Name: s, Err: errors.New("reserved by the database")}
+		}
+		return s, nil
+	}
+	if err := checkName(s); err != nil {
+		return "", &NameError{Kind: kind, Name: s, Err: err}
+	}
+	return s, nil
+}
+
+// checkName returns an error if the name is outside the strict name policy
+func checkName(s string) error {
+	switch {
+	case s == "":
+		return errors.New("empty name")
+	case len(s) > 255:
+		return errors.New("longer than 255 bytes")
+	case strings.HasPrefix(s, ".") || strings.HasPrefix(s, " "):
+		return errors.New("starts with a dot or space")
+	case strings.HasSuffix(s, ".") || strings.HasSuffix(s, " "):
+		return errors.New("ends with a dot or space")
+	}
+	for _, c := range s {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune(" .-_+@()", c) {
+			return fmt.Errorf("character %q not allowed", c)
+		}
+	}
+	return nil
+}
+
+// validateRecord validates the folder and file name of the record
+func (db *RepoDB) validateRecord(rec Record) error {
+	switch rec.(type) {
+	case *Repo, *Hold, *Stats, *Deletion, *Expiry:
+		return nil
+	}
+	if err := checkFolder(rec.Folder()); err != nil {
+		return err
+	}
+	if db.strictNames {
+		for _, p := range strings.Split(rec.Folder(), "/") {
+			if err := checkName(p); err != nil {
+				return &NameError{Kind: FolderName, Name: rec.Folder(), Err: err}
+			}
+		}
+		if err := checkName(rec.FileName()); err != nil {
+			return &NameError{Kind: RecordName, Name: rec.FileName(), Err: err}
+		}
+	}
+	if err := db.validateName(FolderName, rec.Folder()); err != nil {
+		return err
+	}
+	return db.validateName(RecordName, rec.FileName())
+}
@@ -0,0 +1,113 @@
+package repodb_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithNameValidator(t *testing.T) {
+	lowercase := func(kind repodb.NameKind, s string) error {
+		if s != strings.ToLower(s) {
+			return fmt.Errorf("must be lowercase")
+		}
+		return nil
+	}
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithNameValidator(lowercase))
+
+	if err := db.CreateRepo(&repodb.Repo{Name: "Upper", DB: db}); !errors.Is(err, repodb.ErrInvalidName) {
+		t.Errorf("RepoDB.CreateRepo() error = %v, want %v", err, repodb.ErrInvalidName)
+	}
+	repo := &repodb.Repo{Name: "lower", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		rec     repodb.Record
+		wantErr bool
+	}{
+		{"valid", &FileRecord{Name: "valid.txt"}, false},
+		{"invalid record", &FileRecord{Name: "Invalid.txt"}, true},
+		{"invalid folder", &upperFolderRecord{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.WriteFile(tt.rec, strings.NewReader(""), repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repo.WriteFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			err = repo.WriteMeta(tt.rec, repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repo.WriteMeta() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type upperFolderRecord struct{}
+
+func (r *upperFolderRecord) FileName() string { return "record.txt" }
+func (r *upperFolderRecord) Folder() string   { return "Files" }
+
+func TestWithStrictNames(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithStrictNames())
+
+	repos := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"a..b", false},
+		{"Case 2024 (draft)", false},
+		{"a/b", true},
+		{"..", true},
+		{".hidden", true},
+		{"trailing.", true},
+		{"tab\tname", true},
+		{"", true},
+	}
+	for _, tt := range repos {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.CreateRepo(&repodb.Repo{Name: tt.name, DB: db})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RepoDB.CreateRepo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var nameErr *repodb.NameError
+			if tt.wantErr && (!errors.Is(err, repodb.ErrInvalidName) || !errors.As(err, &nameErr) || nameErr.Kind != repodb.RepoName) {
+				t.Errorf("RepoDB.CreateRepo() error = %v, want %T of kind %v", err, nameErr, repodb.RepoName)
+			}
+		})
+	}
+	if _, err := db.OpenRepo("a..b"); err != nil {
+		t.Errorf("RepoDB.OpenRepo() error = %v, want name kept as is", err)
+	}
+
+	repo, err := db.OpenRepo("Case 2024 (draft)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []struct {
+		name    string
+		rec     repodb.Record
+		wantErr bool
+	}{
+		{"valid", &FileRecord{Name: "report v1.2.txt"}, false},
+		{"invalid record", &FileRecord{Name: "a:b.txt"}, true},
+		{"invalid folder", &nestedRecord{folder: "files/.cache", name: "a.txt"}, true},
+	}
+	for _, tt := range records {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.WriteFile(tt.rec, strings.NewReader(""), repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr || (tt.wantErr && !errors.Is(err, repodb.ErrInvalidName)) {
+				t.Errorf("Repo.WriteFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := repo.RenameRecord(&FileRecord{Name: "report v1.2.txt"}, "../escaped.txt", repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrInvalidName) {
+		t.Errorf("Repo.RenameRecord() error = %v, want %v", err, repodb.ErrInvalidName)
+	}
+}
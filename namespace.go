@@ -0,0 +1,85 @@
+package repodb
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// NamespacesDir is the directory of the database holding its namespaces
+const NamespacesDir = ".namespaces"
+
+// reservedDir reports whether the directory of the database is not a repo
+func reservedDir(name string) bool {
+	return name == LargeFilesDir || name == NamespacesDir
+}
+
+// Namespace returns the RepoDB of the named namespace, such as a tenant, in its own
+// directory under NamespacesDir. Repos of a namespace are isolated from those of the
+// database and of other namespaces, so names may repeat across namespaces. The
+// namespace is configured with the options of the database followed by opts, such as
+// WithQuota or WithMaxFileSize for the limits of each repo of a tenant; there is no
+// limit on the total size of a namespace. The options are applied when the namespace
+// is first returned, later calls return the same RepoDB. Namespaces are frozen with
+// their database, and operations on the whole database, such as Backup, Fsck, GCAll,
+// Stats, Migrate and Sync, include the repos of its namespaces.
+func (db *RepoDB) Namespace(name string, opts ...Option) (*RepoDB, error) {
+	name, err := db.cleanName(RepoName, name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("namespace name cannot be empty")
+	}
+
+	db.nsMu.Lock()
+	defer db.nsMu.Unlock()
+	if ns, ok := db.namespaces[name]; ok {
+		return ns, nil
+	}
+	dir := path.Join(db.dir, NamespacesDir, name)
+	if err := db.fs.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to make namespace %s: %v", name, err)
+	}
+	ns := NewDB(dir, append(append([]Option{}, db.opts...), opts...)...)
+	ns.parent = db
+	if db.namespaces == nil {
+		db.namespaces = make(map[string]*RepoDB)
+	}
+	db.namespaces[name] = ns
+	return ns, nil
+}
+
+// Namespaces returns the names of the namespaces of the database, ordered by name
+func (db *RepoDB) Namespaces() ([]string, error) {
+	fileInfos, err := db.readDir(path.Join(db.dir, NamespacesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to list namespaces in %s: %v", db.dir, err)
+	}
+	names := []string{}
+	for _, f := range fileInfos {
+		if f.IsDir() {
+			names = append(names, f.Name())
+		}
+	}
+	return names, nil
+}
+
+// eachNamespace calls fn with the RepoDB of every namespace of the database and its
+// directory relative to the database, for operations on the whole database
+func (db *RepoDB) eachNamespace(fn func(ns *RepoDB, dir string) error) error {
+	names, err := db.Namespaces()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		ns, err := db.Namespace(name)
+		if err != nil {
+			return err
+		}
+		if err := fn(ns, path.Join(NamespacesDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_Namespace(t *testing.T) {
+	db := newTestDB(t)
+	tenantA, err := db.Namespace("tenantA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenantB, err := db.Namespace("tenantB", repodb.WithMaxFileSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := db.Namespace("tenantA"); err != nil || again != tenantA {
+		t.Errorf("RepoDB.Namespace() = %p, %v, want the same namespace %p", again, err, tenantA)
+	}
+	if _, err := db.Namespace(repodb.NamespacesDir); err == nil {
+		t.Errorf("RepoDB.Namespace() %s expected error", repodb.NamespacesDir)
+	}
+
+	// the same repo name in each namespace
+	rec := &FileRecord{Name: "a.txt"}
+	for _, ns := range []*repodb.RepoDB{tenantA, tenantB} {
+		repo := &repodb.Repo{Name: "Project", DB: ns}
+		if err := ns.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteFile(rec, strings.NewReader(ns.Dir()), repodb.DBRepoCommitOptions); err != nil && ns == tenantA {
+			t.Fatal(err)
+		} else if ns == tenantB && !errors.Is(err, repodb.ErrFileTooLarge) {
+			t.Errorf("Repo.WriteFile() namespace limit error = %v, want %v", err, repodb.ErrFileTooLarge)
+		}
+	}
+
+	repo, err := tenantA.OpenRepo("Project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := repo.ReadFile(rec, buf); err != nil || buf.String() != tenantA.Dir() {
+		t.Errorf("Repo.ReadFile() = %q, %v, want %q", buf.String(), err, tenantA.Dir())
+	}
+	if _, err := db.OpenRepo("Project"); !errors.Is(err, repodb.ErrRepoNotExists) {
+		t.Errorf("RepoDB.OpenRepo() namespaced repo error = %v, want %v", err, repodb.ErrRepoNotExists)
+	}
+	if _, err := db.OpenRepo(repodb.NamespacesDir); !errors.Is(err, repodb.ErrRepoNotExists) {
+		t.Errorf("RepoDB.OpenRepo() %s error = %v, want %v", repodb.NamespacesDir, err, repodb.ErrRepoNotExists)
+	}
+
+	repos, err := db.ListReposPage(repodb.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 0 {
+		t.Errorf("RepoDB.ListReposPage() = %d repos, want namespaced repos excluded", len(repos))
+	}
+	if repos := tenantA.ListRepos(); len(repos) != 1 || repos[0].Name != "Project" {
+		t.Errorf("RepoDB.ListRepos() namespace = %v, want [Project]", repos)
+	}
+	names, err := db.Namespaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "tenantA,tenantB" {
+		t.Errorf("RepoDB.Namespaces() = %v, want [tenantA tenantB]", names)
+	}
+}
+
+func TestRepoDB_Namespace_wholeDB(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithCommitCoalescing(0, 0))
+	tenant, err := db.Namespace("tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &repodb.Repo{Name: "Project", DB: tenant}
+	if err := tenant.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	rec := &FileRecord{Name: "a.txt"}
+	if err := repo.WriteFile(rec, strings.NewReader("body"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !committed(t, repo, "files/a.txt") {
+		t.Error("RepoDB.Sync() did not commit the namespace repo")
+	}
+	name := repodb.NamespacesDir + "/tenant/Project"
+
+	if err := db.Freeze("maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(rec, strings.NewReader("frozen"), repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrDBFrozen) {
+		t.Errorf("Repo.WriteFile() frozen database error = %v, want %v", err, repodb.ErrDBFrozen)
+	}
+	if reason, ok := tenant.Frozen(); !ok || reason != "maintenance" {
+		t.Errorf("RepoDB.Frozen() namespace = %q, %v, want %q, true", reason, ok, "maintenance")
+	}
+	if err := db.Unfreeze(); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := db.Fsck()
+	if err != nil || !report.OK() || strings.Join(report.Repos, ",") != name {
+		t.Errorf("RepoDB.Fsck() = %+v, error = %v, want %s checked", report, err, name)
+	}
+	gcReports, err := db.GCAll(repodb.GCOptions{})
+	if err != nil || len(gcReports) != 1 || gcReports[0].Repo != name {
+		t.Errorf("RepoDB.GCAll() = %v, error = %v, want %s collected", gcReports, err, name)
+	}
+	stats, err := db.Stats()
+	if err != nil || stats.Repos != 1 || stats.Records != 1 || len(stats.Largest) != 1 || stats.Largest[0].Repo != name {
+		t.Errorf("RepoDB.Stats() = %+v, error = %v, want %s counted", stats, err, name)
+	}
+
+	backup := &bytes.Buffer{}
+	if err := db.Backup(backup); err != nil {
+		t.Fatal(err)
+	}
+	dir := newTestDB(t).Dir()
+	if err := repodb.Restore(dir, backup); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := repodb.NewDB(dir).Namespace("tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restoredRepo, err := restored.OpenRepo("Project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := restoredRepo.ReadFile(rec, buf); err != nil || buf.String() != "body" {
+		t.Errorf("Repo.ReadFile() restored = %q, %v, want %q", buf.String(), err, "body")
+	}
+}
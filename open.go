@@ -0,0 +1,154 @@
+package repodb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// OpenRecord opens the record file for reading and seeking, such as to serve HTTP range
+// requests. The content is decoded to a temporary file encrypted under a key kept only
+// in memory, so no plaintext reaches the disk, and the file is a snapshot of the record
+// that later writes to the repo neither wait for nor change. The temporary file is
+// removed once closed. Returns ErrRecordNotExists if there is no record file.
+func (repo *Repo) OpenRecord(rec Record) (_ io.ReadSeekCloser, err error) {
+	defer repo.DB.metrics.observe("open_record", time.Now(), &err)
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return nil, err
+	}
+	repo.DB.metrics.lock("repo", repo.RLocker())
+	defer repo.RUnlock()
+	return repo.openSpooled(rec)
+}
+
+// spoolChunkSize is the plaintext size of the chunks of spooled files
+const spoolChunkSize = 64 * 1024
+
+// openSpooled decodes the record file to a temporary file, sealing it in chunks with a
+// new key. The caller must hold the repo lock.
+func (repo *Repo) openSpooled(rec Record) (io.ReadSeekCloser, error) {
+	f, err := repo.openFile(rec)
+	if err != nil {
+		return nil, notExist(ErrRecordNotExists, rec, err)
+	}
+	defer f.Close()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	tmp, tmpName, err := repo.DB.tempFile(repo.spoolDir(), "repodb-open-")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledFile{file: tmp, aead: aead, chunk: -1, remove: func() { repo.DB.fs.Remove(tmpName) }}
+	err = spooled.spool(f)
+	repo.DB.metrics.read(spooled.size)
+	if err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// spooledFile is a temporary file of chunks sealed under a key used for no other file,
+// so the chunk index is the nonce. Chunks are opened as they are read.
+type spooledFile struct {
+	file   billy.File
+	aead   cipher.AEAD
+	size   int64  // plaintext size
+	off    int64  // plaintext offset of the next read
+	chunk  int64  // index of the chunk in buf, -1 if none
+	buf    []byte // plaintext of the chunk
+	remove func()
+	once   sync.Once
+}
+
+// nonce returns the nonce of chunk i
+func (f *spooledFile) nonce(i int64) []byte {
+	nonce := make([]byte, f.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], uint64(i))
+	return nonce
+}
+
+// spool seals the content of r to the file
+func (f *spooledFile) spool(r io.Reader) error {
+	buf := make([]byte, spoolChunkSize)
+	for i := int64(0); ; i++ {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if _, err := f.file.Write(f.aead.Seal(nil, f.nonce(i), buf[:n], nil)); err != nil {
+			return err
+		}
+		f.size += int64(n)
+		if n < spoolChunkSize {
+			return nil
+		}
+	}
+}
+
+func (f *spooledFile) Read(p []byte) (int, error) {
+	if f.off >= f.size {
+		return 0, io.EOF
+	}
+	i := f.off / spoolChunkSize
+	if i != f.chunk {
+		n := spoolChunkSize
+		if rest := f.size - i*spoolChunkSize; rest < int64(n) {
+			n = int(rest)
+		}
+		sealed := make([]byte, n+f.aead.Overhead())
+		if _, err := f.file.ReadAt(sealed, i*int64(spoolChunkSize+f.aead.Overhead())); err != nil && err != io.EOF {
+			return 0, err
+		}
+		plain, err := f.aead.Open(sealed[:0], f.nonce(i), sealed, nil)
+		if err != nil {
+			return 0, ErrDecrypt
+		}
+		f.chunk, f.buf = i, plain
+	}
+	n := copy(p, f.buf[f.off-i*spoolChunkSize:])
+	f.off += int64(n)
+	return n, nil
+}
+
+func (f *spooledFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += f.size
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("invalid seek to offset %d", offset)
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// Close closes and removes the file
+func (f *spooledFile) Close() error {
+	err := f.file.Close()
+	f.once.Do(f.remove)
+	return err
+}
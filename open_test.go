@@ -0,0 +1,125 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_OpenRecord(t *testing.T) {
+	text := strings.Repeat("0123456789", 100)
+	tests := []struct {
+		name        string
+		opts        []repodb.Option
+		compression string
+	}{
+		{"worktree", nil, ""},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}, ""},
+		{"compressed", nil, "gzip"},
+		{"encrypted", []repodb.Option{repodb.WithEncryption(repoKeys{"OpenRepo": bytes.Repeat([]byte{7}, 32)})}, ""},
+		{"blob store", []repodb.Option{repodb.WithLargeFiles(64)}, ""},
+		{"verified reads", []repodb.Option{repodb.WithVerifiedReads()}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), tt.opts...)
+			repo := &repodb.Repo{Name: "OpenRepo", DB: db, Compression: tt.compression}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			rec := &FileRecord{Name: "a.txt"}
+			if err := repo.WriteFile(rec, strings.NewReader(text), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := repo.OpenRecord(rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Seek(-5, io.SeekEnd); err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "56789" {
+				t.Errorf("Repo.OpenRecord() read from end = %q, want %q", b, "56789")
+			}
+			if _, err := f.Seek(10, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			b = make([]byte, 3)
+			if _, err := io.ReadFull(f, b); err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "012" {
+				t.Errorf("Repo.OpenRecord() read at 10 = %q, want %q", b, "012")
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// the repo is writable once closed
+			if err := repo.WriteFile(rec, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.OpenRecord(&FileRecord{Name: "missing.txt"}); !errors.Is(err, repodb.ErrRecordNotExists) {
+				t.Errorf("Repo.OpenRecord() missing error = %v, want %v", err, repodb.ErrRecordNotExists)
+			}
+		})
+	}
+}
+
+func TestRepo_OpenRecord_snapshot(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithEncryption(repoKeys{"OpenRepo": bytes.Repeat([]byte{7}, 32)}))
+	repo := &repodb.Repo{Name: "OpenRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	rec := &FileRecord{Name: "a.txt"}
+	text := strings.Repeat("plaintext ", 20*1024)
+	if err := repo.WriteFile(rec, strings.NewReader(text), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	f, err := repo.OpenRecord(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// no plaintext is spooled to disk
+	spooled, err := filepath.Glob(filepath.Join(repo.Dir(), ".git", "repodb-open-*"))
+	if err != nil || len(spooled) != 1 {
+		t.Fatalf("spooled files = %v, error = %v, want 1", spooled, err)
+	}
+	if b, err := ioutil.ReadFile(spooled[0]); err != nil || bytes.Contains(b, []byte("plaintext")) {
+		t.Errorf("spooled file has plaintext, error = %v", err)
+	}
+
+	// the repo is writable while the file is open, which keeps the content opened
+	if err := repo.WriteFile(rec, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	for _, off := range []int64{0, 64*1024 - 3, 150 * 1024, int64(len(text)) - 4} {
+		if _, err := f.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 4)
+		if _, err := io.ReadFull(f, b); err != nil || string(b) != text[off:off+4] {
+			t.Errorf("Repo.OpenRecord() read at %d = %q, error = %v, want %q", off, b, err, text[off:off+4])
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if spooled, _ := filepath.Glob(filepath.Join(repo.Dir(), ".git", "repodb-open-*")); len(spooled) != 0 {
+		t.Errorf("spooled files = %v after Close, want none", spooled)
+	}
+}
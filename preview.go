@@ -0,0 +1,56 @@
+package repodb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// Preview returns up to maxBytes from the start of the record file, and whether the
+// content is likely text rather than binary. Only the previewed bytes are read, so it
+// is suitable for rendering lists of large records.
+func (repo *Repo) Preview(rec Record, maxBytes int) (preview []byte, text bool, err error) {
+	defer repo.DB.metrics.observe("preview", time.Now(), &err)
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return nil, false, err
+	}
+	if maxBytes <= 0 {
+		return nil, false, fmt.Errorf("Preview requires positive maxBytes: %d", maxBytes)
+	}
+
+	repo.DB.metrics.lock("repo", repo.RLocker())
+	defer repo.RUnlock()
+
+	f, err := repo.openFile(rec)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	preview = make([]byte, maxBytes)
+	n, err := io.ReadFull(f, preview)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, false, err
+	}
+	repo.DB.metrics.read(int64(n))
+	preview = preview[:n]
+	return preview, isText(preview), nil
+}
+
+// isText reports if b is likely text: valid UTF-8 without NUL bytes. An incomplete
+// rune at the end of b is allowed, as b may be truncated.
+func isText(b []byte) bool {
+	if bytes.IndexByte(b, 0) >= 0 {
+		return false
+	}
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			return len(b)-i < utf8.UTFMax && !utf8.FullRune(b[i:])
+		}
+		i += size
+	}
+	return true
+}
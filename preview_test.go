@@ -0,0 +1,58 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_Preview(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "PreviewRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"text.txt":  "hello world",
+		"utf8.txt":  "héllo",
+		"bin.dat":   "\x89PNG\x00\x00\x01",
+		"latin.txt": "h\xe9llo",
+		"empty.txt": "",
+	}
+	for name, content := range files {
+		if err := repo.WriteFile(&FileRecord{Name: name}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		maxBytes int
+		want     string
+		wantText bool
+		wantErr  bool
+	}{
+		{"text", "text.txt", 5, "hello", true, false},
+		{"whole file", "text.txt", 100, "hello world", true, false},
+		{"truncated rune", "utf8.txt", 2, "h\xc3", true, false},
+		{"binary", "bin.dat", 4, "\x89PNG", false, false},
+		{"nul", "bin.dat", 10, "\x89PNG\x00\x00\x01", false, false},
+		{"invalid utf8", "latin.txt", 10, "h\xe9llo", false, false},
+		{"empty", "empty.txt", 10, "", true, false},
+		{"missing", "missing.txt", 10, "", false, true},
+		{"zero bytes", "text.txt", 0, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, text, err := repo.Preview(&FileRecord{Name: tt.file}, tt.maxBytes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repo.Preview() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want || text != tt.wantText {
+				t.Errorf("Repo.Preview() = %q, %v, want %q, %v", got, text, tt.want, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package repodb
+
+import (
+	"io"
+)
+
+// Progress is called with the total bytes transferred so far by WriteFileWithProgress
+// and ReadFileWithProgress. Returning an error aborts the transfer with it, such as to
+// enforce a timeout.
+type Progress func(transferred int64) error
+
+// WriteFileWithProgress is WriteFile calling progress as the content is read from r
+func (repo *Repo) WriteFileWithProgress(rec Record, r io.Reader, progress Progress, opts CommitOptions) error {
+	if progress == nil {
+		return repo.WriteFile(rec, r, opts)
+	}
+	pr := &progressReader{r: r, progress: progress}
+	err := repo.WriteFile(rec, pr, opts)
+	if pr.err != nil {
+		// return the progress error however it was wrapped on the way out
+		return pr.err
+	}
+	return err
+}
+
+// ReadFileWithProgress is ReadFile calling progress as the content is written to w
+func (repo *Repo) ReadFileWithProgress(rec Record, w io.Writer, progress Progress) (int64, error) {
+	if w == nil || progress == nil {
+		return repo.ReadFile(rec, w)
+	}
+	pw := &progressWriter{w: w, progress: progress}
+	n, err := repo.ReadFile(rec, pw)
+	if pw.err != nil {
+		return n, pw.err
+	}
+	return n, err
+}
+
+// progressReader reads from r, reporting the bytes read to progress
+type progressReader struct {
+	r        io.Reader
+	n        int64
+	progress Progress
+	err      error
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.n += int64(n)
+		if p.err = p.progress(p.n); p.err != nil {
+			return 0, p.err
+		}
+	}
+	return n, err
+}
+
+// progressWriter writes to w, reporting the bytes written to progress
+type progressWriter struct {
+	w        io.Writer
+	n        int64
+	progress Progress
+	err      error
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	n, err := p.w.Write(b)
+	p.n += int64(n)
+	if n > 0 {
+		if p.err = p.progress(p.n); p.err != nil {
+			return n, p.err
+		}
+	}
+	return n, err
+}
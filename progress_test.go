@@ -0,0 +1,75 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_Progress(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "ProgressRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Repeat("progress", 10000)
+	errAbort := errors.New("aborted")
+
+	tests := []struct {
+		name  string
+		abort int64 // abort once more than abort bytes are transferred, 0 never
+	}{
+		{"complete", 0},
+		{"aborted", 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var last int64
+			progress := func(n int64) error {
+				calls++
+				last = n
+				if tt.abort > 0 && n > tt.abort {
+					return errAbort
+				}
+				return nil
+			}
+			rec := &FileRecord{Name: tt.name + ".txt"}
+			err := repo.WriteFileWithProgress(rec, strings.NewReader(text), progress, repodb.DBRepoCommitOptions)
+			if tt.abort > 0 {
+				if !errors.Is(err, errAbort) {
+					t.Errorf("Repo.WriteFileWithProgress() error = %v, want %v", err, errAbort)
+				}
+				if repo.FileExists(rec) {
+					t.Errorf("Repo.WriteFileWithProgress() aborted write left %s", rec.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if calls == 0 || last != int64(len(text)) {
+				t.Errorf("Repo.WriteFileWithProgress() progress calls = %d, last = %d, want %d", calls, last, len(text))
+			}
+
+			calls, last = 0, 0
+			buf := &bytes.Buffer{}
+			if _, err := repo.ReadFileWithProgress(rec, buf, progress); err != nil {
+				t.Fatal(err)
+			}
+			if calls == 0 || last != int64(len(text)) || buf.String() != text {
+				t.Errorf("Repo.ReadFileWithProgress() progress calls = %d, last = %d, want %d", calls, last, len(text))
+			}
+		})
+	}
+
+	// reads are aborted too
+	rec := &FileRecord{Name: "complete.txt"}
+	abort := func(n int64) error { return errAbort }
+	if _, err := repo.ReadFileWithProgress(rec, &bytes.Buffer{}, abort); !errors.Is(err, errAbort) {
+		t.Errorf("Repo.ReadFileWithProgress() error = %v, want %v", err, errAbort)
+	}
+}
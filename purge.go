@@ -0,0 +1,94 @@
+package repodb
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// PurgeDeleted permanently removes the file, meta-data and attributes of every soft
+// deleted record whose deletion time is older than olderThan, in all folders, in a
+// single commit, so soft deleted records do not grow the repo forever. Records are soft
+// deleted if their meta-data has a true SoftDeleted field, and deleted at the time of
+// their DeletedOn field, matching the field names case-insensitively and ignoring
+// underscores, e.g. soft_deleted or deleted_on. Records without a deletion time, under
+// legal hold, or content still referenced are kept. Returns the number of records
+// purged, or that would be purged if opts.DryRun is set. History keeps the content.
+func (repo *Repo) PurgeDeleted(olderThan time.Duration, opts CommitOptions) (purged int, err error) {
+	defer repo.DB.metrics.observe("purge_deleted", time.Now(), &err)
+	if err := repo.authorize(ActionDelete, nil); err != nil {
+		return 0, err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	if replayed, err := repo.replayed(OpPurgeDeleted, nil, opts); err != nil || replayed {
+		return 0, err
+	}
+	defer repo.stageBare()()
+	cutoff := time.Now().Add(-olderThan)
+	expired := func(m Meta) bool {
+		deleted, _ := metaField(m, "softdeleted").(bool)
+		s, _ := metaField(m, "deletedon").(string)
+		deletedOn, err := time.Parse(time.RFC3339Nano, s)
+		return deleted && err == nil && !deletedOn.IsZero() && deletedOn.Before(cutoff)
+	}
+
+	folders, err := repo.recordFolders()
+	if err != nil {
+		return 0, err
+	}
+	var matched []*recordRef
+	for _, folder := range folders {
+		if !repo.isBare() && !repo.DB.fileExists(path.Join(repo.Dir(), folder, MetaDir)) {
+			continue
+		}
+		names, err := repo.queryRecords(folder, expired)
+		if err != nil {
+			return 0, err
+		}
+		for _, name := range names {
+			rec := &recordRef{folder: folder, name: name}
+			if repo.isHeld(rec) || repo.checkReferences(rec) != nil {
+				continue
+			}
+			matched = append(matched, rec)
+		}
+	}
+	if opts.DryRun || len(matched) == 0 {
+		return len(matched), nil
+	}
+	if err := repo.prepareWrite(); err != nil {
+		return 0, err
+	}
+	if err := repo.removeRecords(matched); err != nil {
+		return 0, err
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpPurgeDeleted,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("purged %d soft deleted records older than %s", len(matched), olderThan),
+	})
+	if err := repo.commit(OpPurgeDeleted, nil, opts); err != nil {
+		return 0, err
+	}
+	return len(matched), nil
+}
+
+// metaField returns the meta-data field whose normalized name is name, or nil
+func metaField(m Meta, name string) interface{} {
+	for k, v := range m {
+		if normalizedField(k) == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// normalizedField returns the field name lower cased without underscores, so
+// SoftDeleted, softdeleted and soft_deleted are the same field
+func normalizedField(k string) string {
+	return strings.ToLower(strings.ReplaceAll(k, "_", ""))
+}
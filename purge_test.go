@@ -0,0 +1,87 @@
+package repodb_test
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_PurgeDeleted(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "PurgeRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, fr := range []*FileRecord{
+		{Name: "expired.txt", SoftDeleted: true, DeletedOn: old},
+		{Name: "recent.txt", SoftDeleted: true, DeletedOn: time.Now()},
+		{Name: "undated.txt", SoftDeleted: true},
+		{Name: "live.txt", DeletedOn: old},
+		{Name: "held.txt", SoftDeleted: true, DeletedOn: old},
+	} {
+		if err := repo.WriteFile(fr, strings.NewReader(fr.Name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.LegalHold(&FileRecord{Name: "held.txt"}, "case"); err != nil {
+		t.Fatal(err)
+	}
+	// records of other types and nested folders are purged too
+	if err := repo.WriteMeta(&archivedRecord{Name: "a.txt", SoftDeleted: true, DeletedOn: old}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		dryRun     bool
+		want       int
+		wantCommit bool
+	}{
+		{"dry run", true, 2, false},
+		{"purged", false, 2, true},
+		{"none left", false, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := repo.Head()
+			opts := repodb.DBRepoCommitOptions
+			opts.DryRun = tt.dryRun
+			got, err := repo.PurgeDeleted(24*time.Hour, opts)
+			if err != nil || got != tt.want {
+				t.Fatalf("Repo.PurgeDeleted() = %d, error = %v, want %d", got, err, tt.want)
+			}
+			if after, _ := repo.Head(); (before != after) != tt.wantCommit {
+				t.Errorf("Repo.PurgeDeleted() committed = %v, want %v", before != after, tt.wantCommit)
+			}
+		})
+	}
+	remaining, err := repo.ListRecords("", true)
+	hold := &repodb.Hold{CaseID: "case", RecordFolder: "files", RecordName: "held.txt"}
+	want := []string{"files/held.txt", "files/live.txt", "files/recent.txt", "files/undated.txt", path.Join(hold.Folder(), hold.FileName())}
+	if err != nil || !reflect.DeepEqual(remaining, want) {
+		t.Errorf("Repo.ListRecords() after PurgeDeleted = %v, error = %v, want %v", remaining, err, want)
+	}
+	for _, name := range []string{"files/expired.txt", "files/meta-data/expired.txt.json", "archive/2020/meta-data/a.txt.json"} {
+		if committed(t, repo, name) {
+			t.Errorf("%s still committed after PurgeDeleted", name)
+		}
+	}
+}
+
+// archivedRecord is a record in a nested folder with snake case field names
+type archivedRecord struct {
+	Name        string    `json:"name"`
+	SoftDeleted bool      `json:"soft_deleted"`
+	DeletedOn   time.Time `json:"deleted_on"`
+}
+
+func (r *archivedRecord) FileName() string { return r.Name }
+func (r *archivedRecord) Folder() string   { return "archive/2020" }
@@ -0,0 +1,234 @@
+package repodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Meta is json meta-data decoded into a map, as evaluated by a Filter
+type Meta map[string]interface{}
+
+// String returns the field as a string, or "" if not a string
+func (m Meta) String(field string) string {
+	s, _ := m[field].(string)
+	return s
+}
+
+// Bool returns the field as a bool, or false if not a bool
+func (m Meta) Bool(field string) bool {
+	b, _ := m[field].(bool)
+	return b
+}
+
+// Number returns the field as a float64, or 0 if not a number
+func (m Meta) Number(field string) float64 {
+	f, _ := m[field].(float64)
+	return f
+}
+
+// Time returns the field parsed as an RFC 3339 time, or the zero time
+func (m Meta) Time(field string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, m.String(field))
+	return t
+}
+
+// Filter reports if meta-data matches a query
+type Filter func(m Meta) bool
+
+// Match returns a Filter matching meta-data where every field equals the value, after
+// both are converted to json. Field names are the json names of the meta-data, e.g.
+// Match(map[string]interface{}{"Protected": true}).
+func Match(fields map[string]interface{}) Filter {
+	want := Meta{}
+	b, err := json.Marshal(fields)
+	if err == nil {
+		err = json.Unmarshal(b, &want)
+	}
+	return func(m Meta) bool {
+		if err != nil {
+			return false
+		}
+		for k, v := range want {
+			if !reflect.DeepEqual(m[k], v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// QueryRepos returns the repos whose meta-data matches the filter. Only the meta-data
+// file of each repo, from HEAD in bare repos, is read to evaluate the filter.
+func (db *RepoDB) QueryRepos(f Filter) ([]*Repo, error) {
+	return db.queryRepos("", f)
+}
+
+// queryRepos returns the repos named with the prefix whose meta-data matches the filter
+func (db *RepoDB) queryRepos(prefix string, f Filter) ([]*Repo, error) {
+	fileInfos, err := db.readDir(db.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list repos in %s: %v", db.dir, err)
+	}
+	repos := []*Repo{}
+	for _, fi := range fileInfos {
+		if !fi.IsDir() || !strings.HasPrefix(fi.Name(), prefix) {
+			continue
+		}
+		m, err := db.repoMeta(fi.Name())
+		if err != nil || !f(m) {
+			continue
+		}
+		repo, err := db.OpenRepo(fi.Name())
+		switch {
+		case errors.Is(err, ErrRepoNotExists):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// repoMeta reads the meta-data file of the named repo, from HEAD if the repo is bare
+func (db *RepoDB) repoMeta(name string) (Meta, error) {
+	m := Meta{}
+	err := db.metaStore(path.Join(db.dir, name)).read(name, &m)
+	if !errors.Is(err, os.ErrNotExist) {
+		return m, err
+	}
+	repo, locateErr := db.locateRepo(name)
+	if locateErr != nil || !repo.isBare() {
+		return nil, err
+	}
+	rd, err := repo.openBare(db.metaPath(repo))
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	b, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	return m, db.MetaCodec().Unmarshal(b, &m)
+}
+
+// ListReposFilter selects the repos listed by ListReposFiltered, zero fields match
+// every repo
+type ListReposFilter struct {
+	NamePrefix   string            // repo names starting with the prefix
+	Labels       map[string]string // repos with every label set to its value
+	Protected    *bool             // repos protected, or not
+	SoftDeleted  *bool             // repos soft deleted, or not
+	UpdatedSince time.Time         // repos updated at or after the time, see Repo.UpdatedOn
+}
+
+// ListReposFiltered returns the repos matching the filter, ordered by name. Names are
+// matched before any meta-data is read, and labels and flags before a repo is opened.
+func (db *RepoDB) ListReposFiltered(f ListReposFilter) ([]*Repo, error) {
+	repos, err := db.queryRepos(f.NamePrefix, f.match)
+	if err != nil || f.UpdatedSince.IsZero() {
+		return repos, err
+	}
+	// UpdatedOn is only known once opened, it includes commits made since the
+	// meta-data was written
+	updated := repos[:0]
+	for _, repo := range repos {
+		if !repo.UpdatedOn.Before(f.UpdatedSince) {
+			updated = append(updated, repo)
+		}
+	}
+	return updated, nil
+}
+
+// match reports if the repo meta-data matches the labels and flags of the filter
+func (f ListReposFilter) match(m Meta) bool {
+	if f.Protected != nil && m.Bool("Protected") != *f.Protected {
+		return false
+	}
+	if f.SoftDeleted != nil && m.Bool("SoftDeleted") != *f.SoftDeleted {
+		return false
+	}
+	labels, _ := m["Labels"].(map[string]interface{})
+	for k, v := range f.Labels {
+		if s, ok := labels[k].(string); !ok || s != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ListReposByLabel returns the repos whose label key has the value, see Repo.Labels
+func (db *RepoDB) ListReposByLabel(key, value string) ([]*Repo, error) {
+	return db.ListReposFiltered(ListReposFilter{Labels: map[string]string{key: value}})
+}
+
+// QueryRecords returns the names of records in folder whose meta-data matches the
+// filter, sorted by name. Load the matching records with LoadMeta.
+func (repo *Repo) QueryRecords(folder string, f Filter) ([]string, error) {
+	if err := repo.authorizeFolder(ActionRead, folder); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.queryRecords(folder, f)
+}
+
+// queryRecords returns the matching record names, the caller must hold the repo lock
+func (repo *Repo) queryRecords(folder string, f Filter) ([]string, error) {
+	if err := checkFolder(folder); err != nil {
+		return nil, err
+	}
+	if repo.isBare() {
+		return repo.queryBare(folder, f)
+	}
+	dir := path.Join(repo.Dir(), folder, MetaDir)
+	fileInfos, err := repo.DB.readDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list meta-data in %s: %v", dir, err)
+	}
+	names := []string{}
+	for _, fi := range fileInfos {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), repo.DB.metaExt()) {
+			continue
+		}
+		b, err := repo.DB.readFile(path.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m := Meta{}
+		if err := repo.DB.MetaCodec().Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("invalid meta-data %s: %v", path.Join(folder, MetaDir, fi.Name()), err)
+		}
+		if f(m) {
+			names = append(names, strings.TrimSuffix(fi.Name(), repo.DB.metaExt()))
+		}
+	}
+	return names, nil
+}
+
+// queryBare is queryRecords for bare repos, reading the meta-data from HEAD
+func (repo *Repo) queryBare(folder string, f Filter) ([]string, error) {
+	names, records, err := repo.bareMeta(folder)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list meta-data in %s: %v", path.Join(repo.Name, folder), err)
+	}
+	matched := []string{}
+	for i, b := range records {
+		m := Meta{}
+		if err := repo.DB.MetaCodec().Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("invalid meta-data %s: %v", path.Join(folder, MetaDir, names[i]), err)
+		}
+		if f(m) {
+			matched = append(matched, names[i])
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,175 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_QueryRepos(t *testing.T) {
+	db := newTestDB(t)
+	for _, r := range []*repodb.Repo{
+		{Name: "a", DB: db, Protected: true},
+		{Name: "b", DB: db},
+		{Name: "c", DB: db, Protected: true, Description: "c"},
+	} {
+		if err := db.CreateRepo(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter repodb.Filter
+		want   []string
+	}{
+		{"protected", repodb.Match(map[string]interface{}{"Protected": true}), []string{"a", "c"}},
+		{"protected with description", repodb.Match(map[string]interface{}{"Protected": true, "Description": "c"}), []string{"c"}},
+		{"predicate", func(m repodb.Meta) bool { return !m.Bool("Protected") }, []string{"b"}},
+		{"none", repodb.Match(map[string]interface{}{"Name": "x"}), []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos, err := db.QueryRepos(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, r := range repos {
+				got = append(got, r.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("RepoDB.QueryRepos() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoDB_ListReposByLabel(t *testing.T) {
+	db := newTestDB(t)
+	for _, r := range []*repodb.Repo{
+		{Name: "a", DB: db, Labels: map[string]string{"env": "prod", "owner": "billing"}},
+		{Name: "b", DB: db, Labels: map[string]string{"env": "staging"}},
+		{Name: "c", DB: db},
+	} {
+		if err := db.CreateRepo(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// labels round-trip through the repo meta-data
+	repo, err := db.OpenRepo("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Labels["env"] != "staging" {
+		t.Fatalf("Repo.Labels = %v after OpenRepo, want env staging", repo.Labels)
+	}
+	repo.Labels["env"] = "prod"
+	if err := repo.WriteMeta(repo, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value string
+		want       []string
+	}{
+		{"env", "prod", []string{"a", "b"}},
+		{"owner", "billing", []string{"a"}},
+		{"env", "staging", []string{}},
+		{"missing", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			repos, err := db.ListReposByLabel(tt.key, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, r := range repos {
+				got = append(got, r.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("RepoDB.ListReposByLabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoDB_ListReposFiltered(t *testing.T) {
+	db := newTestDB(t)
+	for _, r := range []*repodb.Repo{
+		{Name: "prod-a", DB: db, Protected: true, Labels: map[string]string{"env": "prod", "team": "a"}},
+		{Name: "prod-b", DB: db, Labels: map[string]string{"env": "prod", "team": "b"}},
+		{Name: "dev-a", DB: db, SoftDeleted: true, Labels: map[string]string{"env": "dev", "team": "a"}},
+	} {
+		if err := db.CreateRepo(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		filter repodb.ListReposFilter
+		want   []string
+	}{
+		{"all", repodb.ListReposFilter{}, []string{"dev-a", "prod-a", "prod-b"}},
+		{"prefix", repodb.ListReposFilter{NamePrefix: "prod-"}, []string{"prod-a", "prod-b"}},
+		{"labels", repodb.ListReposFilter{Labels: map[string]string{"team": "a"}}, []string{"dev-a", "prod-a"}},
+		{"prefix and labels", repodb.ListReposFilter{NamePrefix: "prod", Labels: map[string]string{"team": "a"}}, []string{"prod-a"}},
+		{"protected", repodb.ListReposFilter{Protected: &yes}, []string{"prod-a"}},
+		{"not soft deleted", repodb.ListReposFilter{SoftDeleted: &no}, []string{"prod-a", "prod-b"}},
+		{"updated recently", repodb.ListReposFilter{UpdatedSince: time.Now().Add(-time.Hour)}, []string{"dev-a", "prod-a", "prod-b"}},
+		{"updated later", repodb.ListReposFilter{UpdatedSince: time.Now().Add(time.Hour)}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos, err := db.ListReposFiltered(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, r := range repos {
+				got = append(got, r.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("RepoDB.ListReposFiltered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepo_QueryRecords(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "QueryRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	cutoff := time.Now()
+	for i, name := range []string{"old.txt", "new.txt", "deleted.txt"} {
+		fr := &FileRecord{Name: name, UpdatedOn: cutoff.Add(time.Duration(i*2-1) * time.Hour), SoftDeleted: i == 2}
+		if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := repo.QueryRecords("files", func(m repodb.Meta) bool {
+		return m.Time("updated_on").After(cutoff)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "deleted.txt,new.txt" {
+		t.Errorf("Repo.QueryRecords() updated after = %v", got)
+	}
+
+	got, err = repo.QueryRecords("files", repodb.Match(map[string]interface{}{"softdeleted": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "deleted.txt" {
+		t.Errorf("Repo.QueryRecords() soft deleted = %v", got)
+	}
+}
@@ -0,0 +1,113 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a write would take a repo over its MaxSize, or the
+// MaxBytes of the DB Quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota limits the size of record files in each repo of the database, like Repo.MaxSize
+// which takes precedence for the repo. Meta-data and git history are not counted.
+type Quota struct {
+	MaxBytes int64
+	// WarnAt are fractions of the repo limit, e.g. 0.8, at which a HookQuotaWarning
+	// event is sent to the DB hooks when a write takes the repo usage across the
+	// threshold.
+	WarnAt []float64
+}
+
+// WithQuota sets the quota applied to each repo in the database
+func WithQuota(q Quota) Option {
+	return func(db *RepoDB) {
+		db.quota = &q
+	}
+}
+
+// maxSize returns the limit of the repo record files size, its MaxSize or else the
+// MaxBytes of the DB quota. Zero is unlimited.
+func (repo *Repo) maxSize() int64 {
+	if repo.MaxSize > 0 {
+		return repo.MaxSize
+	}
+	if q := repo.DB.quota; q != nil && q.MaxBytes > 0 {
+		return q.MaxBytes
+	}
+	return 0
+}
+
+// checkQuota sends quota warnings for thresholds crossed by a write that changed the
+// repo usage by delta bytes. The caller must hold the repo lock.
+func (repo *Repo) checkQuota(rec Record, delta int64) {
+	q, limit := repo.DB.quota, repo.maxSize()
+	if q == nil || limit <= 0 || len(q.WarnAt) == 0 || delta <= 0 {
+		return
+	}
+	usage, err := repo.usage()
+	if err != nil {
+		repo.DB.warn("unable to check quota", "repo", repo.Name, "err", err)
+		return
+	}
+
+	prev := usage.Size - delta
+	for _, t := range q.WarnAt {
+		threshold := int64(t * float64(limit))
+		if prev < threshold && usage.Size >= threshold {
+			repo.DB.runHooks(HookEvent{
+				Type:      HookQuotaWarning,
+				Repo:      repo.Name,
+				Record:    path.Join(rec.Folder(), rec.FileName()),
+				Message:   fmt.Sprintf("repo %s is using %d of %d bytes (%.0f%%)", repo.Name, usage.Size, limit, t*100),
+				Time:      time.Now(),
+				Usage:     usage.Size,
+				Limit:     limit,
+				Threshold: t,
+			})
+		}
+	}
+}
+
+// spoolQuota spools the content of a write replacing a record file of prev bytes,
+// returning ErrQuotaExceeded before the record file is touched if the write would take
+// the repo usage over its limit. The returned reader reads the spooled content, and done
+// removes it. The caller must hold the repo lock.
+func (repo *Repo) spoolQuota(rec Record, r io.Reader, prev int64) (spooled io.Reader, done func(), err error) {
+	usage, err := repo.usage()
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := repo.maxSize()
+	allowed := limit - (usage.Size - prev)
+	if allowed < 0 {
+		allowed = 0
+	}
+
+	tmp, tmpName, err := repo.DB.tempFile(repo.spoolDir(), "repodb-quota-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to spool %s: %v", rec.FileName(), err)
+	}
+	done = func() {
+		tmp.Close()
+		repo.DB.fs.Remove(tmpName)
+	}
+	// copy one byte past the allowance to learn if the content is larger
+	n, err := io.CopyN(tmp, r, allowed+1)
+	if err != nil && err != io.EOF {
+		done()
+		return nil, nil, fmt.Errorf("unable to spool %s: %v", rec.FileName(), err)
+	}
+	if n > allowed {
+		done()
+		return nil, nil, fmt.Errorf("%w: writing %s would take repo %s over %d bytes", ErrQuotaExceeded, path.Join(rec.Folder(), rec.FileName()), repo.Name, limit)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		done()
+		return nil, nil, err
+	}
+	return tmp, done, nil
+}
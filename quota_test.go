@@ -0,0 +1,155 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithQuota_warnings(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithQuota(repodb.Quota{
+		MaxBytes: 100,
+		WarnAt:   []float64{0.5, 0.8},
+	}))
+	var warnings []repodb.HookEvent
+	db.AddHook(func(ev repodb.HookEvent) {
+		if ev.Type == repodb.HookQuotaWarning {
+			warnings = append(warnings, ev)
+		}
+	})
+
+	repo := &repodb.Repo{Name: "QuotaRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		size int
+		want []float64 // thresholds warned by this write
+	}{
+		{"under", 40, nil},
+		{"cross 50%", 20, []float64{0.5}},
+		{"already warned", 5, nil},
+		{"cross 80%", 20, []float64{0.8}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings = nil
+			fr := &FileRecord{Name: tt.name}
+			if err := repo.WriteFile(fr, strings.NewReader(strings.Repeat("x", tt.size)), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) != len(tt.want) {
+				t.Fatalf("write %d got %d warnings, want %d", i, len(warnings), len(tt.want))
+			}
+			for j, w := range warnings {
+				if w.Threshold != tt.want[j] || w.Limit != 100 {
+					t.Errorf("warning = %+v, want threshold %v", w, tt.want[j])
+				}
+			}
+		})
+	}
+}
+
+func TestWithQuota_limit(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithQuota(repodb.Quota{
+		MaxBytes: 10,
+		WarnAt:   []float64{0.5},
+	}))
+	var warnings []repodb.HookEvent
+	db.AddHook(func(ev repodb.HookEvent) {
+		if ev.Type == repodb.HookQuotaWarning {
+			warnings = append(warnings, ev)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		maxSize   int64
+		size      int
+		wantErr   bool
+		wantLimit int64 // limit of the warning sent, zero for none
+	}{
+		{"db quota", 0, 5, false, 10},
+		{"over db quota", 0, 11, true, 0},
+		{"repo max size", 20, 11, false, 20},
+		{"over repo max size", 20, 21, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings = nil
+			repo := &repodb.Repo{Name: strings.ReplaceAll(tt.name, " ", "-"), DB: db, MaxSize: tt.maxSize}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader(strings.Repeat("x", tt.size)), repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, repodb.ErrQuotaExceeded)) {
+				t.Fatalf("Repo.WriteFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var limit int64
+			if len(warnings) > 0 {
+				limit = warnings[0].Limit
+			}
+			if len(warnings) > 1 || limit != tt.wantLimit {
+				t.Errorf("Repo.WriteFile() warnings = %+v, want limit %d", warnings, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestRepo_MaxSize(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "Limited", DB: db, MaxSize: 10}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := db.OpenRepo("Limited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.MaxSize != 10 {
+		t.Fatalf("Repo.MaxSize = %d after OpenRepo, want 10", repo.MaxSize)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"a.txt", "123456", false},
+		{"b.txt", "12345", true},
+		{"b.txt", "1234", false},
+		{"a.txt", "12345", false}, // replacing a.txt frees its size
+		{"a.txt", "1234567", true},
+	}
+	for _, tt := range tests {
+		rec := &FileRecord{Name: tt.name}
+		var before bytes.Buffer
+		if repo.FileExists(rec) {
+			if _, err := repo.ReadFile(rec, &before); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := repo.WriteFile(rec, strings.NewReader(tt.content), repodb.DBRepoCommitOptions)
+		if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, repodb.ErrQuotaExceeded)) {
+			t.Fatalf("Repo.WriteFile(%s, %q) error = %v, wantErr %v", tt.name, tt.content, err, tt.wantErr)
+		}
+		if !tt.wantErr {
+			continue
+		}
+		// a rejected write leaves the file unchanged
+		var after bytes.Buffer
+		if repo.FileExists(rec) {
+			if _, err := repo.ReadFile(rec, &after); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if after.String() != before.String() {
+			t.Errorf("Repo.ReadFile(%s) = %q after rejected write, want %q", tt.name, after.String(), before.String())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package repodb
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// RenameRecord moves the record file and its meta-data to newName in the same folder,
+// in a single commit so git rename detection follows the history of the record. The
+// meta-data is moved unchanged, the file of an encrypted repo is encrypted again for its
+// new name. Returns ErrRecordAlreadyExists if newName is taken,
+// ErrLegalHold if the record is under legal hold, or an error satisfying
+// errors.Is(err, os.ErrNotExist) if the record has neither file nor meta-data.
+func (repo *Repo) RenameRecord(rec Record, newName string, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("rename_record", time.Now(), &err)
+	if err := repo.authorize(ActionDelete, rec); err != nil {
+		return err
+	}
+	if !repo.DB.strictNames {
+		newName = cleanPath(newName)
+	}
+	renamed := &recordRef{folder: rec.Folder(), name: newName}
+	if renamed.name == "" {
+		return fmt.Errorf("RenameRecord new name cannot be empty")
+	}
+	if err := repo.DB.validateRecord(renamed); err != nil {
+		return err
+	}
+	if err := repo.authorize(ActionWrite, renamed); err != nil {
+		return err
+	}
+	if err := repo.DB.checkMutable(renamed); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	if replayed, err := repo.replayed(OpRenameRecord, renamed, opts); err != nil || replayed {
+		return err
+	}
+	if repo.isHeld(rec) {
+		return ErrLegalHold
+	}
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+
+	dir := path.Join(repo.Dir(), rec.Folder())
+	moves := [][2]string{
+		{path.Join(dir, rec.FileName()), path.Join(dir, renamed.name)},
+		{path.Join(repo.Dir(), repo.DB.metaPath(rec)), path.Join(repo.Dir(), repo.DB.metaPath(renamed))},
+		{path.Join(repo.Dir(), attrPath(rec)), path.Join(repo.Dir(), attrPath(renamed))},
+	}
+	found := false
+	for _, m := range moves {
+		if _, err := repo.DB.fs.Stat(m[1]); err == nil {
+			return fmt.Errorf("%w: %s", ErrRecordAlreadyExists, path.Join(renamed.folder, renamed.name))
+		}
+		if _, err := repo.DB.fs.Stat(m[0]); err == nil {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("unable to rename %s: %w", path.Join(rec.Folder(), rec.FileName()), os.ErrNotExist)
+	}
+
+	// encrypted files are bound to their path, the record file is encrypted again
+	key, err := repo.encKey()
+	if err != nil {
+		return err
+	}
+	move := func(i int, from, to string) error {
+		if i == 0 && key != nil {
+			return repo.reseal(key, from, to)
+		}
+		return repo.DB.fs.Rename(from, to)
+	}
+	for i, m := range moves {
+		err := move(i, m[0], m[1])
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			// move back the already renamed file, so the record is left unchanged
+			for j, done := range moves[:i] {
+				move(j, done[1], done[0])
+			}
+			return fmt.Errorf("unable to rename %s: %v", m[0], err)
+		}
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpRenameRecord,
+		Record:    path.Join(rec.Folder(), rec.FileName()),
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("renamed record %s to %s", path.Join(rec.Folder(), rec.FileName()), path.Join(renamed.folder, renamed.name)),
+	})
+
+	return repo.commit(OpRenameRecord, renamed, opts)
+}
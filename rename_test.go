@@ -0,0 +1,109 @@
+package repodb_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_RenameRecord(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "RenameRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "taken.txt", "held.txt"} {
+		fr := &FileRecord{Name: name}
+		if err := repo.WriteFile(fr, strings.NewReader(name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.WriteMeta(&FileRecord{Name: "meta.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.LegalHold(&FileRecord{Name: "held.txt"}, "case"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr bool
+		is      error
+	}{
+		{"renamed", "a.txt", "b.txt", false, nil},
+		{"meta only", "meta.txt", "meta2.txt", false, nil},
+		{"taken", "b.txt", "taken.txt", true, repodb.ErrRecordAlreadyExists},
+		{"held", "held.txt", "c.txt", true, repodb.ErrLegalHold},
+		{"missing", "a.txt", "c.txt", true, os.ErrNotExist},
+		{"empty", "b.txt", "", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.RenameRecord(&FileRecord{Name: tt.from}, tt.to, repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("Repo.RenameRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := os.Stat(path.Join(repo.Dir(), "files", repodb.MetaDir, "meta2.txt.json")); err != nil {
+		t.Errorf("renamed meta-data missing, err = %v", err)
+	}
+	b := &strings.Builder{}
+	if _, err := repo.ReadFile(&FileRecord{Name: "b.txt"}, b); err != nil || b.String() != "a.txt" {
+		t.Errorf("Repo.ReadFile() renamed record = %q, error = %v", b.String(), err)
+	}
+	fr := &FileRecord{Name: "b.txt"}
+	if err := repo.LoadMeta(fr); err != nil {
+		t.Errorf("Repo.LoadMeta() renamed record error = %v", err)
+	}
+
+	// the file and meta-data moved in one commit, unchanged so git detects the rename
+	r, err := git.PlainOpen(repo.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	activity, err := repo.RecentActivity(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity[1].Operation != repodb.OpRenameRecord || activity[1].Record != "files/b.txt" {
+		t.Fatalf("Repo.RecentActivity() = %v, want rename of files/b.txt", activity[1])
+	}
+	head, err := r.CommitObject(plumbing.NewHash(activity[1].Hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := head.Parent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for from, to := range map[string]string{"files/a.txt": "files/b.txt", "files/meta-data/a.txt.json": "files/meta-data/b.txt.json"} {
+		before, err := parent.File(from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := head.File(to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if before.Hash != after.Hash {
+			t.Errorf("renamed %s hash = %s, want %s", to, after.Hash, before.Hash)
+		}
+		if _, err := head.File(from); err == nil {
+			t.Errorf("%s still committed after rename", from)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package repodb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// RepairMeta regenerates the meta-data of record files whose meta-data is missing or is
+// not valid json, such as after hand edits or partial corruption, and commits the
+// reconstruction in one commit. newRecord returns the record to write as the meta-data
+// of the file, or nil to skip it. Records implementing TimestampedRecord are stamped
+// with the times of the first and last commits of the file, or its modification time
+// if it is not committed. Folders not expected to pair files and meta-data, see Fsck,
+// are skipped. Returns the repaired meta-data paths, relative to the repo.
+func (repo *Repo) RepairMeta(newRecord func(folder, name string) Record, opts CommitOptions) (repaired []string, err error) {
+	defer repo.DB.metrics.observe("repair_meta", time.Now(), &err)
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return nil, err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return nil, err
+	}
+
+	folders, err := repo.recordFolders()
+	if err != nil {
+		return nil, err
+	}
+	repaired = []string{}
+	for _, folder := range folders {
+		if !repo.isPairedFolder(folder) {
+			continue
+		}
+		names, _, err := repo.readFolder(folder)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			ref := &recordRef{folder: folder, name: name}
+			if !repo.FileExists(ref) || repo.validMeta(ref) {
+				continue
+			}
+			rec := newRecord(folder, name)
+			if rec == nil {
+				continue
+			}
+			if rec.Folder() != folder || rec.FileName() != name {
+				return nil, fmt.Errorf("RepairMeta record %s is not %s", path.Join(rec.Folder(), rec.FileName()), path.Join(folder, name))
+			}
+			if err := repo.rebuildMeta(rec); err != nil {
+				return nil, fmt.Errorf("unable to repair meta-data of %s: %v", path.Join(folder, name), err)
+			}
+			repaired = append(repaired, repo.DB.metaPath(rec))
+		}
+	}
+	if len(repaired) == 0 {
+		return repaired, nil
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpRepairMeta,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("repaired meta-data of %d records", len(repaired)),
+	})
+	if err := repo.commit(OpRepairMeta, nil, opts); err != nil {
+		return nil, err
+	}
+	return repaired, nil
+}
+
+// validMeta reports if the record has meta-data that is valid json, the caller must
+// hold the repo lock
+func (repo *Repo) validMeta(rec Record) bool {
+	var b []byte
+	var err error
+	if repo.isBare() {
+		var f *object.File
+		if f, err = repo.bareFile(repo.DB.metaPath(rec)); err == nil {
+			var s string
+			s, err = f.Contents()
+			b = []byte(s)
+		}
+	} else {
+		b, err = repo.DB.readFile(path.Join(repo.Dir(), repo.DB.metaPath(rec)))
+	}
+	if err != nil {
+		return false
+	}
+	var v interface{}
+	return repo.DB.MetaCodec().Unmarshal(b, &v) == nil
+}
+
+// rebuildMeta stamps the record with the commit times of its file and writes it as
+// meta-data, the caller must hold the repo lock
+func (repo *Repo) rebuildMeta(rec Record) error {
+	if tr, ok := rec.(TimestampedRecord); ok {
+		first, last, err := repo.fileTimes(rec)
+		if err != nil {
+			return err
+		}
+		tr.SetTimestamps(first, last)
+	}
+	if err := repo.DB.validateRecord(rec); err != nil {
+		return err
+	}
+	if repo.staged != nil {
+		b, err := repo.DB.metaStore(rec.Folder()).encode(rec)
+		if err != nil {
+			return err
+		}
+		if _, err := repo.stageFile(repo.DB.metaPath(rec), bytes.NewReader(b), nil); err != nil {
+			return err
+		}
+	} else if err := repo.DB.metaStore(path.Join(repo.Dir(), rec.Folder())).write(rec.FileName(), rec); err != nil {
+		return err
+	}
+	return repo.writeExpiry(rec)
+}
+
+// fileTimes returns the times of the first and last commits of the record file on the
+// first parent history of HEAD, or its modification time if it is not committed. The
+// caller must hold the repo lock.
+func (repo *Repo) fileTimes(rec Record) (first, last time.Time, err error) {
+	if last, err = repo.committedAt(rec); err != nil {
+		return first, last, err
+	}
+	if last.IsZero() {
+		fi, err := repo.DB.fs.Stat(path.Join(repo.Dir(), rec.Folder(), rec.FileName()))
+		if err != nil {
+			return first, last, err
+		}
+		return fi.ModTime(), fi.ModTime(), nil
+	}
+
+	r, err := repo.git()
+	if err != nil {
+		return first, last, err
+	}
+	head, err := repo.head()
+	if err != nil {
+		return first, last, err
+	}
+	c, err := r.CommitObject(head)
+	if err != nil {
+		return first, last, err
+	}
+	name := path.Join(rec.Folder(), rec.FileName())
+	first = c.Committer.When
+	for c.NumParents() > 0 {
+		if c, err = c.Parent(0); err != nil {
+			return first, last, err
+		}
+		if _, err := c.File(name); errors.Is(err, object.ErrFileNotFound) {
+			break
+		} else if err != nil {
+			return first, last, err
+		}
+		first = c.Committer.When
+	}
+	return first, last, nil
+}
@@ -0,0 +1,100 @@
+package repodb_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_RepairMeta(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []repodb.Option
+	}{
+		{"worktree", nil},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), tt.opts...)
+			repo := &repodb.Repo{Name: "Damaged", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			write := func(name, content string) {
+				t.Helper()
+				if err := repo.WriteFile(&stampedRecord{Name: name}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write("a.txt", "one")
+			first, err := repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			write("a.txt", "two")
+			last, err := repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			write("skipped.txt", "skipped")
+			intact := &stampedRecord{Name: "intact.txt"}
+			write(intact.Name, "intact")
+			if err := repo.WriteMeta(intact, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if tt.name == "worktree" {
+				// a hand edit corrupting meta-data
+				corrupt := &stampedRecord{Name: "corrupt.txt"}
+				write(corrupt.Name, "corrupt")
+				if err := repo.WriteMeta(corrupt, repodb.DBRepoCommitOptions); err != nil {
+					t.Fatal(err)
+				}
+				filename := filepath.Join(repo.Dir(), "stamped", repodb.MetaDir, "corrupt.txt.json")
+				if err := ioutil.WriteFile(filename, []byte(`{"Name": "corr`), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			repaired, err := repo.RepairMeta(func(folder, name string) repodb.Record {
+				if name == "skipped.txt" {
+					return nil
+				}
+				return &stampedRecord{Name: name}
+			}, repodb.DBRepoCommitOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"stamped/meta-data/a.txt.json"}
+			if tt.name == "worktree" {
+				want = append(want, "stamped/meta-data/corrupt.txt.json")
+			}
+			if !reflect.DeepEqual(repaired, want) {
+				t.Errorf("Repo.RepairMeta() = %v, want %v", repaired, want)
+			}
+
+			got := &stampedRecord{Name: "a.txt"}
+			if err := repo.LoadMeta(got); err != nil {
+				t.Fatal(err)
+			}
+			created, err := repo.Commit(first.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			updated, err := repo.Commit(last.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.CreatedOn.Equal(created.Committer.When) || !got.UpdatedOn.Equal(updated.Committer.When) {
+				t.Errorf("repaired timestamps = %v, %v, want %v and %v", got.CreatedOn, got.UpdatedOn, created.Committer.When, updated.Committer.When)
+			}
+			if repaired, err := repo.RepairMeta(func(folder, name string) repodb.Record { return nil }, repodb.DBRepoCommitOptions); err != nil || len(repaired) != 0 {
+				t.Errorf("Repo.RepairMeta() again = %v, %v, want none", repaired, err)
+			}
+		})
+	}
+}
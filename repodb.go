@@ -1,20 +1,27 @@
 package repodb
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"log/slog"
 	"os"
 	"path"
+	"runtime"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
+	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-
-	scribble "github.com/nanobox-io/golang-scribble"
+	"golang.org/x/crypto/openpgp"
 )
 
 // package variables
@@ -32,102 +39,372 @@ var (
 
 // errors
 var (
-	ErrRepoAlreadyExists = errors.New("repo already exists")
-	ErrRepoNotExists     = errors.New("repo does not exist")
+	ErrRepoAlreadyExists   = errors.New("repo already exists")
+	ErrRepoNotExists       = errors.New("repo does not exist")
+	ErrRecordAlreadyExists = errors.New("record already exists")
+
+	// ErrRecordNotExists and ErrMetaNotExists are returned when the record file or
+	// meta-data does not exist, they are also os.ErrNotExist
+	ErrRecordNotExists error = &errorKind{"record does not exist", os.ErrNotExist}
+	ErrMetaNotExists   error = &errorKind{"meta-data does not exist", os.ErrNotExist}
 )
 
+// errorKind is a sentinel error which is also a more general error, for errors.Is
+type errorKind struct {
+	msg    string
+	parent error
+}
+
+func (e *errorKind) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the more general error
+func (e *errorKind) Unwrap() error {
+	return e.parent
+}
+
+// notExist returns the sentinel kind for the record if err is a not exist error,
+// otherwise err
+func notExist(kind error, rec Record, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", kind, path.Join(rec.Folder(), rec.FileName()))
+	}
+	return err
+}
+
 // CommitOptions is a wrapper struct arount git.CommitOptions with the addition of the message
 type CommitOptions struct {
 	Msg  string
 	Opts git.CommitOptions
+
+	// Actor optionally is the user making the change, such as the user of a request. It
+	// is the commit author unless Opts has one, while the committer remains the identity
+	// of the DB, so the history records who made each change.
+	Actor *Signature
+
+	// IdempotencyKey optionally identifies the operation, such as a client request id.
+	// An operation is skipped if the same operation on the same record was committed
+	// with the key within the last IdempotencyWindow commits, so retries are safe.
+	IdempotencyKey string
+
+	// DryRun makes bulk operations, such as DeleteWhere, report what they would change
+	// without changing the repo.
+	DryRun bool
+
+	// expectedHead, if set, is the commit HEAD must still be at for the commit to be
+	// made, as checked under the commit lock by compare-and-swap writes
+	expectedHead *plumbing.Hash
 }
 
-// Record is a RepoDB record interface.
+// Record is a RepoDB record interface. Folder is a slash separated path in the repo,
+// such as "files" or "invoices/2024/06", and the record meta-data is kept in the
+// meta-data directory of its last folder.
 type Record interface {
 	FileName() string
 	Folder() string
 }
 
+// recordRef is a Record identified only by its folder and name
+type recordRef struct {
+	folder, name string
+}
+
+func (r *recordRef) FileName() string { return r.name }
+func (r *recordRef) Folder() string   { return r.folder }
+
 // RepoDB is a file based database of git repositories.
 type RepoDB struct {
 	sync.RWMutex
 	dir string
+
+	hookMu sync.RWMutex
+	hooks  []Hook
+
+	metrics        *metrics
+	logger         *slog.Logger
+	keys           KeyProvider
+	quota          *Quota
+	maxFileSize    int64
+	codecs         map[string]Codec
+	headKeyRing    string
+	signKey        *openpgp.Entity
+	validator      NameValidator
+	validators     map[string][]Validator
+	migrations     map[string][]Migration
+	strictNames    bool
+	retryPolicy    *RetryPolicy
+	gitCache       *gitCache
+	fs             billy.Filesystem
+	search         *searchIndex
+	contentFolders map[string]bool
+	blobs          BlobStore
+	blobThreshold  int64
+	bare           bool
+	compactMeta    bool
+	meta           MetaCodec
+	strict         bool
+	deferCommits   bool
+	coalescer      *coalescer
+	commitTemplate *template.Template
+	verifyReads    bool
+	osIdentity     bool
+	authorizer     Authorizer
+	opts           []Option // of NewDB, for namespaces
+
+	nsMu       sync.Mutex
+	namespaces map[string]*RepoDB
+	parent     *RepoDB // of a namespace
+	author     *object.Signature
+	committer  *object.Signature
+
+	commitMu    sync.Mutex
+	commitLocks map[string]*sync.Mutex // by repo directory
+
+	repairMu       sync.Mutex
+	repairing      map[string]bool
+	repairCtx      context.Context
+	repairInterval time.Duration
 }
 
+// Option configures optional RepoDB behavior in NewDB
+type Option func(db *RepoDB)
+
 // NewDB returns a new RepoDB in the named directory
-func NewDB(dir string) *RepoDB {
+func NewDB(dir string, opts ...Option) *RepoDB {
 
 	db := &RepoDB{
-		dir: dir,
+		gitCache: newGitCache(DefaultRepoCacheSize),
+		fs:       osFS,
+	}
+	for _, opt := range opts {
+		opt(db)
 	}
+	db.dir = db.cleanDir(dir)
+	db.opts = opts
+	db.metrics.init(db)
 	return db
 }
 
+// WithCompactMeta writes meta-data as compact rather than indented json, reducing
+// meta-data size for repos with many records. Either format is read.
+func WithCompactMeta() Option {
+	return func(db *RepoDB) {
+		db.compactMeta = true
+	}
+}
+
+// Dir is the database directory
+func (db *RepoDB) Dir() string {
+	return db.dir
+}
+
 // CreateRepo will create a git repository as a subdirectory dir in the RepoDB.
-// Will return ErrRepoAlreadyExists if it already exists
-func (db *RepoDB) CreateRepo(repo *Repo) error {
-	db.Lock()
+// CreatedOn is set to the current time unless given. Will return ErrRepoAlreadyExists
+// if it already exists
+func (db *RepoDB) CreateRepo(repo *Repo) (err error) {
+	defer db.metrics.observe("create_repo", time.Now(), &err)
+	return db.createRepo(repo, nil)
+}
+
+// createRepo creates the repo, committing its meta-data with the records, if any
+func (db *RepoDB) createRepo(repo *Repo, records []RecordData) (err error) {
+	db.metrics.lock("db", db)
 	defer db.Unlock()
 
 	if repo == nil {
 		return fmt.Errorf("CreateRepo repo pointer cannot be nil")
 	}
+	if err := db.checkFrozen(); err != nil {
+		return err
+	}
 
 	// don't allow .. or Pathseparator in repo Name
-	repo.Name = cleanPath(repo.Name)
+	if repo.Name, err = db.cleanName(RepoName, repo.Name); err != nil {
+		return err
+	}
 	if repo.Name == "" {
 		return fmt.Errorf("CreateRepo repo name cannot be empty")
 	}
+	if err := db.validateName(RepoName, repo.Name); err != nil {
+		return err
+	}
 
-	_, err := git.PlainInit(repo.Dir(), false)
+	r, err := db.initGit(repo.Dir(), db.bare)
 	switch {
 	case errors.Is(err, git.ErrRepositoryAlreadyExists):
 		return ErrRepoAlreadyExists
 	case err != nil:
 		return fmt.Errorf("unable to create repo at %s: %v", repo.Dir(), err)
 	}
-	err = repo.WriteMeta(repo, DBRepoCommitOptions)
-	if err != nil {
+	db.gitCache.put(repo.Dir(), r)
+	if len(records) == 0 {
+		return repo.WriteMeta(repo, CommitOptions{Msg: DBRepoName})
+	}
+	if err := repo.WriteFiles(records, CommitOptions{Msg: DBRepoName}); err != nil {
+		db.gitCache.remove(repo.Dir())
+		if rmErr := db.removeAll(repo.Dir()); rmErr != nil {
+			db.warn("unable to remove repo after failed create", "repo", repo.Name, "err", rmErr)
+		}
 		return err
 	}
-
 	return nil
 }
 
-// OpenRepo will open the git repository at the specified directory Will return ErrRepoNotExists if no valid repository is found
-func (db *RepoDB) OpenRepo(name string) (*Repo, error) {
-	db.Lock()
+// AdoptRepo registers an existing git repository in the named subdirectory of the
+// RepoDB, such as one copied in manually, by writing its meta-data. From then on it is
+// managed like a created repo. Any uncommitted changes in the worktree are committed
+// with the meta-data. Will return ErrRepoNotExists if there is no git repository, or
+// ErrRepoAlreadyExists if it already has meta-data.
+func (db *RepoDB) AdoptRepo(name string) (_ *Repo, err error) {
+	defer db.metrics.observe("adopt_repo", time.Now(), &err)
+	db.metrics.lock("db", db)
 	defer db.Unlock()
 
 	// don't allow .. or Pathseparator in repo Name
-	name = cleanPath(name)
+	name, err = db.cleanName(RepoName, name)
+	if err != nil {
+		return nil, err
+	}
+	repo := &Repo{Name: name, DB: db}
+	if repo.Name == "" {
+		return nil, fmt.Errorf("AdoptRepo repo name cannot be empty")
+	}
+	if err := db.checkFrozen(); err != nil {
+		return nil, err
+	}
+	if err := db.validateName(RepoName, repo.Name); err != nil {
+		return nil, err
+	}
+
+	r, err := db.openGit(repo.Dir())
+	switch {
+	case errors.Is(err, git.ErrRepositoryNotExists):
+		return nil, ErrRepoNotExists
+	case err != nil:
+		return nil, fmt.Errorf("unable to adopt repo at %s: %v", repo.Dir(), err)
+	}
+	if _, err := r.Worktree(); err != nil {
+		return nil, fmt.Errorf("unable to adopt repo at %s: %v", repo.Dir(), err)
+	}
+	if _, err := db.fs.Stat(db.metaStore(repo.Dir()).filename(repo.FileName())); err == nil {
+		return nil, ErrRepoAlreadyExists
+	}
+
+	db.gitCache.put(repo.Dir(), r)
+	repo.CreatedOn = time.Now()
+	repo.UpdatedOn = repo.CreatedOn
+	if err := repo.WriteMeta(repo, CommitOptions{Msg: DBRepoName}); err != nil {
+		return nil, err
+	}
+	db.debug("adopted repo", "repo", repo.Name)
+	return repo, nil
+}
+
+// OpenRepo will open the git repository at the specified directory Will return ErrRepoNotExists if no valid repository is found.
+// If the DB was created WithVerifiedHead, the HEAD commit signature is verified before opening.
+// UpdatedOn is the time of the last commit, as every commit to a Repo updates it.
+func (db *RepoDB) OpenRepo(name string) (_ *Repo, err error) {
+	defer db.metrics.observe("open_repo", time.Now(), &err)
+	return db.openRepo(name, true)
+}
+
+// openRepo opens the repo, loading its meta-data if loadMeta is true
+func (db *RepoDB) openRepo(name string, loadMeta bool) (*Repo, error) {
+	repo, err := db.locateRepo(name)
+	if err != nil || !loadMeta {
+		return repo, err
+	}
+	if err := repo.loadRepoMeta(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// locateRepo opens the git repository of the named repo, holding the DB lock, without
+// loading its meta-data
+func (db *RepoDB) locateRepo(name string) (*Repo, error) {
+	db.metrics.lock("db", db)
+	defer db.Unlock()
+
+	if reservedDir(name) {
+		return nil, ErrRepoNotExists
+	}
+	// don't allow .. or Pathseparator in repo Name
+	name, err := db.cleanName(RepoName, name)
+	if err != nil {
+		return nil, err
+	}
 
 	repo := &Repo{
 		Name: name,
 		DB:   db,
 	}
 
-	_, err := git.PlainOpen(repo.Dir())
+	// always check the repo exists on disk, it may have been removed outside the DB
+	r, err := db.openGit(repo.Dir())
 	switch {
 	case errors.Is(err, git.ErrRepositoryNotExists):
+		db.gitCache.remove(repo.Dir())
 		return nil, ErrRepoNotExists
 	case err != nil:
 		return nil, fmt.Errorf("unable to open repo at %s: %v", repo.Dir(), err)
 	}
-
-	err = repo.LoadMeta(repo)
-	if err != nil {
-		return nil, err
+	if db.gitCache.get(repo.Dir()) == nil {
+		db.gitCache.put(repo.Dir(), r)
 	}
 
+	if db.headKeyRing != "" {
+		if err := repo.verifyHead(db.headKeyRing); err != nil {
+			return nil, fmt.Errorf("unable to open repo at %s: %w", repo.Dir(), err)
+		}
+	}
 	return repo, nil
 }
 
+// loadRepoMeta loads the meta-data of the located repo, which needs no DB lock
+func (repo *Repo) loadRepoMeta() error {
+	if err := repo.LoadMeta(repo); err != nil {
+		return err
+	}
+	// UpdatedOn is not written by every commit, the HEAD commit time is used when later
+	if t, err := repo.headTime(); err == nil && t.After(repo.UpdatedOn) {
+		repo.UpdatedOn = t
+	}
+	return nil
+}
+
+// loadReposMeta loads the meta-data of the repos concurrently, one worker per CPU, and
+// returns the error of each repo
+func (db *RepoDB) loadReposMeta(repos []*Repo) []error {
+	errs := make([]error, len(repos))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU() && i < len(repos); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				errs[j] = repos[j].loadRepoMeta()
+			}
+		}()
+	}
+	for j := range repos {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
+
 // RemoveRepo will remove the current database and all files/sub-directories. Use with caution.
-func (db *RepoDB) RemoveRepo(dir string) error {
+func (db *RepoDB) RemoveRepo(dir string) (err error) {
+	defer db.metrics.observe("remove_repo", time.Now(), &err)
 
 	// don't allow .. or Pathseparator in repo Name
-	dir = cleanPath(dir)
+	if dir, err = db.cleanName(RepoName, dir); err != nil {
+		return err
+	}
 
 	repo, err := db.OpenRepo(dir)
 
@@ -137,29 +414,176 @@ func (db *RepoDB) RemoveRepo(dir string) error {
 	case err != nil:
 		return fmt.Errorf("unable to remove repo %s: %v", dir, err)
 	}
-	db.Lock()
+	if holds, err := repo.Holds(); err != nil || len(holds) > 0 {
+		return ErrLegalHold
+	}
+	if err := db.checkFrozen(); err != nil {
+		return err
+	}
+	db.metrics.lock("db", db)
 	defer db.Unlock()
-	return os.RemoveAll(repo.Dir())
+	db.gitCache.remove(repo.Dir())
+	db.search.remove(repo.Name)
+	return db.removeAll(repo.Dir())
 }
 
-// ListRepos returns a list of repositories in the database
+// RenameRepo renames the repo directory and its meta-data, committing the change so the
+// repo history is kept. Will return ErrRepoNotExists if there is no repo oldName, or
+// ErrRepoAlreadyExists if newName is taken. Other Repo values opened as oldName must
+// not be used after the rename.
+func (db *RepoDB) RenameRepo(oldName, newName string) (_ *Repo, err error) {
+	defer db.metrics.observe("rename_repo", time.Now(), &err)
+
+	repo, err := db.OpenRepo(oldName)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.prepareWrite(); err != nil {
+		return nil, err
+	}
+	// don't allow .. or Pathseparator in repo Name
+	if newName, err = db.cleanName(RepoName, newName); err != nil {
+		return nil, err
+	}
+	if newName == "" {
+		return nil, fmt.Errorf("RenameRepo repo name cannot be empty")
+	}
+	if err := db.validateName(RepoName, newName); err != nil {
+		return nil, err
+	}
+	oldName, oldDir := repo.Name, repo.Dir()
+	oldMeta, err := db.readFile(db.metaStore(oldDir).filename(oldName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to rename repo %s: %v", oldName, err)
+	}
+
+	// the target is checked and the directory moved under the DB lock, so the name
+	// cannot be taken by a concurrent CreateRepo
+	newDir := path.Join(db.dir, newName)
+	db.metrics.lock("db", db)
+	if _, err := db.fs.Stat(newDir); err == nil {
+		db.Unlock()
+		return nil, ErrRepoAlreadyExists
+	}
+	err = db.fs.Rename(oldDir, newDir)
+	db.gitCache.remove(oldDir)
+	db.search.remove(oldName)
+	db.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("unable to rename repo %s: %v", oldName, err)
+	}
+
+	repo.Lock()
+	defer repo.Unlock()
+	formerNames := repo.FormerNames
+	repo.Name = newName
+	repo.FormerNames = appendName(formerNames, oldName)
+	repo.UpdatedOn = time.Now()
+	store := db.metaStore(newDir)
+	err = store.write(repo.FileName(), repo)
+	if err == nil {
+		err = db.fs.Remove(store.filename(oldName))
+	}
+	if err == nil {
+		err = repo.commit(OpRenameRepo, repo, CommitOptions{
+			Msg: fmt.Sprintf("renamed repo %s to %s", oldName, newName),
+		})
+	}
+	if err != nil {
+		// move the repo back, the next commit stages the restored meta-data
+		db.fs.Remove(store.filename(newName))
+		db.writeFile(store.filename(oldName), oldMeta, 0644)
+		db.metrics.lock("db", db)
+		db.gitCache.remove(newDir)
+		if renameErr := db.fs.Rename(newDir, oldDir); renameErr != nil {
+			db.warn("unable to restore renamed repo", "repo", oldName, "err", renameErr)
+		}
+		db.Unlock()
+		repo.Name, repo.FormerNames = oldName, formerNames
+		return nil, fmt.Errorf("unable to rename repo %s: %v", oldName, err)
+	}
+	db.debug("renamed repo", "repo", oldName, "name", newName)
+	return repo, nil
+}
+
+// ListRepos returns a list of repositories in the database. Repos that cannot be opened
+// are skipped, use ListReposPage for errors and paging.
 func (db *RepoDB) ListRepos() []*Repo {
 	repos := []*Repo{}
 
-	fileInfos, err := ioutil.ReadDir(db.dir)
+	fileInfos, err := db.readDir(db.dir)
 	if err != nil {
+		db.warn("unable to list repos", "dir", db.dir, "err", err)
 		return repos
 	}
+	located := []*Repo{}
 	for _, f := range fileInfos {
-		repo, err := db.OpenRepo(f.Name())
+		repo, err := db.locateRepo(f.Name())
 		if err != nil {
+			db.warn("skipping repo in list", "repo", f.Name(), "err", err)
 			continue
 		}
-		repos = append(repos, repo)
+		located = append(located, repo)
+	}
+	for i, err := range db.loadReposMeta(located) {
+		if err != nil {
+			db.warn("skipping repo in list", "repo", located[i].Name, "err", err)
+			continue
+		}
+		repos = append(repos, located[i])
 	}
 	return repos
 }
 
+// ListOptions configures ListReposPage
+type ListOptions struct {
+	Offset   int  // number of repos to skip
+	Limit    int  // maximum number of repos to return, zero for no limit
+	SkipMeta bool // only Name and DB are set on returned repos
+}
+
+// ListReposPage returns a page of repositories in the database ordered by name.
+// Directories that are not repos are skipped, any other error opening a repo is
+// returned. The meta-data of the repos in the page is loaded concurrently.
+func (db *RepoDB) ListReposPage(opts ListOptions) ([]*Repo, error) {
+	repos := []*Repo{}
+
+	fileInfos, err := db.readDir(db.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list repos in %s: %v", db.dir, err)
+	}
+	skipped := 0
+	for _, f := range fileInfos {
+		if opts.Limit > 0 && len(repos) >= opts.Limit {
+			break
+		}
+		if !f.IsDir() {
+			continue
+		}
+		// opening without meta-data is enough to check the directory is a repo
+		repo, err := db.locateRepo(f.Name())
+		switch {
+		case errors.Is(err, ErrRepoNotExists):
+			continue
+		case err != nil:
+			return nil, err
+		case skipped < opts.Offset:
+			skipped++
+			continue
+		}
+		repos = append(repos, repo)
+	}
+	if opts.SkipMeta {
+		return repos, nil
+	}
+	for _, err := range db.loadReposMeta(repos) {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return repos, nil
+}
+
 // Repo is a git repository as a subdirectory under the RepoDB
 type Repo struct {
 	sync.RWMutex
@@ -171,12 +595,38 @@ type Repo struct {
 	CreatedOn   time.Time
 	UpdatedOn   time.Time
 	DeletedOn   time.Time
+	ForkedFrom  string `json:",omitempty"` // source repo name, if created by ForkRepo
+	// MaxSize limits the size in bytes of the record files of a worktree repo, as
+	// counted by Stats. WriteFile returns ErrQuotaExceeded for a write that would
+	// exceed it. Zero defaults to the MaxBytes of WithQuota, if any.
+	MaxSize int64 `json:",omitempty"`
+	// Compression names the codec compressing record files written to the repo, such
+	// as "gzip" or a codec registered by WithCodecs. The codec of each file is stored
+	// with it, so files are read back however the repo is later configured.
+	Compression string `json:",omitempty"`
+	// Labels are application defined tags of the repo, such as owner, environment or
+	// project, see ListReposByLabel
+	Labels map[string]string `json:",omitempty"`
+	// FormerNames are the names the repo had before RenameRepo, and those of the repos
+	// it was forked from. Encrypted files and blob store content written under them stay
+	// readable, files and pointers of any other repo are rejected.
+	FormerNames []string `json:",omitempty"`
+
+	// changes of the current operation on a bare repo, committed by commit
+	staged map[string]*treeEntry
+	// subject of OpenRepoAs, checked by the Authorizer
+	subject string
+	// meta-data not yet loaded, see OpenRepoLazy
+	lazy bool
 }
 
 // Protect the repo from deletion
 func (repo *Repo) Protect() error {
+	if err := repo.EnsureMeta(); err != nil {
+		return err
+	}
 	repo.Protected = true
-	err := repo.WriteMeta(repo, DBRepoCommitOptions)
+	err := repo.WriteMeta(repo, CommitOptions{Msg: DBRepoName})
 	if err != nil {
 		return fmt.Errorf("unable to protect repo %s", repo.Dir())
 	}
@@ -200,147 +650,612 @@ func (repo *Repo) Folder() string {
 
 // CommitAll does a git add . && git commit -m "msg"
 func (repo *Repo) CommitAll(opts CommitOptions) error {
-	r, err := git.PlainOpen(repo.Dir())
-	if err != nil {
+	if err := repo.authorize(ActionWrite, nil); err != nil {
 		return err
 	}
-	w, err := r.Worktree()
-	if err != nil {
+	repo.RLock()
+	replayed, err := repo.replayed(OpCommit, nil, opts)
+	repo.RUnlock()
+	if err != nil || replayed {
+		return err
+	}
+	return repo.commit(OpCommit, nil, opts)
+}
+
+// commit does a git add . && git commit -m "msg", and runs the DB hooks for the
+// commit. The operation and record, which may be nil, are recorded as trailers in
+// the commit message for RecentActivity.
+func (repo *Repo) commit(op string, rec Record, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("commit", time.Now(), &err)
+	if err := repo.DB.checkFrozen(); err != nil {
+		return err
+	}
+	if err := repo.checkSparse(); err != nil {
 		return err
 	}
-	_, err = w.Add(".")
+	if repo.deferred(op, rec) {
+		if c := repo.DB.coalescer; c == nil || !c.add(repo, opts) {
+			return nil
+		}
+		// maxWrites are pending, commit them now with this one
+		return repo.commitPending(CommitOptions{Opts: opts.Opts}, "")
+	}
+	err = repo.DB.retry("commit", func() error {
+		return repo.commitOnce(op, rec, opts)
+	})
+	if err != nil && repo.DB.repairing != nil && repo.staged == nil && !errors.Is(err, ErrConflict) {
+		return repo.degrade(op, rec, opts, err)
+	}
+	return err
+}
+
+// commitOnce makes a single commit attempt
+func (repo *Repo) commitOnce(op string, rec Record, opts CommitOptions) error {
+	r, err := repo.git()
 	if err != nil {
 		return err
 	}
-	s, _ := w.Status()
-	if s.IsClean() {
-		return nil
+	lock := repo.DB.commitLock(repo.Dir())
+	lock.Lock()
+	defer lock.Unlock()
+	if err := repo.checkHead(opts.expectedHead); err != nil {
+		return err
+	}
+	if err := repo.DB.defaultSignatures(&opts); err != nil {
+		return err
+	}
+	// signed commits are always made by go-git, the system git cannot use the key
+	systemGit := repo.staged == nil && opts.Opts.SignKey == nil && repo.systemGit(r)
+	var w *git.Worktree
+	switch {
+	case repo.staged != nil:
+		// bare repo, changes are already staged as objects
+	case systemGit:
+		if clean, err := repo.execStage(); err != nil || clean {
+			return err
+		}
+	default:
+		w, err = r.Worktree()
+		if err != nil {
+			return err
+		}
+		_, err = w.Add(".")
+		if err != nil {
+			return err
+		}
+		s, _ := w.Status()
+		if s.IsClean() {
+			return nil
+		}
+		// w.Add does not stage removed files, the commit stages them
+		opts.Opts.All = true
 	}
 
 	// remove leading and trailing spaces from message
 	opts.Msg = strings.TrimSpace(opts.Msg)
 
-	// sets When for both Author and Commiter to time.Now
+	// sets When for both Author and Commiter to time.Now, on copies as the signatures
+	// may be shared by concurrent commits, e.g. DBRepoCommitOptions
 	if opts.Opts.Author != nil {
-		opts.Opts.Author.When = time.Now()
+		author := *opts.Opts.Author
+		author.When = time.Now()
+		opts.Opts.Author = &author
 	}
 	if opts.Opts.Committer != nil {
-		opts.Opts.Committer.When = time.Now()
+		committer := *opts.Opts.Committer
+		committer.When = time.Now()
+		opts.Opts.Committer = &committer
 	}
 
-	_, err = w.Commit(opts.Msg, &opts.Opts)
+	ev := HookEvent{
+		Type:      HookCommit,
+		Operation: op,
+		Repo:      repo.Name,
+		Message:   opts.Msg,
+	}
+	if rec != nil {
+		ev.Record = path.Join(rec.Folder(), rec.FileName())
+	}
+
+	msg := opts.Msg + "\n\n" + trailers(op, ev.Record, strings.TrimSpace(opts.IdempotencyKey))
+	var hash plumbing.Hash
+	switch {
+	case repo.staged != nil:
+		if hash, err = repo.commitStaged(r, msg, &opts.Opts); err == nil && hash.IsZero() {
+			return nil
+		}
+	case systemGit:
+		hash, err = repo.execCommit(msg, &opts.Opts)
+	default:
+		hash, err = w.Commit(msg, &opts.Opts)
+	}
 	if err != nil {
 		return err
 	}
+	ev.Hash = hash.String()
+	ev.Time = time.Now()
+	// the commit time, as read back from HEAD by OpenRepo
+	repo.UpdatedOn = ev.Time
+	if opts.Opts.Committer != nil {
+		repo.UpdatedOn = opts.Opts.Committer.When
+	}
+	if err := repo.heartbeat(r, hash); err != nil {
+		return err
+	}
+
+	repo.DB.debug("committed", "repo", repo.Name, "commit", ev.Hash, "record", ev.Record)
+	repo.DB.runHooks(ev)
 	return nil
 }
 
-// FileExists checks if file exists
+// FileExists checks if file exists, false if the subject of the repo may not read it
 func (repo *Repo) FileExists(rec Record) bool {
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return false
+	}
+	return repo.recordExists(rec)
+}
+
+// recordExists reports whether the record file exists
+func (repo *Repo) recordExists(rec Record) bool {
+	if repo.isBare() {
+		_, err := repo.bareFile(path.Join(rec.Folder(), rec.FileName()))
+		return err == nil
+	}
 	filename := path.Join(repo.Dir(), rec.Folder(), rec.FileName())
-	_, err := os.Stat(filename)
+	_, err := repo.DB.fs.Stat(filename)
 	return !os.IsNotExist(err)
 }
 
 // WriteFile will create and write the record to file. If the directory does not exist, it will be created.
-func (repo *Repo) WriteFile(rec Record, r io.Reader, opts CommitOptions) error {
+// Computed fields of the record, see FieldSize, are set once committed.
+func (repo *Repo) WriteFile(rec Record, r io.Reader, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("write_file", time.Now(), &err)
+	return repo.writeRecord(rec, r, nil, opts)
+}
+
+// writeRecord locks the repo and writes the record file with attr for WriteFile and
+// WriteFileAttr
+func (repo *Repo) writeRecord(rec Record, r io.Reader, attr *FileAttr, opts CommitOptions) error {
 	// reader is nil, return
 	if r == nil {
 		return fmt.Errorf("WriteFile requires non-nil reader: %s", rec.FileName())
 	}
-	repo.Lock()
+	if err := repo.DB.validateRecord(rec); err != nil {
+		return err
+	}
+	if err := repo.DB.checkMutable(rec); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
 	defer repo.Unlock()
 
+	if replayed, err := repo.replayed(OpWriteFile, rec, opts); err != nil || replayed {
+		return err
+	}
+	defer repo.stageBare()()
+	return repo.writeFile(OpWriteFile, rec, r, attr, opts)
+}
+
+// writeFile writes the record file and its attributes, if any, and commits them as the
+// operation, the caller must hold the repo lock
+func (repo *Repo) writeFile(op string, rec Record, r io.Reader, attr *FileAttr, opts CommitOptions) error {
+	if err := repo.authorize(ActionWrite, rec); err != nil {
+		return err
+	}
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+	w, err := repo.writeContent(rec, r, attr)
+	if err != nil {
+		return err
+	}
+	if err := repo.commitWritten(op, rec, w, opts); err != nil {
+		return err
+	}
+	w.checkQuota(repo, rec)
+	return nil
+}
+
+// writtenFile is a record file written by writeContent, to be committed
+type writtenFile struct {
+	n        int64 // bytes written, or the content size if offloaded to the blob store
+	sum      *contentSum
+	fields   []computedField
+	prevSize int64 // size of the worktree file replaced
+}
+
+// setComputed sets the computed fields of the record once the written file is committed
+func (w *writtenFile) setComputed(repo *Repo, rec Record) error {
+	if len(w.fields) == 0 {
+		return nil
+	}
+	values := computedValues{size: w.sum.n, sha256: w.sum.String()}
+	var err error
+	if values.committedAt, err = repo.committedAt(rec); err != nil {
+		return err
+	}
+	setComputed(rec, w.fields, values)
+	return nil
+}
+
+// checkQuota checks the repo quota once the written file is committed
+func (w *writtenFile) checkQuota(repo *Repo, rec Record) {
+	if repo.staged != nil {
+		return
+	}
+	if fi, err := repo.DB.fs.Stat(path.Join(repo.Dir(), rec.Folder(), rec.FileName())); err == nil {
+		repo.checkQuota(rec, fi.Size()-w.prevSize)
+	}
+}
+
+// writeContent writes the record file and its attributes to be committed, the caller
+// must hold the repo lock
+func (repo *Repo) writeContent(rec Record, r io.Reader, attr *FileAttr) (w *writtenFile, err error) {
+	if repo.DB.maxFileSize > 0 {
+		lr := &sizeLimitReader{r: r, limit: repo.DB.maxFileSize, rec: rec}
+		r = lr
+		// return the typed error however it was wrapped on the way out
+		defer func() {
+			if lr.err != nil {
+				err = lr.err
+			}
+		}()
+	}
+	r, done, err := repo.validateFile(rec, r)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	fields, err := computedFields(rec)
+	if err != nil {
+		return nil, err
+	}
+	key, err := repo.encKey()
+	if err != nil {
+		return nil, err
+	}
+	sum := newContentSum()
+	r = io.TeeReader(r, sum)
+	codec, err := repo.DB.codec(repo.Compression)
+	if err != nil {
+		return nil, err
+	}
+	if codec != nil {
+		cr := newCompressReader(r, codec)
+		defer cr.Close()
+		r = cr
+	}
+	size := int64(-1)
+	if repo.DB.blobs != nil {
+		if r, size, err = repo.offload(r, key); err != nil {
+			return nil, err
+		}
+	}
+
 	dir := path.Join(repo.Dir(), rec.Folder())
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("unable to make directory %s: %v", dir, err)
+	filename := path.Join(dir, rec.FileName())
+	var prevSize int64
+	if repo.staged == nil {
+		if fi, err := repo.DB.fs.Stat(filename); err == nil {
+			prevSize = fi.Size()
+		}
+		if repo.maxSize() > 0 {
+			spooled, done, err := repo.spoolQuota(rec, r, prevSize)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+			r = spooled
+		}
+	}
+
+	// the attributes are written with the checksum once the content is copied
+	written := func(n int64) (*writtenFile, error) {
+		ra := &recordAttr{FileAttr: attr, Blob: size >= 0, Size: sum.n, SHA256: sum.String()}
+		if codec != nil {
+			ra.Codec = codec.Name()
+		}
+		if err := repo.writeAttr(rec, ra); err != nil {
+			return nil, fmt.Errorf("unable to write attributes of %s: %v", rec.FileName(), err)
+		}
+		if size >= 0 {
+			n = size
+		}
+		return &writtenFile{n: n, sum: sum, fields: fields, prevSize: prevSize}, nil
 	}
 
-	f, err := os.Create(path.Join(dir, rec.FileName()))
+	if repo.staged != nil {
+		n, err := repo.stageFile(path.Join(rec.Folder(), rec.FileName()), r, key)
+		repo.DB.metrics.written(n)
+		if err != nil {
+			return nil, err
+		}
+		return written(n)
+	}
+
+	if err := repo.DB.fs.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to make directory %s: %v", dir, err)
+	}
+
+	var f billy.File
+	err = repo.DB.retry("create_file", func() (err error) {
+		f, err = repo.DB.fs.Create(filename)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("unable to create file %s: %v", rec.FileName(), err)
+		return nil, fmt.Errorf("unable to create file %s: %v", rec.FileName(), err)
 	}
+	defer f.Close()
 
-	// Copy from the record reader to the created file.
-	n, err := io.Copy(f, r)
+	// Copy from the record reader to the created file, encrypting if enabled for the repo.
+	var fw io.Writer = f
+	var ew *encryptWriter
+	if key != nil {
+		if ew, err = newEncryptWriter(f, key, repo.fileAAD(path.Join(rec.Folder(), rec.FileName()))[0]); err != nil {
+			return nil, fmt.Errorf("unable to encrypt %s: %v", rec.FileName(), err)
+		}
+		fw = ew
+	}
+	n, err := io.Copy(fw, r)
+	if err == nil && ew != nil {
+		err = ew.Close()
+	}
+	repo.DB.metrics.written(n)
 	if err != nil {
-		return fmt.Errorf("copy failed to %s: %v", rec.FileName(), err)
+		// leave no partial file behind
+		f.Close()
+		if rerr := repo.restore(path.Join(rec.Folder(), rec.FileName()), attrPath(rec)); rerr != nil {
+			repo.DB.warn("unable to restore file after failed write", "repo", repo.Name, "record", rec.FileName(), "err", rerr)
+		}
+		return nil, fmt.Errorf("copy failed to %s: %v", rec.FileName(), err)
 	}
 
-	// appends to git commit message separated by blank line. If original message is blank it will remove leading blank spaces
-	opts.Msg = fmt.Sprintf("%s\n\nwrote %d bytes to file %s", opts.Msg, n, path.Join(rec.Folder(), rec.FileName()))
+	return written(n)
+}
 
-	return repo.CommitAll(opts)
+// commitWritten commits the written record file, and sets its computed fields from the
+// checksum of the contents
+func (repo *Repo) commitWritten(op string, rec Record, w *writtenFile, opts CommitOptions) error {
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: op,
+		Record:    path.Join(rec.Folder(), rec.FileName()),
+		Bytes:     w.n,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("wrote %d bytes to file %s", w.n, path.Join(rec.Folder(), rec.FileName())),
+	})
+
+	if err := repo.commit(op, rec, opts); err != nil {
+		return err
+	}
+	return w.setComputed(repo, rec)
 }
 
 // ReadFile will read the file to the provided io.Writer
 func (repo *Repo) ReadFile(rec Record, w io.Writer) (written int64, err error) {
+	defer repo.DB.metrics.observe("read_file", time.Now(), &err)
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return 0, err
+	}
 	// reader is nil, return
 	if w == nil {
 		return 0, fmt.Errorf("ReadFile requires non-nil writer: %s", rec.FileName())
 	}
 
-	repo.RLock()
+	repo.DB.metrics.lock("repo", repo.RLocker())
 	defer repo.RUnlock()
 
-	filename := path.Join(repo.Dir(), rec.Folder(), rec.FileName())
-	f, err := os.Open(filename)
-	defer f.Close()
+	n, err := repo.readFile(rec, w)
+	return n, notExist(ErrRecordNotExists, rec, err)
+}
+
+// readFile copies the record file to w, decrypting if enabled. The caller must hold
+// the repo lock.
+func (repo *Repo) readFile(rec Record, w io.Writer) (int64, error) {
+	f, err := repo.openFile(rec)
 	if err != nil {
 		return 0, err
 	}
+	defer f.Close()
+
 	n, err := io.Copy(w, f)
+	repo.DB.metrics.read(n)
 	return n, err
 }
 
-// RemoveFile removes the record. If there is an error it will
-// be of type *os.PathError. This function will not remove the
+// openFile opens the record file for reading, decrypting if enabled, or the blob it points
+// to. The caller must hold the repo lock until the file is closed.
+func (repo *Repo) openFile(rec Record) (io.ReadCloser, error) {
+	key, err := repo.encKey()
+	if err != nil {
+		return nil, err
+	}
+
+	filename := path.Join(repo.Dir(), rec.Folder(), rec.FileName())
+	bare := repo.isBare()
+	var f io.ReadCloser
+	err = repo.DB.retry("open_file", func() (err error) {
+		if bare {
+			f, err = repo.openBare(path.Join(rec.Folder(), rec.FileName()))
+			return err
+		}
+		f, err = repo.DB.fs.Open(filename)
+		return err
+	})
+	switch {
+	case os.IsNotExist(err):
+		// records outside the folders of a sparse checkout are read from git
+		if blob, blobErr := repo.openBlob(rec); !errors.Is(blobErr, os.ErrNotExist) {
+			f, err = blob, blobErr
+		}
+	case err == nil && repo.DB.verifyReads && !bare:
+		if f, err = repo.verifyFile(rec, f); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		fr, err := newDecryptReader(f, key, repo.fileAAD(path.Join(rec.Folder(), rec.FileName()))...)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f = struct {
+			io.Reader
+			io.Closer
+		}{fr, f}
+	}
+	attr, err := repo.readAttr(rec)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	codec, err := repo.DB.codec(attr.Codec)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if f, err = repo.resolveBlob(f, attr, key); err != nil {
+		return nil, err
+	}
+	return decompress(f, codec)
+}
+
+// RemoveFile removes the record. Returns ErrLegalHold if the record is under legal hold,
+// ErrContentReferenced if it is content addressed and referenced by meta-data, or
+// ErrRecordNotExists if there is no record file. This function will not remove the
 // coresponding meta-data file, use in conjunction with RemoveMeta.
-func (repo *Repo) RemoveFile(rec Record, opts CommitOptions) error {
-	repo.Lock()
+func (repo *Repo) RemoveFile(rec Record, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("remove_file", time.Now(), &err)
+	if err := repo.authorize(ActionDelete, rec); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
 	defer repo.Unlock()
 
+	if replayed, err := repo.replayed(OpRemoveFile, rec, opts); err != nil || replayed {
+		return err
+	}
+	if repo.isHeld(rec) {
+		return ErrLegalHold
+	}
+	if err := repo.checkReferences(rec); err != nil {
+		return err
+	}
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+
 	filename := path.Join(repo.Dir(), rec.Folder(), rec.FileName())
-	err := os.Remove(filename)
+	if repo.staged != nil {
+		err = repo.stageRemove(path.Join(rec.Folder(), rec.FileName()))
+	} else {
+		err = repo.DB.fs.Remove(filename)
+	}
 	if err != nil {
+		return notExist(ErrRecordNotExists, rec, err)
+	}
+	if err := repo.writeAttr(rec, nil); err != nil {
 		return err
 	}
 
-	// appends to git commit message separated by blank line. If original message is blank it will remove leading blank spaces
-	opts.Msg = fmt.Sprintf("%s\n\nremoved file %s", opts.Msg, filename)
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpRemoveFile,
+		Record:    path.Join(rec.Folder(), rec.FileName()),
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("removed file %s", filename),
+	})
 
-	return repo.CommitAll(opts)
+	return repo.commit(OpRemoveFile, rec, opts)
 }
 
-// WriteMeta data for record to json file db.
-func (repo *Repo) WriteMeta(rec Record, opts CommitOptions) error {
-	repo.Lock()
+// WriteMeta data for record to json file in the record folder MetaDir, or the encoding
+// of a MetaMarshaler. The timestamps of a TimestampedRecord, such as a Repo, are set
+// first.
+func (repo *Repo) WriteMeta(rec Record, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("write_meta", time.Now(), &err)
+	if err := repo.authorize(ActionWrite, rec); err != nil {
+		return err
+	}
+	if err := repo.DB.validateRecord(rec); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
 	defer repo.Unlock()
+	if r, ok := rec.(*Repo); ok && r.lazy {
+		return fmt.Errorf("cannot write meta-data for %s: meta-data not loaded, see EnsureMeta", r.Name)
+	}
+	if replayed, err := repo.replayed(OpWriteMeta, rec, opts); err != nil || replayed {
+		return err
+	}
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+	if err := repo.writeMeta(rec); err != nil {
+		return err
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpWriteMeta,
+		Record:    path.Join(rec.Folder(), rec.FileName()),
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("wrote meta-data to %s", path.Join(MetaDir, rec.FileName())+repo.DB.metaExt()),
+	})
+
+	return repo.commit(OpWriteMeta, rec, opts)
+}
+
+// writeMeta stamps, validates and writes the record meta-data to be committed, the
+// caller must hold the repo lock
+func (repo *Repo) writeMeta(rec Record) error {
 	dir := path.Join(repo.Dir(), rec.Folder())
 	_, ok := rec.(*Repo)
 	if ok {
 		dir = path.Join(repo.Dir(), "")
 	}
-
-	// TODO(readpe): create own scribble package without logger
-	meta, err := scribble.New(dir, &scribble.Options{})
-	if err != nil {
-		return fmt.Errorf("cannot create scribble db %s: %v", dir, err)
+	stamp(rec, time.Now())
+	if b, err := repo.DB.metaStore(dir).encode(rec); err != nil {
+		return fmt.Errorf("cannot write meta-data for %s: %v", rec.FileName(), err)
+	} else if err := repo.DB.validateMeta(rec, b); err != nil {
+		return err
 	}
 
-	err = meta.Write(MetaDir, rec.FileName(), rec)
+	err := repo.DB.retry("write_meta", func() error {
+		if repo.staged != nil {
+			b, err := repo.DB.metaStore(dir).encode(rec)
+			if err != nil {
+				return err
+			}
+			if _, err = repo.stageFile(repo.DB.metaPath(rec), bytes.NewReader(b), nil); err != nil {
+				return err
+			}
+			return repo.writeExpiry(rec)
+		}
+		if err := repo.DB.metaStore(dir).write(rec.FileName(), rec); err != nil {
+			return err
+		}
+		return repo.writeExpiry(rec)
+	})
 	if err != nil {
 		return fmt.Errorf("cannot write meta-data for %s: %v", rec.FileName(), err)
 	}
-
-	// appends to git commit message separated by blank line. If original message is blank it will remove leading blank spaces
-	opts.Msg = fmt.Sprintf("%s\n\nwrote meta-data to %s", opts.Msg, path.Join(MetaDir, rec.FileName())+".json")
-
-	return repo.CommitAll(opts)
+	repo.DB.debug("wrote meta-data", "repo", repo.Name, "folder", rec.Folder(), "record", rec.FileName())
+	return nil
 }
 
-// LoadMeta data for record to Record concrete type
-func (repo *Repo) LoadMeta(rec Record) error {
+// LoadMeta data for record to Record concrete type, decoded by a MetaUnmarshaler if
+// implemented. Computed fields of the record, see FieldSize, are set from the record file.
+// Returns ErrMetaNotExists if the record has no meta-data.
+func (repo *Repo) LoadMeta(rec Record) (err error) {
+	defer repo.DB.metrics.observe("load_meta", time.Now(), &err)
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return err
+	}
 	repo.RLock()
 	repo.RUnlock()
 
@@ -349,39 +1264,94 @@ func (repo *Repo) LoadMeta(rec Record) error {
 		dir = path.Join(repo.Dir())
 	}
 
-	meta, err := scribble.New(dir, &scribble.Options{})
+	err = repo.DB.retry("load_meta", func() error {
+		if repo.isBare() {
+			return repo.readBareMeta(rec)
+		}
+		return repo.DB.metaStore(dir).read(rec.FileName(), rec)
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return notExist(ErrMetaNotExists, rec, err)
+	}
 	if err != nil {
-		return fmt.Errorf("cannot load scribble db %s: %v", dir, err)
+		return fmt.Errorf("cannot read meta-data for %s: %v", rec.FileName(), err)
 	}
 
-	err = meta.Read(MetaDir, rec.FileName(), rec)
-	if err != nil {
-		return fmt.Errorf("cannot write meta-data for %s: %v", rec.FileName(), err)
+	fields, err := computedFields(rec)
+	if err != nil || len(fields) == 0 {
+		return err
 	}
-	return nil
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.loadComputed(rec, fields)
 }
 
-// RemoveMeta removes the records meta-data file. If there is an error it will
-// be of type *os.PathError. This function will not remove the
-// referenced record file, use in conjunction with RemoveFIle.
-func (repo *Repo) RemoveMeta(rec Record, opts CommitOptions) error {
-	repo.Lock()
+// RemoveMeta removes the records meta-data file. Returns ErrLegalHold if the record is
+// under legal hold, or ErrMetaNotExists if there is no meta-data file. This function will
+// not remove the referenced record file, use in conjunction with RemoveFIle.
+func (repo *Repo) RemoveMeta(rec Record, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("remove_meta", time.Now(), &err)
+	if err := repo.authorize(ActionDelete, rec); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
 	defer repo.Unlock()
 
-	filename := path.Join(repo.Dir(), rec.Folder(), MetaDir, rec.FileName()) + ".json"
-	err := os.Remove(filename)
-	if err != nil {
+	if replayed, err := repo.replayed(OpRemoveMeta, rec, opts); err != nil || replayed {
 		return err
 	}
+	if repo.isHeld(rec) {
+		return ErrLegalHold
+	}
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+
+	filename := path.Join(repo.Dir(), repo.DB.metaPath(rec))
+	if repo.staged != nil {
+		err = repo.stageRemove(repo.DB.metaPath(rec))
+	} else {
+		err = repo.DB.fs.Remove(filename)
+	}
+	if err != nil {
+		return notExist(ErrMetaNotExists, rec, err)
+	}
 
-	// appends to git commit message separated by blank line. If original message is blank it will remove leading blank spaces
-	opts.Msg = fmt.Sprintf("%s\n\nremoved meta-data file %s", opts.Msg, filename)
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpRemoveMeta,
+		Record:    path.Join(rec.Folder(), rec.FileName()),
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("removed meta-data file %s", filename),
+	})
 
-	return repo.CommitAll(opts)
+	return repo.commit(OpRemoveMeta, rec, opts)
 }
 
-// cleanPath used to remove .. and PathSeparator from file and directory names
+// recordKey returns a file name unique to the parts, for meta-data kept about records
+// in a folder of its own, such as legal holds. Parts are length prefixed and hashed, so
+// no two lists of parts share a key whatever the characters of the parts.
+func recordKey(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		fmt.Fprintf(h, "%d:%s", len(p), p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cleanPath used to remove .. and path separators from file and directory names. Both
+// / and \ are removed on every platform so names are the same on Windows, where the
+// other characters Windows does not allow in names are removed too.
 func cleanPath(s string) string {
 	s = strings.ReplaceAll(s, "..", "")
-	return strings.ReplaceAll(s, string(os.PathSeparator), "")
+	remove := `/\`
+	if runtime.GOOS == "windows" {
+		remove += `:*?"<>|`
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(remove, r) {
+			return -1
+		}
+		return r
+	}, s)
 }
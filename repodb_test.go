@@ -1,10 +1,17 @@
 package repodb_test
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"path"
+	"strings"
 	"testing"
 
+	"github.com/go-git/go-git/v5"
+
 	"github.com/readpe/repodb"
 )
 
@@ -26,6 +33,33 @@ func setup() string {
 	return dir
 }
 
+// newTestDB returns a new RepoDB in its own temp directory, for tests that should not
+// share state with the package level db.
+func newTestDB(t testing.TB) *repodb.RepoDB {
+	t.Helper()
+	dir, err := ioutil.TempDir(os.TempDir(), "repodb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return repodb.NewDB(dir)
+}
+
+// committed reports whether the named file is in the HEAD commit of the repo
+func committed(t testing.TB, repo *repodb.Repo, name string) bool {
+	t.Helper()
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := repo.FSAt(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fs.Stat(fsys, name)
+	return err == nil
+}
+
 func TestRepoDB_CreateRepo(t *testing.T) {
 	type args struct {
 		repo *repodb.Repo
@@ -102,6 +136,26 @@ func TestRepoDB_CreateRepo(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "slash",
+			args: args{
+				&repodb.Repo{
+					Name: "/",
+					DB:   db,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "backslash",
+			args: args{
+				&repodb.Repo{
+					Name: `\`,
+					DB:   db,
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "exists",
 			args: args{
@@ -230,3 +284,244 @@ func TestRepo_Protect(t *testing.T) {
 		t.Errorf("Repo.Protect() Protected = %v, %v", newRepo.Protected, true)
 	}
 }
+
+func TestRepoDB_ListReposPage(t *testing.T) {
+	db := newTestDB(t)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := db.CreateRepo(&repodb.Repo{Name: name, DB: db, Description: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not a repo, skipped
+	if err := os.Mkdir(path.Join(db.Dir(), "bb"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts repodb.ListOptions
+		want []string
+	}{
+		{"all", repodb.ListOptions{}, []string{"a", "b", "c", "d", "e"}},
+		{"first page", repodb.ListOptions{Limit: 2}, []string{"a", "b"}},
+		{"second page", repodb.ListOptions{Offset: 2, Limit: 2}, []string{"c", "d"}},
+		{"last page", repodb.ListOptions{Offset: 4, Limit: 2}, []string{"e"}},
+		{"past end", repodb.ListOptions{Offset: 10}, []string{}},
+		{"skip meta", repodb.ListOptions{Limit: 1, SkipMeta: true}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos, err := db.ListReposPage(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, r := range repos {
+				got = append(got, r.Name)
+				if (r.Description == "") != tt.opts.SkipMeta {
+					t.Errorf("RepoDB.ListReposPage() %s Description = %q, SkipMeta %v", r.Name, r.Description, tt.opts.SkipMeta)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("RepoDB.ListReposPage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// a repo with unreadable meta-data is reported
+	if err := os.Remove(path.Join(db.Dir(), "c", repodb.MetaDir, "c.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ListReposPage(repodb.ListOptions{}); err == nil {
+		t.Errorf("RepoDB.ListReposPage() expected error for missing meta-data")
+	}
+}
+
+func TestRepoDB_ListReposConcurrentMeta(t *testing.T) {
+	db := newTestDB(t)
+	var want []string
+	for i := 0; i < 40; i++ {
+		name := fmt.Sprintf("repo%02d", i)
+		if err := db.CreateRepo(&repodb.Repo{Name: name, DB: db, Description: name}); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, name)
+	}
+	// a repo with unreadable meta-data is skipped
+	if err := os.Remove(path.Join(db.Dir(), "repo07", repodb.MetaDir, "repo07.json")); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want[:7], want[8:]...)
+
+	got := []string{}
+	for _, r := range db.ListRepos() {
+		got = append(got, r.Name)
+		if r.Description != r.Name {
+			t.Errorf("RepoDB.ListRepos() %s Description = %q, want %q", r.Name, r.Description, r.Name)
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("RepoDB.ListRepos() = %v, want %v", got, want)
+	}
+}
+
+func TestRepoDB_AdoptRepo(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.CreateRepo(&repodb.Repo{Name: "created", DB: db}); err != nil {
+		t.Fatal(err)
+	}
+	// a repo with history copied into the DB directory
+	r, err := git.PlainInit(path.Join(db.Dir(), "copied"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(db.Dir(), "copied", "readme.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w, _ := r.Worktree()
+	if _, err := w.Add("readme.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Commit("initial", &repodb.DBRepoCommitOptions.Opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.PlainInit(path.Join(db.Dir(), "bare"), true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		repo    string
+		wantErr bool
+		is      error
+	}{
+		{"copied", "copied", false, nil},
+		{"already adopted", "copied", true, repodb.ErrRepoAlreadyExists},
+		{"created", "created", true, repodb.ErrRepoAlreadyExists},
+		{"not a repo", "missing", true, repodb.ErrRepoNotExists},
+		{"bare", "bare", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := db.AdoptRepo(tt.repo)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("RepoDB.AdoptRepo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if repo.CreatedOn.IsZero() {
+				t.Errorf("RepoDB.AdoptRepo() CreatedOn not set")
+			}
+		})
+	}
+
+	opened, err := db.OpenRepo("copied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	activity, err := opened.RecentActivity(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity) != 2 || activity[1].Message != "initial" {
+		t.Errorf("adopted repo history = %v, want meta-data commit after initial", activity)
+	}
+}
+
+func TestRepoDB_RenameRepo(t *testing.T) {
+	db := newTestDB(t)
+	for _, name := range []string{"old", "taken"} {
+		if err := db.CreateRepo(&repodb.Repo{Name: name, DB: db, Description: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, err := db.OpenRepo("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		oldName string
+		newName string
+		wantErr bool
+		is      error
+	}{
+		{"taken", "old", "taken", true, repodb.ErrRepoAlreadyExists},
+		{"missing", "missing", "other", true, repodb.ErrRepoNotExists},
+		{"empty", "old", "", true, nil},
+		{"renamed", "old", "new", false, nil},
+		{"already renamed", "old", "newer", true, repodb.ErrRepoNotExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.RenameRepo(tt.oldName, tt.newName)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("RepoDB.RenameRepo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	renamed, err := db.OpenRepo("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name != "new" || renamed.Description != "old" {
+		t.Errorf("renamed repo meta-data = %s %q, want new %q", renamed.Name, renamed.Description, "old")
+	}
+	if _, err := os.Stat(path.Join(renamed.Dir(), repodb.MetaDir, "old.json")); !os.IsNotExist(err) {
+		t.Errorf("old repo meta-data not removed, err = %v", err)
+	}
+	if committed(t, renamed, path.Join(repodb.MetaDir, "old.json")) {
+		t.Errorf("old repo meta-data still committed")
+	}
+	if _, err := renamed.ReadFile(&FileRecord{Name: "a.txt"}, ioutil.Discard); err != nil {
+		t.Errorf("renamed repo ReadFile() error = %v", err)
+	}
+	activity, err := renamed.RecentActivity(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity) != 3 || activity[0].Operation != repodb.OpRenameRepo || activity[1].Operation != repodb.OpWriteFile {
+		t.Errorf("renamed repo history = %v, want rename after previous commits", activity)
+	}
+}
+
+func TestRecordNotExistsErrors(t *testing.T) {
+	for _, bare := range []bool{false, true} {
+		opts := []repodb.Option{}
+		if bare {
+			opts = append(opts, repodb.WithBareRepos())
+		}
+		db := repodb.NewDB(newTestDB(t).Dir(), opts...)
+		repo := &repodb.Repo{Name: "NotExistsRepo", DB: db}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		missing := &FileRecord{Name: "missing.txt"}
+
+		tests := []struct {
+			name string
+			fn   func() error
+			is   error
+		}{
+			{"ReadFile", func() error { _, err := repo.ReadFile(missing, ioutil.Discard); return err }, repodb.ErrRecordNotExists},
+			{"Stat", func() error { _, err := repo.Stat(missing); return err }, repodb.ErrRecordNotExists},
+			{"RemoveFile", func() error { return repo.RemoveFile(missing, repodb.DBRepoCommitOptions) }, repodb.ErrRecordNotExists},
+			{"LoadMeta", func() error { return repo.LoadMeta(missing) }, repodb.ErrMetaNotExists},
+			{"RemoveMeta", func() error { return repo.RemoveMeta(missing, repodb.DBRepoCommitOptions) }, repodb.ErrMetaNotExists},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/bare=%v", tt.name, bare), func(t *testing.T) {
+				err := tt.fn()
+				if !errors.Is(err, tt.is) || !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("Repo.%s() error = %v, want %v", tt.name, err, tt.is)
+				}
+			})
+		}
+	}
+}
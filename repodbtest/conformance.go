@@ -0,0 +1,166 @@
+package repodbtest
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+// conformanceRecord is the record written by the conformance checks
+type conformanceRecord struct {
+	Name  string
+	Title string
+}
+
+func (r *conformanceRecord) FileName() string { return r.Name }
+func (r *conformanceRecord) Folder() string   { return "conformance" }
+
+// RunMetaStoreTests runs the repo, record and meta-data conformance checks of repodb
+// against databases returned by newDB, so custom storage backends plugged in with
+// options such as WithFilesystem, WithMetaCodec and WithBlobStore can be verified to
+// keep repodb's semantics. newDB is called for each check and must return an empty
+// database.
+func RunMetaStoreTests(t *testing.T, newDB func(t *testing.T) *repodb.RepoDB) {
+	t.Run("repos", func(t *testing.T) { checkRepos(t, newDB(t)) })
+	t.Run("records", func(t *testing.T) { checkRecords(t, newConformanceRepo(t, newDB(t))) })
+	t.Run("meta-data", func(t *testing.T) { checkMeta(t, newConformanceRepo(t, newDB(t))) })
+	t.Run("history", func(t *testing.T) { checkHistory(t, newConformanceRepo(t, newDB(t))) })
+}
+
+// newConformanceRepo creates the repo the record checks run in
+func newConformanceRepo(t *testing.T, db *repodb.RepoDB) *repodb.Repo {
+	t.Helper()
+	repo := &repodb.Repo{Name: "conformance", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatalf("RepoDB.CreateRepo() error = %v", err)
+	}
+	return repo
+}
+
+// checkRepos checks repos are created, listed, opened with their meta-data and removed
+func checkRepos(t *testing.T, db *repodb.RepoDB) {
+	repo := &repodb.Repo{Name: "conformance", DB: db, Description: "conformance repo", Labels: map[string]string{"suite": "conformance"}}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatalf("RepoDB.CreateRepo() error = %v", err)
+	}
+	if err := db.CreateRepo(&repodb.Repo{Name: "conformance", DB: db}); !errors.Is(err, repodb.ErrRepoAlreadyExists) {
+		t.Errorf("RepoDB.CreateRepo() existing repo error = %v, want %v", err, repodb.ErrRepoAlreadyExists)
+	}
+
+	opened, err := db.OpenRepo("conformance")
+	if err != nil {
+		t.Fatalf("RepoDB.OpenRepo() error = %v", err)
+	}
+	if opened.Description != repo.Description || !reflect.DeepEqual(opened.Labels, repo.Labels) {
+		t.Errorf("RepoDB.OpenRepo() = %q %v, want %q %v", opened.Description, opened.Labels, repo.Description, repo.Labels)
+	}
+	if repos := db.ListRepos(); len(repos) != 1 || repos[0].Name != "conformance" {
+		t.Errorf("RepoDB.ListRepos() = %v, want the conformance repo", repos)
+	}
+	if _, err := db.OpenRepo("missing"); !errors.Is(err, repodb.ErrRepoNotExists) {
+		t.Errorf("RepoDB.OpenRepo() missing repo error = %v, want %v", err, repodb.ErrRepoNotExists)
+	}
+
+	if err := db.RemoveRepo("conformance"); err != nil {
+		t.Fatalf("RepoDB.RemoveRepo() error = %v", err)
+	}
+	if _, err := db.OpenRepo("conformance"); !errors.Is(err, repodb.ErrRepoNotExists) {
+		t.Errorf("RepoDB.OpenRepo() removed repo error = %v, want %v", err, repodb.ErrRepoNotExists)
+	}
+}
+
+// checkRecords checks record files are written, replaced, listed, renamed and removed
+func checkRecords(t *testing.T, repo *repodb.Repo) {
+	read := func(name string) (string, error) {
+		buf := &strings.Builder{}
+		_, err := repo.ReadFile(&conformanceRecord{Name: name}, buf)
+		return buf.String(), err
+	}
+	for _, content := range []string{"first", "second, longer than the first"} {
+		if err := repo.WriteFile(&conformanceRecord{Name: "a.txt"}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatalf("Repo.WriteFile() error = %v", err)
+		}
+		if got, err := read("a.txt"); err != nil || got != content {
+			t.Errorf("Repo.ReadFile() = %q, error = %v, want %q", got, err, content)
+		}
+	}
+	if err := repo.WriteFile(&conformanceRecord{Name: "b.txt"}, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.WriteFile() error = %v", err)
+	}
+	if got, err := repo.ListRecords("conformance", false); err != nil || !reflect.DeepEqual(got, []string{"conformance/a.txt", "conformance/b.txt"}) {
+		t.Errorf("Repo.ListRecords() = %v, error = %v, want [conformance/a.txt conformance/b.txt]", got, err)
+	}
+
+	if err := repo.RenameRecord(&conformanceRecord{Name: "b.txt"}, "c.txt", repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.RenameRecord() error = %v", err)
+	}
+	if got, err := read("c.txt"); err != nil || got != "b" {
+		t.Errorf("Repo.ReadFile() renamed record = %q, error = %v, want %q", got, err, "b")
+	}
+
+	if err := repo.RemoveFile(&conformanceRecord{Name: "a.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.RemoveFile() error = %v", err)
+	}
+	if repo.FileExists(&conformanceRecord{Name: "a.txt"}) {
+		t.Error("Repo.FileExists() = true for removed record")
+	}
+	if _, err := read("a.txt"); !errors.Is(err, repodb.ErrRecordNotExists) {
+		t.Errorf("Repo.ReadFile() removed record error = %v, want %v", err, repodb.ErrRecordNotExists)
+	}
+}
+
+// checkMeta checks record meta-data is written, loaded and removed
+func checkMeta(t *testing.T, repo *repodb.Repo) {
+	rec := &conformanceRecord{Name: "a.txt", Title: "conformance"}
+	if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.WriteMeta() error = %v", err)
+	}
+	loaded := &conformanceRecord{Name: "a.txt"}
+	if err := repo.LoadMeta(loaded); err != nil || loaded.Title != rec.Title {
+		t.Errorf("Repo.LoadMeta() Title = %q, error = %v, want %q", loaded.Title, err, rec.Title)
+	}
+	if err := repo.RemoveMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.RemoveMeta() error = %v", err)
+	}
+	if err := repo.LoadMeta(&conformanceRecord{Name: "a.txt"}); !errors.Is(err, repodb.ErrMetaNotExists) {
+		t.Errorf("Repo.LoadMeta() removed meta-data error = %v, want %v", err, repodb.ErrMetaNotExists)
+	}
+}
+
+// checkHistory checks each change is committed, including removals
+func checkHistory(t *testing.T, repo *repodb.Repo) {
+	committed := func(name string) bool {
+		t.Helper()
+		head, err := repo.Head()
+		if err != nil {
+			t.Fatalf("Repo.Head() error = %v", err)
+		}
+		fsys, err := repo.FSAt(head)
+		if err != nil {
+			t.Fatalf("Repo.FSAt() error = %v", err)
+		}
+		_, err = fs.Stat(fsys, "conformance/"+name)
+		return err == nil
+	}
+
+	before, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Repo.Head() error = %v", err)
+	}
+	if err := repo.WriteFile(&conformanceRecord{Name: "a.txt"}, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.WriteFile() error = %v", err)
+	}
+	if after, _ := repo.Head(); after == before || !committed("a.txt") {
+		t.Error("Repo.WriteFile() did not commit the record")
+	}
+	if err := repo.RemoveFile(&conformanceRecord{Name: "a.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatalf("Repo.RemoveFile() error = %v", err)
+	}
+	if committed("a.txt") {
+		t.Error("Repo.RemoveFile() left the record committed")
+	}
+}
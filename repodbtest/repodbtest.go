@@ -0,0 +1,175 @@
+// Package repodbtest provides utilities for testing code using repodb, including a
+// throwaway bare git remote for integration testing push, pull and replication without
+// network access.
+package repodbtest
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+
+	"github.com/readpe/repodb"
+)
+
+// NewDB returns a RepoDB in a temp directory, removed when the test completes
+func NewDB(t testing.TB) *repodb.RepoDB {
+	t.Helper()
+	return repodb.NewDB(TempDir(t))
+}
+
+// TempDir returns a new temp directory, removed when the test completes
+func TempDir(t testing.TB) string {
+	t.Helper()
+	dir, err := ioutil.TempDir(os.TempDir(), "repodbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// Remote is a throwaway bare git repository on the local filesystem, usable as a
+// remote by any git client using its URL.
+type Remote struct {
+	t   testing.TB
+	Dir string
+	URL string
+}
+
+// NewRemote creates an empty bare repository in a temp directory, removed when the
+// test completes.
+func NewRemote(t testing.TB) *Remote {
+	t.Helper()
+	dir := TempDir(t)
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatalf("unable to create remote at %s: %v", dir, err)
+	}
+	return &Remote{t: t, Dir: dir, URL: dir}
+}
+
+// AddTo adds the remote to the repo under name, for pushing and pulling from the repo
+func (r *Remote) AddTo(repo *repodb.Repo, name string) {
+	r.t.Helper()
+	g, err := git.PlainOpen(repo.Dir())
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	_, err = g.CreateRemote(&config.RemoteConfig{Name: name, URLs: []string{r.URL}})
+	if err != nil {
+		r.t.Fatalf("unable to add remote %s to %s: %v", name, repo.Name, err)
+	}
+}
+
+// Seed commits files, keyed by slash separated path, to the remote master branch,
+// simulating a change pushed by another client.
+func (r *Remote) Seed(files map[string]string, msg string) plumbing.Hash {
+	r.t.Helper()
+	dir := TempDir(r.t)
+	g, err := git.PlainClone(dir, false, &git.CloneOptions{URL: r.URL})
+	switch {
+	case err == nil:
+	case errors.Is(err, transport.ErrEmptyRemoteRepository):
+		g, err = git.PlainInit(dir, false)
+		if err == nil {
+			_, err = g.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{r.URL}})
+		}
+	}
+	if err != nil {
+		r.t.Fatalf("unable to clone remote %s: %v", r.URL, err)
+	}
+
+	for name, body := range files {
+		filename := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(filename), 0700); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(body), 0600); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+
+	w, err := g.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if _, err := w.Add("."); err != nil {
+		r.t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "repodbtest", When: time.Now()}
+	hash, err := w.Commit(msg, &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := g.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+		r.t.Fatalf("unable to push to remote %s: %v", r.URL, err)
+	}
+	return hash
+}
+
+// Head returns the hash of the remote branch, or plumbing.ZeroHash if it does not exist
+func (r *Remote) Head(branch string) plumbing.Hash {
+	r.t.Helper()
+	g, err := git.PlainOpen(r.Dir)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	ref, err := g.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return ref.Hash()
+}
+
+// Log returns the commit messages of the remote branch, newest first
+func (r *Remote) Log(branch string) []string {
+	r.t.Helper()
+	head := r.Head(branch)
+	if head.IsZero() {
+		return nil
+	}
+	g, err := git.PlainOpen(r.Dir)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	iter, err := g.Log(&git.LogOptions{From: head})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	var msgs []string
+	iter.ForEach(func(c *object.Commit) error {
+		msgs = append(msgs, c.Message)
+		return nil
+	})
+	return msgs
+}
+
+// ReadFile returns the contents of the slash separated path on the remote branch
+func (r *Remote) ReadFile(branch, name string) (string, error) {
+	r.t.Helper()
+	g, err := git.PlainOpen(r.Dir)
+	if err != nil {
+		return "", err
+	}
+	ref, err := g.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return "", err
+	}
+	c, err := g.CommitObject(ref.Hash())
+	if err != nil {
+		return "", err
+	}
+	f, err := c.File(name)
+	if err != nil {
+		return "", err
+	}
+	return f.Contents()
+}
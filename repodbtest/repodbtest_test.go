@@ -0,0 +1,80 @@
+package repodbtest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+
+	"github.com/readpe/repodb"
+	"github.com/readpe/repodb/repodbtest"
+)
+
+type record struct{ name string }
+
+func (r *record) FileName() string { return r.name }
+func (r *record) Folder() string   { return "files" }
+
+func TestRemote(t *testing.T) {
+	remote := repodbtest.NewRemote(t)
+	if !remote.Head("master").IsZero() {
+		t.Fatalf("Remote.Head() of new remote is not zero")
+	}
+
+	db := repodbtest.NewDB(t)
+	repo := &repodb.Repo{Name: "PushRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	rec := &record{"pushed.txt"}
+	if err := repo.WriteFile(rec, strings.NewReader("pushed body"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	remote.AddTo(repo, "origin")
+	g, err := git.PlainOpen(repo.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(remote.Log("master")); got != 2 {
+		t.Errorf("Remote.Log() len = %v, want %v", got, 2)
+	}
+	body, err := remote.ReadFile("master", "files/pushed.txt")
+	if err != nil || body != "pushed body" {
+		t.Errorf("Remote.ReadFile() = %q, %v, want %q", body, err, "pushed body")
+	}
+
+	remote.Seed(map[string]string{"files/seeded.txt": "seeded body"}, "seeded")
+	if msgs := remote.Log("master"); len(msgs) != 3 || msgs[0] != "seeded" {
+		t.Errorf("Remote.Log() after Seed = %q", msgs)
+	}
+}
+
+func TestRunMetaStoreTests(t *testing.T) {
+	tests := []struct {
+		name  string
+		newDB func(t *testing.T) *repodb.RepoDB
+	}{
+		{"default", func(t *testing.T) *repodb.RepoDB { return repodbtest.NewDB(t) }},
+		{"compact meta", func(t *testing.T) *repodb.RepoDB {
+			return repodb.NewDB(repodbtest.TempDir(t), repodb.WithCompactMeta())
+		}},
+		{"memfs", func(t *testing.T) *repodb.RepoDB {
+			return repodb.NewDB("/repodb-memfs", repodb.WithFilesystem(memfs.New()))
+		}},
+		{"blob store", func(t *testing.T) *repodb.RepoDB {
+			store := repodb.DirBlobStore(repodbtest.TempDir(t))
+			return repodb.NewDB(repodbtest.TempDir(t), repodb.WithBlobStore(store, 1))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repodbtest.RunMetaStoreTests(t, tt.newDB)
+		})
+	}
+}
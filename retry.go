@@ -0,0 +1,104 @@
+package repodb
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryObserver is called before each retry of an operation with the attempt that
+// failed, starting at 1, its error and the wait before the next attempt.
+type RetryObserver func(op string, attempt int, err error, wait time.Duration)
+
+// RetryPolicy retries operations failing with transient errors using exponential
+// backoff with jitter.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first, less than 2 disables retries
+	Initial     time.Duration // wait before the first retry
+	Max         time.Duration // maximum wait between attempts, zero for no maximum
+	Multiplier  float64       // wait growth per attempt, defaults to 2
+	Jitter      float64       // random fraction, 0 to 1, of the wait added or removed
+
+	// Retryable reports if an error should be retried, defaults to IsTransient
+	Retryable func(err error) bool
+	Observer  RetryObserver
+}
+
+// DefaultRetryPolicy retries transient errors up to 5 attempts, waiting 100ms doubling
+// to at most 5s with 20% jitter.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	Initial:     100 * time.Millisecond,
+	Max:         5 * time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+}
+
+// WithRetry retries commits, meta-data reads and writes, and record file opens that
+// fail with errors accepted by the policy.
+func WithRetry(p RetryPolicy) Option {
+	return func(db *RepoDB) {
+		db.retryPolicy = &p
+	}
+}
+
+// Do runs fn, retrying according to the policy while it returns retryable errors.
+// The last error is returned once attempts are exhausted.
+func (p RetryPolicy) Do(op string, fn func() error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	wait := p.Initial
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		d := wait
+		if p.Jitter > 0 {
+			d += time.Duration(p.Jitter * float64(d) * (2*rand.Float64() - 1))
+		}
+		if p.Observer != nil {
+			p.Observer(op, attempt, err, d)
+		}
+		time.Sleep(d)
+
+		wait = time.Duration(float64(wait) * multiplier)
+		if p.Max > 0 && wait > p.Max {
+			wait = p.Max
+		}
+	}
+}
+
+// IsTransient reports if the error is likely to succeed if retried, such as a full
+// disk or busy resource that may clear, or a network timeout.
+func IsTransient(err error) bool {
+	var ne net.Error
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EAGAIN),
+		errors.Is(err, syscall.EBUSY), errors.Is(err, syscall.EINTR):
+		return true
+	case errors.As(err, &ne) && ne.Timeout():
+		return true
+	}
+	return false
+}
+
+// retry runs fn with the DB retry policy, if any
+func (db *RepoDB) retry(op string, fn func() error) error {
+	if db.retryPolicy == nil {
+		return fn()
+	}
+	return db.retryPolicy.Do(op, fn)
+}
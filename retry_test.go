@@ -0,0 +1,52 @@
+package repodb_test
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRetryPolicy_Do(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []error // errors returned by successive attempts, then nil
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"success", nil, 1, false},
+		{"transient then success", []error{syscall.ENOSPC, fmt.Errorf("wrapped: %w", syscall.EAGAIN)}, 3, false},
+		{"permanent", []error{errors.New("permanent")}, 1, true},
+		{"exhausted", []error{syscall.EBUSY, syscall.EBUSY, syscall.EBUSY, syscall.EBUSY}, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observed := 0
+			p := repodb.RetryPolicy{
+				MaxAttempts: 3,
+				Initial:     time.Millisecond,
+				Jitter:      0.5,
+				Observer: func(op string, attempt int, err error, wait time.Duration) {
+					observed++
+				},
+			}
+			attempts := 0
+			err := p.Do("test", func() error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RetryPolicy.Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts || observed != attempts-1 {
+				t.Errorf("RetryPolicy.Do() attempts = %d, observed = %d, want %d attempts", attempts, observed, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// RollbackTo restores the records of the repo to their state at ref, a snapshot name,
+// tag, branch or commit hash, and commits the result as a new commit so no history is
+// lost. The repo meta-data is reloaded from the restored state. Legal holds are not
+// rolled back, and ErrLegalHold is returned if the rollback would change a held record.
+func (repo *Repo) RollbackTo(ref string, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("rollback", time.Now(), &err)
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	if replayed, err := repo.replayed(OpRollback, nil, opts); err != nil || replayed {
+		return err
+	}
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	target, err := resolveCommit(r, ref)
+	if err != nil {
+		return fmt.Errorf("unable to resolve %s in %s: %v", ref, repo.Name, err)
+	}
+	targetTree, err := target.Tree()
+	if err != nil {
+		return err
+	}
+	headTree, err := repo.headTree(r)
+	if err != nil {
+		return err
+	}
+
+	// held records must be the same in both states
+	holds, err := repo.holds()
+	if err != nil {
+		return err
+	}
+	for _, h := range holds {
+		for _, name := range []string{
+			path.Join(h.RecordFolder, h.RecordName),
+			path.Join(h.RecordFolder, MetaDir, h.RecordName) + repo.DB.metaExt(),
+		} {
+			if fileHash(headTree, name) != fileHash(targetTree, name) {
+				return fmt.Errorf("%w: rollback changes %s", ErrLegalHold, name)
+			}
+		}
+	}
+
+	holdsDir := (&Hold{}).Folder() + "/"
+	err = headTree.Files().ForEach(func(f *object.File) error {
+		if strings.HasPrefix(f.Name, holdsDir) || fileHash(targetTree, f.Name) != plumbing.ZeroHash {
+			return nil
+		}
+		if err := repo.DB.fs.Remove(path.Join(repo.Dir(), f.Name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to rollback %s: %v", repo.Name, err)
+	}
+	err = targetTree.Files().ForEach(func(f *object.File) error {
+		if strings.HasPrefix(f.Name, holdsDir) || fileHash(headTree, f.Name) == f.Hash {
+			return nil
+		}
+		return repo.checkoutFile(f)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to rollback %s: %v", repo.Name, err)
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpRollback,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("rolled back to %s (%s)", ref, target.Hash),
+	})
+	if err := repo.commit(OpRollback, nil, opts); err != nil {
+		return err
+	}
+	return repo.DB.metaStore(repo.Dir()).read(repo.FileName(), repo)
+}
+
+// RevertFile restores the record file to its version at the commit, and commits it as
+// a new commit. Other records, and the record meta-data, are not changed. Returns
+// ErrLegalHold if the record is under legal hold, or an error satisfying
+// errors.Is(err, os.ErrNotExist) if the record file is not in the commit.
+func (repo *Repo) RevertFile(rec Record, hash Hash, opts CommitOptions) (err error) {
+	defer repo.DB.metrics.observe("revert_file", time.Now(), &err)
+	if err := repo.authorize(ActionWrite, rec); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+
+	if replayed, err := repo.replayed(OpRevertFile, rec, opts); err != nil || replayed {
+		return err
+	}
+	if repo.isHeld(rec) {
+		return ErrLegalHold
+	}
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	c, err := r.CommitObject(hash.Git())
+	if err != nil {
+		return fmt.Errorf("unable to read commit %s of %s: %v", hash, repo.Name, err)
+	}
+	name := path.Join(rec.Folder(), rec.FileName())
+	f, err := c.File(name)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return fmt.Errorf("%s not in commit %s: %w", name, hash, os.ErrNotExist)
+	}
+	if err != nil {
+		return err
+	}
+	if err := repo.checkoutFile(f); err != nil {
+		return fmt.Errorf("unable to revert %s: %v", name, err)
+	}
+
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpRevertFile,
+		Record:    name,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("reverted file %s to %s", name, hash),
+	})
+	return repo.commit(OpRevertFile, rec, opts)
+}
+
+// fileHash returns the hash of the named file in the tree, or the zero hash if it is
+// not in the tree
+func fileHash(tree *object.Tree, name string) plumbing.Hash {
+	f, err := tree.File(name)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return f.Hash
+}
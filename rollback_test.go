@@ -0,0 +1,159 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_RollbackTo(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "RollbackRepo", DB: db, Description: "v1"}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := repo.WriteFile(&FileRecord{Name: name}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		b := &bytes.Buffer{}
+		if _, err := repo.ReadFile(&FileRecord{Name: name}, b); err != nil {
+			return ""
+		}
+		return b.String()
+	}
+
+	write("a.txt", "a1")
+	write("held.txt", "h1")
+	if err := repo.Snapshot("v1", "first"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("a.txt", "a2")
+	write("b.txt", "b2")
+	repo.Description = "v2"
+	if err := repo.WriteMeta(repo, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.RollbackTo("v1", repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("a.txt") + "," + read("b.txt") + "," + repo.Description; got != "a1,,v1" {
+		t.Errorf("Repo.RollbackTo() a.txt, b.txt, Description = %q, want %q", got, "a1,,v1")
+	}
+	if committed(t, repo, "files/b.txt") {
+		t.Errorf("Repo.RollbackTo() left files/b.txt committed")
+	}
+	activity, err := repo.RecentActivity(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity) != 7 || activity[0].Operation != repodb.OpRollback {
+		t.Errorf("Repo.RollbackTo() history = %v, want rollback commit after previous commits", activity)
+	}
+
+	// a held record cannot be rolled back, the hold itself is kept
+	write("held.txt", "h2")
+	current, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.LegalHold(&FileRecord{Name: "held.txt"}, "case"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr bool
+		is      error
+	}{
+		{"held", first.String(), true, repodb.ErrLegalHold},
+		{"unknown", "v9", true, nil},
+		{"commit", current.String(), false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.RollbackTo(tt.ref, repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("Repo.RollbackTo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if !repo.IsHeld(&FileRecord{Name: "held.txt"}) {
+		t.Errorf("Repo.RollbackTo() removed legal hold")
+	}
+}
+
+func TestRepo_RevertFile(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "RevertRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) repodb.Hash {
+		t.Helper()
+		if err := repo.WriteFile(&FileRecord{Name: name}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		head, err := repo.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return head
+	}
+	first := write("a.txt", "a1")
+	write("b.txt", "b1")
+	write("a.txt", "a2")
+	write("b.txt", "b2")
+	write("held.txt", "h1")
+	if err := repo.LegalHold(&FileRecord{Name: "held.txt"}, "case"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		hash    repodb.Hash
+		wantErr bool
+		is      error
+	}{
+		{"reverted", "a.txt", first, false, nil},
+		{"not in commit", "b.txt", first, true, os.ErrNotExist},
+		{"unknown commit", "a.txt", repodb.ZeroHash, true, nil},
+		{"held", "held.txt", first, true, repodb.ErrLegalHold},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.RevertFile(&FileRecord{Name: tt.file}, tt.hash, repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("Repo.RevertFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	for name, want := range map[string]string{"a.txt": "a1", "b.txt": "b2"} {
+		b := &bytes.Buffer{}
+		if _, err := repo.ReadFile(&FileRecord{Name: name}, b); err != nil || b.String() != want {
+			t.Errorf("Repo.ReadFile(%s) after revert = %q, error = %v, want %q", name, b.String(), err, want)
+		}
+	}
+	activity, err := repo.RecentActivity(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity[0].Operation != repodb.OpRevertFile || activity[0].Record != "files/a.txt" {
+		t.Errorf("Repo.RecentActivity() = %v, want revert of files/a.txt", activity[0])
+	}
+}
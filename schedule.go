@@ -0,0 +1,164 @@
+package repodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// OpScheduledDelete is the operation of commits made by Repo.RunDeletes
+const OpScheduledDelete = "scheduled_delete"
+
+// Deletion is a scheduled deletion of a record file and its meta-data. Deletions are
+// stored as meta-data in the deletions folder of the repo.
+type Deletion struct {
+	RecordFolder string
+	RecordName   string
+	At           time.Time
+	ScheduledOn  time.Time
+}
+
+// FileName returns the deletion file name, unique per record. Implements Record interface
+func (d *Deletion) FileName() string {
+	return recordKey(d.RecordFolder, d.RecordName)
+}
+
+// Folder is the record folder for deletions. Implements Record interface
+func (d *Deletion) Folder() string {
+	return "deletions"
+}
+
+// ScheduleDelete schedules the record file and meta-data for deletion at the time, by
+// RunDeletes or StartDeletes. Scheduling an already scheduled record replaces the time.
+func (repo *Repo) ScheduleDelete(rec Record, at time.Time) error {
+	if err := repo.authorize(ActionDelete, rec); err != nil {
+		return err
+	}
+	d := &Deletion{
+		RecordFolder: rec.Folder(),
+		RecordName:   rec.FileName(),
+		At:           at,
+		ScheduledOn:  time.Now(),
+	}
+	return repo.WriteMeta(d, CommitOptions{
+		Msg: fmt.Sprintf("scheduled deletion of %s at %s", path.Join(rec.Folder(), rec.FileName()), at.Format(time.RFC3339)),
+	})
+}
+
+// CancelDelete cancels the scheduled deletion of the record
+func (repo *Repo) CancelDelete(rec Record) error {
+	d := &Deletion{RecordFolder: rec.Folder(), RecordName: rec.FileName()}
+	err := repo.RemoveMeta(d, CommitOptions{
+		Msg: fmt.Sprintf("cancelled deletion of %s", path.Join(rec.Folder(), rec.FileName())),
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no deletion scheduled for %s", path.Join(rec.Folder(), rec.FileName()))
+	}
+	return err
+}
+
+// ScheduledDeletes returns all pending deletions in the repo
+func (repo *Repo) ScheduledDeletes() ([]*Deletion, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.deletions()
+}
+
+// RunDeletes deletes the records whose scheduled time has passed, committing each
+// deletion, and returns the number deleted. Records under legal hold are not deleted
+// and remain scheduled until their holds are released.
+func (repo *Repo) RunDeletes(opts CommitOptions) (deleted int, err error) {
+	defer repo.DB.metrics.observe("run_deletes", time.Now(), &err)
+	if err := repo.authorize(ActionDelete, nil); err != nil {
+		return 0, err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+
+	if err := repo.prepareWrite(); err != nil {
+		return 0, err
+	}
+	deletions, err := repo.deletions()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for _, d := range deletions {
+		if d.At.After(now) {
+			continue
+		}
+		rec := &recordRef{folder: d.RecordFolder, name: d.RecordName}
+		if repo.isHeld(rec) {
+			repo.DB.debug("deferred scheduled deletion of held record", "repo", repo.Name, "folder", rec.folder, "record", rec.name)
+			continue
+		}
+		err := repo.removeFiles(path.Join(rec.folder, rec.name), repo.DB.metaPath(rec), attrPath(rec), repo.DB.metaPath(d))
+		if err != nil {
+			return deleted, fmt.Errorf("unable to delete %s: %v", path.Join(rec.folder, rec.name), err)
+		}
+
+		o := opts
+		o.Msg = repo.DB.commitMessage(CommitMessage{
+			Operation: OpScheduledDelete,
+			Record:    path.Join(rec.folder, rec.name),
+			Message:   opts.Msg,
+			Detail:    fmt.Sprintf("scheduled deletion of %s", path.Join(rec.folder, rec.name)),
+		})
+		if err := repo.commit(OpScheduledDelete, rec, o); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// StartDeletes runs the scheduled deletions every interval until ctx is done. Errors
+// are logged to the DB logger and do not stop the schedule.
+func (repo *Repo) StartDeletes(ctx context.Context, interval time.Duration, opts CommitOptions) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if _, err := repo.RunDeletes(opts); err != nil {
+					repo.DB.warn("unable to run scheduled deletions", "repo", repo.Name, "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// deletions reads all scheduled deletions, the caller must hold the repo lock
+func (repo *Repo) deletions() ([]*Deletion, error) {
+	dir := path.Join(repo.Dir(), (&Deletion{}).Folder())
+	var records [][]byte
+	var err error
+	if repo.isBare() {
+		_, records, err = repo.bareMeta((&Deletion{}).Folder())
+	} else {
+		records, err = repo.DB.metaStore(dir).readAll()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read scheduled deletions for %s: %v", repo.Name, err)
+	}
+
+	deletions := make([]*Deletion, 0, len(records))
+	for _, r := range records {
+		d := &Deletion{}
+		if err := repo.DB.MetaCodec().Unmarshal(r, d); err != nil {
+			return nil, fmt.Errorf("cannot read scheduled deletion for %s: %v", repo.Name, err)
+		}
+		deletions = append(deletions, d)
+	}
+	return deletions, nil
+}
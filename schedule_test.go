@@ -0,0 +1,126 @@
+package repodb_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_ScheduleDelete(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "ScheduleRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"due.txt", "later.txt", "cancelled.txt", "held.txt"} {
+		fr := &FileRecord{Name: name}
+		if err := repo.WriteFile(fr, strings.NewReader(name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		at := time.Now().Add(-time.Minute)
+		if name == "later.txt" {
+			at = time.Now().Add(time.Hour)
+		}
+		if err := repo.ScheduleDelete(fr, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CancelDelete(&FileRecord{Name: "cancelled.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CancelDelete(&FileRecord{Name: "cancelled.txt"}); err == nil {
+		t.Error("Repo.CancelDelete() expected error cancelling twice")
+	}
+	if err := repo.LegalHold(&FileRecord{Name: "held.txt"}, "case-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := repo.RunDeletes(repodb.DBRepoCommitOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("Repo.RunDeletes() = %v, want %v", deleted, 1)
+	}
+
+	tests := []struct {
+		name      string
+		wantFile  bool
+		scheduled bool
+	}{
+		{"due.txt", false, false},
+		{"later.txt", true, true},
+		{"cancelled.txt", true, false},
+		{"held.txt", true, true},
+	}
+	deletions, err := repo.ScheduledDeletes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := &FileRecord{Name: tt.name}
+			if got := repo.FileExists(fr); got != tt.wantFile {
+				t.Errorf("Repo.FileExists() = %v, want %v", got, tt.wantFile)
+			}
+			if got := committed(t, repo, "files/"+tt.name); got != tt.wantFile {
+				t.Errorf("files/%s committed = %v, want %v", tt.name, got, tt.wantFile)
+			}
+			if err := repo.LoadMeta(fr); (err == nil) != tt.wantFile {
+				t.Errorf("Repo.LoadMeta() error = %v, want meta-data %v", err, tt.wantFile)
+			}
+			scheduled := false
+			for _, d := range deletions {
+				scheduled = scheduled || d.RecordName == tt.name
+			}
+			if scheduled != tt.scheduled {
+				t.Errorf("Repo.ScheduledDeletes() contains %s = %v, want %v", tt.name, scheduled, tt.scheduled)
+			}
+		})
+	}
+
+	// held records are deleted by the worker once released
+	if err := repo.ReleaseHold(&FileRecord{Name: "held.txt"}, "case-1"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo.StartDeletes(ctx, 10*time.Millisecond, repodb.DBRepoCommitOptions)
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.FileExists(&FileRecord{Name: "held.txt"}) {
+		if time.Now().After(deadline) {
+			t.Fatal("Repo.StartDeletes() did not delete released record")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRepo_ScheduleDelete_distinct(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "ScheduleRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	recA, recB := &nestedRecord{folder: "a_b", name: "c"}, &nestedRecord{folder: "a", name: "b_c"}
+	for _, rec := range []*nestedRecord{recA, recB} {
+		if err := repo.ScheduleDelete(rec, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if deletions, err := repo.ScheduledDeletes(); err != nil || len(deletions) != 2 {
+		t.Errorf("Repo.ScheduledDeletes() = %d deletions, error = %v, want 2", len(deletions), err)
+	}
+	if err := repo.CancelDelete(recA); err != nil {
+		t.Fatal(err)
+	}
+	deletions, err := repo.ScheduledDeletes()
+	if err != nil || len(deletions) != 1 || deletions[0].RecordFolder != "a" || deletions[0].RecordName != "b_c" {
+		t.Errorf("Repo.ScheduledDeletes() after CancelDelete = %+v, error = %v, want a/b_c", deletions, err)
+	}
+}
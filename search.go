@@ -0,0 +1,497 @@
+package repodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxIndexSize is the largest record file indexed for search, larger files are only
+// searchable by their meta-data.
+const maxIndexSize = 1 << 20
+
+// SearchResult is a record matching a search query
+type SearchResult struct {
+	Folder  string
+	Name    string
+	Snippet string // text around the first match, from the content or meta-data
+}
+
+// WithSearch maintains an in-memory full-text index of record contents and meta-data,
+// used by Repo.Search. Repos are indexed on their first search and kept up to date by
+// a commit hook. Binary contents and files over 1MiB are not indexed.
+func WithSearch() Option {
+	return func(db *RepoDB) {
+		db.search = &searchIndex{
+			docs:    make(map[string]map[string]*searchDoc),
+			indexed: make(map[string]bool),
+			pending: make(map[string]map[string]bool),
+			timers:  make(map[string]*time.Timer),
+		}
+		db.AddHook(func(ev HookEvent) {
+			db.search.update(&Repo{Name: ev.Repo, DB: db}, ev)
+		})
+	}
+}
+
+// WithSearchDebounce makes the search index update in the background instead of in the
+// commit hook. Changed records are reindexed once their folder has had no commits for
+// the debounce duration, so bursts of writes are indexed once. Search waits for
+// pending updates of the repo, so results are never stale. Requires WithSearch.
+func WithSearchDebounce(d time.Duration) Option {
+	return func(db *RepoDB) {
+		if db.search != nil {
+			db.search.debounce = d
+		}
+	}
+}
+
+// Search returns the records whose contents or meta-data contain every term of the
+// query, ignoring case, sorted by folder and name. The DB must be created WithSearch.
+func (repo *Repo) Search(query string) ([]SearchResult, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	idx := repo.DB.search
+	if idx == nil {
+		return nil, fmt.Errorf("search is not enabled for %s", repo.DB.dir)
+	}
+	if err := idx.ensure(repo); err != nil {
+		return nil, err
+	}
+	idx.flush(repo, "")
+	return idx.query(repo.Name, tokenize(query)), nil
+}
+
+// ReindexFolder rebuilds the search index of the folder from the records on disk. The
+// DB must be created WithSearch.
+func (repo *Repo) ReindexFolder(folder string) error {
+	if err := repo.authorizeFolder(ActionRead, folder); err != nil {
+		return err
+	}
+	idx := repo.DB.search
+	if idx == nil {
+		return fmt.Errorf("search is not enabled for %s", repo.DB.dir)
+	}
+	if err := checkFolder(folder); err != nil {
+		return err
+	}
+	if err := idx.ensure(repo); err != nil {
+		return err
+	}
+
+	repo.RLock()
+	docs := folderDocs(repo, folder)
+	repo.RUnlock()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for key := range idx.pending[repo.Name] {
+		if path.Dir(key) == folder {
+			delete(idx.pending[repo.Name], key)
+		}
+	}
+	if !idx.indexed[repo.Name] {
+		return nil
+	}
+	for key := range idx.docs[repo.Name] {
+		if path.Dir(key) == folder {
+			delete(idx.docs[repo.Name], key)
+		}
+	}
+	for key, doc := range docs {
+		idx.docs[repo.Name][key] = doc
+	}
+	return nil
+}
+
+// VerifySearchIndex compares the search index of the repo with the records committed
+// at HEAD, and returns the folder/name of records indexed differently, sorted. Records
+// with uncommitted changes are reported too. The DB must be created WithSearch.
+func (repo *Repo) VerifySearchIndex() ([]string, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	idx := repo.DB.search
+	if idx == nil {
+		return nil, fmt.Errorf("search is not enabled for %s", repo.DB.dir)
+	}
+	if err := idx.ensure(repo); err != nil {
+		return nil, err
+	}
+	idx.flush(repo, "")
+
+	repo.RLock()
+	want, err := headDocs(repo)
+	repo.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	got := idx.docs[repo.Name]
+	diff := []string{}
+	for key, doc := range want {
+		if g := got[key]; g == nil || *g != *doc {
+			diff = append(diff, key)
+		}
+	}
+	for key := range got {
+		if want[key] == nil {
+			diff = append(diff, key)
+		}
+	}
+	sort.Strings(diff)
+	return diff, nil
+}
+
+// searchIndex holds the indexed text of each record, keyed by repo then folder/name
+type searchIndex struct {
+	mu      sync.RWMutex
+	docs    map[string]map[string]*searchDoc
+	indexed map[string]bool
+
+	// with a debounce, records changed since the last update of their folder, keyed by
+	// repo then folder/name, and the timer of each repo/folder
+	debounce time.Duration
+	pending  map[string]map[string]bool
+	timers   map[string]*time.Timer
+}
+
+// searchDoc is the indexed text of a record
+type searchDoc struct {
+	content string
+	meta    string
+}
+
+// ensure indexes the repo if it has not been indexed
+func (idx *searchIndex) ensure(repo *Repo) error {
+	idx.mu.RLock()
+	ok := idx.indexed[repo.Name]
+	idx.mu.RUnlock()
+	if ok {
+		return nil
+	}
+
+	repo.RLock()
+	defer repo.RUnlock()
+	docs := make(map[string]*searchDoc)
+	folders, err := repo.recordFolders()
+	if err != nil {
+		return fmt.Errorf("unable to index %s: %v", repo.Name, err)
+	}
+	for _, folder := range folders {
+		for key, doc := range folderDocs(repo, folder) {
+			docs[key] = doc
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs[repo.Name] = docs
+	idx.indexed[repo.Name] = true
+	return nil
+}
+
+// update applies a commit to the index, the repo lock is held by the committer
+func (idx *searchIndex) update(repo *Repo, ev HookEvent) {
+	if ev.Type != HookCommit && ev.Type != HookCheckout {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if !idx.indexed[repo.Name] {
+		return
+	}
+	if ev.Record == "" || ev.Operation == OpRenameRecord {
+		// commit of unknown or several changes, or a checkout, re-index on next search
+		delete(idx.indexed, repo.Name)
+		delete(idx.docs, repo.Name)
+		return
+	}
+
+	if idx.debounce > 0 {
+		idx.schedule(repo, ev.Record)
+		return
+	}
+
+	folder, name := path.Split(ev.Record)
+	rec := &recordRef{folder: path.Clean(folder), name: name}
+	doc := idx.docs[repo.Name][ev.Record]
+	if doc == nil {
+		doc = &searchDoc{}
+		idx.docs[repo.Name][ev.Record] = doc
+	}
+	switch ev.Operation {
+	case OpWriteFile, OpRemoveFile, OpRevertFile:
+		doc.content = indexContent(repo, rec)
+	case OpWriteMeta, OpRemoveMeta:
+		doc.meta = indexMeta(repo, rec)
+	case OpCopyRecord:
+		doc.content = indexContent(repo, rec)
+		doc.meta = indexMeta(repo, rec)
+	}
+	if doc.content == "" && doc.meta == "" {
+		delete(idx.docs[repo.Name], ev.Record)
+	}
+}
+
+// schedule marks the record for reindexing once its folder has had no commits for the
+// debounce duration. The caller must hold idx.mu.
+func (idx *searchIndex) schedule(repo *Repo, record string) {
+	if idx.pending[repo.Name] == nil {
+		idx.pending[repo.Name] = make(map[string]bool)
+	}
+	idx.pending[repo.Name][record] = true
+
+	folder := path.Dir(record)
+	key := path.Join(repo.Name, folder)
+	if t := idx.timers[key]; t != nil {
+		t.Stop()
+	}
+	idx.timers[key] = time.AfterFunc(idx.debounce, func() {
+		idx.flush(repo, folder)
+	})
+}
+
+// flush reindexes the pending records of the repo in the folder, or all folders if
+// folder is empty
+func (idx *searchIndex) flush(repo *Repo, folder string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for key := range idx.pending[repo.Name] {
+		if folder != "" && path.Dir(key) != folder {
+			continue
+		}
+		delete(idx.pending[repo.Name], key)
+		if !idx.indexed[repo.Name] {
+			continue
+		}
+		dir, name := path.Split(key)
+		rec := &recordRef{folder: path.Clean(dir), name: name}
+		doc := &searchDoc{content: indexContent(repo, rec), meta: indexMeta(repo, rec)}
+		if doc.content == "" && doc.meta == "" {
+			delete(idx.docs[repo.Name], key)
+		} else {
+			idx.docs[repo.Name][key] = doc
+		}
+	}
+}
+
+// remove drops a repo from the index
+func (idx *searchIndex) remove(name string) {
+	if idx == nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.indexed, name)
+	delete(idx.docs, name)
+	delete(idx.pending, name)
+}
+
+// query returns the records of the repo containing all terms
+func (idx *searchIndex) query(repo string, terms []string) []SearchResult {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	results := []SearchResult{}
+	if len(terms) == 0 {
+		return results
+	}
+	for key, doc := range idx.docs[repo] {
+		content, meta := strings.ToLower(doc.content), strings.ToLower(doc.meta)
+		match := true
+		for _, t := range terms {
+			if !strings.Contains(content, t) && !strings.Contains(meta, t) {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		folder, name := path.Split(key)
+		results = append(results, SearchResult{
+			Folder:  path.Clean(folder),
+			Name:    name,
+			Snippet: snippet(doc, terms[0]),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Folder != results[j].Folder {
+			return results[i].Folder < results[j].Folder
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// folderDocs indexes the records of the folder from disk, the caller must hold the repo
+// lock
+func folderDocs(repo *Repo, folder string) map[string]*searchDoc {
+	names, _, _ := repo.readFolder(folder)
+	docs := make(map[string]*searchDoc)
+	for _, name := range names {
+		rec := &recordRef{folder: folder, name: name}
+		docs[path.Join(rec.folder, rec.name)] = &searchDoc{content: indexContent(repo, rec), meta: indexMeta(repo, rec)}
+	}
+	return docs
+}
+
+// headDocs indexes the records committed at HEAD, the caller must hold the repo lock
+func headDocs(repo *Repo) (map[string]*searchDoc, error) {
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.head()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := repo.headTree(r)
+	if err != nil {
+		return nil, err
+	}
+	docs := make(map[string]*searchDoc)
+	doc := func(key string) *searchDoc {
+		if docs[key] == nil {
+			docs[key] = &searchDoc{}
+		}
+		return docs[key]
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		dir, name := path.Split(f.Name)
+		dir = path.Clean(dir)
+		switch {
+		case isRecordPath(f.Name):
+			if f.Size > maxIndexSize {
+				doc(f.Name)
+				return nil
+			}
+			_, b, err := repo.revisionFile(f.Name, HashFromGit(head))
+			if err != nil {
+				return err
+			}
+			if isText(b) {
+				doc(f.Name).content = string(b)
+			}
+		case path.Base(dir) == MetaDir && isRecordPath(path.Join(path.Dir(dir), name)) && strings.HasSuffix(name, repo.DB.metaExt()):
+			b, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			doc(path.Join(path.Dir(dir), strings.TrimSuffix(name, repo.DB.metaExt()))).meta = metaText(repo.DB.MetaCodec(), []byte(b))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read head of %s: %v", repo.Name, err)
+	}
+	for key, d := range docs {
+		if d.content == "" && d.meta == "" {
+			delete(docs, key)
+		}
+	}
+	return docs, nil
+}
+
+// indexContent returns the record file text, or "" if missing, too large or binary.
+// The caller must hold the repo lock.
+func indexContent(repo *Repo, rec Record) string {
+	fi, err := repo.DB.fs.Stat(path.Join(repo.Dir(), rec.Folder(), rec.FileName()))
+	if err != nil || fi.Size() > maxIndexSize {
+		return ""
+	}
+	buf := &bytes.Buffer{}
+	if _, err := repo.readFile(rec, buf); err != nil {
+		return ""
+	}
+	if !isText(buf.Bytes()) {
+		return ""
+	}
+	return buf.String()
+}
+
+// indexMeta returns the string values of the record meta-data, or "" if missing
+func indexMeta(repo *Repo, rec Record) string {
+	b, err := repo.DB.readFile(repo.DB.metaStore(path.Join(repo.Dir(), rec.Folder())).filename(rec.FileName()))
+	if err != nil {
+		return ""
+	}
+	return metaText(repo.DB.MetaCodec(), b)
+}
+
+// metaText returns the string values of the meta-data, or "" if invalid
+func metaText(c MetaCodec, b []byte) string {
+	var m interface{}
+	if err := c.Unmarshal(b, &m); err != nil {
+		return ""
+	}
+	var values []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case map[string]interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case nil:
+		default:
+			b, _ := json.Marshal(v)
+			values = append(values, string(b))
+		}
+	}
+	walk(m)
+	sort.Strings(values)
+	return strings.Join(values, " ")
+}
+
+// tokenize splits the query into lower case terms
+func tokenize(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// snippet returns up to 40 bytes either side of the first occurrence of term
+func snippet(doc *searchDoc, term string) string {
+	for _, text := range []string{doc.content, doc.meta} {
+		i := strings.Index(strings.ToLower(text), term)
+		if i < 0 {
+			continue
+		}
+		start, end := i-40, i+len(term)+40
+		if start < 0 {
+			start = 0
+		}
+		if end > len(text) {
+			end = len(text)
+		}
+		// avoid splitting multi-byte characters
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		return strings.Join(strings.Fields(text[start:end]), " ")
+	}
+	return ""
+}
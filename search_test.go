@@ -0,0 +1,138 @@
+package repodb_test
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_Search(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithSearch())
+	repo := &repodb.Repo{Name: "SearchRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	// indexed on first search
+	if err := repo.WriteFile(&FileRecord{Name: "memo.txt"}, strings.NewReader("Quarterly budget review for the finance team"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteMeta(&FileRecord{Name: "memo.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Search("budget"); err != nil {
+		t.Fatal(err)
+	}
+	// indexed by commit hook
+	if err := repo.WriteFile(&FileRecord{Name: "notes.txt"}, strings.NewReader("Team offsite agenda"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteMeta(&FileRecord{Name: "plan.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "bin.dat"}, strings.NewReader("team\x00budget"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"content", "budget", []string{"memo.txt"}},
+		{"case insensitive", "TEAM", []string{"memo.txt", "notes.txt"}},
+		{"all terms", "team agenda", []string{"notes.txt"}},
+		{"meta-data", "plan.txt", []string{"plan.txt"}},
+		{"no match", "missing", []string{}},
+		{"empty", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := repo.Search(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, r := range results {
+				got = append(got, r.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Repo.Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	results, _ := repo.Search("offsite")
+	if len(results) != 1 || results[0].Snippet != "Team offsite agenda" {
+		t.Errorf("Repo.Search() snippet = %v, want %q", results, "Team offsite agenda")
+	}
+
+	if err := repo.RemoveFile(&FileRecord{Name: "notes.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := repo.Search("agenda"); len(results) != 0 {
+		t.Errorf("Repo.Search() after remove = %v, want none", results)
+	}
+	if _, err := (&repodb.Repo{Name: "SearchRepo", DB: newTestDB(t)}).Search("team"); err == nil {
+		t.Error("Repo.Search() without WithSearch should fail")
+	}
+}
+
+func TestRepo_ReindexFolder(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithSearch(), repodb.WithSearchDebounce(20*time.Millisecond))
+	repo := &repodb.Repo{Name: "ReindexRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Search("budget"); err != nil {
+		t.Fatal(err)
+	}
+	// a burst of writes is searchable before the debounce has passed
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := repo.WriteFile(&FileRecord{Name: name}, strings.NewReader("budget "+name), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if results, err := repo.Search("budget"); err != nil || len(results) != 3 {
+		t.Fatalf("Repo.Search() after burst = %v, error = %v, want 3 results", results, err)
+	}
+	if err := repo.WriteMeta(&FileRecord{Name: "a.txt"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if diff, err := repo.VerifySearchIndex(); err != nil || len(diff) != 0 {
+		t.Fatalf("Repo.VerifySearchIndex() = %v, error = %v, want consistent", diff, err)
+	}
+
+	// changes made without a commit are only indexed by a rebuild
+	if err := ioutil.WriteFile(path.Join(repo.Dir(), "files", "b.txt"), []byte("forecast"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		reindex string
+		want    int
+	}{
+		{"stale", "", 0},
+		{"other folder", "other", 0},
+		{"rebuilt", "files", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.reindex != "" {
+				if err := repo.ReindexFolder(tt.reindex); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if results, err := repo.Search("forecast"); err != nil || len(results) != tt.want {
+				t.Errorf("Repo.Search() = %v, error = %v, want %d results", results, err, tt.want)
+			}
+		})
+	}
+	if diff, err := repo.VerifySearchIndex(); err != nil || strings.Join(diff, ",") != "files/b.txt" {
+		t.Errorf("Repo.VerifySearchIndex() = %v, error = %v, want [files/b.txt]", diff, err)
+	}
+}
@@ -0,0 +1,117 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Snapshot is a named state of a repo, stored as an annotated git tag
+type Snapshot struct {
+	Name    string
+	Message string
+	Commit  Hash // the tagged commit
+	Tagger  string
+	Time    time.Time
+}
+
+// Snapshot marks the current HEAD of the repo with an annotated tag, so a known-good
+// state of its records can be found later, e.g. "release-2024-06". The tagger is the
+// OS user if the DB was created WithOSIdentity, otherwise the repodb signature.
+// Snapshot names are unique within the repo.
+func (repo *Repo) Snapshot(name, message string) error {
+	if !validRefName(name) {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return err
+	}
+
+	opts := CommitOptions{}
+	if err := repo.DB.defaultSignatures(&opts); err != nil {
+		return err
+	}
+	tagger := *opts.Opts.Author
+	tagger.When = time.Now()
+
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return fmt.Errorf("unable to read head of %s: %v", repo.Name, err)
+	}
+	_, err = r.CreateTag(name, head.Hash(), &git.CreateTagOptions{Tagger: &tagger, Message: message, SignKey: opts.Opts.SignKey})
+	if errors.Is(err, git.ErrTagExists) {
+		return fmt.Errorf("snapshot %s already exists in %s", name, repo.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to snapshot %s: %v", repo.Name, err)
+	}
+	repo.DB.debug("created snapshot", "repo", repo.Name, "snapshot", name, "commit", head.Hash().String())
+	return nil
+}
+
+// validRefName reports if the name can be used for a git tag or branch
+func validRefName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n:~^?*[\\") && !strings.Contains(name, "..")
+}
+
+// ListSnapshots returns the snapshots of the repo, oldest first. Lightweight tags are
+// not snapshots and are skipped.
+func (repo *Repo) ListSnapshots() ([]Snapshot, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := r.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list snapshots of %s: %v", repo.Name, err)
+	}
+	snapshots := []Snapshot{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		tag, err := r.TagObject(ref.Hash())
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if tag.TargetType != plumbing.CommitObject {
+			return nil
+		}
+		snapshots = append(snapshots, Snapshot{
+			Name:    tag.Name,
+			Message: strings.TrimSpace(tag.Message),
+			Commit:  HashFromGit(tag.Target),
+			Tagger:  tag.Tagger.Name,
+			Time:    tag.Tagger.When,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list snapshots of %s: %v", repo.Name, err)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+	return snapshots, nil
+}
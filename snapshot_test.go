@@ -0,0 +1,83 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_Snapshot(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "SnapshotRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	write := func(content string) repodb.Hash {
+		t.Helper()
+		if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		head, err := repo.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return head
+	}
+	first := write("1")
+
+	tests := []struct {
+		name     string
+		snapshot string
+		wantErr  bool
+	}{
+		{"normal", "release-2024-06", false},
+		{"exists", "release-2024-06", true},
+		{"empty", "", true},
+		{"space", "release 1", true},
+		{"dots", "release..1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Snapshot(tt.snapshot, "known good"); (err != nil) != tt.wantErr {
+				t.Errorf("Repo.Snapshot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	second := write("2")
+	if err := repo.Snapshot("release-2024-07", "second release"); err != nil {
+		t.Fatal(err)
+	}
+	// lightweight tags are not snapshots
+	r, err := git.PlainOpen(repo.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateTag("lightweight", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := repo.ListSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Repo.ListSnapshots() = %v, want 2 snapshots", snapshots)
+	}
+	for i, want := range []repodb.Snapshot{
+		{Name: "release-2024-06", Message: "known good", Commit: first, Tagger: "repodb"},
+		{Name: "release-2024-07", Message: "second release", Commit: second, Tagger: "repodb"},
+	} {
+		got := snapshots[i]
+		got.Time = want.Time
+		if got != want {
+			t.Errorf("Repo.ListSnapshots()[%d] = %v, want %v", i, got, want)
+		}
+	}
+}
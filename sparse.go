@@ -0,0 +1,208 @@
+package repodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// ErrSparseCheckout is returned when writing to a repo cloned with only some folders
+// checked out. Sparse repos are read-only.
+var ErrSparseCheckout = errors.New("repo is a sparse checkout")
+
+// sparseFile lists the checked out folders of a sparse repo, it is kept in the .git
+// directory so it is never committed itself
+const sparseFile = "repodb-sparse.json"
+
+// CloneOptions configures CloneRepo
+type CloneOptions struct {
+	Depth   int      // number of commits fetched, 0 for the full history
+	Folders []string // record folders checked out, nil for all folders
+	Auth    transport.AuthMethod
+
+	// Backend selects the git backend of the repo, see Repo.SetBackend. Clones with
+	// BackendSystem are made by the system git, unless Auth is set.
+	Backend Backend
+}
+
+// CloneRepo clones the repo at url into the database as name, which should match the
+// name of the source repo. If opts.Folders is set only those record folders, and the
+// repo meta-data, are checked out to save disk space, and ReadFile reads the other
+// folders from the git objects. Sparse repos are read-only, writes return
+// ErrSparseCheckout. Shallow clones, with opts.Depth set, have no history before the
+// fetched commits.
+func (db *RepoDB) CloneRepo(name, url string, opts CloneOptions) (_ *Repo, err error) {
+	defer db.metrics.observe("clone_repo", time.Now(), &err)
+	db.metrics.lock("db", db)
+	defer db.Unlock()
+
+	if name, err = db.cleanName(RepoName, name); err != nil {
+		return nil, err
+	}
+	repo := &Repo{Name: name, DB: db}
+	if repo.Name == "" {
+		return nil, fmt.Errorf("CloneRepo repo name cannot be empty")
+	}
+	if err := db.checkFrozen(); err != nil {
+		return nil, err
+	}
+	if err := db.validateName(RepoName, repo.Name); err != nil {
+		return nil, err
+	}
+	if _, err := db.fs.Stat(repo.Dir()); err == nil {
+		return nil, ErrRepoAlreadyExists
+	}
+
+	var r *git.Repository
+	if opts.Backend == BackendSystem && opts.Auth == nil && db.onOS() && systemGitPath() != "" {
+		r, err = repo.execClone(url, opts)
+	} else {
+		r, err = db.cloneGit(repo.Dir(), &git.CloneOptions{
+			URL:        url,
+			Auth:       opts.Auth,
+			Depth:      opts.Depth,
+			NoCheckout: opts.Folders != nil,
+		})
+	}
+	if err != nil {
+		db.removeAll(repo.Dir())
+		return nil, fmt.Errorf("unable to clone %s: %v", url, err)
+	}
+	defer func() {
+		if err != nil {
+			db.gitCache.remove(repo.Dir())
+			db.removeAll(repo.Dir())
+		}
+	}()
+	if opts.Backend != "" {
+		if err := setBackend(r, opts.Backend); err != nil {
+			return nil, fmt.Errorf("unable to clone %s: %v", url, err)
+		}
+	}
+	if opts.Folders != nil {
+		if err := repo.sparseCheckout(r, opts.Folders); err != nil {
+			return nil, fmt.Errorf("unable to checkout %s: %v", url, err)
+		}
+	}
+	db.gitCache.put(repo.Dir(), r)
+	if err := repo.LoadMeta(repo); err != nil {
+		return nil, fmt.Errorf("unable to clone %s: %v", url, err)
+	}
+	return repo, nil
+}
+
+// sparseCheckout writes the files of the HEAD commit in the folders, and at the root
+// of the repo, to the worktree and records the folders checked out
+func (repo *Repo) sparseCheckout(r *git.Repository, folders []string) error {
+	checkedOut := map[string]bool{MetaDir: true}
+	for _, f := range folders {
+		checkedOut[cleanPath(f)] = true
+	}
+	b, err := json.Marshal(folders)
+	if err != nil {
+		return err
+	}
+	if err := repo.DB.writeFile(repo.sparsePath(), b, 0644); err != nil {
+		return err
+	}
+
+	tree, err := repo.headTree(r)
+	if err != nil {
+		return err
+	}
+	return tree.Files().ForEach(func(f *object.File) error {
+		if i := strings.Index(f.Name, "/"); i >= 0 && !checkedOut[f.Name[:i]] {
+			return nil
+		}
+		return repo.checkoutFile(f)
+	})
+}
+
+// checkoutFile writes the committed file to the worktree
+func (repo *Repo) checkoutFile(f *object.File) error {
+	filename := path.Join(repo.Dir(), f.Name)
+	if err := repo.DB.fs.MkdirAll(path.Dir(filename), 0700); err != nil {
+		return err
+	}
+	rc, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := repo.DB.fs.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// checkSparse returns ErrSparseCheckout if the repo is a sparse checkout
+func (repo *Repo) checkSparse() error {
+	if _, err := repo.DB.fs.Stat(repo.sparsePath()); err == nil {
+		return fmt.Errorf("%w: %s is read-only", ErrSparseCheckout, repo.Name)
+	}
+	return nil
+}
+
+// openBlob opens the record from the HEAD commit of a sparse repo, for records in
+// folders that are not checked out. Returns os.ErrNotExist otherwise.
+func (repo *Repo) openBlob(rec Record) (io.ReadCloser, error) {
+	b, err := repo.DB.readFile(repo.sparsePath())
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+	var folders []string
+	if err := json.Unmarshal(b, &folders); err != nil {
+		return nil, fmt.Errorf("unable to read sparse folders of %s: %v", repo.Name, err)
+	}
+	for _, f := range folders {
+		if cleanPath(f) == rec.Folder() {
+			return nil, os.ErrNotExist
+		}
+	}
+
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := repo.headTree(r)
+	if err != nil {
+		return nil, err
+	}
+	f, err := tree.File(path.Join(rec.Folder(), rec.FileName()))
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, os.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f.Reader()
+}
+
+// headTree returns the tree of the HEAD commit
+func (repo *Repo) headTree(r *git.Repository) (*object.Tree, error) {
+	head, err := r.Head()
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return c.Tree()
+}
+
+func (repo *Repo) sparsePath() string {
+	return path.Join(repo.Dir(), ".git", sparseFile)
+}
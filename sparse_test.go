@@ -0,0 +1,91 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+type otherRecord struct{ Name string }
+
+func (r *otherRecord) FileName() string { return r.Name }
+func (r *otherRecord) Folder() string   { return "other" }
+
+func TestRepoDB_CloneRepo(t *testing.T) {
+	src := newTestDB(t)
+	repo := &repodb.Repo{Name: "SparseRepo", DB: src, Description: "source"}
+	if err := src.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("checked out"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(&otherRecord{Name: "b.txt"}, strings.NewReader("from git"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	url := path.Join(repo.Dir(), ".git")
+
+	db := newTestDB(t)
+	sparse, err := db.CloneRepo("SparseRepo", url, repodb.CloneOptions{Folders: []string{"files"}, Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sparse.Description != "source" {
+		t.Errorf("RepoDB.CloneRepo() Description = %q, want %q", sparse.Description, "source")
+	}
+	if _, err := os.Stat(path.Join(sparse.Dir(), "other")); !os.IsNotExist(err) {
+		t.Errorf("RepoDB.CloneRepo() checked out folder other: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		rec     repodb.Record
+		want    string
+		wantErr bool
+	}{
+		{"checked out", &FileRecord{Name: "a.txt"}, "checked out", false},
+		{"from git", &otherRecord{Name: "b.txt"}, "from git", false},
+		{"missing", &otherRecord{Name: "c.txt"}, "", true},
+		{"missing checked out", &FileRecord{Name: "c.txt"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			_, err := sparse.ReadFile(tt.rec, buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repo.ReadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Repo.ReadFile() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	if err := sparse.WriteFile(&FileRecord{Name: "d.txt"}, strings.NewReader("d"), repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrSparseCheckout) {
+		t.Errorf("Repo.WriteFile() error = %v, want %v", err, repodb.ErrSparseCheckout)
+	}
+	if err := sparse.CommitAll(repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrSparseCheckout) {
+		t.Errorf("Repo.CommitAll() error = %v, want %v", err, repodb.ErrSparseCheckout)
+	}
+
+	// a full clone is writable
+	full, err := db.CloneRepo("FullRepo", url, repodb.CloneOptions{})
+	if err == nil {
+		t.Errorf("RepoDB.CloneRepo() with mismatched name expected error, got %s", full.Name)
+	}
+	if _, err := os.Stat(path.Join(db.Dir(), "FullRepo")); !os.IsNotExist(err) {
+		t.Errorf("RepoDB.CloneRepo() left failed clone: %v", err)
+	}
+	full, err = repodb.NewDB(newTestDB(t).Dir()).CloneRepo("SparseRepo", url, repodb.CloneOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := full.WriteFile(&FileRecord{Name: "d.txt"}, strings.NewReader("d"), repodb.DBRepoCommitOptions); err != nil {
+		t.Errorf("Repo.WriteFile() to full clone error = %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+// Package sqlindex mirrors the meta-data of a repodb database into a SQLite file, for
+// sorted and filtered listings and joins across records that the json file per record
+// layout cannot provide. The index is kept up to date by a commit hook, and can be
+// rebuilt from the repos at any time.
+//
+// Meta-data is stored as json in the meta column of the repos and records tables, and
+// can be queried with the SQLite json functions:
+//
+//	SELECT name FROM records
+//	WHERE repo = ? AND folder = 'files' AND json_extract(meta, '$.SoftDeleted') = 0
+//	ORDER BY json_extract(meta, '$.updated_on') DESC
+package sqlindex
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/readpe/repodb"
+
+	// SQLite driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// FileName is the name of the index file in the database directory. Names starting
+// with a dot are not treated as repos.
+const FileName = ".repodb-index.sqlite"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS repos (
+	name TEXT PRIMARY KEY,
+	meta TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS records (
+	repo   TEXT NOT NULL,
+	folder TEXT NOT NULL,
+	name   TEXT NOT NULL,
+	meta   TEXT NOT NULL,
+	PRIMARY KEY (repo, folder, name)
+);`
+
+// Index is a SQLite index of the repo and record meta-data in a RepoDB
+type Index struct {
+	db  *repodb.RepoDB
+	sql *sql.DB
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []repodb.HookEvent
+	busy    bool
+	closed  bool
+	err     error
+	done    chan struct{}
+}
+
+// Open opens or creates the index in the database directory, rebuilds it from the
+// repos and registers a hook keeping it up to date. Close the index when done.
+func Open(db *repodb.RepoDB) (*Index, error) {
+	s, err := sql.Open("sqlite3", path.Join(db.Dir(), FileName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open index: %v", err)
+	}
+	// a single connection serializes writes from the index and reads from Query
+	s.SetMaxOpenConns(1)
+	if _, err := s.Exec(schema); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("unable to create index: %v", err)
+	}
+
+	ix := &Index{db: db, sql: s, done: make(chan struct{})}
+	ix.cond = sync.NewCond(&ix.mu)
+	if err := ix.Rebuild(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	go ix.run()
+	db.AddHook(ix.hook)
+	return ix, nil
+}
+
+// Query runs a SQL query against the index once all pending commits are indexed
+func (ix *Index) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if err := ix.Flush(); err != nil {
+		return nil, err
+	}
+	return ix.sql.Query(query, args...)
+}
+
+// Flush waits for all pending commits to be indexed. Returns the first error from
+// indexing since the last Flush, after which the index may be stale until Rebuild.
+func (ix *Index) Flush() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for len(ix.pending) > 0 || ix.busy {
+		ix.cond.Wait()
+	}
+	err := ix.err
+	ix.err = nil
+	return err
+}
+
+// Rebuild replaces the index contents with the meta-data of all repos in the database,
+// picking up changes made outside of this RepoDB and removed repos.
+func (ix *Index) Rebuild() error {
+	repos, err := ix.db.ListReposPage(repodb.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to rebuild index: %v", err)
+	}
+	tx, err := ix.sql.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to rebuild index: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM repos; DELETE FROM records`); err != nil {
+		return fmt.Errorf("unable to rebuild index: %v", err)
+	}
+	for _, repo := range repos {
+		if err := indexRepo(tx, repo); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close stops indexing and closes the index file. Commits after Close are not indexed.
+func (ix *Index) Close() error {
+	ix.mu.Lock()
+	if ix.closed {
+		ix.mu.Unlock()
+		return nil
+	}
+	ix.closed = true
+	ix.cond.Broadcast()
+	ix.mu.Unlock()
+	<-ix.done
+	return ix.sql.Close()
+}
+
+// hook queues commits for indexing. It runs with the repo lock held, so the meta-data
+// is read by run once the commit completes.
+func (ix *Index) hook(ev repodb.HookEvent) {
+	if ev.Type != repodb.HookCommit && ev.Type != repodb.HookCheckout {
+		return
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return
+	}
+	ix.pending = append(ix.pending, ev)
+	ix.cond.Broadcast()
+}
+
+// run indexes queued commits until the index is closed
+func (ix *Index) run() {
+	defer close(ix.done)
+	for {
+		ix.mu.Lock()
+		for len(ix.pending) == 0 && !ix.closed {
+			ix.cond.Wait()
+		}
+		if ix.closed {
+			ix.mu.Unlock()
+			return
+		}
+		events := ix.pending
+		ix.pending = nil
+		ix.busy = true
+		ix.mu.Unlock()
+
+		err := ix.update(events)
+
+		ix.mu.Lock()
+		if ix.err == nil {
+			ix.err = err
+		}
+		ix.busy = false
+		ix.cond.Broadcast()
+		ix.mu.Unlock()
+	}
+}
+
+// update indexes the changed records of each commit. Commits without a record, such as
+// CommitAll, renames and branch checkouts re-index the whole repo.
+func (ix *Index) update(events []repodb.HookEvent) error {
+	tx, err := ix.sql.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to update index: %v", err)
+	}
+	defer tx.Rollback()
+	for _, ev := range events {
+		repo, err := ix.db.OpenRepo(ev.Repo)
+		if errors.Is(err, repodb.ErrRepoNotExists) {
+			if _, err := tx.Exec(`DELETE FROM repos WHERE name = ?; DELETE FROM records WHERE repo = ?`, ev.Repo, ev.Repo); err != nil {
+				return fmt.Errorf("unable to update index: %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to update index for %s: %v", ev.Repo, err)
+		}
+		if ev.Operation == repodb.OpRenameRepo {
+			if err := ix.prune(tx); err != nil {
+				return err
+			}
+		}
+		if ev.Record == "" || ev.Operation == repodb.OpRenameRepo || ev.Operation == repodb.OpRenameRecord {
+			err = indexRepo(tx, repo)
+		} else {
+			folder, name := path.Split(ev.Record)
+			err = indexRecord(tx, repo, path.Clean(folder), name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// prune removes indexed repos which no longer exist, such as the previous name of a
+// renamed repo
+func (ix *Index) prune(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT name FROM repos`)
+	if err != nil {
+		return fmt.Errorf("unable to update index: %v", err)
+	}
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("unable to update index: %v", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	for _, name := range names {
+		if _, err := ix.db.OpenRepo(name); !errors.Is(err, repodb.ErrRepoNotExists) {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM repos WHERE name = ?; DELETE FROM records WHERE repo = ?`, name, name); err != nil {
+			return fmt.Errorf("unable to update index: %v", err)
+		}
+	}
+	return nil
+}
+
+// indexRepo replaces the indexed meta-data of the repo and all of its records
+func indexRepo(tx *sql.Tx, repo *repodb.Repo) error {
+	b, err := json.Marshal(repo)
+	if err != nil {
+		return fmt.Errorf("unable to index %s: %v", repo.Name, err)
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO repos (name, meta) VALUES (?, ?)`, repo.Name, string(b)); err != nil {
+		return fmt.Errorf("unable to index %s: %v", repo.Name, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM records WHERE repo = ?`, repo.Name); err != nil {
+		return fmt.Errorf("unable to index %s: %v", repo.Name, err)
+	}
+
+	records, err := repo.ListRecords("", true)
+	if err != nil {
+		return fmt.Errorf("unable to index %s: %v", repo.Name, err)
+	}
+	for _, record := range records {
+		folder, name := path.Split(record)
+		if err := indexRecord(tx, repo, path.Clean(folder), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexRecord replaces the indexed meta-data of the record, removing it from the index
+// if the record has no meta-data. Meta-data is indexed as compact json whatever the
+// codec of the database.
+func indexRecord(tx *sql.Tx, repo *repodb.Repo, folder, name string) error {
+	rec := &metaRecord{folder: folder, name: name}
+	err := repo.LoadMeta(rec)
+	if errors.Is(err, repodb.ErrMetaNotExists) {
+		_, err = tx.Exec(`DELETE FROM records WHERE repo = ? AND folder = ? AND name = ?`, repo.Name, folder, name)
+	} else if err == nil {
+		m := repodb.Meta{}
+		if err = repo.DB.MetaCodec().Unmarshal(rec.b, &m); err == nil {
+			var b []byte
+			if b, err = json.Marshal(m); err == nil {
+				_, err = tx.Exec(`INSERT OR REPLACE INTO records (repo, folder, name, meta) VALUES (?, ?, ?, ?)`,
+					repo.Name, folder, name, string(b))
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to index %s: %v", path.Join(repo.Name, folder, name), err)
+	}
+	return nil
+}
+
+// metaRecord is a record whose meta-data is loaded encoded, read through the database
+// like any other so bare repos and custom filesystems are indexed
+type metaRecord struct {
+	folder, name string
+	b            []byte
+}
+
+func (r *metaRecord) Folder() string               { return r.folder }
+func (r *metaRecord) FileName() string             { return r.name }
+func (r *metaRecord) UnmarshalMeta(b []byte) error { r.b = b; return nil }
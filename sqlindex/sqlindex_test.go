@@ -0,0 +1,136 @@
+package sqlindex_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+	"github.com/readpe/repodb/repodbtest"
+	"github.com/readpe/repodb/sqlindex"
+)
+
+type doc struct {
+	Name      string
+	Author    string
+	UpdatedOn time.Time
+}
+
+func (d *doc) FileName() string { return d.Name }
+func (d *doc) Folder() string   { return "docs" }
+
+func names(t *testing.T, ix *sqlindex.Index, query string, args ...interface{}) string {
+	t.Helper()
+	rows, err := ix.Query(query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(got, ",")
+}
+
+func TestIndex(t *testing.T) {
+	db := repodbtest.NewDB(t)
+	repo := &repodb.Repo{Name: "IndexRepo", DB: db, Description: "indexed"}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	// written before the index is opened, picked up by the initial rebuild
+	if err := repo.WriteMeta(&doc{Name: "a", Author: "ann", UpdatedOn: now}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	ix, err := sqlindex.Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+
+	for _, d := range []*doc{
+		{Name: "b", Author: "bob", UpdatedOn: now.Add(-time.Hour)},
+		{Name: "c", Author: "ann", UpdatedOn: now.Add(time.Hour)},
+		{Name: "d", Author: "bob", UpdatedOn: now},
+	} {
+		if err := repo.WriteMeta(d, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.RemoveMeta(&doc{Name: "d"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		args  []interface{}
+		want  string
+	}{
+		{"sorted", `SELECT name FROM records WHERE repo = ? AND folder = 'docs' ORDER BY json_extract(meta, '$.UpdatedOn')`, []interface{}{"IndexRepo"}, "b,a,c"},
+		{"filtered", `SELECT name FROM records WHERE json_extract(meta, '$.Author') = ? ORDER BY name`, []interface{}{"ann"}, "a,c"},
+		{"join", `SELECT r.name FROM records r JOIN repos p ON p.name = r.repo WHERE json_extract(p.meta, '$.Description') = 'indexed' AND r.folder = 'docs' ORDER BY r.name DESC`, nil, "c,b,a"},
+		{"repos", `SELECT name FROM repos`, nil, "IndexRepo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(t, ix, tt.query, tt.args...); got != tt.want {
+				t.Errorf("Index.Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	repo, err = db.RenameRepo(repo.Name, "RenamedRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(t, ix, `SELECT DISTINCT repo FROM records`); got != "RenamedRepo" {
+		t.Errorf("Index.Query() after RenameRepo = %v, want RenamedRepo", got)
+	}
+
+	if err := db.RemoveRepo(repo.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(t, ix, `SELECT name FROM records`); got != "" {
+		t.Errorf("Index.Query() after Rebuild = %v, want none", got)
+	}
+}
+
+func TestIndex_bare(t *testing.T) {
+	db := repodb.NewDB(repodbtest.TempDir(t), repodb.WithBareRepos())
+	repo := &repodb.Repo{Name: "BareRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteMeta(&doc{Name: "a", Author: "ann"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	ix, err := sqlindex.Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	if err := repo.WriteMeta(&doc{Name: "b", Author: "bob"}, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(t, ix, `SELECT json_extract(meta, '$.Author') FROM records WHERE repo = ? ORDER BY name`, "BareRepo"); got != "ann,bob" {
+		t.Errorf("Index.Query() = %v, want ann,bob", got)
+	}
+}
@@ -0,0 +1,117 @@
+package repodb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// OpSquashHistory is the operation of the baseline commit made by Repo.SquashHistory
+const OpSquashHistory = "squash_history"
+
+// SquashOptions selects the commits kept by SquashHistory, a commit is kept if either
+// option keeps it and so are all commits after it
+type SquashOptions struct {
+	KeepSince time.Time // keep commits made at or after the time
+	KeepLast  int       // keep the last n commits
+}
+
+// SquashHistory rewrites the history of the repo, collapsing the commits older than
+// those kept into a single baseline commit with the records as they were, and returns
+// the number of commits collapsed. Kept commits are recreated on the baseline with
+// their authors and messages, but lose any merged parents, and their PGP signatures
+// unless the DB signs its commits WithSignKey. Returns ErrLegalHold if the repo has
+// records under legal hold. Snapshots and other refs keep the old commits, run GC to
+// reclaim the space of those no longer referenced.
+func (repo *Repo) SquashHistory(opts SquashOptions) (squashed int, err error) {
+	defer repo.DB.metrics.observe("squash_history", time.Now(), &err)
+	if err := repo.authorize(ActionWrite, nil); err != nil {
+		return 0, err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	defer repo.stageBare()()
+	if err := repo.prepareWrite(); err != nil {
+		return 0, err
+	}
+
+	r, err := repo.git()
+	if err != nil {
+		return 0, err
+	}
+	head, err := repo.head()
+	if err != nil {
+		return 0, err
+	}
+
+	// first parent history, newest first
+	var chain []*object.Commit
+	for c, err := r.CommitObject(head); ; c, err = c.Parent(0) {
+		if err != nil {
+			return 0, fmt.Errorf("unable to read history of %s: %v", repo.Name, err)
+		}
+		chain = append(chain, c)
+		if c.NumParents() == 0 {
+			break
+		}
+	}
+	keep := 0
+	for keep < len(chain) && (keep < opts.KeepLast || !opts.KeepSince.IsZero() && !chain[keep].Committer.When.Before(opts.KeepSince)) {
+		keep++
+	}
+	if len(chain)-keep <= 1 {
+		return 0, nil
+	}
+
+	holds, err := repo.holds()
+	if err != nil {
+		return 0, err
+	}
+	if len(holds) > 0 {
+		return 0, fmt.Errorf("%w: squashing history of %s", ErrLegalHold, repo.Name)
+	}
+
+	base := chain[keep]
+	squashed = len(chain) - keep
+	hash, err := storeCommit(r.Storer, &object.Commit{
+		Author:    base.Author,
+		Committer: base.Committer,
+		Message:   fmt.Sprintf("squashed %d commits\n\n%s", squashed, trailers(OpSquashHistory, "", "")),
+		TreeHash:  base.TreeHash,
+	}, repo.DB.signKey)
+	if err != nil {
+		return 0, err
+	}
+	for i := keep - 1; i >= 0; i-- {
+		c := chain[i]
+		hash, err = storeCommit(r.Storer, &object.Commit{
+			Author:       c.Author,
+			Committer:    c.Committer,
+			Message:      c.Message,
+			TreeHash:     c.TreeHash,
+			ParentHashes: []plumbing.Hash{hash},
+		}, repo.DB.signKey)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	ref, err := r.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return 0, err
+	}
+	name := plumbing.HEAD
+	if ref.Type() == plumbing.SymbolicReference {
+		name = ref.Target()
+	}
+	if err := r.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+		return 0, err
+	}
+	if err := repo.heartbeat(r, hash); err != nil {
+		return 0, err
+	}
+	repo.DB.debug("squashed history", "repo", repo.Name, "commits", squashed)
+	return squashed, nil
+}
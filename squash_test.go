@@ -0,0 +1,100 @@
+package repodb_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_SquashHistory(t *testing.T) {
+	tests := []struct {
+		name string
+		db   []repodb.Option
+		opts repodb.SquashOptions
+		want int // commits squashed of 6, the create commit and 5 writes
+	}{
+		{"keep last", nil, repodb.SquashOptions{KeepLast: 2}, 4},
+		{"keep none", nil, repodb.SquashOptions{}, 6},
+		{"keep all", nil, repodb.SquashOptions{KeepLast: 6}, 0},
+		{"keep since", nil, repodb.SquashOptions{KeepSince: time.Now().Add(time.Hour)}, 6},
+		{"bare", []repodb.Option{repodb.WithBareRepos()}, repodb.SquashOptions{KeepLast: 3}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), tt.db...)
+			repo := &repodb.Repo{Name: "Squashed", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i++ {
+				opts := repodb.DBRepoCommitOptions
+				opts.Msg = fmt.Sprintf("write %d", i)
+				if err := repo.WriteFile(&FileRecord{Name: fmt.Sprintf("%d.txt", i)}, strings.NewReader("data"), opts); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before, err := repo.RecentActivity(100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(before) != 6 {
+				t.Fatalf("commits before = %d, want 6", len(before))
+			}
+
+			got, err := repo.SquashHistory(tt.opts)
+			if err != nil || got != tt.want {
+				t.Fatalf("Repo.SquashHistory() = %d, error = %v, want %d", got, err, tt.want)
+			}
+			after, err := repo.RecentActivity(100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantLen := 6
+			if tt.want > 0 {
+				wantLen = 6 - tt.want + 1
+				if last := after[len(after)-1]; last.Operation != repodb.OpSquashHistory {
+					t.Errorf("baseline commit = %+v, want %s", last, repodb.OpSquashHistory)
+				}
+			}
+			if len(after) != wantLen {
+				t.Fatalf("commits after = %d, want %d", len(after), wantLen)
+			}
+			for i := 0; i < wantLen-1 && tt.want > 0; i++ {
+				if after[i].Message != before[i].Message {
+					t.Errorf("kept commit %d message = %q, want %q", i, after[i].Message, before[i].Message)
+				}
+			}
+			for i := 0; i < 5; i++ {
+				buf := &strings.Builder{}
+				if _, err := repo.ReadFile(&FileRecord{Name: fmt.Sprintf("%d.txt", i)}, buf); err != nil || buf.String() != "data" {
+					t.Errorf("Repo.ReadFile() after squash = %q, error = %v", buf, err)
+				}
+			}
+			if err := repo.WriteFile(&FileRecord{Name: "new.txt"}, strings.NewReader("new"), repodb.DBRepoCommitOptions); err != nil {
+				t.Errorf("Repo.WriteFile() after squash error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRepo_SquashHistory_legalHold(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "HeldHistory", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "held.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("evidence"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.LegalHold(fr, "case-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SquashHistory(repodb.SquashOptions{}); !errors.Is(err, repodb.ErrLegalHold) {
+		t.Errorf("Repo.SquashHistory() error = %v, want %v", err, repodb.ErrLegalHold)
+	}
+}
@@ -0,0 +1,232 @@
+package repodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Stats is a snapshot of repo usage. Snapshots are written to the same record by
+// WriteStats, so the git log of stats/stats.json is the usage history of the repo.
+type Stats struct {
+	Repo    string    `json:"repo"`
+	Time    time.Time `json:"time"`
+	Size    int64     `json:"size"`    // bytes of record files, excluding meta-data
+	Records int       `json:"records"` // number of record files
+	Folders int       `json:"folders"` // number of record folders
+	Commits int       `json:"commits"` // number of commits on HEAD
+	Growth  int64     `json:"growth"`  // change in Size since the previous snapshot
+
+	FolderRecords map[string]int `json:"folder_records"` // number of record files per folder
+	DiskSize      int64          `json:"disk_size"`      // bytes of the repo directory, including git objects
+	Objects       int            `json:"objects"`        // number of git objects
+	LastCommit    time.Time      `json:"last_commit"`    // time of the HEAD commit
+}
+
+// FileName is the stats snapshot file. Implements Record interface
+func (s *Stats) FileName() string {
+	return "stats.json"
+}
+
+// Folder is the stats folder, it is excluded from the counts. Implements Record interface
+func (s *Stats) Folder() string {
+	return "stats"
+}
+
+// WriteStats computes a usage snapshot and commits it to the stats record, returning
+// the snapshot.
+func (repo *Repo) WriteStats(opts CommitOptions) (*Stats, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	stats, err := repo.stats()
+	if err != nil {
+		return nil, err
+	}
+
+	// growth is relative to the last committed snapshot, if any
+	prev := &Stats{}
+	buf := &bytes.Buffer{}
+	if _, err := repo.ReadFile(prev, buf); err == nil && json.Unmarshal(buf.Bytes(), prev) == nil {
+		stats.Growth = stats.Size - prev.Size
+	}
+
+	b, err := json.MarshalIndent(stats, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	opts.Msg = fmt.Sprintf("%s\n\nstats snapshot: %d records, %d bytes", opts.Msg, stats.Records, stats.Size)
+	if err := repo.WriteFile(stats, bytes.NewReader(b), opts); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Stats computes the current usage of the repo, without writing a snapshot
+func (repo *Repo) Stats() (*Stats, error) {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return nil, err
+	}
+	return repo.stats()
+}
+
+// StartStats writes a stats snapshot every interval until ctx is done. Errors are
+// logged to the DB logger and do not stop the schedule.
+func (repo *Repo) StartStats(ctx context.Context, interval time.Duration, opts CommitOptions) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if _, err := repo.WriteStats(opts); err != nil {
+					repo.DB.warn("unable to write stats snapshot", "repo", repo.Name, "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// stats computes the current usage of the repo
+func (repo *Repo) stats() (*Stats, error) {
+	repo.RLock()
+	defer repo.RUnlock()
+
+	stats, err := repo.usage()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := repo.git()
+	if err != nil {
+		return nil, err
+	}
+	if stats.DiskSize, err = repo.DB.dirSize(repo.Dir()); err != nil {
+		return nil, fmt.Errorf("unable to compute usage for %s: %v", repo.Name, err)
+	}
+	objects, err := r.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return nil, err
+	}
+	err = objects.ForEach(func(plumbing.EncodedObject) error {
+		stats.Objects++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Head(); err != nil {
+		// no commits yet
+		return stats, nil
+	}
+	iter, err := r.Log(&git.LogOptions{})
+	if err != nil {
+		return nil, err
+	}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if stats.Commits == 0 {
+			stats.LastCommit = c.Committer.When
+		}
+		stats.Commits++
+		return nil
+	})
+	return stats, err
+}
+
+// usage computes the record file counts and size of the repo, the caller must hold
+// the repo lock.
+func (repo *Repo) usage() (*Stats, error) {
+	stats := &Stats{Repo: repo.Name, Time: time.Now(), FolderRecords: map[string]int{}}
+	if repo.isBare() {
+		if err := repo.bareUsage(stats); err != nil {
+			return nil, fmt.Errorf("unable to compute usage for %s: %v", repo.Name, err)
+		}
+		return stats, nil
+	}
+	root := repo.Dir()
+	err := repo.DB.walk(root, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := relPath(root, name)
+		depth := strings.Count(rel, "/") + 1
+		switch {
+		case rel == ".":
+			return nil
+		case fi.IsDir() && (fi.Name() == ".git" || fi.Name() == MetaDir || rel == (&Stats{}).Folder()):
+			return filepath.SkipDir
+		case fi.IsDir():
+			stats.Folders++
+		case depth > 1:
+			stats.Records++
+			stats.FolderRecords[path.Dir(rel)]++
+			stats.Size += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute usage for %s: %v", repo.Name, err)
+	}
+	return stats, nil
+}
+
+// bareUsage is usage for bare repos, counting the record files of the HEAD commit. The
+// caller must hold the repo lock.
+func (repo *Repo) bareUsage(stats *Stats) error {
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	tree, err := repo.headTree(r)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// no commits yet
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var walk func(dir string, t *object.Tree) error
+	walk = func(dir string, t *object.Tree) error {
+		for i := range t.Entries {
+			e := &t.Entries[i]
+			name := path.Join(dir, e.Name)
+			switch {
+			case e.Mode == filemode.Dir && (e.Name == MetaDir || name == (&Stats{}).Folder()):
+			case e.Mode == filemode.Dir:
+				stats.Folders++
+				sub, err := t.Tree(e.Name)
+				if err != nil {
+					return err
+				}
+				if err := walk(name, sub); err != nil {
+					return err
+				}
+			case dir != "":
+				f, err := t.TreeEntryFile(e)
+				if err != nil {
+					return err
+				}
+				stats.Records++
+				stats.FolderRecords[dir]++
+				stats.Size += f.Size
+			}
+		}
+		return nil
+	}
+	return walk("", tree)
+}
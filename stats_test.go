@@ -0,0 +1,82 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_WriteStats(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "StatsRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fr := &FileRecord{Name: name}
+		if err := repo.WriteFile(fr, strings.NewReader("12345"), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := repo.WriteStats(repodb.DBRepoCommitOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := repodb.Stats{Repo: "StatsRepo", Size: 10, Records: 2, Folders: 1, Commits: 5, Growth: 0}
+	if stats.Repo != want.Repo || stats.Size != want.Size || stats.Records != want.Records ||
+		stats.Folders != want.Folders || stats.Commits != want.Commits || stats.Growth != want.Growth {
+		t.Errorf("Repo.WriteStats() = %+v, want %+v", *stats, want)
+	}
+
+	if err := repo.WriteFile(&FileRecord{Name: "c.txt"}, strings.NewReader("123"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = repo.WriteStats(repodb.DBRepoCommitOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Growth != 3 || stats.Records != 3 {
+		t.Errorf("Repo.WriteStats() second snapshot = %+v, want Growth 3 and Records 3", *stats)
+	}
+}
+
+func TestRepo_Stats(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "RepoStats", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	for _, rec := range []repodb.Record{&FileRecord{Name: "a.txt"}, &FileRecord{Name: "b.txt"}, &otherRecord{Name: "c.txt"}} {
+		if err := repo.WriteFile(rec, strings.NewReader("12345"), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := repo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != 3 || stats.FolderRecords["files"] != 2 || stats.FolderRecords["other"] != 1 {
+		t.Errorf("Repo.Stats() records = %d, %v, want 3, files 2 and other 1", stats.Records, stats.FolderRecords)
+	}
+	if stats.Commits != 4 {
+		t.Errorf("Repo.Stats() Commits = %d, want 4", stats.Commits)
+	}
+	// each commit writes at least a commit, tree and blob object
+	if stats.Objects < 3*stats.Commits {
+		t.Errorf("Repo.Stats() Objects = %d, want at least %d", stats.Objects, 3*stats.Commits)
+	}
+	if stats.DiskSize <= stats.Size {
+		t.Errorf("Repo.Stats() DiskSize = %d, want more than Size %d", stats.DiskSize, stats.Size)
+	}
+	if stats.LastCommit.Before(before.Truncate(time.Second)) {
+		t.Errorf("Repo.Stats() LastCommit = %v, want after %v", stats.LastCommit, before)
+	}
+}
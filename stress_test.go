@@ -0,0 +1,188 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/readpe/repodb"
+)
+
+var stress = flag.Bool("stress", false, "run the concurrency stress tests, e.g. go test -race -run Stress -stress")
+
+// stress test sizes, per repo
+const (
+	stressRepos   = 4
+	stressWriters = 4
+	stressReaders = 4
+	stressOps     = 40
+	stressRecords = 5 // per writer
+)
+
+// TestStress runs concurrent writers, readers and removers across several repos, then
+// checks each repo history is linear with one commit per successful change, and every
+// record file has meta-data and vice versa. Each writer owns its records, so the final
+// state of every record is known.
+func TestStress(t *testing.T) {
+	if !*stress {
+		t.Skip("stress tests not enabled, run with -stress")
+	}
+	db := newTestDB(t)
+	seed := time.Now().UnixNano()
+	t.Logf("seed %d", seed)
+
+	var wg sync.WaitGroup
+	for i := 0; i < stressRepos; i++ {
+		repo := &repodb.Repo{Name: fmt.Sprintf("StressRepo%d", i), DB: db}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		commits := int64(1) // repo meta-data
+		done := make(chan struct{})
+		var workers sync.WaitGroup
+		for w := 0; w < stressWriters; w++ {
+			workers.Add(1)
+			go func(w int) {
+				defer workers.Done()
+				stressWriter(t, repo, w, rand.New(rand.NewSource(seed+int64(i*stressWriters+w))), &commits)
+			}(w)
+		}
+		for r := 0; r < stressReaders; r++ {
+			wg.Add(1)
+			go func(r int) {
+				defer wg.Done()
+				stressReader(t, repo, rand.New(rand.NewSource(seed-int64(i*stressReaders+r))), done)
+			}(r)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workers.Wait()
+			close(done)
+			checkHistory(t, repo, atomic.LoadInt64(&commits))
+			checkConsistency(t, repo)
+		}()
+	}
+	wg.Wait()
+}
+
+// stressWriter randomly writes and removes its own records, counting the commits
+func stressWriter(t *testing.T, repo *repodb.Repo, w int, rnd *rand.Rand, commits *int64) {
+	exists := map[string]bool{}
+	for op := 0; op < stressOps; op++ {
+		fr := &FileRecord{Name: fmt.Sprintf("w%d-r%d.txt", w, rnd.Intn(stressRecords)), UpdatedOn: time.Now()}
+		if exists[fr.Name] && rnd.Intn(3) == 0 {
+			if err := repo.RemoveFile(fr, repodb.DBRepoCommitOptions); err != nil {
+				t.Errorf("%s RemoveFile(%s) error = %v", repo.Name, fr.Name, err)
+				return
+			}
+			if err := repo.RemoveMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+				t.Errorf("%s RemoveMeta(%s) error = %v", repo.Name, fr.Name, err)
+				return
+			}
+			exists[fr.Name] = false
+			atomic.AddInt64(commits, 2)
+			continue
+		}
+		content := strings.Repeat(fmt.Sprintf("%s:%d\n", fr.Name, op), 1+rnd.Intn(100))
+		if err := repo.WriteFile(fr, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Errorf("%s WriteFile(%s) error = %v", repo.Name, fr.Name, err)
+			return
+		}
+		if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+			t.Errorf("%s WriteMeta(%s) error = %v", repo.Name, fr.Name, err)
+			return
+		}
+		exists[fr.Name] = true
+		atomic.AddInt64(commits, 2)
+	}
+}
+
+// stressReader reads random records until done, checking no partial writes are seen
+func stressReader(t *testing.T, repo *repodb.Repo, rnd *rand.Rand, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		fr := &FileRecord{Name: fmt.Sprintf("w%d-r%d.txt", rnd.Intn(stressWriters), rnd.Intn(stressRecords))}
+		buf := &bytes.Buffer{}
+		_, err := repo.ReadFile(fr, buf)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s ReadFile(%s) error = %v", repo.Name, fr.Name, err)
+			return
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		for _, l := range lines {
+			if l != lines[0] || !strings.HasPrefix(l, fr.Name+":") {
+				t.Errorf("%s ReadFile(%s) read partial write %q", repo.Name, fr.Name, buf.String())
+				return
+			}
+		}
+	}
+}
+
+// checkHistory checks the repo history is linear with the expected number of commits
+func checkHistory(t *testing.T, repo *repodb.Repo, want int64) {
+	r, err := git.PlainOpen(repo.Dir())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	iter, err := r.Log(&git.LogOptions{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var got int64
+	err = iter.ForEach(func(c *object.Commit) error {
+		got++
+		if c.NumParents() > 1 {
+			return fmt.Errorf("commit %s has %d parents", c.Hash, c.NumParents())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("%s history is not linear: %v", repo.Name, err)
+	}
+	if got != want {
+		t.Errorf("%s has %d commits, want %d", repo.Name, got, want)
+	}
+}
+
+// checkConsistency checks every record file has meta-data and vice versa
+func checkConsistency(t *testing.T, repo *repodb.Repo) {
+	names := map[string]int{}
+	files, _ := ioutil.ReadDir(path.Join(repo.Dir(), "files"))
+	for _, f := range files {
+		if !f.IsDir() {
+			names[f.Name()]++
+		}
+	}
+	metas, _ := ioutil.ReadDir(path.Join(repo.Dir(), "files", repodb.MetaDir))
+	for _, m := range metas {
+		names[strings.TrimSuffix(m.Name(), ".json")]--
+	}
+	for name, n := range names {
+		if n != 0 {
+			t.Errorf("%s record %s file and meta-data are inconsistent", repo.Name, name)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ErrExternalModification is returned in strict integrity mode when a repo has been
+// changed outside of repodb since it was last written.
+var ErrExternalModification = errors.New("repo modified outside of repodb")
+
+// ErrRepoDirty is the ErrExternalModification of a worktree with uncommitted changes
+var ErrRepoDirty error = &errorKind{"repo has uncommitted changes", ErrExternalModification}
+
+// heartbeatRef tracks the last commit made by repodb to a repo
+const heartbeatRef = plumbing.ReferenceName("refs/repodb/heartbeat")
+
+// WithStrictIntegrity makes write operations fail with ErrExternalModification if the
+// repo HEAD has moved, or ErrRepoDirty if the worktree has uncommitted changes, since the
+// last commit made by repodb. For applications that must be the sole writer of the database.
+func WithStrictIntegrity() Option {
+	return func(db *RepoDB) {
+		db.strict = true
+	}
+}
+
+// prepareWrite checks and prepares the repo for a write. It verifies the database is
+// not frozen and the repo has not been modified externally in strict mode, then loads
+// the meta-data of a lazily opened repo, as writes depend on it. The caller must hold
+// the repo lock. Repos without a heartbeat, written before strict mode was enabled,
+// are trusted from their current HEAD.
+func (repo *Repo) prepareWrite() error {
+	if err := repo.DB.checkFrozen(); err != nil {
+		return err
+	}
+	if err := repo.checkSparse(); err != nil {
+		return err
+	}
+	if err := repo.checkBare(); err != nil {
+		return err
+	}
+	if err := repo.ensureMeta(); err != nil {
+		return err
+	}
+	if !repo.DB.strict {
+		return nil
+	}
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		// no commits yet
+	case err != nil:
+		return err
+	default:
+		hb, err := r.Reference(heartbeatRef, false)
+		switch {
+		case errors.Is(err, plumbing.ErrReferenceNotFound):
+			if err := repo.heartbeat(r, head.Hash()); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case hb.Hash() != head.Hash():
+			return fmt.Errorf("%w: %s head is %s, last written %s", ErrExternalModification, repo.Name, head.Hash(), hb.Hash())
+		}
+	}
+
+	// writes kept in degraded mode are expected to be uncommitted
+	if incidents, err := repo.incidents(); err != nil || len(incidents) > 0 {
+		return err
+	}
+	// as are deferred writes until flushed
+	if repo.DB.deferCommits {
+		return nil
+	}
+	if repo.staged != nil {
+		// bare repo, there is no worktree to modify
+		return nil
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := w.Status()
+	if err != nil {
+		return err
+	}
+	if !status.IsClean() {
+		return fmt.Errorf("%w: %s", ErrRepoDirty, repo.Name)
+	}
+	return nil
+}
+
+// heartbeat records the hash as the last commit made by repodb, in strict mode
+func (repo *Repo) heartbeat(r *git.Repository, hash plumbing.Hash) error {
+	if !repo.DB.strict {
+		return nil
+	}
+	return r.Storer.SetReference(plumbing.NewHashReference(heartbeatRef, hash))
+}
@@ -0,0 +1,65 @@
+package repodb_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWithStrictIntegrity(t *testing.T) {
+	db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithStrictIntegrity())
+	repo := &repodb.Repo{Name: "StrictRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "strict.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	// uncommitted external change
+	external := path.Join(repo.Dir(), "external.txt")
+	if err := ioutil.WriteFile(external, []byte("external"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile(fr, strings.NewReader("b"), repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrRepoDirty) || !errors.Is(err, repodb.ErrExternalModification) {
+		t.Errorf("Repo.WriteFile() with dirty worktree error = %v, want %v", err, repodb.ErrRepoDirty)
+	}
+
+	// external commit moves head
+	g, err := git.PlainOpen(repo.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := g.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add("external.txt"); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "external", When: time.Now()}
+	if _, err := w.Commit("external", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); !errors.Is(err, repodb.ErrExternalModification) {
+		t.Errorf("Repo.WriteMeta() after external commit error = %v, want %v", err, repodb.ErrExternalModification)
+	}
+
+	// without strict mode the external commit is accepted
+	lenient, err := repodb.NewDB(db.Dir()).OpenRepo("StrictRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lenient.WriteFile(fr, strings.NewReader("c"), repodb.DBRepoCommitOptions); err != nil {
+		t.Errorf("Repo.WriteFile() without strict mode error = %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package repodb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"os"
+)
+
+// TailFile streams changes to the record file to w until ctx is done, like tail -f.
+// Content appended to the file is written as it lands, a version that replaces rather
+// than extends the previous one is written in full. Content present when TailFile is
+// called is not written, use ReadFile first to show it. Changes are detected with
+// Watch, so writes by other processes are followed too. Returns ctx.Err() once done.
+func (repo *Repo) TailFile(ctx context.Context, rec Record, w io.Writer) error {
+	if err := repo.authorize(ActionRead, rec); err != nil {
+		return err
+	}
+	events, err := repo.DB.Watch(ctx)
+	if err != nil {
+		return err
+	}
+	t := &tail{repo: repo, rec: rec, sum: sha256.New()}
+	if _, err := t.read(); err != nil {
+		return err
+	}
+	for ev := range events {
+		if ev.Repo != repo.Name || ev.Folder != rec.Folder() || ev.Name != rec.FileName() {
+			continue
+		}
+		b, err := t.read()
+		if err != nil {
+			repo.DB.warn("unable to tail record", "repo", repo.Name, "record", rec.FileName(), "err", err)
+			continue
+		}
+		if len(b) > 0 {
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// tail tracks the size and hash of the record file content already streamed
+type tail struct {
+	repo *Repo
+	rec  Record
+	size int64
+	sum  hash.Hash
+}
+
+// read returns the content that is new since the last read. Content is appended if
+// the file still starts with the previously read content, otherwise the whole file is
+// new. A missing file resets the tail.
+func (t *tail) read() ([]byte, error) {
+	t.repo.RLock()
+	defer t.repo.RUnlock()
+
+	f, err := t.repo.openFile(t.rec)
+	if errors.Is(err, os.ErrNotExist) {
+		t.size, t.sum = 0, sha256.New()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { f.Close() }()
+
+	h := sha256.New()
+	n, err := io.CopyN(h, f, t.size)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n < t.size || !bytes.Equal(h.Sum(nil), t.sum.Sum(nil)) {
+		// replaced by a new version, read it from the start
+		f.Close()
+		if f, err = t.repo.openFile(t.rec); err != nil {
+			return nil, err
+		}
+		t.size, h = 0, sha256.New()
+	}
+	buf := &bytes.Buffer{}
+	n, err = io.Copy(io.MultiWriter(buf, h), f)
+	if err != nil {
+		return nil, err
+	}
+	t.size += n
+	t.sum = h
+	return buf.Bytes(), nil
+}
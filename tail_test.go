@@ -0,0 +1,81 @@
+package repodb_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRepo_TailFile(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "TailRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	rec := &FileRecord{Name: "app.log"}
+	write := func(content string) {
+		t.Helper()
+		if err := repo.WriteFile(rec, strings.NewReader(content), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("one\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() { done <- repo.TailFile(ctx, rec, out) }()
+	// let the tail read the existing content before changing it
+	time.Sleep(200 * time.Millisecond)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"appended", "one\ntwo\n", "two\n"},
+		{"appended again", "one\ntwo\nthree\n", "two\nthree\n"},
+		{"replaced", "new\n", "two\nthree\nnew\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write(tt.content)
+			timeout := time.After(5 * time.Second)
+			for out.String() != tt.want {
+				select {
+				case <-timeout:
+					t.Fatalf("Repo.TailFile() = %q, want %q", out.String(), tt.want)
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		})
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Repo.TailFile() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,89 @@
+package repodb
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"time"
+)
+
+// RepoTemplate is the initial content of the repos created by CreateRepoFromTemplate,
+// such as what every project repo starts with. A template may be used for many repos.
+type RepoTemplate struct {
+	// Description, Labels, Compression and MaxSize are defaults for repo fields left
+	// unset, labels of the repo take precedence over those of the template
+	Description string
+	Labels      map[string]string
+	Compression string
+	MaxSize     int64
+
+	// Folders are created in the worktree. Git does not track empty folders, so they
+	// are not committed until they hold a record.
+	Folders []string
+	// Records are written in the initial commit with the repo meta-data
+	Records []SeedRecord
+}
+
+// SeedRecord is a record written to repos created from a RepoTemplate
+type SeedRecord struct {
+	Record  Record
+	Content []byte // content of the record file, nil for none
+	Meta    bool   // write the record meta-data
+}
+
+// CreateRepoFromTemplate creates the repo like CreateRepo, with the defaults, folders and
+// seed records of the template, committed as the initial commit. If a seed record
+// cannot be written the repo is removed.
+func (db *RepoDB) CreateRepoFromTemplate(repo *Repo, tmpl RepoTemplate) (err error) {
+	defer db.metrics.observe("create_repo_from_template", time.Now(), &err)
+	if repo == nil {
+		return fmt.Errorf("CreateRepoFromTemplate repo pointer cannot be nil")
+	}
+	for _, folder := range tmpl.Folders {
+		if err := checkFolder(folder); err != nil {
+			return err
+		}
+	}
+
+	if repo.Description == "" {
+		repo.Description = tmpl.Description
+	}
+	if repo.Compression == "" {
+		repo.Compression = tmpl.Compression
+	}
+	if repo.MaxSize == 0 {
+		repo.MaxSize = tmpl.MaxSize
+	}
+	if len(tmpl.Labels) > 0 {
+		labels := make(map[string]string, len(tmpl.Labels)+len(repo.Labels))
+		for k, v := range tmpl.Labels {
+			labels[k] = v
+		}
+		for k, v := range repo.Labels {
+			labels[k] = v
+		}
+		repo.Labels = labels
+	}
+
+	records := []RecordData{{Record: repo, Meta: true}}
+	for _, s := range tmpl.Records {
+		d := RecordData{Record: s.Record, Meta: s.Meta}
+		if s.Content != nil {
+			d.Content = bytes.NewReader(s.Content)
+		}
+		records = append(records, d)
+	}
+	if err := db.createRepo(repo, records); err != nil {
+		return err
+	}
+
+	if db.bare {
+		return nil
+	}
+	for _, folder := range tmpl.Folders {
+		if err := db.fs.MkdirAll(path.Join(repo.Dir(), folder), 0700); err != nil {
+			return fmt.Errorf("unable to make folder %s: %v", folder, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package repodb_test
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_CreateRepoFromTemplate(t *testing.T) {
+	db := newTestDB(t)
+	tmpl := repodb.RepoTemplate{
+		Description: "project",
+		Labels:      map[string]string{"kind": "project", "owner": "nobody"},
+		Folders:     []string{"docs", "files/archive"},
+		Records: []repodb.SeedRecord{
+			{Record: &FileRecord{Name: "README.txt"}, Content: []byte("readme"), Meta: true},
+			{Record: &FileRecord{Name: "settings.txt"}, Meta: true},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		repo    *repodb.Repo
+		desc    string
+		owner   string
+		wantErr bool
+	}{
+		{"defaults", &repodb.Repo{Name: "ProjectA", DB: db}, "project", "nobody", false},
+		{"overrides", &repodb.Repo{Name: "ProjectB", DB: db, Description: "b", Labels: map[string]string{"owner": "b"}}, "b", "b", false},
+		{"exists", &repodb.Repo{Name: "ProjectA", DB: db}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.CreateRepoFromTemplate(tt.repo, tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RepoDB.CreateRepoFromTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			repo, err := db.OpenRepo(tt.repo.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repo.Description != tt.desc || repo.Labels["owner"] != tt.owner || repo.Labels["kind"] != "project" {
+				t.Errorf("RepoDB.CreateRepoFromTemplate() Description = %q, Labels = %v", repo.Description, repo.Labels)
+			}
+			history, err := repo.History(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 1 {
+				t.Errorf("RepoDB.CreateRepoFromTemplate() commits = %d, want 1", len(history))
+			}
+			buf := &bytes.Buffer{}
+			if _, err := repo.ReadFile(&FileRecord{Name: "README.txt"}, buf); err != nil || buf.String() != "readme" {
+				t.Errorf("Repo.ReadFile() seed = %q, %v, want %q", buf.String(), err, "readme")
+			}
+			if err := repo.LoadMeta(&FileRecord{Name: "settings.txt"}); err != nil {
+				t.Errorf("Repo.LoadMeta() seed error = %v", err)
+			}
+			for _, folder := range tmpl.Folders {
+				if fi, err := os.Stat(path.Join(repo.Dir(), folder)); err != nil || !fi.IsDir() {
+					t.Errorf("RepoDB.CreateRepoFromTemplate() folder %s missing: %v", folder, err)
+				}
+			}
+		})
+	}
+
+	// a repo whose seed records fail is not created
+	bad := repodb.RepoTemplate{Records: []repodb.SeedRecord{{Record: &FileRecord{Name: "none.txt"}}}}
+	if err := db.CreateRepoFromTemplate(&repodb.Repo{Name: "Bad", DB: db}, bad); err == nil {
+		t.Errorf("RepoDB.CreateRepoFromTemplate() expected error for empty seed record")
+	}
+	if _, err := db.OpenRepo("Bad"); err == nil {
+		t.Errorf("RepoDB.OpenRepo() repo of failed template exists")
+	}
+}
@@ -0,0 +1,55 @@
+package repodb
+
+import (
+	"time"
+)
+
+// TimestampedRecord is a Record whose meta-data timestamps are maintained by WriteMeta.
+// The created time is set when zero, and the updated time on every write. Load the
+// record with LoadMeta before changing it, so the created time is kept.
+type TimestampedRecord interface {
+	Record
+	Timestamps() (created, updated time.Time)
+	SetTimestamps(created, updated time.Time)
+}
+
+// Timestamps returns CreatedOn and UpdatedOn. Implements TimestampedRecord.
+func (repo *Repo) Timestamps() (created, updated time.Time) {
+	return repo.CreatedOn, repo.UpdatedOn
+}
+
+// SetTimestamps sets CreatedOn and UpdatedOn. Implements TimestampedRecord.
+func (repo *Repo) SetTimestamps(created, updated time.Time) {
+	repo.CreatedOn, repo.UpdatedOn = created, updated
+}
+
+// stamp sets the timestamps of the record for a write at now, if it is a
+// TimestampedRecord
+func stamp(rec Record, now time.Time) {
+	tr, ok := rec.(TimestampedRecord)
+	if !ok {
+		return
+	}
+	created, _ := tr.Timestamps()
+	if created.IsZero() {
+		created = now
+	}
+	tr.SetTimestamps(created, now)
+}
+
+// headTime returns the time of the HEAD commit, the caller must hold the repo lock
+func (repo *Repo) headTime() (time.Time, error) {
+	r, err := repo.git()
+	if err != nil {
+		return time.Time{}, err
+	}
+	head, err := repo.head()
+	if err != nil {
+		return time.Time{}, err
+	}
+	c, err := r.CommitObject(head)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Committer.When, nil
+}
@@ -0,0 +1,74 @@
+package repodb_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+// stampedRecord is a TimestampedRecord
+type stampedRecord struct {
+	Name      string
+	CreatedOn time.Time
+	UpdatedOn time.Time
+}
+
+func (r *stampedRecord) FileName() string { return r.Name }
+func (r *stampedRecord) Folder() string   { return "stamped" }
+
+func (r *stampedRecord) Timestamps() (created, updated time.Time) {
+	return r.CreatedOn, r.UpdatedOn
+}
+
+func (r *stampedRecord) SetTimestamps(created, updated time.Time) {
+	r.CreatedOn, r.UpdatedOn = created, updated
+}
+
+func TestTimestamps(t *testing.T) {
+	db := newTestDB(t)
+	start := time.Now()
+	repo := &repodb.Repo{Name: "Stamped", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if repo.CreatedOn.Before(start) || repo.UpdatedOn.Before(repo.CreatedOn) {
+		t.Errorf("RepoDB.CreateRepo() CreatedOn = %v, UpdatedOn = %v, want after %v", repo.CreatedOn, repo.UpdatedOn, start)
+	}
+	created := repo.CreatedOn
+
+	time.Sleep(10 * time.Millisecond)
+	if err := repo.WriteFile(&FileRecord{Name: "a.txt"}, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.UpdatedOn.After(created) {
+		t.Errorf("Repo.UpdatedOn = %v after WriteFile, want after %v", repo.UpdatedOn, created)
+	}
+	opened, err := db.OpenRepo("Stamped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opened.CreatedOn.Equal(created) || opened.UpdatedOn.Before(repo.UpdatedOn.Truncate(time.Second)) {
+		t.Errorf("RepoDB.OpenRepo() CreatedOn = %v, UpdatedOn = %v, want %v and %v", opened.CreatedOn, opened.UpdatedOn, created, repo.UpdatedOn)
+	}
+
+	rec := &stampedRecord{Name: "rec"}
+	if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if rec.CreatedOn.IsZero() || !rec.UpdatedOn.Equal(rec.CreatedOn) {
+		t.Fatalf("Repo.WriteMeta() CreatedOn = %v, UpdatedOn = %v", rec.CreatedOn, rec.UpdatedOn)
+	}
+	time.Sleep(10 * time.Millisecond)
+	loaded := &stampedRecord{Name: "rec"}
+	if err := repo.LoadMeta(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteMeta(loaded, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.CreatedOn.Equal(rec.CreatedOn) || !loaded.UpdatedOn.After(rec.UpdatedOn) {
+		t.Errorf("Repo.WriteMeta() CreatedOn = %v, UpdatedOn = %v, want %v and after %v", loaded.CreatedOn, loaded.UpdatedOn, rec.CreatedOn, rec.UpdatedOn)
+	}
+}
@@ -0,0 +1,99 @@
+package repodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VacuumReport lists the meta-data files found by VacuumMeta, as slash separated
+// paths relative to the repo directory.
+type VacuumReport struct {
+	RemovedTemp []string // stale temp files removed
+	Invalid     []string // meta-data files that are not valid json
+	Repaired    bool     // invalid files were removed
+}
+
+// VacuumMeta removes stale temp files left in meta-data directories by interrupted
+// writes and validates every meta-data file parses as json. Invalid files are
+// reported, and removed if repair is true; they remain recoverable from history.
+// Changes are committed with opts.
+func (repo *Repo) VacuumMeta(repair bool, opts CommitOptions) (report *VacuumReport, err error) {
+	defer repo.DB.metrics.observe("vacuum_meta", time.Now(), &err)
+	action := ActionWrite
+	if repair {
+		action = ActionDelete
+	}
+	if err := repo.authorize(action, nil); err != nil {
+		return nil, err
+	}
+	repo.DB.metrics.lock("repo", repo)
+	defer repo.Unlock()
+	if err := repo.prepareWrite(); err != nil {
+		return nil, err
+	}
+
+	report = &VacuumReport{}
+	root := repo.Dir()
+	err = repo.DB.walk(root, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path.Base(path.Dir(name)) != MetaDir {
+			return nil
+		}
+		rel := relPath(root, name)
+
+		// writes are made under the repo lock, any temp file now is from an interrupted write
+		if strings.HasSuffix(name, ".tmp") {
+			if err := repo.DB.fs.Remove(name); err != nil {
+				return err
+			}
+			report.RemovedTemp = append(report.RemovedTemp, rel)
+			return nil
+		}
+
+		b, err := repo.DB.readFile(name)
+		if err != nil {
+			return err
+		}
+		// attribute files are always json, meta-data files are of the DB codec
+		var v interface{}
+		if strings.HasSuffix(name, repo.DB.metaExt()) && repo.DB.MetaCodec().Unmarshal(b, &v) == nil ||
+			strings.HasSuffix(name, attrSuffix) && json.Valid(b) {
+			return nil
+		}
+		report.Invalid = append(report.Invalid, rel)
+		if repair {
+			return repo.DB.fs.Remove(name)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("unable to vacuum meta-data for %s: %v", repo.Name, err)
+	}
+	report.Repaired = repair && len(report.Invalid) > 0
+
+	if len(report.RemovedTemp) == 0 && !report.Repaired {
+		return report, nil
+	}
+	opts.Msg = repo.DB.commitMessage(CommitMessage{
+		Operation: OpVacuumMeta,
+		Message:   opts.Msg,
+		Detail:    fmt.Sprintf("vacuumed meta-data, removed %d temp files", len(report.RemovedTemp)),
+	})
+	if report.Repaired {
+		opts.Msg += fmt.Sprintf(" and %d invalid files: %s", len(report.Invalid), strings.Join(report.Invalid, ", "))
+	}
+	return report, repo.commit(OpVacuumMeta, nil, opts)
+}
@@ -0,0 +1,57 @@
+package repodb_test
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_VacuumMeta(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "VacuumRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	fr := &FileRecord{Name: "vacuumed.txt"}
+	if err := repo.WriteMeta(fr, repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate an interrupted write and a corrupt meta-data file
+	metaDir := path.Join(repo.Dir(), fr.Folder(), repodb.MetaDir)
+	if err := ioutil.WriteFile(path.Join(metaDir, "stale.json.tmp"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(metaDir, "corrupt.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := repo.VacuumMeta(false, repodb.DBRepoCommitOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.RemovedTemp) != 1 || len(report.Invalid) != 1 || report.Repaired {
+		t.Errorf("Repo.VacuumMeta() = %+v", report)
+	}
+
+	report, err = repo.VacuumMeta(true, repodb.DBRepoCommitOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.RemovedTemp) != 0 || len(report.Invalid) != 1 || !report.Repaired {
+		t.Errorf("Repo.VacuumMeta() repair = %+v", report)
+	}
+
+	report, err = repo.VacuumMeta(true, repodb.DBRepoCommitOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Invalid) != 0 {
+		t.Errorf("Repo.VacuumMeta() after repair = %+v", report)
+	}
+	if err := repo.LoadMeta(fr); err != nil {
+		t.Errorf("Repo.LoadMeta() after vacuum error = %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package repodb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+// ErrValidation is returned when a Validator rejects a record write
+var ErrValidation = errors.New("record failed validation")
+
+// ValidationError is returned by writes rejected by a Validator, before anything is
+// written or committed
+type ValidationError struct {
+	Record string // slash separated record path in the repo
+	Err    error  // reason the record was rejected
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %s: %v", ErrValidation, e.Record, e.Err)
+}
+
+// Unwrap returns ErrValidation
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// Validator checks records before they are written, such as against a JSON Schema.
+// Returning an error rejects the write.
+type Validator interface {
+	// ValidateMeta checks the meta-data of WriteMeta, as encoded for the meta-data file
+	ValidateMeta(rec Record, meta []byte) error
+	// ValidateFile checks the content of WriteFile, r need not be read to the end
+	ValidateFile(rec Record, r io.Reader) error
+}
+
+// WithValidator validates the records in the folders, or in every folder if none are
+// given. Validators of every folder run first, then those of the record folder, each in
+// the order added. Meta-data of the repo and of internal records is not validated.
+func WithValidator(v Validator, folders ...string) Option {
+	return func(db *RepoDB) {
+		if db.validators == nil {
+			db.validators = make(map[string][]Validator)
+		}
+		if len(folders) == 0 {
+			folders = []string{""}
+		}
+		for _, folder := range folders {
+			db.validators[folder] = append(db.validators[folder], v)
+		}
+	}
+}
+
+// recordValidators returns the validators of the record
+func (db *RepoDB) recordValidators(rec Record) []Validator {
+	switch rec.(type) {
+	case *Repo, *Hold, *Stats, *Deletion, *Expiry:
+		return nil
+	}
+	var vs []Validator
+	vs = append(vs, db.validators[""]...)
+	return append(vs, db.validators[rec.Folder()]...)
+}
+
+// validateMeta checks the encoded meta-data of the record with its validators
+func (db *RepoDB) validateMeta(rec Record, b []byte) error {
+	for _, v := range db.recordValidators(rec) {
+		if err := v.ValidateMeta(rec, b); err != nil {
+			return &ValidationError{Record: path.Join(rec.Folder(), rec.FileName()), Err: err}
+		}
+	}
+	return nil
+}
+
+// validateFile spools the content of a write and checks it with the validators of the
+// record, if any. The returned reader reads the spooled content, and done removes it.
+// The caller must hold the repo lock.
+func (repo *Repo) validateFile(rec Record, r io.Reader) (validated io.Reader, done func(), err error) {
+	vs := repo.DB.recordValidators(rec)
+	if len(vs) == 0 {
+		return r, func() {}, nil
+	}
+
+	tmp, tmpName, err := repo.DB.tempFile(repo.spoolDir(), "repodb-validate-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to spool %s: %v", rec.FileName(), err)
+	}
+	done = func() {
+		tmp.Close()
+		repo.DB.fs.Remove(tmpName)
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		done()
+		return nil, nil, fmt.Errorf("unable to spool %s: %v", rec.FileName(), err)
+	}
+	for _, v := range vs {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			done()
+			return nil, nil, err
+		}
+		if err := v.ValidateFile(rec, tmp); err != nil {
+			done()
+			return nil, nil, &ValidationError{Record: path.Join(rec.Folder(), rec.FileName()), Err: err}
+		}
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		done()
+		return nil, nil, err
+	}
+	return tmp, done, nil
+}
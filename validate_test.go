@@ -0,0 +1,95 @@
+package repodb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+// requiredName rejects meta-data without a name and files without content
+type requiredName struct{}
+
+func (requiredName) ValidateMeta(rec repodb.Record, meta []byte) error {
+	var m struct{ Name string }
+	if err := json.Unmarshal(meta, &m); err != nil {
+		return err
+	}
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	return nil
+}
+
+func (requiredName) ValidateFile(rec repodb.Record, r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return fmt.Errorf("content is required")
+	}
+	return nil
+}
+
+// namedRecord keeps its name out of the meta-data when empty
+type namedRecord struct {
+	File string `json:"-"`
+	Name string `json:",omitempty"`
+}
+
+func (r *namedRecord) FileName() string { return r.File }
+func (r *namedRecord) Folder() string   { return "named" }
+
+func TestWithValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		folders []string
+		wantErr bool
+	}{
+		{"every folder", nil, true},
+		{"record folder", []string{"named"}, true},
+		{"other folder", []string{"files"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repodb.NewDB(newTestDB(t).Dir(), repodb.WithValidator(requiredName{}, tt.folders...))
+			repo := &repodb.Repo{Name: "Validated", DB: db}
+			if err := db.CreateRepo(repo); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := &namedRecord{File: "rec"}
+			err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions)
+			var verr *repodb.ValidationError
+			if (err != nil) != tt.wantErr || tt.wantErr && (!errors.Is(err, repodb.ErrValidation) || !errors.As(err, &verr) || verr.Record != "named/rec") {
+				t.Errorf("Repo.WriteMeta() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			err = repo.WriteFile(rec, strings.NewReader(""), repodb.DBRepoCommitOptions)
+			if (err != nil) != tt.wantErr || tt.wantErr && !errors.Is(err, repodb.ErrValidation) {
+				t.Errorf("Repo.WriteFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && (repo.FileExists(rec) || repo.LoadMeta(&namedRecord{File: "rec"}) == nil) {
+				t.Errorf("rejected record was written")
+			}
+
+			// valid records are written with their content
+			rec.Name = "valid"
+			if err := repo.WriteMeta(rec, repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteFile(rec, strings.NewReader("content"), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			buf := &strings.Builder{}
+			if _, err := repo.ReadFile(rec, buf); err != nil || buf.String() != "content" {
+				t.Errorf("Repo.ReadFile() = %q, error = %v", buf, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package repodb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"golang.org/x/crypto/openpgp"
+)
+
+// signature verification errors
+var (
+	ErrUnsignedCommit   = errors.New("commit is not signed")
+	ErrUnverifiedCommit = errors.New("commit signature cannot be verified")
+)
+
+// WithVerifiedHead makes OpenRepo refuse to open repos whose HEAD commit is unsigned,
+// or not signed by a key in the armored PGP key ring. Commits are signed by setting
+// SignKey in the CommitOptions used for writes, or for every commit WithSignKey.
+func WithVerifiedHead(armoredKeyRing string) Option {
+	return func(db *RepoDB) {
+		db.headKeyRing = armoredKeyRing
+	}
+}
+
+// WithSignKey signs the commits of the DB with key when no SignKey is set in their
+// CommitOptions, including commits of the DB itself such as of repo meta-data, legal
+// holds, merges, squashed history and bare repos, and the tags of snapshots. Use it
+// with WithVerifiedHead so the repos of the DB can be opened.
+func WithSignKey(key *openpgp.Entity) Option {
+	return func(db *RepoDB) {
+		db.signKey = key
+	}
+}
+
+// storeCommit stores the commit in the repository, signed with key if any
+func storeCommit(s storer.EncodedObjectStorer, c *object.Commit, key *openpgp.Entity) (plumbing.Hash, error) {
+	if key != nil {
+		encoded := &plumbing.MemoryObject{}
+		if err := c.EncodeWithoutSignature(encoded); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		r, err := encoded.Reader()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		sig := &bytes.Buffer{}
+		if err := openpgp.ArmoredDetachSign(sig, key, r, nil); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("unable to sign commit: %v", err)
+		}
+		c.PGPSignature = sig.String()
+	}
+	return storeObject(s, c)
+}
+
+// VerifyHistory walks the commit log from HEAD and verifies every commit is signed by
+// a key in the armored PGP key ring. Returns an error wrapping ErrUnsignedCommit or
+// ErrUnverifiedCommit for the newest commit failing verification.
+func (repo *Repo) VerifyHistory(armoredKeyRing string) error {
+	if err := repo.authorize(ActionRead, nil); err != nil {
+		return err
+	}
+	repo.RLock()
+	defer repo.RUnlock()
+
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	iter, err := r.Log(&git.LogOptions{})
+	if err != nil {
+		return err
+	}
+	return iter.ForEach(func(c *object.Commit) error {
+		return verifyCommit(c, armoredKeyRing)
+	})
+}
+
+// verifyHead verifies the HEAD commit signature of the repo
+func (repo *Repo) verifyHead(armoredKeyRing string) error {
+	r, err := repo.git()
+	if err != nil {
+		return err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	return verifyCommit(c, armoredKeyRing)
+}
+
+// verifyCommit checks the commit is signed by a key in the key ring
+func verifyCommit(c *object.Commit, armoredKeyRing string) error {
+	if c.PGPSignature == "" {
+		return fmt.Errorf("commit %s: %w", c.Hash, ErrUnsignedCommit)
+	}
+	if _, err := c.Verify(armoredKeyRing); err != nil {
+		return fmt.Errorf("commit %s: %w: %v", c.Hash, ErrUnverifiedCommit, err)
+	}
+	return nil
+}
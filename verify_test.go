@@ -0,0 +1,110 @@
+package repodb_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepo_VerifyHistory(t *testing.T) {
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "UnsignedRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.VerifyHistory(""); !errors.Is(err, repodb.ErrUnsignedCommit) {
+		t.Errorf("Repo.VerifyHistory() error = %v, want %v", err, repodb.ErrUnsignedCommit)
+	}
+
+	verifiedDB := repodb.NewDB(db.Dir(), repodb.WithVerifiedHead("keys"))
+	if _, err := verifiedDB.OpenRepo("UnsignedRepo"); !errors.Is(err, repodb.ErrUnsignedCommit) {
+		t.Errorf("RepoDB.OpenRepo() error = %v, want %v", err, repodb.ErrUnsignedCommit)
+	}
+}
+
+// newSignKey returns a new PGP key and its armored public key ring
+func newSignKey(t *testing.T, email string) (*openpgp.Entity, string) {
+	t.Helper()
+	key, err := openpgp.NewEntity("repodb", "", email, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := key.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return key, buf.String()
+}
+
+func TestWithSignKey(t *testing.T) {
+	key, keyRing := newSignKey(t, "signer@example.com")
+	_, otherKeyRing := newSignKey(t, "other@example.com")
+
+	for _, bare := range []bool{false, true} {
+		opts := []repodb.Option{repodb.WithSignKey(key), repodb.WithVerifiedHead(keyRing)}
+		if bare {
+			opts = append(opts, repodb.WithBareRepos())
+		}
+		db := repodb.NewDB(newTestDB(t).Dir(), opts...)
+		repo := &repodb.Repo{Name: "SignedRepo", DB: db}
+		if err := db.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+		rec := &FileRecord{Name: "a.txt"}
+		if err := repo.WriteFile(rec, strings.NewReader("a"), repodb.DBRepoCommitOptions); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.LegalHold(rec, "case"); err != nil {
+			t.Fatal(err)
+		}
+		if !bare {
+			// merges are stored by the library, not by a worktree commit
+			if err := repo.CreateBranch("draft"); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.CheckoutBranch("draft"); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteFile(&FileRecord{Name: "b.txt"}, strings.NewReader("b"), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.CheckoutBranch("master"); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.WriteFile(&FileRecord{Name: "c.txt"}, strings.NewReader("c"), repodb.DBRepoCommitOptions); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.Merge("draft", "master", repodb.MergeAuto); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := repo.VerifyHistory(keyRing); err != nil {
+			t.Errorf("Repo.VerifyHistory() bare %v error = %v", bare, err)
+		}
+		if _, err := db.OpenRepo("SignedRepo"); err != nil {
+			t.Errorf("RepoDB.OpenRepo() bare %v error = %v", bare, err)
+		}
+		if err := repo.VerifyHistory(otherKeyRing); !errors.Is(err, repodb.ErrUnverifiedCommit) {
+			t.Errorf("Repo.VerifyHistory() unknown key bare %v error = %v, want %v", bare, err, repodb.ErrUnverifiedCommit)
+		}
+		otherDB := repodb.NewDB(db.Dir(), repodb.WithVerifiedHead(otherKeyRing))
+		if _, err := otherDB.OpenRepo("SignedRepo"); !errors.Is(err, repodb.ErrUnverifiedCommit) {
+			t.Errorf("RepoDB.OpenRepo() unknown key bare %v error = %v, want %v", bare, err, repodb.ErrUnverifiedCommit)
+		}
+	}
+}
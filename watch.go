@@ -0,0 +1,199 @@
+package repodb
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// EventOp is the kind of change reported by Watch.
+type EventOp int
+
+// change event operations
+const (
+	RepoCreated EventOp = iota + 1
+	RepoRemoved
+	RecordWritten
+	RecordRemoved
+)
+
+// String returns a human readable name for the operation
+func (op EventOp) String() string {
+	switch op {
+	case RepoCreated:
+		return "repo created"
+	case RepoRemoved:
+		return "repo removed"
+	case RecordWritten:
+		return "record written"
+	case RecordRemoved:
+		return "record removed"
+	}
+	return fmt.Sprintf("EventOp(%d)", int(op))
+}
+
+// Event is a change to the database directory detected by Watch.
+// Folder and Name are empty for repo events.
+type Event struct {
+	Op     EventOp
+	Repo   string
+	Folder string
+	Name   string
+}
+
+// Watch starts watching the database directory for changes and returns a channel of
+// events. Changes made by other processes are reported as well as those made through
+// this RepoDB. Git internals and meta-data files are not reported, and a single write
+// may be reported more than once. The channel is closed once ctx is done.
+func (db *RepoDB) Watch(ctx context.Context) (<-chan Event, error) {
+	if err := db.checkOS("Watch"); err != nil {
+		return nil, err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create watcher: %v", err)
+	}
+	if err := w.Add(db.dir); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("unable to watch %s: %v", db.dir, err)
+	}
+	fileInfos, err := ioutil.ReadDir(db.dir)
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("unable to read %s: %v", db.dir, err)
+	}
+	for _, f := range fileInfos {
+		if f.IsDir() && !reservedDir(f.Name()) {
+			db.watchRepo(w, path.Join(db.dir, f.Name()))
+		}
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case fe, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				for _, ev := range db.translate(w, fe) {
+					select {
+					case events <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				db.warn("watch error", "dir", db.dir, "err", err)
+			}
+		}
+	}()
+	return events, nil
+}
+
+// watchRepo adds the repo directory and its record folders to the watcher
+func (db *RepoDB) watchRepo(w *fsnotify.Watcher, dir string) []Event {
+	w.Add(dir)
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var events []Event
+	for _, f := range fileInfos {
+		if f.IsDir() && !ignoredDir(f.Name()) {
+			events = append(events, db.watchFolder(w, path.Base(dir), f.Name(), path.Join(dir, f.Name()))...)
+		}
+	}
+	return events
+}
+
+// watchFolder adds the record folder and its sub-folders to the watcher. Records
+// already in the folders are returned as written, as they may have been created before
+// the watch was added.
+func (db *RepoDB) watchFolder(w *fsnotify.Watcher, repo, folder, dir string) []Event {
+	w.Add(dir)
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var events []Event
+	for _, f := range fileInfos {
+		switch {
+		case f.IsDir() && !ignoredDir(f.Name()):
+			events = append(events, db.watchFolder(w, repo, path.Join(folder, f.Name()), path.Join(dir, f.Name()))...)
+		case f.IsDir() || strings.HasSuffix(f.Name(), ".tmp"):
+		default:
+			events = append(events, Event{Op: RecordWritten, Repo: repo, Folder: folder, Name: f.Name()})
+		}
+	}
+	return events
+}
+
+// translate converts a raw fsnotify event to zero or more Events, adding watches for
+// newly created repos and folders.
+func (db *RepoDB) translate(w *fsnotify.Watcher, fe fsnotify.Event) []Event {
+	rel, err := filepath.Rel(db.dir, fe.Name)
+	if err != nil || rel == "." {
+		return nil
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if strings.HasPrefix(parts[0], ".") {
+		// internal files in the database directory, such as indexes
+		return nil
+	}
+	for _, p := range parts[1:] {
+		if ignoredDir(p) {
+			return nil
+		}
+	}
+	isDir := false
+	if fi, err := os.Stat(fe.Name); err == nil {
+		isDir = fi.IsDir()
+	}
+
+	if len(parts) == 1 {
+		switch {
+		case fe.Op&fsnotify.Create != 0 && isDir:
+			events := []Event{{Op: RepoCreated, Repo: parts[0]}}
+			return append(events, db.watchRepo(w, fe.Name)...)
+		case fe.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+			return []Event{{Op: RepoRemoved, Repo: parts[0]}}
+		}
+		return nil
+	}
+	if fe.Op&fsnotify.Create != 0 && isDir {
+		return db.watchFolder(w, parts[0], path.Join(parts[1:]...), fe.Name)
+	}
+	if len(parts) > 2 {
+		last := len(parts) - 1
+		ev := Event{Repo: parts[0], Folder: path.Join(parts[1:last]...), Name: parts[last]}
+		switch {
+		case strings.HasSuffix(ev.Name, ".tmp"):
+		case fe.Op&(fsnotify.Create|fsnotify.Write) != 0 && !isDir:
+			ev.Op = RecordWritten
+			return []Event{ev}
+		case fe.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+			ev.Op = RecordRemoved
+			return []Event{ev}
+		}
+	}
+	return nil
+}
+
+// ignoredDir reports if the directory name is internal to the repo and not watched
+func ignoredDir(name string) bool {
+	return name == ".git" || name == MetaDir
+}
@@ -0,0 +1,63 @@
+package repodb_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readpe/repodb"
+)
+
+func TestRepoDB_Watch(t *testing.T) {
+	db := newTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := db.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for an event matching op, skipping any others
+	wait := func(op repodb.EventOp) repodb.Event {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case ev := <-events:
+				if ev.Op == op {
+					return ev
+				}
+			case <-timeout:
+				t.Fatalf("RepoDB.Watch() timed out waiting for %v", op)
+			}
+		}
+	}
+
+	repo := &repodb.Repo{Name: "WatchRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if ev := wait(repodb.RepoCreated); ev.Repo != "WatchRepo" {
+		t.Errorf("RepoDB.Watch() Repo = %v, want %v", ev.Repo, "WatchRepo")
+	}
+
+	fr := &FileRecord{Name: "watched.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("body"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	ev := wait(repodb.RecordWritten)
+	if ev.Folder != fr.Folder() || ev.Name != fr.FileName() {
+		t.Errorf("RepoDB.Watch() = %+v, want record %s/%s", ev, fr.Folder(), fr.FileName())
+	}
+
+	if err := db.RemoveRepo("WatchRepo"); err != nil {
+		t.Fatal(err)
+	}
+	wait(repodb.RepoRemoved)
+
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,152 @@
+package repodb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SignatureHeader is the request header carrying the HMAC-SHA256 signature of the
+// webhook payload, formatted as "sha256=<hex digest>".
+const SignatureHeader = "X-RepoDB-Signature"
+
+// Webhook is a registered webhook endpoint. If Secret is set the payload is signed
+// using HMAC-SHA256 and the signature sent in the SignatureHeader.
+type Webhook struct {
+	URL    string
+	Secret string
+}
+
+// WebhookDispatcher POSTs a JSON HookEvent payload to each registered webhook after
+// every commit and warning. Deliveries are made in the background, failed deliveries
+// are retried with exponential backoff. Add to a RepoDB using db.AddHook(d.Hook).
+type WebhookDispatcher struct {
+	Client  *http.Client
+	Retries int           // number of retries after the first failed attempt
+	Backoff time.Duration // wait before the first retry, doubled for each retry
+
+	// OnError is called, if set, when a delivery has failed all attempts
+	OnError func(wh Webhook, ev HookEvent, err error)
+
+	mu       sync.RWMutex
+	webhooks []Webhook
+	skipRepo string // events of this repo are not delivered, see DeliveryQueue
+	wg       sync.WaitGroup
+}
+
+// NewWebhookDispatcher returns a WebhookDispatcher with default client and retry settings
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		Retries: 3,
+		Backoff: time.Second,
+	}
+}
+
+// Register adds a webhook endpoint, secret may be empty for unsigned payloads
+func (d *WebhookDispatcher) Register(url, secret string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.webhooks = append(d.webhooks, Webhook{URL: url, Secret: secret})
+}
+
+// Unregister removes all webhooks for the url
+func (d *WebhookDispatcher) Unregister(url string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	webhooks := d.webhooks[:0]
+	for _, wh := range d.webhooks {
+		if wh.URL != url {
+			webhooks = append(webhooks, wh)
+		}
+	}
+	d.webhooks = webhooks
+}
+
+// Hook delivers the event to all registered webhooks in the background. Satisfies Hook.
+func (d *WebhookDispatcher) Hook(ev HookEvent) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.skipRepo != "" && ev.Repo == d.skipRepo {
+		return
+	}
+	for _, wh := range d.webhooks {
+		d.wg.Add(1)
+		go func(wh Webhook) {
+			defer d.wg.Done()
+			if err := d.deliver(wh, payload); err != nil && d.OnError != nil {
+				d.OnError(wh, ev, err)
+			}
+		}(wh)
+	}
+}
+
+// Wait blocks until all in progress deliveries have completed
+func (d *WebhookDispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// deliver POSTs the payload to the webhook, retrying on failure
+func (d *WebhookDispatcher) deliver(wh Webhook, payload []byte) error {
+	backoff := d.Backoff
+	var err error
+	for attempt := 0; attempt <= d.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = d.post(wh, payload); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook delivery to %s failed after %d attempts: %v", wh.URL, d.Retries+1, err)
+}
+
+// post makes a single delivery attempt, any non 2xx response is an error
+func (d *WebhookDispatcher) post(wh Webhook, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if wh.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(payload, wh.Secret))
+	}
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the webhook signature of the payload for the secret, in the format
+// sent in the SignatureHeader. Receivers can use it to verify payloads.
+func Sign(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,67 @@
+package repodb_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/readpe/repodb"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		got      []repodb.HookEvent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(repodb.SignatureHeader) != repodb.Sign(body, "secret") {
+			t.Errorf("WebhookDispatcher invalid signature %q", r.Header.Get(repodb.SignatureHeader))
+		}
+		// fail the first attempt to exercise retries
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var ev repodb.HookEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			t.Error(err)
+		}
+		got = append(got, ev)
+	}))
+	defer srv.Close()
+
+	d := repodb.NewWebhookDispatcher()
+	d.Backoff = 0
+	d.Register(srv.URL, "secret")
+
+	db := newTestDB(t)
+	repo := &repodb.Repo{Name: "WebhookRepo", DB: db}
+	if err := db.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	db.AddHook(d.Hook)
+
+	fr := &FileRecord{Name: "hooked.txt"}
+	if err := repo.WriteFile(fr, strings.NewReader("body"), repodb.DBRepoCommitOptions); err != nil {
+		t.Fatal(err)
+	}
+	d.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 {
+		t.Fatalf("WebhookDispatcher delivered %d events, want %d", len(got), 1)
+	}
+	if got[0].Repo != "WebhookRepo" || got[0].Record != "files/hooked.txt" || got[0].Hash == "" {
+		t.Errorf("WebhookDispatcher delivered %+v", got[0])
+	}
+}